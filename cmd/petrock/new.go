@@ -46,8 +46,11 @@ For component generation, use the subcommands:
   petrock new query <feature>/<name-of-thing>     - Generate query component  
   petrock new worker <feature>/<name-of-thing>    - Generate worker component
 
+Use --auth to include user registration, password login and cookie sessions.
+
 Examples:
   petrock new myblog github.com/youruser/myblog
+  petrock new --auth myblog github.com/youruser/myblog
   petrock new command posts/create
   petrock new query posts/get
   petrock new worker posts/summary`,
@@ -63,7 +66,7 @@ func init() {
 	newCmd.AddCommand(newQueryCmd())
 	newCmd.AddCommand(newWorkerCmd())
 	
-	// Add flags here if needed in the future (e.g., --template-set)
+	newCmd.Flags().Bool("auth", false, "Include the auth feature: user registration, password login and cookie sessions")
 }
 
 
@@ -185,6 +188,10 @@ func runNew(cmd *cobra.Command, args []string) error {
 	// Pass the embedded FS from the root petrock package
 	// Start copying from the 'internal/skeleton' directory within the embed FS
 	exclude := []string{"internal/skeleton/petrock_example_feature_name"}
+	withAuth, _ := cmd.Flags().GetBool("auth")
+	if !withAuth {
+		exclude = append(exclude, "internal/skeleton/auth")
+	}
 	fileCallback := func(operation, filePath string) {
 		cmdCtx.UI.ShowFileOperation(cmdCtx.Ctx, operation, filePath)
 	}
@@ -224,6 +231,13 @@ func runNew(cmd *cobra.Command, args []string) error {
 	}
 	// --- End Copy & Replace ---
 
+	if withAuth {
+		slog.Debug("Registering auth feature", "path", projectName)
+		if err := registerAuthFeature(projectName, modulePath); err != nil {
+			return err
+		}
+	}
+
 	// Tidy Go module dependencies (after go.mod and source files are created)
 	slog.Debug("Running go mod tidy", "path", projectName)
 	cmdCtx.UI.ShowProgress(cmdCtx.Ctx, ui.ProgressState{
@@ -256,6 +270,28 @@ func runNew(cmd *cobra.Command, args []string) error {
 	cmdCtx.UI.Present(cmdCtx.Ctx, ui.MessageTypeInfo, "\nNext steps:\n")
 	cmdCtx.UI.Present(cmdCtx.Ctx, ui.MessageTypeInfo, "  cd ./%s\n", projectName)
 	cmdCtx.UI.Present(cmdCtx.Ctx, ui.MessageTypeInfo, "  go run ./cmd/%s serve\n", projectName)
+	if withAuth {
+		cmdCtx.UI.Present(cmdCtx.Ctx, ui.MessageTypeInfo, "  open http://localhost:8080/register to create the first user\n")
+	}
+
+	return nil
+}
 
+// registerAuthFeature adds the auth feature, copied from the skeleton, to the project's
+// features.go, the same way `petrock feature` registers a generated feature.
+func registerAuthFeature(projectName, modulePath string) error {
+	featuresFilePath := filepath.Join(projectName, "cmd", projectName, "features.go")
+	content, err := os.ReadFile(featuresFilePath)
+	if err != nil {
+		return fmt.Errorf("failed to read features file %s: %w", featuresFilePath, err)
+	}
+	modified, err := insertFeatureRegistration(string(content), modulePath, "auth")
+	if err != nil {
+		return fmt.Errorf("failed to register auth feature: %w", err)
+	}
+	if err := os.WriteFile(featuresFilePath, []byte(modified), 0644); err != nil {
+		return fmt.Errorf("failed to write features file %s: %w", featuresFilePath, err)
+	}
+	cmdCtx.UI.ShowFileOperation(cmdCtx.Ctx, "update", featuresFilePath)
 	return nil
 }
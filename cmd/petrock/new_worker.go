@@ -48,6 +48,7 @@ Generated files:
   - <feature>/workers/main.go           - Worker registry and startup logic
   - <feature>/workers/types.go          - Worker state and type definitions
   - <feature>/workers/<name-of-thing>_worker.go - Entity-specific worker implementation
+  - <feature>/workers/<name-of-thing>_worker_test.go - Example test using core/workertest
 
 Common worker patterns:` + formatWorkerPatterns(),
 		Args: cobra.ExactArgs(1),
@@ -409,7 +409,25 @@ func (s *HTTPPostStep) Execute(ctx *TestContext) *StepResult {
 		result.AddLog("Testing POST request expected to succeed with status %d", s.expectedStatus)
 	}
 	
-	postResp, err := http.PostForm(s.url, s.formData)
+	// The generated app checks a double-submit CSRF cookie, so fetch the form page
+	// first and send its token back the way a browser would.
+	token, err := fetchCSRFToken(s.url)
+	if err != nil {
+		return result.MarkFailure(err)
+	}
+	formData := url.Values{}
+	for key, values := range s.formData {
+		formData[key] = values
+	}
+	formData.Set("csrf_token", token)
+
+	req, err := http.NewRequest(http.MethodPost, s.url, strings.NewReader(formData.Encode()))
+	if err != nil {
+		return result.MarkFailure(fmt.Errorf("failed to create POST request: %w", err))
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(&http.Cookie{Name: "csrf_token", Value: token})
+	postResp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return result.MarkFailure(fmt.Errorf("failed to make POST request: %w", err))
 	}
@@ -431,6 +449,24 @@ func (s *HTTPPostStep) Execute(ctx *TestContext) *StepResult {
 	return result.MarkSuccess()
 }
 
+// fetchCSRFToken GETs pageURL and returns the CSRF token from the csrf_token cookie
+// the generated app sets on it.
+func fetchCSRFToken(pageURL string) (string, error) {
+	resp, err := http.Get(pageURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch form page for CSRF token: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == "csrf_token" {
+			return cookie.Value, nil
+		}
+	}
+	return "", fmt.Errorf("form page %s did not set a csrf_token cookie", pageURL)
+}
+
 // CommandAPIStep tests the command API endpoint
 type CommandAPIStep struct {
 	url         string
@@ -464,7 +500,14 @@ func (s *CommandAPIStep) Execute(ctx *TestContext) *StepResult {
 		return result.MarkFailure(fmt.Errorf("failed to marshal command payload: %w", err))
 	}
 	
-	cmdResp, err := http.Post(s.url, "application/json", bytes.NewBuffer(jsonData))
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return result.MarkFailure(fmt.Errorf("failed to create command API request: %w", err))
+	}
+	req.Header.Set("Content-Type", "application/json")
+	// JSON clients without a CSRF cookie pass the CSRF check with any non-empty header
+	req.Header.Set("X-CSRF-Token", "petrock-test")
+	cmdResp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return result.MarkFailure(fmt.Errorf("failed to make command API request: %w", err))
 	}
@@ -36,6 +36,9 @@ func GetWorkerTemplateFiles(entityName string) WorkerFileMap {
 			skeletonFile := fmt.Sprintf("internal/skeleton/petrock_example_feature_name/workers/%s_worker.go", knownEntity)
 			targetFile := fmt.Sprintf("{{feature}}/workers/%s_worker.go", normalizedEntityName)
 			baseFiles[skeletonFile] = targetFile
+			// Example test using core/workertest; skipped if the skeleton has none for this entity
+			testFile := fmt.Sprintf("internal/skeleton/petrock_example_feature_name/workers/%s_worker_test.go", knownEntity)
+			baseFiles[testFile] = fmt.Sprintf("{{feature}}/workers/%s_worker_test.go", normalizedEntityName)
 			return baseFiles
 		}
 	}
@@ -43,6 +46,8 @@ func GetWorkerTemplateFiles(entityName string) WorkerFileMap {
 	// If no exact match, use the summary_worker.go template as a base for new worker
 	baseFiles["internal/skeleton/petrock_example_feature_name/workers/summary_worker.go"] = 
 		fmt.Sprintf("{{feature}}/workers/%s_worker.go", normalizedEntityName)
+	baseFiles["internal/skeleton/petrock_example_feature_name/workers/summary_worker_test.go"] =
+		fmt.Sprintf("{{feature}}/workers/%s_worker_test.go", normalizedEntityName)
 
 	return baseFiles
 }
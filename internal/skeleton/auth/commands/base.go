@@ -0,0 +1,47 @@
+package commands
+
+import (
+	"context"
+	"time"
+
+	"github.com/petrock/example_module_path/auth/state"
+	"github.com/petrock/example_module_path/core"
+)
+
+// Validator defines an interface for commands that require stateful validation.
+// The feature's Executor will call this method if implemented by a command.
+type Validator interface {
+	Validate(state *state.State) error
+}
+
+// Executor implements the core.FeatureExecutor interface for the auth feature.
+// It holds the user state and provides the state update handlers.
+type Executor struct {
+	state *state.State
+}
+
+// NewExecutor creates a new auth Executor instance.
+func NewExecutor(state *state.State) *Executor {
+	if state == nil {
+		panic("state cannot be nil for auth Executor")
+	}
+	return &Executor{
+		state: state,
+	}
+}
+
+// ValidateCommand calls the command's Validate method with the user state, if it has one.
+func (e *Executor) ValidateCommand(ctx context.Context, cmd core.Command) error {
+	if validator, ok := cmd.(Validator); ok {
+		return validator.Validate(e.state)
+	}
+	return nil
+}
+
+// getTimestamp returns the timestamp from the message metadata if available, otherwise current time
+func getTimestamp(msg *core.Message) time.Time {
+	if msg != nil {
+		return msg.Timestamp
+	}
+	return time.Now().UTC()
+}
@@ -0,0 +1,46 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/petrock/example_module_path/auth/state"
+	"github.com/petrock/example_module_path/core"
+)
+
+// Ensure command implements the marker interfaces
+var (
+	_ core.Command = (*LoginCommand)(nil)
+	_ Validator    = (*LoginCommand)(nil)
+)
+
+// LoginCommand records that a user logged in. The login handler checks the password
+// before executing it; the command itself only keeps the user's last login time.
+type LoginCommand struct {
+	UserID string `json:"user_id" validate:"required"`
+}
+
+// CommandName returns the unique kebab-case name for this command type.
+func (c *LoginCommand) CommandName() string {
+	return "auth/login"
+}
+
+// Validate checks the user exists.
+func (c *LoginCommand) Validate(state *state.State) error {
+	if _, exists := state.GetUser(c.UserID); !exists {
+		return fmt.Errorf("user %q not found", c.UserID)
+	}
+	return nil
+}
+
+// HandleLogin updates the user's last login time.
+func (e *Executor) HandleLogin(ctx context.Context, command core.Command, msg *core.Message, pctx *core.ProcessingContext) error {
+	cmd, ok := command.(*LoginCommand)
+	if !ok {
+		return fmt.Errorf("internal error: incorrect command type (%T) passed to HandleLogin, expected *LoginCommand", command)
+	}
+	if !e.state.RecordLogin(cmd.UserID, getTimestamp(msg)) {
+		return fmt.Errorf("user %q not found", cmd.UserID)
+	}
+	return nil
+}
@@ -0,0 +1,11 @@
+package commands
+
+import (
+	"github.com/petrock/example_module_path/core"
+)
+
+// RegisterTypes registers the message types used by the auth feature.
+func RegisterTypes(log *core.MessageLog) {
+	log.RegisterType(&RegisterUserCommand{})
+	log.RegisterType(&LoginCommand{})
+}
@@ -0,0 +1,73 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/petrock/example_module_path/auth/state"
+	"github.com/petrock/example_module_path/core"
+)
+
+// Ensure command implements the marker interfaces
+var (
+	_ core.Command = (*RegisterUserCommand)(nil)
+	_ Validator    = (*RegisterUserCommand)(nil)
+)
+
+// usernamePattern allows 3 to 32 letters, digits and "._-".
+var usernamePattern = regexp.MustCompile(`^[a-zA-Z0-9._-]{3,32}$`)
+
+// RegisterUserCommand creates a user account. It carries the bcrypt hash of the
+// password, never the password itself, since commands are stored in the message log.
+type RegisterUserCommand struct {
+	ID           string `json:"id" validate:"required"`
+	Username     string `json:"username" validate:"required,minlen=3,maxlen=32"`
+	PasswordHash string `json:"password_hash" validate:"required" secret:"true"`
+}
+
+// CommandName returns the unique kebab-case name for this command type.
+func (c *RegisterUserCommand) CommandName() string {
+	return "auth/register-user"
+}
+
+// Validate checks the username is well-formed and free, and the hash is a bcrypt hash.
+func (c *RegisterUserCommand) Validate(state *state.State) error {
+	if c.ID == "" {
+		return errors.New("user ID cannot be empty")
+	}
+	if !usernamePattern.MatchString(c.Username) {
+		return errors.New("username must be 3 to 32 letters, digits, '.', '_' or '-'")
+	}
+	if _, exists := state.GetUser(c.ID); exists {
+		return fmt.Errorf("user %q already exists", c.ID)
+	}
+	if _, exists := state.GetUserByUsername(c.Username); exists {
+		return fmt.Errorf("username %q is already taken", c.Username)
+	}
+	if _, err := bcrypt.Cost([]byte(c.PasswordHash)); err != nil {
+		return errors.New("password hash is not a bcrypt hash")
+	}
+	return nil
+}
+
+// HandleRegisterUser adds the user to the state.
+func (e *Executor) HandleRegisterUser(ctx context.Context, command core.Command, msg *core.Message, pctx *core.ProcessingContext) error {
+	cmd, ok := command.(*RegisterUserCommand)
+	if !ok {
+		return fmt.Errorf("internal error: incorrect command type (%T) passed to HandleRegisterUser, expected *RegisterUserCommand", command)
+	}
+
+	e.state.AddUser(&state.User{
+		ID:           cmd.ID,
+		Username:     cmd.Username,
+		PasswordHash: cmd.PasswordHash,
+		CreatedAt:    getTimestamp(msg),
+	})
+	slog.Debug("Registered user", "feature", "auth", "user_id", cmd.ID, "username", cmd.Username)
+	return nil
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	g "maragu.dev/gomponents"
+
+	"github.com/petrock/example_module_path/auth/state"
+	"github.com/petrock/example_module_path/core"
+	"github.com/petrock/example_module_path/core/ui"
+)
+
+// AuthServer holds the dependencies of the login, registration and logout handlers.
+type AuthServer struct {
+	app      *core.App
+	state    *state.State
+	sessions *Sessions
+}
+
+// NewAuthServer creates the AuthServer.
+func NewAuthServer(app *core.App, state *state.State, sessions *Sessions) *AuthServer {
+	if app == nil || state == nil || sessions == nil {
+		panic("missing required dependencies for AuthServer")
+	}
+	return &AuthServer{
+		app:      app,
+		state:    state,
+		sessions: sessions,
+	}
+}
+
+// renderPage renders content as a complete HTML page with the given status.
+func renderPage(w http.ResponseWriter, status int, pageTitle string, content g.Node) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := ui.Layout(pageTitle+" - petrock_example_project_name", content).Render(w); err != nil {
+		slog.Error("Failed to render page", "feature", "auth", "title", pageTitle, "error", err)
+	}
+}
+
+// redirectTarget returns next if it is a path on this site, and "/" otherwise, so that
+// the login form cannot be used to send users to another site.
+func redirectTarget(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		return "/"
+	}
+	return next
+}
+
+// newUserID returns a random user ID.
+func newUserID() string {
+	return hex.EncodeToString(randomBytes(16))
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/petrock/example_module_path/auth/commands"
+	"github.com/petrock/example_module_path/core"
+	"github.com/petrock/example_module_path/core/ui"
+)
+
+// HandleLoginForm shows the login form, or sends users who are already logged in on.
+// Route: GET /login
+func (s *AuthServer) HandleLoginForm(w http.ResponseWriter, r *http.Request) {
+	next := r.URL.Query().Get("next")
+	if _, ok := core.PrincipalFromContext(r.Context()); ok {
+		http.Redirect(w, r, redirectTarget(next), http.StatusSeeOther)
+		return
+	}
+	renderPage(w, http.StatusOK, "Log in", LoginPage(r.Context(), ui.NewFormData(nil, nil), next, ""))
+}
+
+// HandleLogin checks the submitted username and password and starts a session.
+// Route: POST /login
+func (s *AuthServer) HandleLogin(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form submission", http.StatusBadRequest)
+		return
+	}
+	username := r.PostForm.Get("username")
+	next := r.PostForm.Get("next")
+
+	// An unknown username leaves PasswordHash empty, which CheckPassword rejects
+	user, _ := s.state.GetUserByUsername(username)
+	if !CheckPassword(user.PasswordHash, r.PostForm.Get("password")) {
+		slog.Info("Failed login", "feature", "auth", "username", username, "request_id", core.RequestIDFromContext(r.Context()))
+		formData := ui.NewFormData(r.PostForm, nil)
+		renderPage(w, http.StatusUnauthorized, "Log in", LoginPage(r.Context(), formData, next, "Invalid username or password."))
+		return
+	}
+
+	if err := s.logIn(w, r, user.ID, user.Username); err != nil {
+		slog.Error("Failed to log in", "feature", "auth", "user_id", user.ID, "error", err)
+		core.WriteErrorPage(w, r, http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, redirectTarget(next), http.StatusSeeOther)
+}
+
+// HandleLogout ends the session.
+// Route: POST /logout
+func (s *AuthServer) HandleLogout(w http.ResponseWriter, r *http.Request) {
+	if err := s.sessions.Destroy(w, r); err != nil {
+		slog.Error("Failed to log out", "feature", "auth", "error", err)
+		core.WriteErrorPage(w, r, http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// logIn replaces any existing session with a new one for the user and records the login.
+func (s *AuthServer) logIn(w http.ResponseWriter, r *http.Request, userID, username string) error {
+	if old, ok := s.sessions.Lookup(r); ok {
+		if err := s.sessions.Delete(old.ID); err != nil {
+			return err
+		}
+	}
+	ctx := core.WithPrincipal(r.Context(), core.Principal{UserID: userID, Username: username})
+	if err := s.app.Executor.Execute(ctx, &commands.LoginCommand{UserID: userID}); err != nil {
+		return err
+	}
+	_, err := s.sessions.Create(w, r, userID)
+	return err
+}
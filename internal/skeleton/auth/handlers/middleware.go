@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/petrock/example_module_path/auth/state"
+	"github.com/petrock/example_module_path/core"
+)
+
+// LoadSession puts the principal of a logged-in request into its context, where
+// core.RequireUser, handlers and the Executor find it. Requests without a valid session,
+// or whose user no longer exists, continue anonymously.
+func LoadSession(sessions *Sessions, users *state.State) core.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			session, ok := sessions.Lookup(r)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			user, ok := users.GetUser(session.UserID)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			principal := core.Principal{UserID: user.ID, Username: user.Username}
+			next.ServeHTTP(w, r.WithContext(core.WithPrincipal(r.Context(), principal)))
+		})
+	}
+}
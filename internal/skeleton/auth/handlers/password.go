@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"fmt"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// MinPasswordLength and MaxPasswordLength bound the passwords HashPassword accepts.
+// bcrypt only uses the first 72 bytes, so longer passwords are rejected.
+const (
+	MinPasswordLength = 8
+	MaxPasswordLength = 72
+)
+
+// dummyHash is compared against when a username doesn't exist, so that logging in takes
+// as long for unknown users as for wrong passwords.
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("petrock-dummy-password"), bcrypt.DefaultCost)
+
+// HashPassword returns the bcrypt hash of password.
+func HashPassword(password string) (string, error) {
+	if len(password) < MinPasswordLength {
+		return "", fmt.Errorf("password must be at least %d characters long", MinPasswordLength)
+	}
+	if len(password) > MaxPasswordLength {
+		return "", fmt.Errorf("password must be at most %d bytes long", MaxPasswordLength)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// CheckPassword reports whether password matches hash. An empty hash compares against
+// dummyHash and never matches.
+func CheckPassword(hash, password string) bool {
+	if hash == "" {
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
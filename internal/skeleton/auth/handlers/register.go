@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/petrock/example_module_path/auth/commands"
+	"github.com/petrock/example_module_path/core"
+	"github.com/petrock/example_module_path/core/ui"
+)
+
+// HandleRegisterForm shows the registration form.
+// Route: GET /register
+func (s *AuthServer) HandleRegisterForm(w http.ResponseWriter, r *http.Request) {
+	renderPage(w, http.StatusOK, "Sign up", RegisterPage(r.Context(), ui.NewFormData(nil, nil), r.URL.Query().Get("next")))
+}
+
+// HandleRegister creates an account from the submitted form and logs the new user in.
+// Route: POST /register
+func (s *AuthServer) HandleRegister(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form submission", http.StatusBadRequest)
+		return
+	}
+	username := strings.TrimSpace(r.PostForm.Get("username"))
+	password := r.PostForm.Get("password")
+	next := r.PostForm.Get("next")
+
+	fail := func(field, message string) {
+		formData := ui.NewFormData(r.PostForm, []ui.ParseError{{Field: field, Message: message, Code: "validation_error"}})
+		renderPage(w, http.StatusUnprocessableEntity, "Sign up", RegisterPage(r.Context(), formData, next))
+	}
+
+	if password != r.PostForm.Get("password_confirmation") {
+		fail("password_confirmation", "The passwords don't match.")
+		return
+	}
+	hash, err := HashPassword(password)
+	if err != nil {
+		fail("password", err.Error())
+		return
+	}
+
+	cmd := &commands.RegisterUserCommand{ID: newUserID(), Username: username, PasswordHash: hash}
+	if err := s.app.Executor.Execute(r.Context(), cmd); err != nil {
+		slog.Info("Registration rejected", "feature", "auth", "username", username, "error", err)
+		message := err.Error()
+		if cause := errors.Unwrap(err); cause != nil {
+			message = cause.Error()
+		}
+		fail("username", message)
+		return
+	}
+
+	if err := s.logIn(w, r, cmd.ID, cmd.Username); err != nil {
+		slog.Error("Failed to log in new user", "feature", "auth", "user_id", cmd.ID, "error", err)
+		core.WriteErrorPage(w, r, http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, redirectTarget(next), http.StatusSeeOther)
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/petrock/example_module_path/core"
+)
+
+const (
+	// SessionCookieName is the name of the cookie holding the signed session ID.
+	SessionCookieName = "session"
+	// SessionTTL is how long a session lasts after logging in.
+	SessionTTL = 14 * 24 * time.Hour
+
+	sessionKeyPrefix = "auth:session:"
+	signingKeyKey    = "auth:signing-key"
+)
+
+// SecretKeys are the KV keys, or their prefixes, that hold sessions and the cookie
+// signing key. Anyone who reads them can log in as any user.
+var SecretKeys = []string{sessionKeyPrefix, signingKeyKey}
+
+// Session is the server-side record of a login, stored in the KVStore until it
+// expires or the user logs out.
+type Session struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Sessions stores sessions in the KVStore and hands out cookies carrying the session
+// ID and an HMAC of it, so that IDs cannot be forged or guessed from the cookie alone.
+type Sessions struct {
+	kv  core.KVStore
+	key []byte
+}
+
+// NewSessions loads the cookie signing key from the KVStore, generating it on first use.
+// Deleting the auth:signing-key key logs everyone out.
+func NewSessions(kv core.KVStore) (*Sessions, error) {
+	var encoded string
+	err := kv.Get(signingKeyKey, &encoded)
+	if errors.Is(err, core.ErrKeyNotFound) {
+		encoded = base64.StdEncoding.EncodeToString(randomBytes(32))
+		if _, err = kv.CompareAndSwap(signingKeyKey, 0, encoded); errors.Is(err, core.ErrVersionConflict) {
+			// Another process created the key first; use theirs
+			err = kv.Get(signingKeyKey, &encoded)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load session signing key: %w", err)
+	}
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode session signing key: %w", err)
+	}
+	return &Sessions{kv: kv, key: key}, nil
+}
+
+// Create starts a session for the user and sets its cookie on the response.
+func (s *Sessions) Create(w http.ResponseWriter, r *http.Request, userID string) (Session, error) {
+	session := Session{ID: hex.EncodeToString(randomBytes(32)), UserID: userID, CreatedAt: time.Now().UTC()}
+	if err := s.kv.SetWithTTL(sessionKeyPrefix+session.ID, session, SessionTTL); err != nil {
+		return Session{}, fmt.Errorf("failed to store session: %w", err)
+	}
+	http.SetCookie(w, s.cookie(r, session.ID+"."+s.sign(session.ID), int(SessionTTL.Seconds())))
+	return session, nil
+}
+
+// Lookup returns the session named by the request's cookie, if the cookie is correctly
+// signed and the session has neither expired nor been destroyed.
+func (s *Sessions) Lookup(r *http.Request) (Session, bool) {
+	cookie, err := r.Cookie(SessionCookieName)
+	if err != nil {
+		return Session{}, false
+	}
+	id, signature, ok := strings.Cut(cookie.Value, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(s.sign(id))) {
+		return Session{}, false
+	}
+	var session Session
+	if err := s.kv.Get(sessionKeyPrefix+id, &session); err != nil {
+		return Session{}, false
+	}
+	return session, true
+}
+
+// Delete ends the session with the given ID.
+func (s *Sessions) Delete(id string) error {
+	if err := s.kv.Delete(sessionKeyPrefix + id); err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	return nil
+}
+
+// Destroy ends the request's session, if any, and clears its cookie.
+func (s *Sessions) Destroy(w http.ResponseWriter, r *http.Request) error {
+	if session, ok := s.Lookup(r); ok {
+		if err := s.Delete(session.ID); err != nil {
+			return err
+		}
+	}
+	http.SetCookie(w, s.cookie(r, "", -1))
+	return nil
+}
+
+// sign returns the base64url HMAC-SHA256 of the session ID.
+func (s *Sessions) sign(id string) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(id))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// cookie builds the session cookie. It is marked Secure when the request came in over
+// HTTPS, directly or through a proxy that sets X-Forwarded-Proto.
+func (s *Sessions) cookie(r *http.Request, value string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     SessionCookieName,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https",
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// randomBytes returns n bytes from crypto/rand, which never fails on supported platforms.
+func randomBytes(n int) []byte {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("failed to read random bytes: %v", err))
+	}
+	return b
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"context"
+	"net/url"
+
+	g "maragu.dev/gomponents"
+	"maragu.dev/gomponents/html"
+
+	"github.com/petrock/example_module_path/core"
+	"github.com/petrock/example_module_path/core/ui"
+)
+
+// LoginPage renders the login form. message, if set, is shown above the form.
+func LoginPage(ctx context.Context, formData *ui.FormData, next, message string) g.Node {
+	return ui.Container(ui.ContainerProps{Variant: "narrow"},
+		ui.Section(ui.SectionProps{Heading: "Log in", Level: 1},
+			g.If(message != "", ui.Alert(ui.AlertProps{Type: "error", Message: message})),
+			ui.Card(ui.CardProps{Variant: "default", Padding: "large"},
+				html.Form(
+					html.Method("POST"),
+					html.Action(core.LoginPath),
+					ui.CSSClass("space-y-6"),
+					ui.CSRFField(ctx),
+					html.Input(html.Type("hidden"), html.Name("next"), html.Value(next)),
+
+					ui.FormGroupWithValidation(formData, "username", "Username",
+						ui.TextInputWithValidation(formData, ui.TextInputProps{
+							Name:         "username",
+							Type:         "text",
+							AutoComplete: "username",
+							Required:     true,
+						}),
+					),
+					ui.FormGroupWithValidation(formData, "password", "Password",
+						// Not TextInputWithValidation: that would send the password back
+						ui.TextInput(ui.TextInputProps{
+							Name:         "password",
+							Type:         "password",
+							AutoComplete: "current-password",
+							Required:     true,
+						}),
+					),
+
+					ui.Button(ui.ButtonProps{Type: "submit", Variant: "primary", Size: "medium"}, g.Text("Log in")),
+				),
+			),
+			html.P(
+				ui.CSSClass("text-gray-600", "mt-4"),
+				g.Text("No account yet? "),
+				html.A(html.Href("/register?next="+url.QueryEscape(next)), ui.CSSClass("text-blue-600", "underline"), g.Text("Sign up")),
+			),
+		),
+	)
+}
+
+// RegisterPage renders the registration form.
+func RegisterPage(ctx context.Context, formData *ui.FormData, next string) g.Node {
+	return ui.Container(ui.ContainerProps{Variant: "narrow"},
+		ui.Section(ui.SectionProps{Heading: "Sign up", Level: 1},
+			ui.Card(ui.CardProps{Variant: "default", Padding: "large"},
+				html.Form(
+					html.Method("POST"),
+					html.Action("/register"),
+					ui.CSSClass("space-y-6"),
+					ui.CSRFField(ctx),
+					html.Input(html.Type("hidden"), html.Name("next"), html.Value(next)),
+
+					ui.FormGroupWithValidation(formData, "username", "Username",
+						ui.TextInputWithValidation(formData, ui.TextInputProps{
+							Name:         "username",
+							Type:         "text",
+							AutoComplete: "username",
+							Required:     true,
+						}),
+						"3 to 32 letters, digits, '.', '_' or '-'",
+					),
+					ui.FormGroupWithValidation(formData, "password", "Password",
+						ui.TextInput(ui.TextInputProps{
+							Name:         "password",
+							Type:         "password",
+							AutoComplete: "new-password",
+							Required:     true,
+						}),
+						"At least 8 characters",
+					),
+					ui.FormGroupWithValidation(formData, "password_confirmation", "Confirm password",
+						ui.TextInput(ui.TextInputProps{
+							Name:         "password_confirmation",
+							Type:         "password",
+							AutoComplete: "new-password",
+							Required:     true,
+						}),
+					),
+
+					ui.Button(ui.ButtonProps{Type: "submit", Variant: "primary", Size: "medium"}, g.Text("Sign up")),
+				),
+			),
+			html.P(
+				ui.CSSClass("text-gray-600", "mt-4"),
+				g.Text("Already have an account? "),
+				html.A(html.Href(core.LoginPath+"?next="+url.QueryEscape(next)), ui.CSSClass("text-blue-600", "underline"), g.Text("Log in")),
+			),
+		),
+	)
+}
@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"log/slog"
+
+	"github.com/petrock/example_module_path/auth/commands"
+	"github.com/petrock/example_module_path/auth/handlers"
+	"github.com/petrock/example_module_path/auth/routes"
+	"github.com/petrock/example_module_path/auth/state"
+	"github.com/petrock/example_module_path/core"
+)
+
+// RegisterFeature registers the auth commands and routes, and adds the middleware that
+// loads the session of every request, so that core.PrincipalFromContext and
+// core.RequireUser work in all features.
+func RegisterFeature(app *core.App, featureState *state.State) {
+	slog.Debug("Registering feature", "feature", "auth")
+
+	if app == nil || app.CommandRegistry == nil || app.MessageLog == nil || app.Executor == nil || app.KVStore == nil {
+		slog.Error("Cannot register feature: App is not fully initialized", "feature", "auth")
+		return
+	}
+	if featureState == nil {
+		slog.Error("Cannot register feature: State is nil", "feature", "auth")
+		return
+	}
+
+	sessions, err := handlers.NewSessions(app.KVStore)
+	if err != nil {
+		slog.Error("Cannot register feature: failed to set up sessions", "feature", "auth", "error", err)
+		return
+	}
+
+	// --- 1. Register Command Handlers and Message Types ---
+	featureExecutor := commands.NewExecutor(featureState)
+	app.CommandRegistry.Register(&commands.RegisterUserCommand{}, featureExecutor.HandleRegisterUser, featureExecutor)
+	app.CommandRegistry.Register(&commands.LoginCommand{}, featureExecutor.HandleLogin, featureExecutor)
+	// Only the /register and /login handlers may run these: they check the password
+	// and apply the auth rate limits, which POST /commands would bypass
+	app.CommandRegistry.SetAdminOnly("auth/register-user", "auth/login")
+	commands.RegisterTypes(app.MessageLog)
+
+	// --- 2. Load Sessions and Register Routes ---
+	app.RegisterSecretKeys(handlers.SecretKeys...)
+	app.Use(handlers.LoadSession(sessions, featureState))
+	routes.RegisterRoutes(app, handlers.NewAuthServer(app, featureState, sessions))
+
+	slog.Info("Feature registered successfully", "feature", "auth")
+}
+
+// NewState creates a new instance of the feature's state
+func NewState() *state.State {
+	return state.NewState()
+}
@@ -0,0 +1,194 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/petrock/example_module_path/auth/commands"
+	"github.com/petrock/example_module_path/auth/handlers"
+	"github.com/petrock/example_module_path/core"
+)
+
+// newTestServer returns the app's handler with the auth feature and a /me route that
+// requires a user and answers with the principal's username.
+func newTestServer(t *testing.T) (*core.App, http.Handler) {
+	t.Helper()
+	app, err := core.NewApp(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewApp failed: %v", err)
+	}
+	t.Cleanup(func() { app.Close() })
+	app.Mux = http.NewServeMux()
+
+	RegisterFeature(app, NewState())
+	app.RegisterRoute("GET /me", func(w http.ResponseWriter, r *http.Request) {
+		principal, _ := core.PrincipalFromContext(r.Context())
+		w.Write([]byte(principal.Username))
+	}, core.RequireUser())
+	return app, app.Handler()
+}
+
+func postForm(h http.Handler, path string, form url.Values, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	for _, c := range cookies {
+		r.AddCookie(c)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func get(h http.Handler, path string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, path, nil)
+	for _, c := range cookies {
+		r.AddCookie(c)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func sessionCookie(t *testing.T, w *httptest.ResponseRecorder) *http.Cookie {
+	t.Helper()
+	for _, c := range w.Result().Cookies() {
+		if c.Name == handlers.SessionCookieName {
+			return c
+		}
+	}
+	t.Fatalf("Expected a session cookie, got headers %v", w.Header())
+	return nil
+}
+
+func TestRegisterLoginLogout(t *testing.T) {
+	_, h := newTestServer(t)
+
+	w := postForm(h, "/register", url.Values{
+		"username": {"ada"}, "password": {"correct horse"}, "password_confirmation": {"correct horse"}, "next": {"/me"},
+	})
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/me" {
+		t.Fatalf("Expected registration to redirect to next, got %d %q: %s", w.Code, w.Header().Get("Location"), w.Body)
+	}
+	cookie := sessionCookie(t, w)
+	if !cookie.HttpOnly || cookie.SameSite != http.SameSiteLaxMode {
+		t.Errorf("Expected an HttpOnly SameSite=Lax cookie, got %+v", cookie)
+	}
+	if w = get(h, "/me", cookie); w.Code != http.StatusOK || w.Body.String() != "ada" {
+		t.Fatalf("Expected the registered user to be logged in, got %d %q", w.Code, w.Body)
+	}
+
+	if w = postForm(h, "/logout", nil, cookie); w.Code != http.StatusSeeOther {
+		t.Fatalf("Expected logout to redirect, got %d", w.Code)
+	}
+	if w = get(h, "/me", cookie); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected the old cookie to stop working after logout, got %d", w.Code)
+	}
+
+	if w = postForm(h, "/login", url.Values{"username": {"ADA"}, "password": {"wrong password"}}); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected a wrong password to be rejected, got %d", w.Code)
+	}
+	w = postForm(h, "/login", url.Values{"username": {"ADA"}, "password": {"correct horse"}, "next": {"https://evil.example"}})
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/" {
+		t.Fatalf("Expected login to redirect to / instead of another site, got %d %q", w.Code, w.Header().Get("Location"))
+	}
+	cookie = sessionCookie(t, w)
+	if w = get(h, "/me", cookie); w.Body.String() != "ada" {
+		t.Errorf("Expected the logged-in user, got %q", w.Body)
+	}
+
+	tampered := *cookie
+	tampered.Value = strings.Replace(cookie.Value, ".", "x.", 1)
+	if w = get(h, "/me", &tampered); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected a tampered cookie to be rejected, got %d", w.Code)
+	}
+}
+
+func TestRegisterValidation(t *testing.T) {
+	_, h := newTestServer(t)
+
+	tests := []struct {
+		name string
+		form url.Values
+	}{
+		{"short password", url.Values{"username": {"ada"}, "password": {"short"}, "password_confirmation": {"short"}}},
+		{"mismatched confirmation", url.Values{"username": {"ada"}, "password": {"correct horse"}, "password_confirmation": {"correct horse!"}}},
+		{"bad username", url.Values{"username": {"a d"}, "password": {"correct horse"}, "password_confirmation": {"correct horse"}}},
+	}
+	for _, tt := range tests {
+		if w := postForm(h, "/register", tt.form); w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("%s: expected 422, got %d", tt.name, w.Code)
+		}
+	}
+
+	form := url.Values{"username": {"ada"}, "password": {"correct horse"}, "password_confirmation": {"correct horse"}}
+	postForm(h, "/register", form)
+	form.Set("username", "Ada")
+	if w := postForm(h, "/register", form); w.Code != http.StatusUnprocessableEntity || !strings.Contains(w.Body.String(), "already taken") {
+		t.Errorf("Expected a taken username to be rejected, got %d", w.Code)
+	}
+	if strings.Contains(postForm(h, "/register", form).Body.String(), "correct horse") {
+		t.Error("Expected the password not to be rendered back into the form")
+	}
+}
+
+func TestCommandsAreAdminOnly(t *testing.T) {
+	app, _ := newTestServer(t)
+
+	for _, name := range []string{"auth/register-user", "auth/login"} {
+		if !app.CommandRegistry.AdminOnly(name) {
+			t.Errorf("Expected %s to be admin-only, so that POST /commands refuses it", name)
+		}
+		for _, public := range app.CommandRegistry.PublicCommandNames() {
+			if public == name {
+				t.Errorf("Expected %s not to be listed as a public command", name)
+			}
+		}
+	}
+}
+
+func TestConcurrentRegistrationWithTheSameUsername(t *testing.T) {
+	app, _ := newTestServer(t)
+	hash, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("GenerateFromPassword failed: %v", err)
+	}
+
+	const attempts = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, attempts)
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- app.Executor.Execute(context.Background(), &commands.RegisterUserCommand{
+				ID: fmt.Sprintf("user-%d", i), Username: "ada", PasswordHash: string(hash),
+			})
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	succeeded := 0
+	for err := range errs {
+		var validationErr *core.ValidationError
+		switch {
+		case err == nil:
+			succeeded++
+		case !errors.As(err, &validationErr):
+			t.Errorf("Expected a validation error for the taken username, got %v", err)
+		}
+	}
+	if succeeded != 1 {
+		t.Errorf("Expected exactly one registration to succeed, got %d", succeeded)
+	}
+}
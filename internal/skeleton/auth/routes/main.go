@@ -0,0 +1,29 @@
+package routes
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/petrock/example_module_path/auth/handlers"
+	"github.com/petrock/example_module_path/core"
+)
+
+// RegisterRoutes registers the login, registration and logout routes.
+func RegisterRoutes(app *core.App, deps *handlers.AuthServer) {
+	if app == nil || app.Mux == nil {
+		slog.Error("Error registering routes: nil app or nil HTTP mux provided", "feature", "auth")
+		return
+	}
+
+	// core.RequireUser sends browsers here when they need to log in
+	app.RegisterRoute("GET "+core.LoginPath, deps.HandleLoginForm)
+	// Slow down password guessing and mass sign-ups from a single address
+	app.RegisterRoute("POST "+core.LoginPath, deps.HandleLogin,
+		app.RateLimiter.Limit("auth/login", core.RateLimit{Key: core.RateLimitByIP, Requests: 10, Per: time.Minute}))
+	app.RegisterRoute("GET /register", deps.HandleRegisterForm)
+	app.RegisterRoute("POST /register", deps.HandleRegister,
+		app.RateLimiter.Limit("auth/register", core.RateLimit{Key: core.RateLimitByIP, Requests: 10, Per: time.Hour}))
+	app.RegisterRoute("POST /logout", deps.HandleLogout)
+
+	slog.Info("Registered feature HTTP routes", "feature", "auth")
+}
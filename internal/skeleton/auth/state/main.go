@@ -0,0 +1,74 @@
+package state
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// User is a registered account. PasswordHash is a bcrypt hash; the password itself
+// never reaches the message log.
+type User struct {
+	ID           string
+	Username     string
+	PasswordHash string
+	CreatedAt    time.Time
+	LastLoginAt  time.Time
+}
+
+// State holds the registered users, rebuilt by replaying the auth commands.
+type State struct {
+	Users      map[string]*User  // Map from user ID to user
+	byUsername map[string]string // Map from lowercased username to user ID
+	mu         sync.RWMutex
+}
+
+// NewState creates an initialized (empty) State.
+func NewState() *State {
+	return &State{
+		Users:      make(map[string]*User),
+		byUsername: make(map[string]string),
+	}
+}
+
+// AddUser adds a newly registered user.
+func (s *State) AddUser(user *User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Users[user.ID] = user
+	s.byUsername[strings.ToLower(user.Username)] = user.ID
+}
+
+// GetUser returns a copy of the user with the given ID.
+func (s *State) GetUser(id string) (User, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	user, found := s.Users[id]
+	if !found {
+		return User{}, false
+	}
+	return *user, true
+}
+
+// GetUserByUsername returns a copy of the user with the given username, ignoring case.
+func (s *State) GetUserByUsername(username string) (User, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	user, found := s.Users[s.byUsername[strings.ToLower(username)]]
+	if !found {
+		return User{}, false
+	}
+	return *user, true
+}
+
+// RecordLogin sets the time the user last logged in.
+func (s *State) RecordLogin(id string, at time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	user, found := s.Users[id]
+	if !found {
+		return false
+	}
+	user.LastLoginAt = at
+	return true
+}
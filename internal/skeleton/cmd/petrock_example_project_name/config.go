@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+	"strings"
+
+	"github.com/petrock/example_module_path/core"
+	"github.com/spf13/cobra"
+)
+
+// configFlags maps command line flags to the config keys they override. A flag only
+// overrides the config file and environment when it is given explicitly.
+var configFlags = map[string]string{
+	"db-path":             "db.path",
+	"log-level":           "log.level",
+	"host":                "server.host",
+	"port":                "server.port",
+	"trust-proxy":         "server.trust_proxy",
+	"persist-rate-limits": "server.persist_rate_limits",
+}
+
+// appName is the name of the binary, as used by rootCmd.
+const appName = "petrock_example_project_name"
+
+// configEnvPrefix returns the prefix of the environment variables that configure the
+// application: the binary name in upper case, such as MYAPP for MYAPP_SERVER_PORT.
+func configEnvPrefix() string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' {
+			return r - 'a' + 'A'
+		}
+		if r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, appName)
+}
+
+// loadConfig loads the configuration from the file named by --config or the
+// PREFIX_CONFIG environment variable, then the environment, then cmd's flags.
+func loadConfig(cmd *cobra.Command) (*core.Config, error) {
+	prefix := configEnvPrefix()
+	file, _ := cmd.Flags().GetString("config")
+	if file == "" {
+		file = os.Getenv(prefix + "_CONFIG")
+	}
+
+	flags := make(map[string]string)
+	for name, key := range configFlags {
+		if cmd.Flags().Changed(name) {
+			flags[key] = cmd.Flags().Lookup(name).Value.String()
+		}
+	}
+
+	return core.LoadConfig(core.ConfigOptions{
+		File:      file,
+		EnvPrefix: prefix,
+		Flags:     flags,
+	})
+}
@@ -11,6 +11,10 @@ import (
 // It iterates through features generated by `petrock feature` and calls their
 // respective RegisterFeature functions, passing necessary core components.
 func RegisterAllFeatures(app *core.App) {
+	// Outbound webhooks, managed from the admin area with the admin-only
+	// webhooks/subscribe and webhooks/unsubscribe commands
+	app.UseWebhooks()
+
 	// The `petrock feature <name>` command will insert code below this line
 	// to initialize each feature's state and call its RegisterFeature function.
 	// It should now pass the 'executor' variable to RegisterFeature.
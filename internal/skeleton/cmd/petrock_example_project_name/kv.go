@@ -4,6 +4,12 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/petrock/example_module_path/core"
 	"github.com/petrock/example_module_path/core/ui"
@@ -22,6 +28,12 @@ func NewKVCmd() *cobra.Command {
 	kvCmd.AddCommand(NewKVGetCmd())
 	kvCmd.AddCommand(NewKVSetCmd())
 	kvCmd.AddCommand(NewKVListCmd())
+	kvCmd.AddCommand(NewKVDeleteCmd())
+	kvCmd.AddCommand(NewKVExportCmd())
+	kvCmd.AddCommand(NewKVImportCmd())
+	kvCmd.AddCommand(NewKVHistoryCmd())
+	kvCmd.AddCommand(NewKVRestoreCmd())
+	kvCmd.AddCommand(NewKVRetentionCmd())
 
 	return kvCmd
 }
@@ -36,8 +48,6 @@ func NewKVGetCmd() *cobra.Command {
 		RunE:  runKVGet,
 	}
 
-	getCmd.Flags().String("db-path", "app.db", "Path to the SQLite database file")
-
 	return getCmd
 }
 
@@ -51,7 +61,6 @@ func NewKVSetCmd() *cobra.Command {
 		RunE:  runKVSet,
 	}
 
-	setCmd.Flags().String("db-path", "app.db", "Path to the SQLite database file")
 	setCmd.Flags().Bool("json", false, "Parse value as JSON")
 
 	return setCmd
@@ -67,17 +76,107 @@ func NewKVListCmd() *cobra.Command {
 		RunE:  runKVList,
 	}
 
-	listCmd.Flags().String("db-path", "app.db", "Path to the SQLite database file")
-
 	return listCmd
 }
 
+// NewKVDeleteCmd creates the 'kv delete' command
+func NewKVDeleteCmd() *cobra.Command {
+	deleteCmd := &cobra.Command{
+		Use:   "delete <key>",
+		Short: "Delete a key from the key-value store",
+		Long:  `Removes a key and its value from the key-value store. Deleting a missing key is not an error.`,
+		Args:  cobra.ExactArgs(1),
+		RunE:  runKVDelete,
+	}
+
+	return deleteCmd
+}
+
+// NewKVExportCmd creates the 'kv export' command
+func NewKVExportCmd() *cobra.Command {
+	exportCmd := &cobra.Command{
+		Use:   "export [prefix]",
+		Short: "Export entries from the key-value store as JSON",
+		Long: `Writes all entries whose key starts with the prefix, or all entries without one,
+as a JSON array of {"key", "value", "version", "expires_at"} objects.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: runKVExport,
+	}
+
+	exportCmd.Flags().StringP("output", "o", "", "Write to this file instead of stdout")
+
+	return exportCmd
+}
+
+// NewKVImportCmd creates the 'kv import' command
+func NewKVImportCmd() *cobra.Command {
+	importCmd := &cobra.Command{
+		Use:   "import <file>",
+		Short: "Import entries into the key-value store",
+		Long: `Stores the entries of a file written by 'kv export' ("-" reads stdin) in one transaction.
+Existing keys are overwritten and get a new version. Entries that have expired are skipped,
+the others keep their expiry time.`,
+		Args: cobra.ExactArgs(1),
+		RunE: runKVImport,
+	}
+
+	return importCmd
+}
+
+// NewKVHistoryCmd creates the 'kv history' command
+func NewKVHistoryCmd() *cobra.Command {
+	historyCmd := &cobra.Command{
+		Use:   "history <key>",
+		Short: "Show previous values of a key",
+		Long: `Lists the recorded previous values of a key, newest first. Only keys whose prefix has
+a history retention limit keep previous values (see 'kv retention').`,
+		Args: cobra.ExactArgs(1),
+		RunE: runKVHistory,
+	}
+
+	historyCmd.Flags().Bool("json", false, "Print the history as JSON")
+
+	return historyCmd
+}
+
+// NewKVRestoreCmd creates the 'kv restore' command
+func NewKVRestoreCmd() *cobra.Command {
+	restoreCmd := &cobra.Command{
+		Use:   "restore <key> (--at <time> | --version <n>)",
+		Short: "Roll a key back to a previous value",
+		Long: `Sets a key back to the value it had at a point in time, or to a recorded version.
+--at takes an RFC 3339 time or a duration before now, e.g. 2h. The value being replaced
+is recorded in the history, so a restore can itself be undone.`,
+		Args: cobra.ExactArgs(1),
+		RunE: runKVRestore,
+	}
+
+	restoreCmd.Flags().String("at", "", "Restore the value the key had at this time")
+	restoreCmd.Flags().Uint64("version", 0, "Restore this version of the key")
+
+	return restoreCmd
+}
+
+// NewKVRetentionCmd creates the 'kv retention' command
+func NewKVRetentionCmd() *cobra.Command {
+	retentionCmd := &cobra.Command{
+		Use:   "retention [prefix [keep]]",
+		Short: "Show or set how many previous values keys keep",
+		Long: `Without arguments, lists the history retention limits by key prefix. With a prefix,
+shows the limit that applies to it; with a prefix and a number, sets how many previous
+values keys starting with the prefix keep. 0 turns history off for the prefix.`,
+		Args: cobra.MaximumNArgs(2),
+		RunE: runKVRetention,
+	}
+
+	return retentionCmd
+}
+
 func runKVGet(cmd *cobra.Command, args []string) error {
-	dbPath, _ := cmd.Flags().GetString("db-path")
 	key := args[0]
 
 	// Initialize the application
-	app, err := core.NewApp(dbPath)
+	app, err := core.NewAppFromConfig(cmdCtx.Config)
 	if err != nil {
 		return fmt.Errorf("failed to initialize application: %w", err)
 	}
@@ -102,13 +201,12 @@ func runKVGet(cmd *cobra.Command, args []string) error {
 }
 
 func runKVSet(cmd *cobra.Command, args []string) error {
-	dbPath, _ := cmd.Flags().GetString("db-path")
 	isJSON, _ := cmd.Flags().GetBool("json")
 	key := args[0]
 	valueStr := args[1]
 
 	// Initialize the application
-	app, err := core.NewApp(dbPath)
+	app, err := core.NewAppFromConfig(cmdCtx.Config)
 	if err != nil {
 		return fmt.Errorf("failed to initialize application: %w", err)
 	}
@@ -134,8 +232,6 @@ func runKVSet(cmd *cobra.Command, args []string) error {
 }
 
 func runKVList(cmd *cobra.Command, args []string) error {
-	dbPath, _ := cmd.Flags().GetString("db-path")
-	
 	// Default to listing all keys if no glob provided
 	glob := "*"
 	if len(args) > 0 {
@@ -143,7 +239,7 @@ func runKVList(cmd *cobra.Command, args []string) error {
 	}
 
 	// Initialize the application
-	app, err := core.NewApp(dbPath)
+	app, err := core.NewAppFromConfig(cmdCtx.Config)
 	if err != nil {
 		return fmt.Errorf("failed to initialize application: %w", err)
 	}
@@ -164,3 +260,267 @@ func runKVList(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+func runKVDelete(cmd *cobra.Command, args []string) error {
+	key := args[0]
+
+	app, err := core.NewAppFromConfig(cmdCtx.Config)
+	if err != nil {
+		return fmt.Errorf("failed to initialize application: %w", err)
+	}
+	defer app.Close()
+
+	if err := app.KVStore.Delete(key); err != nil {
+		return fmt.Errorf("failed to delete key '%s': %w", key, err)
+	}
+
+	return cmdCtx.UI.ShowSuccess(cmdCtx.Ctx, "Successfully deleted key '%s'\n", key)
+}
+
+func runKVExport(cmd *cobra.Command, args []string) error {
+	output, _ := cmd.Flags().GetString("output")
+
+	prefix := ""
+	if len(args) > 0 {
+		prefix = args[0]
+	}
+
+	app, err := core.NewAppFromConfig(cmdCtx.Config)
+	if err != nil {
+		return fmt.Errorf("failed to initialize application: %w", err)
+	}
+	defer app.Close()
+
+	entries, err := app.KVStore.Scan(prefix)
+	if err != nil {
+		return fmt.Errorf("failed to scan keys with prefix '%s': %w", prefix, err)
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(entries); err != nil {
+		return fmt.Errorf("failed to encode entries as JSON: %w", err)
+	}
+
+	if output == "" {
+		return cmdCtx.UI.Present(cmdCtx.Ctx, ui.MessageTypeInfo, buf.String())
+	}
+	if err := os.WriteFile(output, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", output, err)
+	}
+	return cmdCtx.UI.ShowSuccess(cmdCtx.Ctx, "Exported %d entries to %s\n", len(entries), output)
+}
+
+func runKVImport(cmd *cobra.Command, args []string) error {
+	path := args[0]
+
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var entries []core.KVEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	app, err := core.NewAppFromConfig(cmdCtx.Config)
+	if err != nil {
+		return fmt.Errorf("failed to initialize application: %w", err)
+	}
+	defer app.Close()
+
+	imported, skipped := 0, 0
+	err = app.KVStore.Update(func(tx core.KVTx) error {
+		for _, entry := range entries {
+			if entry.ExpiresAt == nil {
+				if err := tx.Set(entry.Key, entry.Value); err != nil {
+					return err
+				}
+				imported++
+				continue
+			}
+			ttl := time.Until(*entry.ExpiresAt)
+			if ttl <= 0 {
+				skipped++
+				continue
+			}
+			if err := tx.SetWithTTL(entry.Key, entry.Value, ttl); err != nil {
+				return err
+			}
+			imported++
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to import entries: %w", err)
+	}
+
+	return cmdCtx.UI.ShowSuccess(cmdCtx.Ctx, "Imported %d entries, skipped %d expired\n", imported, skipped)
+}
+
+// kvHistory returns the application's KVStore as a KVHistory
+func kvHistory(app *core.App) (core.KVHistory, error) {
+	history, ok := app.KVStore.(core.KVHistory)
+	if !ok {
+		return nil, fmt.Errorf("the key-value store does not keep history")
+	}
+	return history, nil
+}
+
+func runKVHistory(cmd *cobra.Command, args []string) error {
+	asJSON, _ := cmd.Flags().GetBool("json")
+	key := args[0]
+
+	app, err := core.NewAppFromConfig(cmdCtx.Config)
+	if err != nil {
+		return fmt.Errorf("failed to initialize application: %w", err)
+	}
+	defer app.Close()
+
+	history, err := kvHistory(app)
+	if err != nil {
+		return err
+	}
+	revisions, err := history.History(key)
+	if err != nil {
+		return fmt.Errorf("failed to load history of key '%s': %w", key, err)
+	}
+
+	if asJSON {
+		var buf bytes.Buffer
+		encoder := json.NewEncoder(&buf)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(revisions); err != nil {
+			return fmt.Errorf("failed to encode history as JSON: %w", err)
+		}
+		return cmdCtx.UI.Present(cmdCtx.Ctx, ui.MessageTypeInfo, buf.String())
+	}
+
+	if len(revisions) == 0 {
+		return cmdCtx.UI.Present(cmdCtx.Ctx, ui.MessageTypeInfo, "No history for key '%s'\n", key)
+	}
+	for _, revision := range revisions {
+		setAt := "unknown"
+		if revision.SetAt != nil {
+			setAt = revision.SetAt.Format(time.RFC3339)
+		}
+		ended := "replaced"
+		if revision.Deleted {
+			ended = "deleted"
+		}
+		if err := cmdCtx.UI.Present(cmdCtx.Ctx, ui.MessageTypeInfo, "v%d\tset=%s\t%s=%s\n    %s\n",
+			revision.Version, setAt, ended, revision.ReplacedAt.Format(time.RFC3339), revision.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runKVRestore(cmd *cobra.Command, args []string) error {
+	atFlag, _ := cmd.Flags().GetString("at")
+	version, _ := cmd.Flags().GetUint64("version")
+	key := args[0]
+
+	if (atFlag == "") == (version == 0) {
+		return fmt.Errorf("specify exactly one of --at and --version")
+	}
+	var at time.Time
+	if atFlag != "" {
+		var err error
+		if at, err = parseKVTime(atFlag); err != nil {
+			return err
+		}
+	}
+
+	app, err := core.NewAppFromConfig(cmdCtx.Config)
+	if err != nil {
+		return fmt.Errorf("failed to initialize application: %w", err)
+	}
+	defer app.Close()
+
+	history, err := kvHistory(app)
+	if err != nil {
+		return err
+	}
+	var revision core.KVRevision
+	if atFlag != "" {
+		revision, err = history.RestoreAt(key, at)
+	} else {
+		revision, err = history.RestoreVersion(key, version)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to restore key '%s': %w", key, err)
+	}
+
+	return cmdCtx.UI.ShowSuccess(cmdCtx.Ctx, "Restored key '%s' to version %d: %s\n", key, revision.Version, revision.Value)
+}
+
+func runKVRetention(cmd *cobra.Command, args []string) error {
+	app, err := core.NewAppFromConfig(cmdCtx.Config)
+	if err != nil {
+		return fmt.Errorf("failed to initialize application: %w", err)
+	}
+	defer app.Close()
+
+	history, err := kvHistory(app)
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 2 {
+		keep, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid number of values to keep %q: %w", args[1], err)
+		}
+		if err := history.SetHistoryRetention(args[0], keep); err != nil {
+			return err
+		}
+		return cmdCtx.UI.ShowSuccess(cmdCtx.Ctx, "Keys starting with '%s' keep %d previous values\n", args[0], keep)
+	}
+
+	retention, err := history.HistoryRetention()
+	if err != nil {
+		return err
+	}
+	prefixes := make([]string, 0, len(retention))
+	for prefix := range retention {
+		if len(args) == 1 && !strings.HasPrefix(args[0], prefix) {
+			continue
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	if len(prefixes) == 0 {
+		return cmdCtx.UI.Present(cmdCtx.Ctx, ui.MessageTypeInfo, "No history is kept\n")
+	}
+	sort.Strings(prefixes)
+	if len(args) == 1 {
+		// Only the longest matching prefix applies
+		prefixes = prefixes[len(prefixes)-1:]
+	}
+	for _, prefix := range prefixes {
+		if err := cmdCtx.UI.Present(cmdCtx.Ctx, ui.MessageTypeInfo, "%q\t%d\n", prefix, retention[prefix]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseKVTime parses an RFC 3339 time, or a duration meaning that long ago
+func parseKVTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q: use RFC 3339 or a duration such as 2h", value)
+	}
+	return time.Now().Add(-d), nil
+}
@@ -4,26 +4,38 @@ import (
 	"context"
 	"log/slog"
 	"os"
-	"strings"
 
+	"github.com/petrock/example_module_path/core"
 	"github.com/petrock/example_module_path/core/ui"
 	"github.com/spf13/cobra"
 )
 
 // CommandContext holds shared dependencies for all commands
 type CommandContext struct {
-	UI  ui.UI
-	Ctx context.Context
+	UI     ui.UI
+	Ctx    context.Context
+	Config *core.Config // Loaded before any command runs, see loadConfig
 }
 
 // Global command context
 var cmdCtx *CommandContext
 
 var rootCmd = &cobra.Command{
-	Use:   "petrock_example_project_name",
+	Use:   appName,
 	Short: "The main command for the petrock_example_project_name application.",
 	Long:  `petrock_example_project_name application entry point.`,
 	// Run: func(cmd *cobra.Command, args []string) { }, // Or remove if subcommands are mandatory
+
+	// Every command starts from the same validated configuration and log level
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+		cmdCtx.Config = cfg
+		configureLogging(cfg.Log.SlogLevel())
+		return nil
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -48,25 +60,25 @@ func init() {
 	rootCmd.AddCommand(NewDeployCmd())
 	rootCmd.AddCommand(NewSelfCmd())
 	rootCmd.AddCommand(NewKVCmd())
+	rootCmd.AddCommand(NewWorkerCmd())
+	rootCmd.AddCommand(NewOutboxCmd())
 
-	// Configure logging level based on environment variable
-	logLevel := slog.LevelInfo // Default level
-	levelStr := strings.ToLower(os.Getenv("PETROCK_LOG_LEVEL"))
-	switch levelStr {
-	case "debug":
-		logLevel = slog.LevelDebug
-	case "warn":
-		logLevel = slog.LevelWarn
-	case "error":
-		logLevel = slog.LevelError
-	}
+	// Flags shared by all commands; see config.go for how they override the config file
+	rootCmd.PersistentFlags().String("config", "", "Config file (TOML, or JSON if it ends in .json); defaults to $"+configEnvPrefix()+"_CONFIG")
+	rootCmd.PersistentFlags().String("db-path", core.DefaultConfig().DB.Path, "Path to the SQLite database file")
+	rootCmd.PersistentFlags().String("log-level", core.DefaultConfig().Log.Level, "Log level: debug, info, warn or error")
+
+	configureLogging(slog.LevelInfo)
+}
 
+// configureLogging sends logs at level and above to stderr. Debug logs include the
+// source location, and logs made with a traced context include its trace and span IDs.
+func configureLogging(level slog.Level) {
 	opts := &slog.HandlerOptions{
-		Level:     logLevel,
-		AddSource: logLevel <= slog.LevelDebug, // Add source only for debug or lower
+		Level:     level,
+		AddSource: level <= slog.LevelDebug,
 	}
-	handler := slog.NewTextHandler(os.Stderr, opts)
-	slog.SetDefault(slog.New(handler))
+	slog.SetDefault(slog.New(core.NewTraceLogHandler(slog.NewTextHandler(os.Stderr, opts))))
 }
 
 // newCommandContext creates a new command context with UI
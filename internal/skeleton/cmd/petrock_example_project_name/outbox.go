@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/petrock/example_module_path/core"
+	"github.com/petrock/example_module_path/core/ui"
+	"github.com/spf13/cobra"
+)
+
+// NewOutboxCmd creates the 'outbox' parent command for inspecting outbound side effects
+func NewOutboxCmd() *cobra.Command {
+	outboxCmd := &cobra.Command{
+		Use:   "outbox",
+		Short: "Inspect and retry side effects recorded in the outbox",
+		Long: `Workers record outbound HTTP requests and other side effects in the outbox, from where
+the outbox worker delivers them with retries. These commands inspect and retry those entries.`,
+	}
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List outbox entries, newest first",
+		Args:  cobra.NoArgs,
+		RunE:  runOutboxList,
+	}
+	listCmd.Flags().String("status", "", "Only list entries with this status (pending, delivered or failed)")
+	listCmd.Flags().Int("limit", 50, "Maximum number of entries to list")
+
+	showCmd := &cobra.Command{
+		Use:   "show <id>",
+		Short: "Show an outbox entry including its payload and recorded result",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runOutboxShow,
+	}
+	retryCmd := &cobra.Command{
+		Use:   "retry <id>",
+		Short: "Make a failed or delivered entry pending again",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runOutboxRetry,
+	}
+
+	for _, c := range []*cobra.Command{listCmd, showCmd, retryCmd} {
+		outboxCmd.AddCommand(c)
+	}
+
+	return outboxCmd
+}
+
+// parseOutboxID parses the <id> argument of outbox subcommands
+func parseOutboxID(arg string) (uint64, error) {
+	id, err := strconv.ParseUint(arg, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid outbox entry id %q: %w", arg, err)
+	}
+	return id, nil
+}
+
+func runOutboxList(cmd *cobra.Command, args []string) error {
+	status, _ := cmd.Flags().GetString("status")
+	limit, _ := cmd.Flags().GetInt("limit")
+
+	app, err := core.NewAppFromConfig(cmdCtx.Config)
+	if err != nil {
+		return fmt.Errorf("failed to initialize application: %w", err)
+	}
+	defer app.Close()
+
+	entries, err := app.Outbox.List(context.Background(), status, limit)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return cmdCtx.UI.Present(cmdCtx.Ctx, ui.MessageTypeInfo, "No outbox entries\n")
+	}
+
+	for _, entry := range entries {
+		if err := cmdCtx.UI.Present(cmdCtx.Ctx, ui.MessageTypeInfo, "%d\t%s\t%s\t%s\tattempts=%d\tsource=%s\tcreated=%s\n",
+			entry.ID, entry.Status, entry.Kind, entry.Key, entry.Attempts, entry.Source, entry.CreatedAt.Format(time.RFC3339)); err != nil {
+			return err
+		}
+		if entry.LastError != "" {
+			if err := cmdCtx.UI.Present(cmdCtx.Ctx, ui.MessageTypeInfo, "    %s\n", entry.LastError); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func runOutboxShow(cmd *cobra.Command, args []string) error {
+	id, err := parseOutboxID(args[0])
+	if err != nil {
+		return err
+	}
+
+	app, err := core.NewAppFromConfig(cmdCtx.Config)
+	if err != nil {
+		return fmt.Errorf("failed to initialize application: %w", err)
+	}
+	defer app.Close()
+
+	entry, err := app.Outbox.Get(context.Background(), id)
+	if err != nil {
+		return err
+	}
+	// Pretty print the entry as JSON
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(entry); err != nil {
+		return fmt.Errorf("failed to encode outbox entry as JSON: %w", err)
+	}
+
+	return cmdCtx.UI.Present(cmdCtx.Ctx, ui.MessageTypeInfo, buf.String())
+}
+
+func runOutboxRetry(cmd *cobra.Command, args []string) error {
+	id, err := parseOutboxID(args[0])
+	if err != nil {
+		return err
+	}
+
+	app, err := core.NewAppFromConfig(cmdCtx.Config)
+	if err != nil {
+		return fmt.Errorf("failed to initialize application: %w", err)
+	}
+	defer app.Close()
+
+	if err := app.Outbox.Retry(context.Background(), id); err != nil {
+		return err
+	}
+	return cmdCtx.UI.ShowSuccess(cmdCtx.Ctx, "Outbox entry %d will be delivered on the outbox worker's next cycle\n", id)
+}
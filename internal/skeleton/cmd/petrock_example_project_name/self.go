@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"text/tabwriter"
 
 	"github.com/petrock/example_module_path/core"
 	"github.com/spf13/cobra"
@@ -20,6 +21,7 @@ func NewSelfCmd() *cobra.Command {
 
 	// Add subcommands
 	selfCmd.AddCommand(NewSelfInspectCmd())
+	selfCmd.AddCommand(NewSelfConfigCmd())
 
 	return selfCmd
 }
@@ -34,8 +36,7 @@ func NewSelfInspectCmd() *cobra.Command {
 	}
 
 	// Add flags
-	inspectCmd.Flags().String("format", "json", "Output format: json")
-	inspectCmd.Flags().String("db-path", "app.db", "Path to the SQLite database file")
+	inspectCmd.Flags().String("format", "json", "Output format: json, or openapi for an OpenAPI 3.1 document of the command and query API")
 	inspectCmd.Flags().Bool("debug", false, "Show debug information on stderr")
 
 	return inspectCmd
@@ -43,15 +44,13 @@ func NewSelfInspectCmd() *cobra.Command {
 
 func runSelfInspect(cmd *cobra.Command, args []string) error {
 	format, _ := cmd.Flags().GetString("format")
-	dbPath, _ := cmd.Flags().GetString("db-path")
 
-	// Only support JSON for now
-	if format != "json" {
-		return fmt.Errorf("unsupported format: %s (only 'json' is currently supported)", format)
+	if format != "json" && format != "openapi" {
+		return fmt.Errorf("unsupported format: %s (supported formats are 'json' and 'openapi')", format)
 	}
 
 	// Initialize the application
-	app, err := core.NewApp(dbPath)
+	app, err := core.NewAppFromConfig(cmdCtx.Config)
 	if err != nil {
 		return fmt.Errorf("failed to initialize application: %w", err)
 	}
@@ -74,10 +73,19 @@ func runSelfInspect(cmd *cobra.Command, args []string) error {
 	app.RegisterRoute("GET /commands", handleListCommands(app.CommandRegistry))
 	app.RegisterRoute("POST /commands", handleExecuteCommand(app.Executor, app.CommandRegistry))
 	app.RegisterRoute("GET /queries", handleListQueries(app.QueryRegistry))
+	app.RegisterRoute("GET /openapi.json", handleOpenAPI(app))
+	app.RegisterRoute("POST /queries", handleBatchQueries(app))
 	app.RegisterRoute("GET /queries/{feature}/{queryName}", handleExecuteQuery(app.QueryRegistry))
+	app.RegisterRoute("GET /queries/{feature}/{queryName}/stream", handleStreamQuery(app))
+	app.RegisterRoute("GET /_/workers", handleWorkerStatus(app), app.RequireAdmin()) // Lists errors and lease holders, so admins only
+	app.RegisterAdminRoutes() // /_/admin, guarded by the [admin] config section or app.AdminCheck
+	app.RegisterRoute("GET /_/metrics", core.HandleMetrics(app.Metrics))
 
 	// Gather application metadata
-	result := app.GetInspectResult()
+	var result any = app.GetInspectResult()
+	if format == "openapi" {
+		result = app.OpenAPI("petrock_example_project_name", apiVersion)
+	}
 
 	// Output as JSON
 	encoder := json.NewEncoder(os.Stdout)
@@ -87,4 +95,60 @@ func runSelfInspect(cmd *cobra.Command, args []string) error {
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+// NewSelfConfigCmd creates the 'self config' command
+func NewSelfConfigCmd() *cobra.Command {
+	configCmd := &cobra.Command{
+		Use:   "config",
+		Short: "Print the effective configuration",
+		Long: `Registers all features and prints every config key with its effective value and
+where it came from: the default, the config file, an environment variable or a flag.
+Secrets are masked. Exits with an error if the configuration is invalid.`,
+		RunE: runSelfConfig,
+	}
+
+	configCmd.Flags().String("format", "text", "Output format: text or json")
+
+	return configCmd
+}
+
+func runSelfConfig(cmd *cobra.Command, args []string) error {
+	format, _ := cmd.Flags().GetString("format")
+	if format != "text" && format != "json" {
+		return fmt.Errorf("unsupported format: %s (supported formats are 'text' and 'json')", format)
+	}
+
+	// Features load their config sections while they are registered
+	app, err := newRegisteredApp()
+	if err != nil {
+		return err
+	}
+	defer app.Close()
+
+	settings := app.Config.Settings()
+	if format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(settings); err != nil {
+			return fmt.Errorf("failed to encode config as JSON: %w", err)
+		}
+	} else {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "KEY\tVALUE\tSOURCE")
+		for _, setting := range settings {
+			source := setting.Source
+			if setting.Origin != "" {
+				source += " " + setting.Origin
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", setting.Key, setting.Value, source)
+		}
+		if err := w.Flush(); err != nil {
+			return fmt.Errorf("failed to write config: %w", err)
+		}
+	}
+
+	if err := app.Config.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+	return nil
+}
@@ -3,14 +3,18 @@ package main
 import (
 	"context"
 	"encoding/json" // Added for JSON handling in API endpoints
+	"errors"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
 	"net/url" // Added for parsing query parameters
 	"os"
+	"os/signal"
 	"reflect" // Added for command/query execution handlers
 	"strconv" // Added for converting query parameters
 	"strings" // Added for query parameter population helper
+	"syscall"
 	"time"
 
 	"github.com/petrock/example_module_path/core" // Assuming core package exists
@@ -62,63 +66,30 @@ func NewServeCmd() *cobra.Command {
 		RunE:  runServe,
 	}
 
-	// Add flags like --port, --host
-	serveCmd.Flags().IntP("port", "p", 8080, "Port to listen on")
-	serveCmd.Flags().String("host", "localhost", "Host to bind to")
-	serveCmd.Flags().String("db-path", "app.db", "Path to the SQLite database file") // Added db-path flag
-	serveCmd.Flags().String("log-level", "", "Log level: debug, info, warn, error (defaults to LOG_LEVEL env var or 'info')")
+	// Flags override the [server] section of the config file, see config.go
+	defaults := core.DefaultConfig().Server
+	serveCmd.Flags().IntP("port", "p", defaults.Port, "Port to listen on")
+	serveCmd.Flags().String("host", defaults.Host, "Host to bind to")
+	serveCmd.Flags().Bool("trust-proxy", defaults.TrustProxy, "Take the client IP for rate limits from X-Forwarded-For, as set by a reverse proxy")
+	serveCmd.Flags().Bool("persist-rate-limits", defaults.PersistRateLimits, "Keep rate limit buckets in the database so they survive restarts")
 
 	return serveCmd
 }
 
 func runServe(cmd *cobra.Command, args []string) error {
-	// Configure logging level from environment variable or flag
-	logLevelStr, _ := cmd.Flags().GetString("log-level")
-	if logLevelStr == "" {
-		// Check environment variable if flag is not set
-		logLevelStr = os.Getenv("LOG_LEVEL")
-		if logLevelStr == "" {
-			// Default to info if neither flag nor env var is set
-			logLevelStr = "info"
-		}
-	}
-
-	// Parse log level string to slog.Level
-	var level slog.Level
-	switch strings.ToLower(logLevelStr) {
-	case "debug":
-		level = slog.LevelDebug
-	case "info":
-		level = slog.LevelInfo
-	case "warn", "warning":
-		level = slog.LevelWarn
-	case "error":
-		level = slog.LevelError
-	default:
-		// Invalid log level, default to info and warn
-		cmdCtx.UI.Present(cmdCtx.Ctx, ui.MessageTypeWarning, "Warning: invalid log level '%s', using 'info'\n", logLevelStr)
-		level = slog.LevelInfo
-	}
-
-	// Configure slog with the appropriate level
-	logHandler := slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
-		Level: level,
-	})
-	slog.SetDefault(slog.New(logHandler))
-	cmdCtx.UI.Present(cmdCtx.Ctx, ui.MessageTypeInfo, "Log level set to: %s\n", logLevelStr)
-
-	port, _ := cmd.Flags().GetInt("port")
-	host, _ := cmd.Flags().GetString("host")
-	dbPath, _ := cmd.Flags().GetString("db-path") // Get db-path flag value
-	addr := fmt.Sprintf("%s:%d", host, port)
+	cfg := cmdCtx.Config
+	addr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port)
 
 	// --- Initialization using core.App ---
 	// Initialize the application using the new core.App struct
-	app, err := core.NewApp(dbPath)
+	app, err := core.NewAppFromConfig(cfg)
 	if err != nil {
 		return fmt.Errorf("failed to initialize application: %w", err)
 	}
 	// Don't use defer app.Close() - we'll handle shutdown more carefully below
+	if cfg.Server.PersistRateLimits {
+		app.RateLimiter.Persist(app.KVStore)
+	}
 
 	// Initialize Application State
 	slog.Debug("Initializing application state")
@@ -131,26 +102,31 @@ func runServe(cmd *cobra.Command, args []string) error {
 	// Set app state
 	app.AppState = appState
 	
+	// Setup middleware around every route; the first one sees the request first.
+	// Features can add more with app.Use, which runs inside these, or wrap single
+	// routes via app.RegisterRoute.
+	app.Use(
+		core.RequestID(),
+		core.ClientIP(cfg.Server.TrustProxy),
+		core.Tracing(app.Tracer),
+		core.AccessLog(slog.Default()),
+		core.HTTPMetrics(app.Metrics),
+		core.Recover(),
+		core.SecurityHeaders(nil),
+		core.MaxBodySize(core.DefaultMaxBodySize),
+		core.CSRF(core.InboundWebhookPrefix), // Inbound webhooks verify signatures instead
+		core.Compress(),
+	)
+
 	// Register features BEFORE replaying the log
 	RegisterAllFeatures(app)
 
-	// Replay the message log to build application state
-	if err := app.ReplayLog(); err != nil {
-		return fmt.Errorf("failed to replay message log: %w", err)
-	}
-	
-	// Start workers after log replay
-	cmdCtx.UI.Present(cmdCtx.Ctx, ui.MessageTypeInfo, "Starting background workers...\n")
-	if err := app.StartWorkers(context.Background()); err != nil {
-		return fmt.Errorf("failed to start workers: %w", err)
+	// Features have loaded their config sections; refuse to start with invalid settings
+	if err := app.Config.Validate(); err != nil {
+		app.Close()
+		return fmt.Errorf("invalid configuration: %w", err)
 	}
 
-	// Example: Setup middleware (logging, CSRF)
-	// Note: Standard library middleware is often wrapped around specific handlers or the global mux.
-	// var handler http.Handler = mux
-	// handler = loggingMiddleware(handler)
-	// handler = csrfMiddleware(handler) // CSRF often needs session state, which was removed. Re-evaluate CSRF strategy.
-
 	// Example: Setup static file serving (if using embedded assets)
 	// coreAssetsFS := core.GetAssetsFS() // Assuming core has embedded assets
 	// mux.Handle("/assets/core/", http.StripPrefix("/assets/core/", http.FileServer(http.FS(coreAssetsFS))))
@@ -166,7 +142,15 @@ func runServe(cmd *cobra.Command, args []string) error {
 	app.RegisterRoute("GET /commands", handleListCommands(app.CommandRegistry))
 	app.RegisterRoute("POST /commands", handleExecuteCommand(app.Executor, app.CommandRegistry))
 	app.RegisterRoute("GET /queries", handleListQueries(app.QueryRegistry))
+	app.RegisterRoute("GET /openapi.json", handleOpenAPI(app))
+	app.RegisterRoute("POST /queries", handleBatchQueries(app))
 	app.RegisterRoute("GET /queries/{feature}/{queryName}", handleExecuteQuery(app.QueryRegistry))
+	app.RegisterRoute("GET /queries/{feature}/{queryName}/stream", handleStreamQuery(app))
+	app.RegisterRoute("GET /_/workers", handleWorkerStatus(app), app.RequireAdmin()) // Lists errors and lease holders, so admins only
+	app.RegisterAdminRoutes() // /_/admin, guarded by the [admin] config section or app.AdminCheck
+	app.RegisterRoute("GET /_/health/live", handleHealthLive())
+	app.RegisterRoute("GET /_/health/ready", handleHealthReady(app))
+	app.RegisterRoute("GET /_/metrics", core.HandleMetrics(app.Metrics))
 	
 	// Setup UI Gallery routes
 	app.RegisterRoute("GET /_/ui", gallery.HandleGallery(app))
@@ -180,57 +164,89 @@ func runServe(cmd *cobra.Command, args []string) error {
 	// --- Server Start and Shutdown ---
 	server := &http.Server{
 		Addr:         addr,
-		Handler:      app.Mux, // Use the configured mux (potentially wrapped in middleware)
-		ReadTimeout:  5 * time.Second,
-		WriteTimeout: 10 * time.Second,
-		IdleTimeout:  120 * time.Second,
+		Handler:      serveAfterReplay(app, app.Handler()), // The mux wrapped in the middleware added with app.Use
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+		IdleTimeout:  cfg.Server.IdleTimeout,
 	}
 
-	// Set up graceful shutdown with signal handling
-	stopCtx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-	
-	// Handle shutdown in a goroutine
+	// SIGINT or SIGTERM starts a graceful shutdown; a second signal kills the process
+	stopCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Listen before replaying, so health checks can report progress while the state is rebuilt
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		app.Close()
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	serverErr := make(chan error, 1)
+	cmdCtx.UI.Present(cmdCtx.Ctx, ui.MessageTypeInfo, "Starting server at %s\n", addr)
 	go func() {
-		// Here you would typically use signal.Notify to capture SIGINT, SIGTERM
-		// But for simplicity in this template, we'll just wait for context cancellation
-		<-stopCtx.Done()
-		slog.Info("Shutting down server...")
-		
-		// Create shutdown context with timeout
-		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer shutdownCancel()
-		
-		// First gracefully stop all workers
-		slog.Info("Stopping background workers...")
-		if err := app.StopWorkers(shutdownCtx); err != nil {
-			slog.Warn("Error stopping workers", "error", err)
-		}
-		
-		// Then gracefully shut down the HTTP server
-		if err := server.Shutdown(shutdownCtx); err != nil {
-			slog.Error("Error during server shutdown", "error", err)
-		}
-		
-		// Close all remaining resources
-		if err := app.Close(); err != nil {
-			slog.Error("Error closing application resources", "error", err)
-		}
+		serverErr <- server.Serve(listener)
 	}()
+
+	// Replay the message log to build application state
+	if err := app.ReplayLog(); err != nil {
+		server.Close()
+		app.Close()
+		return fmt.Errorf("failed to replay message log: %w", err)
+	}
 	
-	// Start the server
-	cmdCtx.UI.Present(cmdCtx.Ctx, ui.MessageTypeInfo, "Starting server at %s\n", addr)
-	err = server.ListenAndServe()
-	if err != http.ErrServerClosed {
-		// Only return an error if it's not due to graceful shutdown
-		cancel() // Signal shutdown to the goroutine
+	// Start workers after log replay
+	cmdCtx.UI.Present(cmdCtx.Ctx, ui.MessageTypeInfo, "Starting background workers...\n")
+	if err := app.StartWorkers(context.Background()); err != nil {
+		server.Close()
+		app.Close()
+		return fmt.Errorf("failed to start workers: %w", err)
+	}
+
+	select {
+	case err := <-serverErr:
+		app.Close()
 		return fmt.Errorf("server error: %w", err)
+	case <-stopCtx.Done():
+		stop()
 	}
-	
+	slog.Info("Shutting down server...")
+
+	// Create shutdown context with timeout
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer shutdownCancel()
+
+	// First stop accepting requests and let the ones in flight finish
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		slog.Error("Error during server shutdown", "error", err)
+	}
+
+	// Then gracefully stop all workers
+	slog.Info("Stopping background workers...")
+	if err := app.StopWorkers(shutdownCtx); err != nil {
+		slog.Warn("Error stopping workers", "error", err)
+	}
+
+	// Close all remaining resources
+	if err := app.Close(); err != nil {
+		slog.Error("Error closing application resources", "error", err)
+	}
+
 	cmdCtx.UI.ShowSuccess(cmdCtx.Ctx, "Server shut down successfully\n")
 	return nil
 }
 
+// serveAfterReplay answers every request except the health checks with 503 Service
+// Unavailable until the message log has been replayed, so no request sees partial state.
+func serveAfterReplay(app *core.App, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !app.Replayed() && !strings.HasPrefix(r.URL.Path, "/_/health/") {
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, "Service Unavailable: replaying message log", http.StatusServiceUnavailable)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 // handleListCommands creates an http.HandlerFunc that lists registered command types.
 func handleListCommands(registry *core.CommandRegistry) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -239,7 +255,7 @@ func handleListCommands(registry *core.CommandRegistry) http.HandlerFunc {
 			return
 		}
 
-		commandNames := registry.RegisteredCommandNames()
+		commandNames := registry.PublicCommandNames()
 
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
@@ -288,8 +304,9 @@ func handleExecuteCommand(executor *core.Executor, registry *core.CommandRegistr
 		}
 
 		// Look up the command type in the registry using the kebab-case name
+		// Admin-only commands are treated as unknown, so that they can't be discovered either
 		cmdType, found := registry.GetCommandType(req.Type) // req.Type should be "feature/command-name"
-		if !found {
+		if !found || registry.AdminOnly(req.Type) {
 			slog.Warn("Received request for unknown command type", "name", req.Type)
 			http.Error(w, fmt.Sprintf("Bad Request: unknown command type %q", req.Type), http.StatusBadRequest)
 			return
@@ -325,9 +342,8 @@ func handleExecuteCommand(executor *core.Executor, registry *core.CommandRegistr
 			return
 		}
 
-		// Get the actual command value (dereferenced) to pass to Dispatch
-		// Ensure the command instance implements core.Command (which includes CommandName)
-		cmdValue, ok := reflect.ValueOf(cmdInstancePtr).Elem().Interface().(core.Command)
+		// Commands implement core.Command with pointer receivers and are executed as pointers
+		cmdValue, ok := cmdInstancePtr.(core.Command)
 		if !ok {
 			// Defensive check
 			slog.Error("Internal error: command instance does not implement core.Command", "name", req.Type, "type", reflect.TypeOf(cmdInstancePtr).Elem())
@@ -340,15 +356,20 @@ func handleExecuteCommand(executor *core.Executor, registry *core.CommandRegistr
 		execErr := executor.Execute(r.Context(), cmdValue) // Use executor.Execute
 
 		if execErr != nil {
+			if retryAfter, limited := core.RateLimited(execErr); limited {
+				core.WriteRateLimited(w, r, retryAfter)
+				return
+			}
 			slog.Error("Error executing command", "name", req.Type, "error", execErr)
-			// Handle validation errors vs. other errors
-			// Example: Check if the error is a validation error (you might need to define custom error types or check wrapped errors)
-			// if errors.As(execErr, &core.ValidationError{}) { // Assuming a ValidationError type
-			//     respondJSON(w, http.StatusBadRequest, map[string]string{"error": execErr.Error()})
-			// } else {
-			// Treat other errors (logging failure, etc.) as internal server errors
+			// Rejected commands are the client's fault; other errors (logging failure, etc.) are not
+			var validationErr *core.ValidationError
+			if errors.As(execErr, &validationErr) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": execErr.Error()})
+				return
+			}
 			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-			// }
 			return
 		}
 
@@ -361,6 +382,21 @@ func handleExecuteCommand(executor *core.Executor, registry *core.CommandRegistr
 	}
 }
 
+// apiVersion is the version reported in the OpenAPI document. Change it when the
+// command or query API changes in a way clients notice.
+const apiVersion = "1.0.0"
+
+// handleOpenAPI creates an http.HandlerFunc that serves the OpenAPI document describing
+// POST /commands and the query endpoints.
+func handleOpenAPI(app *core.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(app.OpenAPI("petrock_example_project_name", apiVersion)); err != nil {
+			slog.Error("Failed to encode OpenAPI document", "error", err)
+		}
+	}
+}
+
 // handleListQueries creates an http.HandlerFunc that lists registered query types.
 func handleListQueries(registry *core.QueryRegistry) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -466,6 +502,157 @@ func handleExecuteQuery(registry *core.QueryRegistry) http.HandlerFunc {
 	}
 }
 
+// batchQueryRequest is used to decode the incoming JSON payload for batch query execution.
+type batchQueryRequest struct {
+	Queries []core.BatchQuery `json:"queries"`
+}
+
+// handleBatchQueries creates an http.HandlerFunc that dispatches several queries at once.
+// All queries run against the same log version; each result carries its own error.
+func handleBatchQueries(app *core.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" {
+			http.Error(w, "Unsupported Media Type: Content-Type must be application/json", http.StatusUnsupportedMediaType)
+			return
+		}
+
+		var req batchQueryRequest
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&req); err != nil {
+			slog.Error("Failed to decode batch query request body", "error", err)
+			http.Error(w, fmt.Sprintf("Bad Request: %s", err.Error()), http.StatusBadRequest)
+			return
+		}
+		defer r.Body.Close()
+
+		if err := core.ValidateBatch(req.Queries); err != nil {
+			http.Error(w, fmt.Sprintf("Bad Request: %s", err.Error()), http.StatusBadRequest)
+			return
+		}
+
+		response, err := app.DispatchBatch(r.Context(), req.Queries)
+		if err != nil {
+			slog.Error("Error dispatching query batch", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		slog.Info("Query batch executed via API", "count", len(req.Queries), "version", response.Version)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			slog.Error("Failed to encode batch query response", "error", err)
+		}
+	}
+}
+
+// handleWorkerStatus creates an http.HandlerFunc that reports the status of all workers as JSON.
+func handleWorkerStatus(app *core.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		statuses, err := app.WorkerStatuses(r.Context())
+		if err != nil {
+			slog.Error("Failed to collect worker statuses", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{"workers": statuses}); err != nil {
+			slog.Error("Failed to encode worker statuses", "error", err)
+		}
+	}
+}
+
+// handleHealthLive creates an http.HandlerFunc that reports the process is up. It does no
+// checks, so a supervisor only restarts the process if it stops answering.
+func handleHealthLive() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(map[string]string{"status": "ok"}); err != nil {
+			slog.Error("Failed to encode liveness", "error", err)
+		}
+	}
+}
+
+// handleHealthReady creates an http.HandlerFunc that runs the readiness checks and answers
+// 200 if the app is ready for traffic and 503 Service Unavailable otherwise.
+func handleHealthReady(app *core.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		readiness := app.Readiness(r.Context())
+
+		status := http.StatusOK
+		if !readiness.Ready {
+			status = http.StatusServiceUnavailable
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		if err := json.NewEncoder(w).Encode(readiness); err != nil {
+			slog.Error("Failed to encode readiness", "error", err)
+		}
+	}
+}
+
+// handleStreamQuery creates an http.HandlerFunc that streams query results as Server-Sent Events.
+// The query is re-dispatched whenever the log advances and a "result" event is sent only
+// when the result changed. Errors end the stream with an "error" event.
+func handleStreamQuery(app *core.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		fullQueryName := fmt.Sprintf("%s/%s", r.PathValue("feature"), r.PathValue("queryName"))
+
+		query, err := app.QueryRegistry.NewQuery(fullQueryName, core.URLValuesSource{Values: r.URL.Query()})
+		if err != nil {
+			if errors.Is(err, core.ErrUnknownQuery) {
+				http.Error(w, fmt.Sprintf("Not Found: unknown query type %q", fullQueryName), http.StatusNotFound)
+				return
+			}
+			if parseErrors, ok := err.(*core.ParseErrors); ok {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"error":   "Validation failed",
+					"details": parseErrors.Errors,
+				})
+				return
+			}
+			http.Error(w, fmt.Sprintf("Bad Request: %s", err.Error()), http.StatusBadRequest)
+			return
+		}
+
+		// Streams outlive the server's WriteTimeout, so lift the deadline for this response
+		rc := http.NewResponseController(w)
+		if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+			slog.Warn("Could not clear write deadline for query stream", "name", fullQueryName, "error", err)
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+		rc.Flush()
+
+		slog.Debug("Query stream opened", "name", fullQueryName)
+		err = app.StreamQuery(r.Context(), query, core.DefaultStreamPollInterval, func(update core.QueryUpdate) error {
+			data, err := json.Marshal(update.Result)
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintf(w, "id: %d\nevent: result\ndata: %s\n\n", update.Version, data); err != nil {
+				return err
+			}
+			return rc.Flush()
+		})
+		if err != nil {
+			slog.Error("Query stream failed", "name", fullQueryName, "error", err)
+			fmt.Fprintf(w, "event: error\ndata: %q\n\n", err.Error())
+			rc.Flush()
+		}
+		slog.Debug("Query stream closed", "name", fullQueryName)
+	}
+}
+
 // populateStructFromURLParams uses reflection to set fields of a struct
 // based on values found in URL query parameters.
 // It supports string, int, and bool field types.
@@ -530,24 +717,8 @@ func populateStructFromURLParams(structVal reflect.Value, params url.Values) err
 
 // --- Placeholder Middleware/Handlers (Replace with actual implementations) ---
 
-// func loggingMiddleware(next http.Handler) http.Handler {
-// 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-// 		slog.Info("Request received", "method", r.Method, "path", r.URL.Path)
-// 		next.ServeHTTP(w, r)
-// 	})
-// }
-
-// func csrfMiddleware(next http.Handler) http.Handler {
-// 	// TODO: Implement CSRF protection (e.g., using standard library techniques or other allowed libraries)
-// 	// Re-evaluate CSRF strategy as session state (often used by CSRF libraries) was removed.
-// 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-// 		// Add CSRF token check logic here
-// 		next.ServeHTTP(w, r)
-// 	})
-// }
-
-// Session middleware removed as gorilla/sessions is not allowed.
-// Consider alternative session management if needed (e.g., client-side tokens, other libraries).
+// Sessions are provided by the auth feature (petrock new --auth), which adds its
+// session middleware with app.Use; see docs/auth.md.
 
 // func HandleIndex(/* queryRegistry *core.QueryRegistry */) http.HandlerFunc {
 // 	return func(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/petrock/example_module_path/core"
+)
+
+// namedCommand stands in for commands of optional features, such as auth, that
+// may not be part of the project.
+type namedCommand struct{ name string }
+
+func (c *namedCommand) CommandName() string { return c.name }
+
+type acceptAll struct{}
+
+func (acceptAll) ValidateCommand(ctx context.Context, cmd core.Command) error { return nil }
+
+func TestExecuteCommand_RefusesAdminOnlyCommands(t *testing.T) {
+	registry := core.NewCommandRegistry()
+	handler := func(ctx context.Context, cmd core.Command, msg *core.Message, pctx *core.ProcessingContext) error {
+		t.Errorf("Expected the admin-only command %s not to be applied", cmd.CommandName())
+		return nil
+	}
+	for _, name := range []string{"auth/register-user", "auth/login"} {
+		registry.Register(&namedCommand{name: name}, handler, acceptAll{})
+	}
+	registry.SetAdminOnly("auth/register-user", "auth/login")
+	// The executor is never reached for admin-only commands
+	h := handleExecuteCommand(nil, registry)
+
+	for _, name := range []string{"auth/register-user", "auth/login"} {
+		body := `{"type":"` + name + `","payload":{}}`
+		r := httptest.NewRequest(http.MethodPost, "/commands", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "unknown command type") {
+			t.Errorf("%s: expected 400 unknown command type, got %d %q", name, w.Code, w.Body)
+		}
+	}
+}
+
+func TestWorkerStatus_IsAdminOnly(t *testing.T) {
+	app, err := core.NewApp(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewApp failed: %v", err)
+	}
+	t.Cleanup(func() { app.Close() })
+	app.Mux = http.NewServeMux()
+	admin := false
+	app.AdminCheck = func(r *http.Request) bool { return admin }
+	app.RegisterRoute("GET /_/workers", handleWorkerStatus(app), app.RequireAdmin())
+	h := app.Handler()
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/_/workers", nil))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for a request that isn't an admin's, got %d: %s", w.Code, w.Body)
+	}
+
+	admin = true
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/_/workers", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"workers"`) {
+		t.Errorf("Expected admins to get the worker statuses, got %d: %s", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,364 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/petrock/example_module_path/core"
+	"github.com/petrock/example_module_path/core/ui"
+	"github.com/spf13/cobra"
+)
+
+// NewWorkerCmd creates the 'worker' parent command for background worker operations
+func NewWorkerCmd() *cobra.Command {
+	workerCmd := &cobra.Command{
+		Use:   "worker",
+		Short: "Commands for background worker operations",
+		Long:  `Commands for inspecting and operating the application's background workers.`,
+	}
+
+	// Add subcommands
+	workerCmd.AddCommand(NewWorkerListCmd())
+	workerCmd.AddCommand(NewWorkerRunCmd())
+	workerCmd.AddCommand(NewWorkerControlCmds()...)
+	workerCmd.AddCommand(NewWorkerDLQCmd())
+
+	return workerCmd
+}
+
+// NewWorkerListCmd creates the 'worker list' command
+func NewWorkerListCmd() *cobra.Command {
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List workers with their saved position, lag and pause state",
+		Args:  cobra.NoArgs,
+		RunE:  runWorkerList,
+	}
+
+	return listCmd
+}
+
+// NewWorkerControlCmds creates the 'worker pause', 'resume', 'seek' and 'replay' commands
+func NewWorkerControlCmds() []*cobra.Command {
+	pauseCmd := &cobra.Command{
+		Use:   "pause <name>",
+		Short: "Stop a worker in every process until it is resumed",
+		Long: `Pauses the named worker. A serving process that runs it stops it within a second, and no
+process starts it again, including after a restart, until 'worker resume' is called.`,
+		Args: cobra.ExactArgs(1),
+		RunE: runWorkerPause,
+	}
+	resumeCmd := &cobra.Command{
+		Use:   "resume <name>",
+		Short: "Let a paused worker run again",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runWorkerResume,
+	}
+	seekCmd := &cobra.Command{
+		Use:   "seek <name> <message-id>",
+		Short: "Set the position after which a worker handles messages again",
+		Long: `Sets the saved position of the named worker. The next time it runs, the worker rebuilds its
+state from the messages up to <message-id> and handles every later message again, with side
+effects. Use 0 to handle the whole log again. The worker must be paused or not running.`,
+		Args: cobra.ExactArgs(2),
+		RunE: runWorkerSeek,
+	}
+	replayCmd := &cobra.Command{
+		Use:   "replay <name>",
+		Short: "Rebuild a worker's state from the log without side effects",
+		Long: `Makes the named worker discard its state and rebuild it from the messages up to its saved
+position the next time it runs. Handlers see these messages as replayed and skip their side
+effects. The worker must be paused or not running.`,
+		Args: cobra.ExactArgs(1),
+		RunE: runWorkerReplay,
+	}
+
+	return []*cobra.Command{pauseCmd, resumeCmd, seekCmd, replayCmd}
+}
+
+// NewWorkerRunCmd creates the 'worker run' command
+func NewWorkerRunCmd() *cobra.Command {
+	runCmd := &cobra.Command{
+		Use:   "run <name>",
+		Short: "Run a single work cycle of a worker",
+		Long: `Rebuilds application and worker state from the message log and then runs exactly one
+Work() cycle of the named worker, regardless of its schedule.`,
+		Args: cobra.ExactArgs(1),
+		RunE: runWorkerRun,
+	}
+
+	return runCmd
+}
+
+// NewWorkerDLQCmd creates the 'worker dlq' parent command for dead-letter operations
+func NewWorkerDLQCmd() *cobra.Command {
+	dlqCmd := &cobra.Command{
+		Use:   "dlq",
+		Short: "Inspect, requeue or drop a worker's dead-lettered messages",
+		Long: `Messages whose handler kept failing after the worker's retry policy was exhausted are
+moved to a per-worker dead-letter list. These commands operate on that list.`,
+	}
+
+	listCmd := &cobra.Command{
+		Use:   "list <worker>",
+		Short: "List the dead-lettered messages of a worker",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runWorkerDLQList,
+	}
+	requeueCmd := &cobra.Command{
+		Use:   "requeue <worker> <message-id>",
+		Short: "Retry a dead-lettered message on the worker's next cycle",
+		Args:  cobra.ExactArgs(2),
+		RunE:  runWorkerDLQRequeue,
+	}
+	dropCmd := &cobra.Command{
+		Use:   "drop <worker> <message-id>",
+		Short: "Remove a dead-lettered message without handling it",
+		Args:  cobra.ExactArgs(2),
+		RunE:  runWorkerDLQDrop,
+	}
+
+	for _, c := range []*cobra.Command{listCmd, requeueCmd, dropCmd} {
+		dlqCmd.AddCommand(c)
+	}
+
+	return dlqCmd
+}
+
+// newRegisteredApp initializes the application with all features registered,
+// without rebuilding application state from the message log.
+func newRegisteredApp() (*core.App, error) {
+	app, err := core.NewAppFromConfig(cmdCtx.Config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize application: %w", err)
+	}
+
+	app.AppState = NewAppState()
+	app.Mux = http.NewServeMux()
+	RegisterAllFeatures(app)
+	return app, nil
+}
+
+// newWorkerApp initializes the application with all features registered and
+// application state rebuilt, as needed for running workers outside of serve.
+func newWorkerApp() (*core.App, error) {
+	app, err := newRegisteredApp()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := app.Config.Validate(); err != nil {
+		app.Close()
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	if err := app.ReplayLog(); err != nil {
+		app.Close()
+		return nil, fmt.Errorf("failed to replay message log: %w", err)
+	}
+	return app, nil
+}
+
+// requireWorker returns an error listing the available workers if name is not one of them
+func requireWorker(app *core.App, name string) error {
+	if _, found := app.FindWorker(name); !found {
+		return fmt.Errorf("unknown worker %q (available: %s)", name, strings.Join(app.WorkerNames(), ", "))
+	}
+	return nil
+}
+
+func runWorkerRun(cmd *cobra.Command, args []string) error {
+	name := args[0]
+
+	app, err := newWorkerApp()
+	if err != nil {
+		return err
+	}
+	defer app.Close()
+
+	if err := requireWorker(app, name); err != nil {
+		return err
+	}
+
+	if err := app.RunWorkerOnce(context.Background(), name); err != nil {
+		return err
+	}
+
+	return cmdCtx.UI.ShowSuccess(cmdCtx.Ctx, "Worker '%s' completed one cycle\n", name)
+}
+
+func runWorkerList(cmd *cobra.Command, args []string) error {
+	app, err := newRegisteredApp()
+	if err != nil {
+		return err
+	}
+	defer app.Close()
+
+	ctx := context.Background()
+	head, err := app.MessageLog.Version(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read log version: %w", err)
+	}
+	leases, err := app.Leases.List(ctx)
+	if err != nil {
+		return err
+	}
+	holders := map[string]string{}
+	for _, lease := range leases {
+		if !lease.Expired(time.Now()) {
+			holders[lease.Name] = lease.Holder
+		}
+	}
+
+	names := app.WorkerNames()
+	if len(names) == 0 {
+		return cmdCtx.UI.Present(cmdCtx.Ctx, ui.MessageTypeInfo, "No workers registered\n")
+	}
+
+	for _, name := range names {
+		control, err := core.LoadWorkerControl(app.KVStore, name)
+		if err != nil {
+			return err
+		}
+		position, found, err := core.LoadWorkerPosition(app.KVStore, name)
+		if err != nil {
+			return err
+		}
+
+		state := "idle"
+		if holder, ok := holders[core.WorkerLeaseName(name)]; ok {
+			state = "running in " + holder
+		}
+		if control.Paused {
+			state = "paused since " + control.PausedAt.Format(time.RFC3339)
+		}
+		progress := "position=-"
+		if found {
+			lag := uint64(0)
+			if head > position {
+				lag = head - position
+			}
+			progress = fmt.Sprintf("position=%d\tlag=%d", position, lag)
+		}
+
+		if err := cmdCtx.UI.Present(cmdCtx.Ctx, ui.MessageTypeInfo, "%s\t%s\t%s\n", name, progress, state); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runWorkerPause(cmd *cobra.Command, args []string) error {
+	return controlWorker(cmd, args[0], func(app *core.App, name string) error {
+		return app.PauseWorker(context.Background(), name)
+	}, "Paused worker '%s'\n")
+}
+
+func runWorkerResume(cmd *cobra.Command, args []string) error {
+	return controlWorker(cmd, args[0], func(app *core.App, name string) error {
+		return app.ResumeWorker(context.Background(), name)
+	}, "Resumed worker '%s'\n")
+}
+
+func runWorkerSeek(cmd *cobra.Command, args []string) error {
+	position, err := strconv.ParseUint(args[1], 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid message id %q: %w", args[1], err)
+	}
+	return controlWorker(cmd, args[0], func(app *core.App, name string) error {
+		return app.SeekWorker(context.Background(), name, position)
+	}, "Worker '%s' will handle the messages after "+strconv.FormatUint(position, 10)+" the next time it runs\n")
+}
+
+func runWorkerReplay(cmd *cobra.Command, args []string) error {
+	return controlWorker(cmd, args[0], func(app *core.App, name string) error {
+		return app.ReplayWorker(context.Background(), name)
+	}, "Worker '%s' will rebuild its state from the log the next time it runs\n")
+}
+
+// controlWorker applies op to the named worker and reports success
+func controlWorker(cmd *cobra.Command, name string, op func(*core.App, string) error, successFormat string) error {
+	app, err := newRegisteredApp()
+	if err != nil {
+		return err
+	}
+	defer app.Close()
+
+	if err := requireWorker(app, name); err != nil {
+		return err
+	}
+
+	if err := op(app, name); err != nil {
+		return err
+	}
+	return cmdCtx.UI.ShowSuccess(cmdCtx.Ctx, successFormat, name)
+}
+
+func runWorkerDLQList(cmd *cobra.Command, args []string) error {
+	name := args[0]
+
+	app, err := newRegisteredApp()
+	if err != nil {
+		return err
+	}
+	defer app.Close()
+
+	if err := requireWorker(app, name); err != nil {
+		return err
+	}
+
+	letters, err := core.LoadDeadLetters(app.KVStore, name)
+	if err != nil {
+		return err
+	}
+	if len(letters) == 0 {
+		return cmdCtx.UI.Present(cmdCtx.Ctx, ui.MessageTypeInfo, "No dead-lettered messages for worker '%s'\n", name)
+	}
+
+	for _, letter := range letters {
+		status := ""
+		if letter.Requeued {
+			status = " (requeued)"
+		}
+		if err := cmdCtx.UI.Present(cmdCtx.Ctx, ui.MessageTypeInfo, "%d\t%s\tattempts=%d\tfailed=%s%s\n    %s\n",
+			letter.MessageID, letter.Type, letter.Attempts, letter.FailedAt.Format(time.RFC3339), status, letter.LastError); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runWorkerDLQRequeue(cmd *cobra.Command, args []string) error {
+	return updateDeadLetter(cmd, args, core.RequeueDeadLetter, "Requeued message %d for worker '%s'\n")
+}
+
+func runWorkerDLQDrop(cmd *cobra.Command, args []string) error {
+	return updateDeadLetter(cmd, args, core.DropDeadLetter, "Dropped message %d from worker '%s'\n")
+}
+
+// updateDeadLetter applies op to the dead letter identified by the <worker> <message-id> arguments
+func updateDeadLetter(cmd *cobra.Command, args []string, op func(core.KVStore, string, uint64) error, successFormat string) error {
+	name := args[0]
+	messageID, err := strconv.ParseUint(args[1], 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid message id %q: %w", args[1], err)
+	}
+
+	app, err := newRegisteredApp()
+	if err != nil {
+		return err
+	}
+	defer app.Close()
+
+	if err := requireWorker(app, name); err != nil {
+		return err
+	}
+
+	if err := op(app.KVStore, name, messageID); err != nil {
+		return err
+	}
+	return cmdCtx.UI.ShowSuccess(cmdCtx.Ctx, successFormat, messageID, name)
+}
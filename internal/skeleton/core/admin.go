@@ -0,0 +1,72 @@
+package core
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// AdminPath is the root of the admin area.
+const AdminPath = "/_/admin"
+
+// AdminCheck decides whether a request may use the admin area.
+type AdminCheck func(r *http.Request) bool
+
+// RequireAdmin only lets requests through that App.AdminCheck allows, or the [admin]
+// config section if AdminCheck is nil. Denied browsers without a principal are
+// redirected to LoginPath; everyone else denied gets 403 Forbidden.
+func (a *App) RequireAdmin() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			allowed := false
+			if a.AdminCheck != nil {
+				allowed = a.AdminCheck(r)
+			} else if a.Config != nil {
+				allowed = a.Config.Admin.Allows(r)
+			}
+			if allowed {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			_, loggedIn := PrincipalFromContext(r.Context())
+			if !loggedIn && r.Method == http.MethodGet && strings.Contains(r.Header.Get("Accept"), "text/html") {
+				http.Redirect(w, r, LoginPath+"?next="+url.QueryEscape(r.URL.RequestURI()), http.StatusSeeOther)
+				return
+			}
+			WriteErrorPageMessage(w, r, http.StatusForbidden, "The admin area is only open to administrators.")
+		})
+	}
+}
+
+// RegisterSecretKeys marks the KV keys starting with any of prefixes as secret, such
+// as session tokens and signing keys. The admin area lists them without their values.
+func (a *App) RegisterSecretKeys(prefixes ...string) {
+	a.secretKeys = append(a.secretKeys, prefixes...)
+}
+
+// secretKey reports whether key was marked as secret with RegisterSecretKeys.
+func (a *App) secretKey(key string) bool {
+	for _, prefix := range a.secretKeys {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// RegisterAdminRoutes registers the pages of the admin area under AdminPath, each
+// guarded by RequireAdmin: the log browser, command and query forms, the KV store
+// browser and the worker and webhook pages.
+func (a *App) RegisterAdminRoutes() {
+	admin := a.RequireAdmin()
+	a.RegisterRoute("GET "+AdminPath, HandleAdminIndex(a), admin)
+	a.RegisterRoute("GET "+AdminPath+"/log", HandleAdminLog(a), admin)
+	a.RegisterRoute("GET "+AdminPath+"/commands/{name...}", HandleAdminCommand(a), admin)
+	a.RegisterRoute("POST "+AdminPath+"/commands/{name...}", HandleAdminCommand(a), admin)
+	a.RegisterRoute("GET "+AdminPath+"/queries/{name...}", HandleAdminQuery(a), admin)
+	a.RegisterRoute("GET "+AdminPath+"/kv", HandleAdminKV(a), admin)
+	a.RegisterRoute("GET "+AdminPath+"/workers", HandleWorkersPage(a), admin)
+	a.RegisterRoute("GET "+AdminPath+"/webhooks", HandleWebhooksPage(a), admin)
+	a.RegisterRoute("POST "+AdminPath+"/webhooks/deliveries/{id}/redeliver", HandleWebhookRedeliver(a), admin)
+}
@@ -0,0 +1,161 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestRequireAdmin(t *testing.T) {
+	if DefaultConfig().Admin.AllowLocal {
+		t.Error("Expected local requests to need a login by default")
+	}
+	app := newTestApp(t)
+	app.Config.Admin.Users = []string{"alice"}
+	handler := Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), app.RequireAdmin())
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		principal  string
+		allowLocal bool
+		check      AdminCheck
+		status     int
+	}{
+		{"local", "127.0.0.1:1234", "", true, nil, http.StatusOK},
+		{"local IPv6", "[::1]:1234", "", true, nil, http.StatusOK},
+		{"local without allow_local", "127.0.0.1:1234", "", false, nil, http.StatusSeeOther},
+		{"remote anonymous", "192.0.2.1:1234", "", true, nil, http.StatusSeeOther},
+		{"remote admin", "192.0.2.1:1234", "alice", true, nil, http.StatusOK},
+		{"remote user", "192.0.2.1:1234", "bob", true, nil, http.StatusForbidden},
+		{"custom check", "127.0.0.1:1234", "bob", true, func(r *http.Request) bool { return PrincipalID(r.Context()) == "bob" }, http.StatusOK},
+		{"custom check denies", "127.0.0.1:1234", "", true, func(r *http.Request) bool { return false }, http.StatusSeeOther},
+	}
+	for _, tt := range tests {
+		app.Config.Admin.AllowLocal = tt.allowLocal
+		app.AdminCheck = tt.check
+
+		req := httptest.NewRequest("GET", "/_/admin", nil)
+		req.RemoteAddr = tt.remoteAddr
+		req.Header.Set("Accept", "text/html")
+		if tt.principal != "" {
+			req = req.WithContext(WithPrincipal(req.Context(), Principal{UserID: tt.principal, Username: tt.principal}))
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tt.status {
+			t.Errorf("%s: expected %d, got %d", tt.name, tt.status, rec.Code)
+		}
+	}
+}
+
+func TestAdminConsole(t *testing.T) {
+	app := newTestApp(t)
+	app.Mux = http.NewServeMux()
+	app.Config.Admin.AllowLocal = true
+	app.CommandRegistry.Register(&streamIncrementCommand{}, func(ctx context.Context, cmd Command, msg *Message, pctx *ProcessingContext) error {
+		return nil
+	}, streamAcceptAll{})
+	app.MessageLog.RegisterType(&streamIncrementCommand{})
+	app.QueryRegistry.Register(batchEchoQuery{}, func(ctx context.Context, query Query) (QueryResult, error) {
+		return map[string]string{"echo": query.(batchEchoQuery).Text}, nil
+	})
+	app.RegisterAdminRoutes()
+
+	serve := func(method, target string, form url.Values) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, target, strings.NewReader(form.Encode()))
+		req.RemoteAddr = "127.0.0.1:1234"
+		if form != nil {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+		rec := httptest.NewRecorder()
+		app.Handler().ServeHTTP(rec, req)
+		return rec
+	}
+
+	// The form is generated from the command's fields and executes it
+	if rec := serve("GET", "/_/admin/commands/test/increment", nil); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `name="by"`) {
+		t.Fatalf("Unexpected command form %d: %s", rec.Code, rec.Body.String())
+	}
+	for _, by := range []string{"3", "5"} {
+		if rec := serve("POST", "/_/admin/commands/test/increment", url.Values{"by": {by}}); rec.Code != http.StatusOK {
+			t.Fatalf("Expected the command to execute, got %d: %s", rec.Code, rec.Body.String())
+		}
+	}
+	if rec := serve("POST", "/_/admin/commands/test/increment", url.Values{"by": {"many"}}); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid field, got %d", rec.Code)
+	}
+	if rec := serve("GET", "/_/admin/commands/test/unknown", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown command, got %d", rec.Code)
+	}
+
+	// The log browser shows decoded payloads, per-type counts and finds messages by content
+	rec := serve("GET", "/_/admin/log?type=test/increment", nil)
+	if body := rec.Body.String(); rec.Code != http.StatusOK || !strings.Contains(body, `&#34;by&#34;: 5`) || !strings.Contains(body, "test/increment (2)") {
+		t.Errorf("Unexpected log page %d: %s", rec.Code, body)
+	}
+	messages, err := app.MessageLog.Search(context.Background(), LogFilter{Text: `"by":3`})
+	if err != nil || len(messages) != 1 || messages[0].DecodedPayload.(*streamIncrementCommand).By != 3 {
+		t.Errorf("Expected to find the first message, got %+v, %v", messages, err)
+	}
+	if messages, _ := app.MessageLog.Search(context.Background(), LogFilter{Text: "%"}); len(messages) != 0 {
+		t.Errorf("Expected %% to be matched literally, got %d messages", len(messages))
+	}
+	if messages, _ := app.MessageLog.Search(context.Background(), LogFilter{BeforeID: 2}); len(messages) != 1 || messages[0].ID != 1 {
+		t.Errorf("Expected only the message before 2, got %+v", messages)
+	}
+
+	// Queries run from their form
+	if rec := serve("GET", "/_/admin/queries/test/echo?run=1&text=hello", nil); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `&#34;echo&#34;: &#34;hello&#34;`) {
+		t.Errorf("Unexpected query result %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := serve("GET", "/_/admin/queries/test/echo?run=1", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a missing required field, got %d", rec.Code)
+	}
+
+	// The KV browser lists keys by prefix
+	if err := app.KVStore.Set("admin/test", map[string]int{"answer": 42}); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if rec := serve("GET", "/_/admin/kv?prefix=admin/", nil); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `&#34;answer&#34;: 42`) {
+		t.Errorf("Unexpected KV page %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// Secret keys are listed without their values
+	app.RegisterSecretKeys("admin/secret")
+	app.KVStore.Set("admin/secret-token", "hunter2")
+	if body := serve("GET", "/_/admin/kv?prefix=admin/", nil).Body.String(); strings.Contains(body, "hunter2") || !strings.Contains(body, "admin/secret-token") {
+		t.Errorf("Expected the secret value to be hidden: %s", body)
+	}
+
+	// Only the first adminKVLimit keys are read
+	app.KVStore.Update(func(tx KVTx) error {
+		for i := 0; i <= adminKVLimit; i++ {
+			tx.Set(fmt.Sprintf("many/%04d", i), i)
+		}
+		return nil
+	})
+	if body := serve("GET", "/_/admin/kv?prefix=many/", nil).Body.String(); !strings.Contains(body, "Only the first") || strings.Contains(body, fmt.Sprintf("many/%04d", adminKVLimit)) {
+		t.Errorf("Expected the KV page to be truncated")
+	}
+}
+
+func TestMaskSecretFields(t *testing.T) {
+	type credentials struct {
+		User string `json:"user"`
+		Hash string `json:"hash" secret:"true"`
+	}
+	masked, ok := maskSecretFields(&credentials{User: "alice", Hash: "$2a$10$abc"}).(map[string]any)
+	if !ok || masked["hash"] != "********" || masked["user"] != "alice" {
+		t.Errorf("Expected the hash to be masked, got %v", masked)
+	}
+	plain := &streamIncrementCommand{By: 1}
+	if maskSecretFields(plain) != plain {
+		t.Error("Expected payloads without secret fields to be unchanged")
+	}
+}
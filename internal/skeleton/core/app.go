@@ -3,22 +3,32 @@ package core
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log/slog"
-	"math/rand"
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // App is the central struct that holds all application dependencies and state
 type App struct {
 	DB              *sql.DB
+	Config          *Config // Settings of the core and of features, see Config.Section
 	MessageLog      *MessageLog
 	CommandRegistry *CommandRegistry
 	QueryRegistry   *QueryRegistry
 	Executor        *Executor
 	KVStore         KVStore        // Key-value store for worker state persistence
+	Leases          *LeaseManager  // Leases ensuring each worker runs in one process at a time
+	Outbox          *Outbox        // Durable side effects, dispatched once a feature calls UseOutbox
+	Webhooks        *Webhooks      // Outbound webhooks, nil until UseWebhooks is called
+	WebhookInbox    *WebhookInbox  // Payloads of inbound webhooks, see RegisterInboundWebhook
+	RateLimiter     *RateLimiter   // Token buckets for command and route rate limits
+	Metrics         *Metrics       // Counters, gauges and histograms served at /_/metrics
+	Tracer          *Tracer        // Spans of requests, commands, queries and worker handlers
+	AdminCheck      AdminCheck     // Who may use the admin area; nil defers to the [admin] config section
 	Features        []string       // Track registered feature names
 	Routes          []string       // Track registered routes
 	Mux             *http.ServeMux // Store the HTTP mux
@@ -26,12 +36,44 @@ type App struct {
 
 	// Worker management
 	workers      []Worker           // Registered background workers
+	workerStats  []*workerStats     // Cycle statistics, indexed like workers
 	workerCtx    context.Context    // Context for worker goroutines
 	workerCancel context.CancelFunc // Function to cancel worker context
 	workerWg     sync.WaitGroup     // WaitGroup for worker goroutines
+
+	// HTTP
+	middleware []Middleware // Added with Use, wrapped around Mux by Handler
+
+	// Admin area
+	secretKeys []string // Prefixes of KV keys whose values are hidden, see RegisterSecretKeys
+
+	// Core metrics, see registerCoreMetrics
+	replayDuration *Gauge
+	workerCycles   *Counter
+	workerErrors   *Counter
+	workerLag      *Gauge
+
+	// Health reporting
+	replayed     atomic.Bool        // Set once ReplayLog has completed
+	healthMu     sync.Mutex         // Guards healthChecks
+	healthChecks []namedHealthCheck // Checks added with RegisterHealthCheck
+}
+
+// NewAppFromConfig creates the application with the database and settings of cfg.
+func NewAppFromConfig(cfg *Config) (*App, error) {
+	app, err := NewApp(cfg.DB.Path)
+	if err != nil {
+		return nil, err
+	}
+	app.Config = cfg
+	if exporter := cfg.Trace.NewSpanExporter(); exporter != nil {
+		app.Tracer.SetExporter(exporter)
+	}
+	return app, nil
 }
 
-// NewApp creates and initializes all core dependencies
+// NewApp creates and initializes all core dependencies. Its Config holds the
+// defaults; use NewAppFromConfig to start from a loaded configuration.
 func NewApp(dbPath string) (*App, error) {
 	slog.Info("Initializing application...")
 
@@ -69,22 +111,98 @@ func NewApp(dbPath string) (*App, error) {
 		return nil, fmt.Errorf("failed to initialize KV store: %w", err)
 	}
 
-	// 6. Initialize Central Command Executor
+	// 6. Initialize worker leases
+	slog.Debug("Initializing lease manager")
+	leases, err := NewLeaseManager(db, "", DefaultLeaseTTL)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize lease manager: %w", err)
+	}
+
+	// 7. Initialize Central Command Executor
 	slog.Debug("Initializing central command executor")
 	executor := NewExecutor(messageLog, commandRegistry)
+	rateLimiter := NewRateLimiter()
+	executor.UseRateLimiter(rateLimiter)
+
+	// 8. Initialize outbox
+	slog.Debug("Initializing outbox")
+	outbox, err := NewOutbox(db, executor)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize outbox: %w", err)
+	}
+
+	// 9. Initialize the inbox of inbound webhooks
+	slog.Debug("Initializing webhook inbox")
+	inbox, err := NewWebhookInbox(db, executor)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize webhook inbox: %w", err)
+	}
 
-	// 7. Return the App struct with all dependencies
-	return &App{
+	config := DefaultConfig()
+	config.DB.Path = dbPath
+
+	// 10. Assemble the App struct with all dependencies
+	app := &App{
 		DB:              db,
+		Config:          config,
 		MessageLog:      messageLog,
 		CommandRegistry: commandRegistry,
 		QueryRegistry:   queryRegistry,
 		Executor:        executor,
 		KVStore:         kvStore,
+		Leases:          leases,
+		Outbox:          outbox,
+		WebhookInbox:    inbox,
+		RateLimiter:     rateLimiter,
+		Metrics:         NewMetrics(),
+		Tracer:          NewTracer(nil),
 		Features:        []string{},
 		Routes:          []string{},
 		// AppState will be initialized by the caller
-	}, nil
+	}
+
+	// 11. Instrument the core
+	app.registerCoreMetrics()
+	executor.UseTracer(app.Tracer)
+	queryRegistry.UseTracer(app.Tracer)
+	return app, nil
+}
+
+// registerCoreMetrics instruments the executor, the message log, queries, replay and
+// workers with App.Metrics. HTTP requests are counted by the HTTPMetrics middleware.
+func (a *App) registerCoreMetrics() {
+	a.Executor.UseMetrics(a.Metrics)
+	a.MessageLog.UseMetrics(a.Metrics)
+	a.QueryRegistry.UseMetrics(a.Metrics)
+
+	a.replayDuration = a.Metrics.Gauge("petrock_replay_duration_seconds", "Time taken by the last replay of the log at startup")
+	a.workerCycles = a.Metrics.Counter("petrock_worker_cycles_total", "Work cycles run by worker", "worker")
+	a.workerErrors = a.Metrics.Counter("petrock_worker_cycle_errors_total", "Work cycles that returned an error by worker", "worker")
+	a.workerLag = a.Metrics.Gauge("petrock_worker_lag_messages", "Messages in the log a worker has yet to process", "worker")
+	a.Metrics.OnCollect(a.collectWorkerLag)
+}
+
+// collectWorkerLag updates the lag of every worker that reports its progress.
+func (a *App) collectWorkerLag() {
+	head, err := a.MessageLog.Version(context.Background())
+	if err != nil {
+		slog.Error("Failed to read log version for metrics", "error", err)
+		return
+	}
+	for _, w := range a.workers {
+		reporter, ok := w.(ProgressReporter)
+		if !ok {
+			continue
+		}
+		lag := uint64(0)
+		if position := reporter.Progress().Position; head > position {
+			lag = head - position
+		}
+		a.workerLag.Set(float64(lag), workerName(w))
+	}
 }
 
 // RegisterFeatures registers all application features
@@ -106,12 +224,14 @@ func (a *App) RegisterFeature(name string, registerFn func(app *App, state inter
 }
 
 // RegisterRoute registers an HTTP route with the application
-// This is a wrapper around mux.HandleFunc that tracks the route
-func (a *App) RegisterRoute(pattern string, handler http.HandlerFunc) {
+// This is a wrapper around mux.Handle that tracks the route. The optional middleware
+// wraps only this route, inside the middleware added with Use. HTTPMetrics labels the
+// route's requests with pattern
+func (a *App) RegisterRoute(pattern string, handler http.HandlerFunc, middleware ...Middleware) {
 	slog.Debug("Registering route", "pattern", pattern)
 	a.Routes = append(a.Routes, pattern)
 	if a.Mux != nil {
-		a.Mux.HandleFunc(pattern, handler)
+		a.Mux.Handle(pattern, recordRoutePattern(pattern, Chain(handler, middleware...)))
 	}
 }
 
@@ -123,20 +243,31 @@ func (a *App) RegisterWorker(worker Worker) {
 	// If it's a CommandWorker, set up its dependencies
 	if cmdWorker, ok := worker.(*CommandWorker); ok {
 		cmdWorker.SetDependencies(a.MessageLog, a.Executor, a.KVStore)
+		cmdWorker.UseTracer(a.Tracer)
 	}
 	
 	a.workers = append(a.workers, worker)
+	a.workerStats = append(a.workerStats, &workerStats{})
 }
 
 // StartWorkers initializes and starts all registered workers
 // Each worker is started in its own goroutine where it first replays all messages
-// and then periodically calls Work()
+// and then calls Work() according to its Schedule (see ScheduledWorker)
 func (a *App) StartWorkers(ctx context.Context) error {
 	slog.Info("Starting workers...")
 
 	// Create a cancelable context for worker operations
 	a.workerCtx, a.workerCancel = context.WithCancel(ctx)
 
+	// Reclaim expired keys and old history in the background; reads already skip them
+	if store, ok := a.KVStore.(*SQLiteKVStore); ok {
+		a.workerWg.Add(1)
+		go func() {
+			defer a.workerWg.Done()
+			sweepKVStore(a.workerCtx, store)
+		}()
+	}
+
 	// Start each worker in its own goroutine
 	for i, worker := range a.workers {
 		a.workerWg.Add(1)
@@ -144,52 +275,341 @@ func (a *App) StartWorkers(ctx context.Context) error {
 		// Start worker goroutine
 		go func(index int, w Worker) {
 			defer a.workerWg.Done()
+			defer a.workerStats[index].setState(WorkerStateStopped)
+
+			// Only the process holding a worker's lease runs it; the others stand by
+			// and take over once the lease is released or expires. A paused worker
+			// runs nowhere until it is resumed.
+			name := workerName(w)
+			var generation uint64
+			ran := false
+			for {
+				control, ok := a.awaitWorkerResumed(index, name)
+				if !ok {
+					return
+				}
+				if ran && control.Generation != generation {
+					// Sought or replayed since this process last ran it
+					if resetter, ok := w.(Resetter); ok {
+						slog.Info("Resetting worker state", "worker", name)
+						resetter.Reset()
+					}
+				}
+				generation = control.Generation
 
-			// Initialize worker
-			slog.Debug("Initializing worker", "index", index, "type", fmt.Sprintf("%T", w))
-			if err := w.Start(a.workerCtx); err != nil {
-				slog.Error("Worker initialization failed", "index", index, "error", err)
-				return
+				lease, ok := a.awaitWorkerLease(index, name)
+				if !ok {
+					return
+				}
+				ran = true
+				err := a.runLeasedWorker(index, w, lease, control)
+				switch {
+				case a.workerCtx.Err() != nil:
+					return
+				case errors.Is(err, errWorkerControlChanged):
+					slog.Info("Worker stopped after a control change", "index", index, "worker", name)
+				case err != nil:
+					slog.Error("Worker initialization failed", "index", index, "error", err)
+					return
+				default:
+					slog.Warn("Worker lease lost, standing by", "index", index, "worker", name)
+				}
 			}
+		}(i, worker)
+	}
 
-			// Replay all messages first (synchronously within this goroutine)
-			slog.Debug("Worker replaying messages", "index", index)
+	slog.Info("All workers started", "count", len(a.workers))
+	return nil
+}
 
-			// Call the new Replay method to reconstruct state from all messages
-			if err := w.Replay(a.workerCtx); err != nil {
-				slog.Error("Worker message replay failed", "index", index, "error", err)
+// WorkerLeaseName returns the name of the lease guarding the named worker.
+func WorkerLeaseName(name string) string {
+	return "worker:" + name
+}
+
+// awaitWorkerLease blocks until this process holds the worker's lease. It returns false
+// if the worker context is cancelled first. Without a LeaseManager the worker always runs.
+func (a *App) awaitWorkerLease(index int, name string) (Lease, bool) {
+	if a.Leases == nil {
+		return Lease{Name: WorkerLeaseName(name)}, a.workerCtx.Err() == nil
+	}
+
+	standingBy := false
+	for {
+		lease, err := a.Leases.Acquire(a.workerCtx, WorkerLeaseName(name))
+		if err == nil {
+			slog.Info("Acquired worker lease", "worker", name, "holder", lease.Holder, "token", lease.Token)
+			return lease, true
+		}
+
+		if errors.Is(err, ErrLeaseHeld) {
+			if !standingBy {
+				slog.Info("Worker is running in another process, standing by", "worker", name, "holder", lease.Holder)
+				a.workerStats[index].setState(WorkerStateStandby)
+				standingBy = true
 			}
+		} else if a.workerCtx.Err() == nil {
+			slog.Error("Failed to acquire worker lease", "worker", name, "error", err)
+		}
 
-			slog.Info("Worker message replay completed", "index", index)
+		select {
+		case <-a.workerCtx.Done():
+			return Lease{}, false
+		case <-time.After(a.Leases.TTL() / 3):
+		}
+	}
+}
 
-			// Create ticker with jitter (1-2 seconds)
-			baseTick := time.Second
-			jitter := time.Duration(rand.Intn(1000)) * time.Millisecond
-			ticker := time.NewTicker(baseTick + jitter)
-			defer ticker.Stop()
+// runLeasedWorker starts, replays and schedules a worker for as long as the lease is held.
+// It returns nil when the lease is lost or the worker context is cancelled,
+// errWorkerControlChanged when the worker was paused, sought or replayed, and an error
+// if the worker could not be started.
+func (a *App) runLeasedWorker(index int, w Worker, lease Lease, control WorkerControl) error {
+	stats := a.workerStats[index]
+	ctx, cancel := context.WithCancel(a.Leases.WithLease(a.workerCtx, lease))
+	defer cancel()
 
-			slog.Debug("Worker started", "index", index, "interval", baseTick+jitter)
+	heartbeatDone := make(chan struct{})
+	go func() {
+		defer close(heartbeatDone)
+		a.heartbeatLease(ctx, cancel, lease)
+	}()
+	defer func() {
+		cancel()
+		<-heartbeatDone
+		if a.Leases != nil {
+			releaseCtx, releaseCancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer releaseCancel()
+			if err := a.Leases.Release(releaseCtx, lease); err != nil {
+				slog.Warn("Failed to release worker lease", "index", index, "error", err)
+			}
+		}
+	}()
 
-			// Periodically call Work()
-			for {
-				select {
-				case <-a.workerCtx.Done():
-					// Context was cancelled, exit the goroutine
-					slog.Debug("Worker stopping due to context cancellation", "index", index)
-					return
+	// Initialize worker
+	slog.Debug("Initializing worker", "index", index, "type", fmt.Sprintf("%T", w))
+	if err := w.Start(ctx); err != nil {
+		return err
+	}
+	defer w.Stop(context.Background())
 
-				case <-ticker.C:
-					// Time to do work
-					if err := w.Work(); err != nil {
-						// Log error but don't stop worker on work errors
-						slog.Error("Worker cycle failed", "index", index, "error", err)
-					}
-				}
+	// Replay all messages first (synchronously within this goroutine)
+	stats.setState(WorkerStateReplaying)
+	slog.Debug("Worker replaying messages", "index", index)
+
+	// Call the new Replay method to reconstruct state from all messages
+	if err := w.Replay(ctx); err != nil {
+		slog.Error("Worker message replay failed", "index", index, "error", err)
+	}
+
+	slog.Info("Worker message replay completed", "index", index)
+	stats.setState(WorkerStateRunning)
+
+	// Run Work() whenever the worker's schedule says so
+	return a.runWorkerSchedule(ctx, index, w, workerSchedule(w), control)
+}
+
+// heartbeatLease renews the lease every third of its TTL and calls cancel once it is lost.
+func (a *App) heartbeatLease(ctx context.Context, cancel context.CancelFunc, lease Lease) {
+	if a.Leases == nil {
+		return
+	}
+
+	ticker := time.NewTicker(a.Leases.TTL() / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			renewed, err := a.Leases.Renew(ctx, lease)
+			if err == nil {
+				lease = renewed
+				continue
 			}
-		}(i, worker)
+			if errors.Is(err, ErrLeaseLost) {
+				slog.Warn("Worker lease lost", "lease", lease.Name, "token", lease.Token, "error", err)
+				cancel()
+				return
+			}
+			if ctx.Err() == nil {
+				// Keep trying until the lease runs out; Renew reports expiry as ErrLeaseLost
+				slog.Error("Failed to renew worker lease", "lease", lease.Name, "error", err)
+			}
+		}
 	}
+}
 
-	slog.Info("All workers started", "count", len(a.workers))
+// runWorkerSchedule calls w.Work() according to schedule until ctx is cancelled, or until
+// the worker's control state differs from control, in which case it returns
+// errWorkerControlChanged. Event-driven schedules are woken by appends through this process
+// and, to notice appends made by other processes, by a periodic check of the log version.
+func (a *App) runWorkerSchedule(ctx context.Context, index int, w Worker, schedule Schedule, control WorkerControl) error {
+	name := workerName(w)
+	slog.Debug("Worker started", "index", index, "schedule", schedule.String())
+
+	// The control poll, the log poll and the timer of the next run are kept across
+	// wakeups, so that the most frequent of them can't keep pushing the others out
+	controlPoll := time.NewTicker(WorkerControlPollInterval)
+	defer controlPoll.Stop()
+
+	var changed <-chan struct{}
+	var logPoll <-chan time.Time
+	var lastVersion uint64
+	if schedule.EventDriven() {
+		changed = a.MessageLog.Changed()
+		lastVersion, _ = a.MessageLog.Version(ctx)
+		ticker := time.NewTicker(DefaultStreamPollInterval)
+		defer ticker.Stop()
+		logPoll = ticker.C
+	}
+
+	var timer *time.Timer
+	var timerC <-chan time.Time
+	scheduleNext := func() {
+		if timer != nil {
+			timer.Stop()
+		}
+		timer, timerC = nil, nil
+		if next := schedule.Next(time.Now()); !next.IsZero() {
+			timer = time.NewTimer(time.Until(next))
+			timerC = timer.C
+		}
+	}
+	scheduleNext()
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+
+	for {
+		run := false
+		select {
+		case <-ctx.Done():
+			// Context was cancelled, exit the goroutine
+			slog.Debug("Worker stopping due to context cancellation", "index", index)
+			return nil
+		case <-timerC:
+			run = true
+		case <-changed:
+			run = true
+		case <-logPoll:
+			// Only run if another process advanced the log in the meantime
+			version, err := a.MessageLog.Version(ctx)
+			run = err == nil && version > lastVersion
+		case <-controlPoll.C:
+		}
+		if a.workerControlChanged(name, control) {
+			return errWorkerControlChanged
+		}
+		if !run {
+			continue
+		}
+		if schedule.EventDriven() {
+			// Taken before Work so that appends made while it runs wake it again
+			changed = a.MessageLog.Changed()
+			lastVersion, _ = a.MessageLog.Version(ctx)
+		}
+
+		err := w.Work()
+		a.workerStats[index].recordCycle(err)
+		a.workerCycles.Inc(name)
+		if err != nil {
+			a.workerErrors.Inc(name)
+			// Log error but don't stop worker on work errors
+			slog.Error("Worker cycle failed", "index", index, "error", err)
+		}
+		scheduleNext()
+	}
+}
+
+// workerName returns the name a worker is addressed by in the CLI and introspection.
+func workerName(w Worker) string {
+	if info := w.WorkerInfo(); info != nil && info.Name != "" {
+		return info.Name
+	}
+	return fmt.Sprintf("%T", w)
+}
+
+// WorkerNames returns the names of all registered workers in registration order.
+func (a *App) WorkerNames() []string {
+	names := make([]string, 0, len(a.workers))
+	for _, w := range a.workers {
+		names = append(names, workerName(w))
+	}
+	return names
+}
+
+// FindWorker returns the registered worker with the given name.
+func (a *App) FindWorker(name string) (Worker, bool) {
+	for _, w := range a.workers {
+		if workerName(w) == name {
+			return w, true
+		}
+	}
+	return nil, false
+}
+
+// RunWorkerOnce starts the named worker, replays the log into its state and runs a
+// single Work() cycle outside of its schedule. The worker is stopped afterwards.
+// It fails if the worker is paused or another process holds its lease, and if the
+// lease is lost during the run despite being renewed.
+// Features must be registered and the application log replayed before calling this.
+func (a *App) RunWorkerOnce(ctx context.Context, name string) (err error) {
+	w, found := a.FindWorker(name)
+	if !found {
+		return &WorkerError{Op: "run", Err: fmt.Errorf("no worker named %q", name)}
+	}
+
+	if a.KVStore != nil {
+		control, err := LoadWorkerControl(a.KVStore, name)
+		if err != nil {
+			return &WorkerError{Op: "run", Err: err}
+		}
+		if control.Paused {
+			return &WorkerError{Op: "run", Err: fmt.Errorf("worker %q is paused, resume it first", name)}
+		}
+	}
+
+	// Refuse to run a worker that a serving process is running right now
+	if a.Leases != nil {
+		lease, acquireErr := a.Leases.Acquire(ctx, WorkerLeaseName(name))
+		if acquireErr != nil {
+			return &WorkerError{Op: "run", Err: fmt.Errorf("cannot run worker %q: %w", name, acquireErr)}
+		}
+		// Renew the lease while the worker runs, and fail the run if it is lost anyway
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(a.Leases.WithLease(ctx, lease))
+		var lost atomic.Bool
+		heartbeatDone := make(chan struct{})
+		go func() {
+			defer close(heartbeatDone)
+			a.heartbeatLease(ctx, func() { lost.Store(true); cancel() }, lease)
+		}()
+		defer func() {
+			cancel()
+			<-heartbeatDone
+			a.Leases.Release(context.Background(), lease)
+			if lost.Load() && err == nil {
+				err = &WorkerError{Op: "run", Err: fmt.Errorf("worker %q lost its lease during the run: %w", name, ErrLeaseLost)}
+			}
+		}()
+	}
+
+	if err := w.Start(ctx); err != nil {
+		return &WorkerError{Op: "run", Err: fmt.Errorf("failed to start worker %q: %w", name, err)}
+	}
+	defer w.Stop(context.Background())
+
+	if err := w.Replay(ctx); err != nil {
+		return &WorkerError{Op: "run", Err: fmt.Errorf("failed to replay worker %q: %w", name, err)}
+	}
+
+	slog.Info("Running single worker cycle", "worker", name)
+	if err := w.Work(); err != nil {
+		return &WorkerError{Op: "run", Err: fmt.Errorf("worker %q cycle failed: %w", name, err)}
+	}
 	return nil
 }
 
@@ -218,9 +638,8 @@ func (a *App) StopWorkers(ctx context.Context) error {
 	// Wait for workers to finish or timeout
 	select {
 	case <-done:
-		// All workers finished successfully
-		slog.Info("All workers stopped successfully")
-		return nil
+		// All worker goroutines have returned
+		a.workerCancel = nil
 
 	case <-ctx.Done():
 		// Timeout or parent context canceled
@@ -247,12 +666,14 @@ func (a *App) StopWorkers(ctx context.Context) error {
 		return &WorkerError{Op: "stop", Err: fmt.Errorf("%d worker(s) failed to stop properly", len(stopErrors))}
 	}
 
+	slog.Info("All workers stopped successfully")
 	return nil
 }
 
 // ReplayLog replays the message log to build application state
 func (a *App) ReplayLog() error {
 	slog.Info("Replaying message log to build application state...")
+	start := time.Now()
 
 	// Get the starting version
 	startVersion := uint64(0)         // Start from the beginning
@@ -301,11 +722,13 @@ func (a *App) ReplayLog() error {
 		}
 	}
 
+	a.replayDuration.Set(time.Since(start).Seconds())
 	slog.Info("State replay completed", "message_count", messageCount, "replay_errors", replayErrors)
 	if replayErrors > 0 {
 		slog.Warn("Some messages were skipped during state replay due to missing handlers.")
 	}
 
+	a.replayed.Store(true)
 	return nil
 }
 
@@ -325,6 +748,15 @@ func (a *App) Close() error {
 		}
 	}
 
+	// Export the remaining spans
+	if a.Tracer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := a.Tracer.Shutdown(ctx); err != nil {
+			slog.Warn("Error exporting spans", "error", err)
+		}
+	}
+
 	// Close the database connection
 	if a.DB != nil {
 		slog.Debug("Closing database connection")
@@ -0,0 +1,58 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// LoginPath is where RequireUser sends browsers that are not logged in. The auth
+// feature serves its login page here.
+var LoginPath = "/login"
+
+// Principal identifies who a request or command acts on behalf of.
+type Principal struct {
+	UserID   string `json:"user_id"`
+	Username string `json:"username"`
+}
+
+// principalKey is the context key holding the Principal.
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying the given principal. Session middleware
+// calls it for logged-in requests; handlers pass the context on to the Executor.
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext returns the principal stored in ctx, if any.
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
+
+// PrincipalID returns the user ID of the principal stored in ctx, or "" for anonymous
+// requests. Handlers use it to fill in fields such as CreatedBy.
+func PrincipalID(ctx context.Context) string {
+	p, _ := PrincipalFromContext(ctx)
+	return p.UserID
+}
+
+// RequireUser only lets requests with a principal through. Browsers are redirected to
+// LoginPath with the requested URL in "next"; other clients get 401 Unauthorized.
+func RequireUser() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := PrincipalFromContext(r.Context()); ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if r.Method == http.MethodGet && strings.Contains(r.Header.Get("Accept"), "text/html") {
+				http.Redirect(w, r, LoginPath+"?next="+url.QueryEscape(r.URL.RequestURI()), http.StatusSeeOther)
+				return
+			}
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		})
+	}
+}
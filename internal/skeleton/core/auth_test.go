@@ -0,0 +1,36 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequireUser(t *testing.T) {
+	var seen Principal
+	h := RequireUser()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen, _ = PrincipalFromContext(r.Context())
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/posts/new?draft=1", nil)
+	r.Header.Set("Accept", "text/html")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/login?next=%2Fposts%2Fnew%3Fdraft%3D1" {
+		t.Errorf("Expected browsers to be redirected to the login page, got %d %q", w.Code, w.Header().Get("Location"))
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/commands", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for other clients, got %d", w.Code)
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "/posts/new", nil)
+	r = r.WithContext(WithPrincipal(r.Context(), Principal{UserID: "u1", Username: "ada"}))
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK || seen.Username != "ada" || PrincipalID(r.Context()) != "u1" {
+		t.Errorf("Expected the principal to reach the handler, got %d %+v", w.Code, seen)
+	}
+}
@@ -0,0 +1,28 @@
+package core
+
+import (
+	"context"
+	"time"
+)
+
+// Clock tells the current time. Workers read it with Now(ctx) instead of calling
+// time.Now, so that tests can control time with a fake clock.
+type Clock interface {
+	Now() time.Time
+}
+
+type clockContextKey struct{}
+
+// WithClock returns a context whose Now reports the time of clock.
+func WithClock(ctx context.Context, clock Clock) context.Context {
+	return context.WithValue(ctx, clockContextKey{}, clock)
+}
+
+// Now returns the current time according to the clock carried by ctx, or time.Now()
+// if there is none.
+func Now(ctx context.Context) time.Time {
+	if clock, ok := ctx.Value(clockContextKey{}).(Clock); ok {
+		return clock.Now()
+	}
+	return time.Now()
+}
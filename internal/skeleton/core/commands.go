@@ -6,6 +6,7 @@ import (
 	"log/slog"
 	"reflect"
 	"sync"
+	"time"
 )
 
 // NamedMessage defines an interface for messages that know their registered name.
@@ -46,6 +47,8 @@ type CommandRegistry struct {
 	handlers         map[string]CommandHandler  // Key: "feature/TypeName" -> State update handler
 	featureExecutors map[string]FeatureExecutor // Key: "feature/TypeName" -> Feature executor instance
 	types            map[string]reflect.Type    // Key: "feature/TypeName" -> Command type
+	rateLimits       map[string][]RateLimit     // Key: "feature/TypeName" -> Limits enforced by the Executor
+	adminOnly        map[string]bool            // Key: "feature/TypeName" -> Hidden from the public command API
 	mu               sync.RWMutex
 }
 
@@ -55,6 +58,8 @@ func NewCommandRegistry() *CommandRegistry {
 		handlers:         make(map[string]CommandHandler),
 		featureExecutors: make(map[string]FeatureExecutor),
 		types:            make(map[string]reflect.Type),
+		rateLimits:       make(map[string][]RateLimit),
+		adminOnly:        make(map[string]bool),
 	}
 }
 
@@ -148,6 +153,21 @@ func (r *CommandRegistry) RegisteredCommandNames() []string {
 	return names
 }
 
+// PublicCommandNames returns the names of the registered commands that are not
+// admin-only, i.e. those the public command API lists and executes.
+func (r *CommandRegistry) PublicCommandNames() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.handlers))
+	for name := range r.handlers {
+		if !r.adminOnly[name] {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
 // GetCommandType retrieves the reflect.Type for a registered command by its full name.
 // This is useful for decoding commands from external sources like API requests.
 func (r *CommandRegistry) GetCommandType(name string) (reflect.Type, bool) {
@@ -159,6 +179,42 @@ func (r *CommandRegistry) GetCommandType(name string) (reflect.Type, bool) {
 	return cmdType, found
 }
 
+// SetRateLimits replaces the rate limits the Executor enforces for a command. Several
+// limits can be combined, for example a per-user limit and a global one:
+//
+//	registry.SetRateLimits("posts/create",
+//	    core.RateLimit{Key: core.RateLimitByPrincipal, Requests: 10, Per: time.Minute},
+//	    core.RateLimit{Key: core.RateLimitByCommand, Requests: 600, Per: time.Minute})
+func (r *CommandRegistry) SetRateLimits(name string, limits ...RateLimit) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rateLimits[name] = limits
+}
+
+// RateLimits returns the rate limits configured for a command.
+func (r *CommandRegistry) RateLimits(name string) []RateLimit {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.rateLimits[name]
+}
+
+// SetAdminOnly marks commands as admin-only: the public command API neither lists nor
+// executes them, so they can only be run from the admin area, workers or the CLI.
+func (r *CommandRegistry) SetAdminOnly(names ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, name := range names {
+		r.adminOnly[name] = true
+	}
+}
+
+// AdminOnly reports whether a command was marked with SetAdminOnly.
+func (r *CommandRegistry) AdminOnly(name string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.adminOnly[name]
+}
+
 // --- Global Registry (Optional - consider dependency injection instead) ---
 // var Commands = NewCommandRegistry()
 
@@ -171,10 +227,33 @@ func (r *CommandRegistry) GetCommandType(name string) (reflect.Type, bool) {
 
 // --- Central Command Executor ---
 
+// ValidationError is returned by Executor.Execute when the feature's ValidateCommand
+// rejects a command. Err is the reason given by the feature.
+type ValidationError struct {
+	Command string
+	Err     error
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("validation failed for command %q: %v", e.Command, e.Err)
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
 // Executor orchestrates the validation, logging, and execution of commands.
 type Executor struct {
-	log      *MessageLog      // Dependency for appending commands
-	registry *CommandRegistry // Dependency for finding handlers and feature executors
+	log      *MessageLog                                  // Dependency for appending commands
+	registry *CommandRegistry                             // Dependency for finding handlers and feature executors
+	mu       sync.RWMutex                                 // Serializes append+apply against consistent reads
+	capture  func(ctx context.Context, cmd Command) error // Set by NewCapturingExecutor
+	limiter  *RateLimiter                                 // Enforces the registry's rate limits, if set
+
+	executed *Counter   // Set by UseMetrics
+	failed   *Counter   // Set by UseMetrics
+	latency  *Histogram // Set by UseMetrics
+	tracer   *Tracer    // Set by UseTracer
 }
 
 // NewExecutor creates a new central command executor.
@@ -191,24 +270,61 @@ func NewExecutor(log *MessageLog, registry *CommandRegistry) *Executor {
 	}
 }
 
+// NewCapturingExecutor creates an executor that passes every command to capture instead of
+// validating, logging and applying it. Tests use it to observe the commands a worker issues.
+func NewCapturingExecutor(capture func(ctx context.Context, cmd Command) error) *Executor {
+	if capture == nil {
+		panic("capture function cannot be nil for Executor")
+	}
+	return &Executor{capture: capture}
+}
+
+// UseRateLimiter makes Execute enforce the rate limits configured in the registry
+// with limiter. NewApp calls it with App.RateLimiter.
+func (e *Executor) UseRateLimiter(limiter *RateLimiter) {
+	e.limiter = limiter
+}
+
+// UseMetrics makes Execute count executed and failed commands by name in m and
+// record how long they take. NewApp calls it with App.Metrics.
+func (e *Executor) UseMetrics(m *Metrics) {
+	e.executed = m.Counter("petrock_commands_executed_total", "Commands executed successfully by name", "command")
+	e.failed = m.Counter("petrock_commands_failed_total", "Commands rejected by rate limits or validation, or that failed to be logged, by name", "command")
+	e.latency = m.Histogram("petrock_command_duration_seconds", "Time taken to execute commands by name, including failed ones", nil, "command")
+}
+
+// UseTracer makes Execute trace every command with a span, and its validation,
+// append and apply steps with child spans. NewApp calls it with App.Tracer.
+func (e *Executor) UseTracer(tracer *Tracer) {
+	e.tracer = tracer
+}
+
 // Execute orchestrates the full lifecycle of a command:
 // 1. Retrieves the state update handler and the responsible feature executor.
-// 2. Calls the feature executor's ValidateCommand method.
-// 3. Appends the command to the message log.
-// 4. Executes the state update handler.
+// 2. Checks the command's rate limits, returning a *RateLimitError if one is exceeded.
+// 3. Calls the feature executor's ValidateCommand method.
+// 4. Appends the command to the message log.
+// 5. Executes the state update handler.
 // Commands must be passed as pointer types (*CommandType), not value types.
+// ctx is passed to the feature executor and the handler, so both can see the
+// principal set with WithPrincipal; it is not stored in the log, so commands
+// that need it during replay must copy it into a field such as CreatedBy.
 // It returns an error if validation or logging fails.
 // It panics if the state update handler returns an error after the command has been logged,
 // indicating an unrecoverable inconsistency.
-func (e *Executor) Execute(ctx context.Context, cmd Command) error {
+func (e *Executor) Execute(ctx context.Context, cmd Command) (err error) {
 	// Check if command is a pointer type
 	if reflect.TypeOf(cmd).Kind() != reflect.Ptr {
 		slog.Warn("Non-pointer command received", "type", reflect.TypeOf(cmd), "name", cmd.CommandName(), 
 			"message", "Commands should be pointer types (*CommandType) for future compatibility")
 	}
 
+	if e.capture != nil {
+		return e.capture(ctx, cmd)
+	}
+
 	name := cmd.CommandName()
-	slog.Debug("Executing command", "name", name)
+	slog.Debug("Executing command", "name", name, "principal", PrincipalID(ctx))
 
 	// 1. Get Handler and Feature Executor
 	handler, featureExecutor, found := e.registry.GetHandlerAndFeatureExecutor(name)
@@ -217,29 +333,68 @@ func (e *Executor) Execute(ctx context.Context, cmd Command) error {
 		return fmt.Errorf("command %q not registered", name)
 	}
 
-	// 2. Validate Command using Feature Executor
-	slog.Debug("Validating command", "name", name)
-	if err := featureExecutor.ValidateCommand(ctx, cmd); err != nil {
-		slog.Warn("Command validation failed", "name", name, "error", err)
-		// TODO: Consider defining specific validation error types to return distinct HTTP status codes (e.g., 400 Bad Request)
-		return fmt.Errorf("validation failed for command %q: %w", name, err)
+	start := time.Now()
+	ctx, span := e.tracer.Start(ctx, "command "+name)
+	span.SetAttribute("command", name)
+	defer func() {
+		span.RecordError(err)
+		span.Finish()
+		e.latency.ObserveSince(start, name)
+		if err != nil {
+			e.failed.Inc(name)
+		} else {
+			e.executed.Inc(name)
+		}
+	}()
+
+	// 2. Check rate limits; only commands from HTTP requests carry a client IP
+	if e.limiter != nil {
+		if err := e.limiter.Check(ctx, name, e.registry.RateLimits(name)...); err != nil {
+			slog.WarnContext(ctx, "Command rate limited", "name", name, "principal", PrincipalID(ctx), "error", err)
+			return err
+		}
+	}
+
+	// Validating, appending and applying happen under the write lock, so that two
+	// commands can't both pass a check against the same state (such as a username
+	// being free) and readers using ReadConsistent never observe a version whose
+	// state is only half applied.
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	// 3. Validate Command using Feature Executor
+	slog.DebugContext(ctx, "Validating command", "name", name)
+	validateCtx, validateSpan := e.tracer.Start(ctx, "validate")
+	err = featureExecutor.ValidateCommand(validateCtx, cmd)
+	validateSpan.RecordError(err)
+	validateSpan.Finish()
+	if err != nil {
+		slog.WarnContext(ctx, "Command validation failed", "name", name, "error", err)
+		return &ValidationError{Command: name, Err: err}
 	}
 	slog.Debug("Command validation successful", "name", name)
 
-	// 3. Append Command to Log
-	slog.Debug("Appending command to log", "name", name)
-	if err := e.log.Append(ctx, cmd); err != nil {
-		slog.Error("Failed to append command to log", "name", name, "error", err)
+	// 4. Append Command to Log
+	slog.DebugContext(ctx, "Appending command to log", "name", name)
+	appendCtx, appendSpan := e.tracer.Start(ctx, "append")
+	err = e.log.Append(appendCtx, cmd)
+	appendSpan.RecordError(err)
+	appendSpan.Finish()
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to append command to log", "name", name, "error", err)
 		// This is a critical error, as the action wasn't persisted.
 		return fmt.Errorf("failed to persist command %q: %w", name, err)
 	}
 	slog.Debug("Command appended to log successfully", "name", name)
 
-	// 4. Execute State Update Handler
+	// 5. Execute State Update Handler
 	slog.Debug("Executing state update handler", "name", name)
 	// Create normal processing context for live execution
 	normalPctx := &ProcessingContext{IsReplay: false}
-	handlerErr := handler(ctx, cmd, nil, normalPctx) // Pass nil for message metadata during live execution
+	applyCtx, applySpan := e.tracer.Start(ctx, "apply")
+	handlerErr := handler(applyCtx, cmd, nil, normalPctx) // Pass nil for message metadata during live execution
+	applySpan.RecordError(handlerErr)
+	applySpan.Finish()
 	if handlerErr != nil {
 		// PANIC! If the handler fails *after* the command was logged,
 		// the state is inconsistent with the log. This is unrecoverable
@@ -250,6 +405,20 @@ func (e *Executor) Execute(ctx context.Context, cmd Command) error {
 	}
 	slog.Debug("State update handler executed successfully", "name", name)
 
-	slog.Info("Command executed successfully", "name", name)
+	slog.InfoContext(ctx, "Command executed successfully", "name", name, "principal", PrincipalID(ctx))
 	return nil
 }
+
+// ReadConsistent runs fn while no command can be appended or applied.
+// The version passed to fn is the log version that all reads performed by fn observe.
+// fn must not execute commands itself, as that would deadlock.
+func (e *Executor) ReadConsistent(ctx context.Context, fn func(version uint64) error) error {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	version, err := e.log.Version(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read log version: %w", err)
+	}
+	return fn(version)
+}
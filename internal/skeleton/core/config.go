@@ -0,0 +1,400 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Config sources, in increasing order of precedence.
+const (
+	ConfigSourceDefault = "default"
+	ConfigSourceFile    = "file"
+	ConfigSourceEnv     = "env"
+	ConfigSourceFlag    = "flag"
+)
+
+// DBConfig is the [db] section of the configuration.
+type DBConfig struct {
+	Path string `json:"path" validate:"required" description:"Path to the SQLite database file"`
+}
+
+// LogConfig is the [log] section of the configuration.
+type LogConfig struct {
+	Level string `json:"level" description:"Log level: debug, info, warn or error"`
+}
+
+// Validate checks that the log level is known.
+func (c *LogConfig) Validate() error {
+	_, err := ParseLogLevel(c.Level)
+	return err
+}
+
+// SlogLevel returns the configured log level, or info if it is invalid.
+func (c *LogConfig) SlogLevel() slog.Level {
+	level, _ := ParseLogLevel(c.Level)
+	return level
+}
+
+// ParseLogLevel parses a log level name as used in the configuration.
+func ParseLogLevel(name string) (slog.Level, error) {
+	switch strings.ToLower(name) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return slog.LevelInfo, fmt.Errorf("invalid log level %q: use debug, info, warn or error", name)
+}
+
+// ServerConfig is the [server] section of the configuration, used by serve.
+type ServerConfig struct {
+	Host              string        `json:"host" description:"Host to bind to"`
+	Port              int           `json:"port" validate:"min=1,max=65535" description:"Port to listen on"`
+	ReadTimeout       time.Duration `json:"read_timeout" description:"Maximum time to read a request"`
+	WriteTimeout      time.Duration `json:"write_timeout" description:"Maximum time to write a response"`
+	IdleTimeout       time.Duration `json:"idle_timeout" description:"How long keep-alive connections stay open between requests"`
+	ShutdownTimeout   time.Duration `json:"shutdown_timeout" description:"How long to wait for requests and workers to finish on shutdown"`
+	TrustProxy        bool          `json:"trust_proxy" description:"Take the client IP for rate limits from X-Forwarded-For, as set by a reverse proxy"`
+	PersistRateLimits bool          `json:"persist_rate_limits" description:"Keep rate limit buckets in the database so they survive restarts"`
+}
+
+// TraceConfig is the [trace] section of the configuration.
+type TraceConfig struct {
+	Exporter    string `json:"exporter" description:"Where to send spans: none, file or otlp"`
+	File        string `json:"file" description:"File the file exporter appends spans to, one JSON object per line"`
+	Endpoint    string `json:"endpoint" description:"Base URL of the OTLP/HTTP collector the otlp exporter posts spans to"`
+	ServiceName string `json:"service_name" description:"Service name reported with spans; defaults to the name of the binary"`
+}
+
+// Validate checks that the exporter is known and has what it needs.
+func (c *TraceConfig) Validate() error {
+	switch c.Exporter {
+	case "", TraceExporterNone:
+	case TraceExporterFile:
+		if c.File == "" {
+			return fmt.Errorf("the file exporter needs a file")
+		}
+	case TraceExporterOTLP:
+		if !strings.HasPrefix(c.Endpoint, "http://") && !strings.HasPrefix(c.Endpoint, "https://") {
+			return fmt.Errorf("the otlp exporter needs an http or https endpoint, got %q", c.Endpoint)
+		}
+	default:
+		return fmt.Errorf("invalid trace exporter %q: use none, file or otlp", c.Exporter)
+	}
+	return nil
+}
+
+// AdminConfig is the [admin] section of the configuration, used by App.RequireAdmin.
+type AdminConfig struct {
+	Users      []string `json:"users" description:"Usernames of the principals allowed into the admin area at /_/admin"`
+	AllowLocal bool     `json:"allow_local" description:"Let requests from the local machine into the admin area without logging in"`
+}
+
+// Allows reports whether the request may use the admin area: its principal is one of
+// Users, or AllowLocal is set and the client IP is a loopback address.
+func (c *AdminConfig) Allows(r *http.Request) bool {
+	if p, ok := PrincipalFromContext(r.Context()); ok && slices.Contains(c.Users, p.Username) {
+		return true
+	}
+	if !c.AllowLocal {
+		return false
+	}
+	ip := ClientIPFromContext(r.Context())
+	if ip == "" {
+		ip = remoteIP(r.RemoteAddr)
+	}
+	parsed := net.ParseIP(ip)
+	return parsed != nil && parsed.IsLoopback()
+}
+
+// ConfigValidator is implemented by config sections that check more than their
+// validate tags can express. Validate is called after the section has been loaded.
+type ConfigValidator interface {
+	Validate() error
+}
+
+// ConfigSetting describes the effective value of one config key.
+type ConfigSetting struct {
+	Key         string `json:"key"`              // Section and field, such as "server.port"
+	Value       string `json:"value"`            // Secrets are masked
+	Source      string `json:"source"`           // One of the ConfigSource constants
+	Origin      string `json:"origin,omitempty"` // The file or environment variable the value came from
+	Description string `json:"description,omitempty"`
+}
+
+// ConfigOptions lists the layers LoadConfig reads on top of the defaults.
+type ConfigOptions struct {
+	File      string                      // TOML file, or JSON if the name ends in .json; empty for none
+	EnvPrefix string                      // Environment variables are named PREFIX_SECTION_KEY
+	LookupEnv func(string) (string, bool) // Defaults to os.LookupEnv
+	Flags     map[string]string           // Values of flags given on the command line, by config key
+}
+
+// Config holds the application's configuration. The core sections are fields;
+// features load their own sections with Section.
+type Config struct {
+	DB     DBConfig
+	Log    LogConfig
+	Server ServerConfig
+	Trace  TraceConfig
+	Admin  AdminConfig
+
+	options    ConfigOptions
+	fileValues map[string][]string // Values from the config file, by key
+	sections   map[string]bool     // Names of the loaded sections
+	settings   []ConfigSetting
+	errs       []error
+}
+
+// DefaultConfig returns the configuration used when nothing is configured.
+func DefaultConfig() *Config {
+	return &Config{
+		DB:  DBConfig{Path: "app.db"},
+		Log: LogConfig{Level: "info"},
+		Server: ServerConfig{
+			Host:            "localhost",
+			Port:            8080,
+			ReadTimeout:     5 * time.Second,
+			WriteTimeout:    10 * time.Second,
+			IdleTimeout:     120 * time.Second,
+			ShutdownTimeout: 10 * time.Second,
+		},
+		Trace: TraceConfig{
+			Exporter: TraceExporterNone,
+			File:     "traces.jsonl",
+			Endpoint: "http://localhost:4318",
+		},
+		Admin:    AdminConfig{},
+		sections: make(map[string]bool),
+	}
+}
+
+// LoadConfig loads the core sections from the defaults, then the config file, then
+// environment variables and finally flags, each overriding the one before.
+func LoadConfig(opts ConfigOptions) (*Config, error) {
+	c := DefaultConfig()
+	if opts.LookupEnv == nil {
+		opts.LookupEnv = os.LookupEnv
+	}
+	c.options = opts
+
+	if opts.File != "" {
+		values, err := readConfigFile(opts.File)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file %s: %w", opts.File, err)
+		}
+		c.fileValues = values
+	}
+
+	c.Section("db", &c.DB)
+	c.Section("log", &c.Log)
+	c.Section("server", &c.Server)
+	c.Section("trace", &c.Trace)
+	c.Section("admin", &c.Admin)
+	if len(c.errs) > 0 {
+		return nil, errors.Join(c.errs...)
+	}
+	return c, nil
+}
+
+// Section loads the config section name into target, a pointer to a struct whose
+// fields hold the defaults. Fields are named by their json tags, checked with validate
+// tags and described by description tags; fields tagged secret:"true" are masked in
+// Settings. Values can be strings, numbers, booleans, durations such as "5s", or
+// slices of these. Features call Section while they are registered:
+//
+//	cfg := Config{Timeout: 30 * time.Second}
+//	app.Config.Section("posts.summarization", &cfg)
+//
+// The section [posts.summarization] of the config file and variables such as
+// PREFIX_POSTS_SUMMARIZATION_TIMEOUT set its fields. Invalid values leave target
+// partly loaded and are reported by Validate, which serve calls before it starts.
+func (c *Config) Section(name string, target interface{}) {
+	if err := c.loadSection(name, target); err != nil {
+		c.errs = append(c.errs, err)
+	}
+}
+
+func (c *Config) loadSection(name string, target interface{}) error {
+	targetValue := reflect.ValueOf(target)
+	if targetValue.Kind() != reflect.Ptr || targetValue.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("config section %q: target must be a pointer to a struct", name)
+	}
+	if c.sections[name] {
+		return fmt.Errorf("config section %q is loaded twice", name)
+	}
+	c.sections[name] = true
+
+	fields := configFields(targetValue.Elem())
+	values := url.Values{}
+	settings := make([]ConfigSetting, len(fields))
+	for i, field := range fields {
+		key := name + "." + field.name
+		setting := ConfigSetting{Key: key, Source: ConfigSourceDefault, Description: field.description}
+		vals := formatConfigValue(field.value)
+
+		if fileVals, ok := c.fileValues[key]; ok {
+			vals = fileVals
+			setting.Source, setting.Origin = ConfigSourceFile, c.options.File
+		}
+		if c.options.LookupEnv != nil {
+			envName := configEnvName(c.options.EnvPrefix, key)
+			if envVal, ok := c.options.LookupEnv(envName); ok {
+				vals = []string{envVal}
+				if field.value.Kind() == reflect.Slice {
+					vals = splitConfigList(envVal)
+				}
+				setting.Source, setting.Origin = ConfigSourceEnv, envName
+			}
+		}
+		if flagVal, ok := c.options.Flags[key]; ok {
+			vals = []string{flagVal}
+			setting.Source, setting.Origin = ConfigSourceFlag, ""
+		}
+		values[field.name] = vals
+		settings[i] = setting
+	}
+
+	err := DefaultParser.ParseFrom(URLValuesSource{Values: values}, target)
+	if err == nil {
+		if validator, ok := target.(ConfigValidator); ok {
+			err = validator.Validate()
+		}
+	}
+
+	for i, field := range fields {
+		settings[i].Value = strings.Join(formatConfigValue(field.value), ", ")
+		if field.secret && settings[i].Value != "" {
+			settings[i].Value = "********"
+		}
+	}
+	c.settings = append(c.settings, settings...)
+
+	if err != nil {
+		return fmt.Errorf("invalid config section %q: %w", name, err)
+	}
+	return nil
+}
+
+// Validate reports the invalid sections and the keys in the config file that no
+// section uses, which are usually typos. Call it once all features are registered.
+func (c *Config) Validate() error {
+	errs := append([]error(nil), c.errs...)
+	keys := make([]string, 0, len(c.fileValues))
+	for key := range c.fileValues {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if !c.hasSetting(key) {
+			errs = append(errs, fmt.Errorf("unknown config key %q in %s", key, c.options.File))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Settings returns the effective value of every key of the loaded sections and
+// where it came from, sorted by key.
+func (c *Config) Settings() []ConfigSetting {
+	settings := append([]ConfigSetting(nil), c.settings...)
+	sort.Slice(settings, func(i, j int) bool { return settings[i].Key < settings[j].Key })
+	return settings
+}
+
+// File returns the config file that was loaded, or "" if there is none.
+func (c *Config) File() string {
+	return c.options.File
+}
+
+func (c *Config) hasSetting(key string) bool {
+	for _, setting := range c.settings {
+		if setting.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// configField is an exported field of a config section.
+type configField struct {
+	name        string
+	description string
+	secret      bool
+	value       reflect.Value
+}
+
+// configFields lists the fields of a section struct that can be configured.
+func configFields(section reflect.Value) []configField {
+	var fields []configField
+	sectionType := section.Type()
+	for i := 0; i < section.NumField(); i++ {
+		fieldType := sectionType.Field(i)
+		if !fieldType.IsExported() {
+			continue
+		}
+		name := fieldType.Name
+		if jsonTag := fieldType.Tag.Get("json"); jsonTag != "" {
+			tagName := strings.Split(jsonTag, ",")[0]
+			if tagName == "-" {
+				continue
+			}
+			if tagName != "" {
+				name = tagName
+			}
+		}
+		fields = append(fields, configField{
+			name:        name,
+			description: fieldType.Tag.Get("description"),
+			secret:      fieldType.Tag.Get("secret") == "true",
+			value:       section.Field(i),
+		})
+	}
+	return fields
+}
+
+// formatConfigValue returns the values the parser reads back into v.
+func formatConfigValue(v reflect.Value) []string {
+	if v.Kind() == reflect.Slice {
+		values := make([]string, v.Len())
+		for i := range values {
+			values[i] = fmt.Sprint(v.Index(i).Interface())
+		}
+		return values
+	}
+	return []string{fmt.Sprint(v.Interface())}
+}
+
+// configEnvName turns a key such as "posts.summarization.api_url" into an environment
+// variable name such as PREFIX_POSTS_SUMMARIZATION_API_URL.
+func configEnvName(prefix, key string) string {
+	name := strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(key))
+	if prefix == "" {
+		return name
+	}
+	return prefix + "_" + name
+}
+
+// splitConfigList splits the comma separated value of a list setting.
+func splitConfigList(value string) []string {
+	var values []string
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			values = append(values, part)
+		}
+	}
+	return values
+}
@@ -0,0 +1,347 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// readConfigFile reads a config file into values by key, such as "server.port".
+// Files ending in .json are JSON objects of sections; others are TOML.
+func readConfigFile(path string) (map[string][]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasSuffix(strings.ToLower(path), ".json") {
+		return parseJSONConfig(data)
+	}
+	return parseTOMLConfig(data)
+}
+
+// parseJSONConfig flattens nested objects into dotted keys:
+// {"server": {"port": 8080}} sets "server.port".
+func parseJSONConfig(data []byte) (map[string][]string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var root map[string]interface{}
+	if err := decoder.Decode(&root); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	values := make(map[string][]string)
+	if err := flattenJSONConfig("", root, values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+func flattenJSONConfig(prefix string, object map[string]interface{}, values map[string][]string) error {
+	for name, value := range object {
+		key := name
+		if prefix != "" {
+			key = prefix + "." + name
+		}
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if err := flattenJSONConfig(key, v, values); err != nil {
+				return err
+			}
+		case []interface{}:
+			list := make([]string, len(v))
+			for i, item := range v {
+				s, ok := jsonConfigScalar(item)
+				if !ok {
+					return fmt.Errorf("%s: lists may only hold strings, numbers and booleans", key)
+				}
+				list[i] = s
+			}
+			values[key] = list
+		default:
+			s, ok := jsonConfigScalar(v)
+			if !ok {
+				return fmt.Errorf("%s: null is not a valid value", key)
+			}
+			values[key] = []string{s}
+		}
+	}
+	return nil
+}
+
+func jsonConfigScalar(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case json.Number:
+		return v.String(), true
+	case bool:
+		return strconv.FormatBool(v), true
+	}
+	return "", false
+}
+
+// parseTOMLConfig reads the subset of TOML that config files need: [tables] and
+// dotted keys, strings, integers, floats, booleans, arrays of these, and comments.
+// Arrays of tables, inline tables and dates are not supported; write dates and
+// durations as strings.
+func parseTOMLConfig(data []byte) (map[string][]string, error) {
+	p := &tomlParser{src: string(data)}
+	values := make(map[string][]string)
+	table := ""
+	for {
+		p.skipBlank()
+		if p.eof() {
+			return values, nil
+		}
+
+		if p.peek() == '[' {
+			if strings.HasPrefix(p.src[p.pos:], "[[") {
+				return nil, p.errorf("arrays of tables are not supported")
+			}
+			p.pos++
+			name, err := p.key()
+			if err != nil {
+				return nil, err
+			}
+			p.skipInline()
+			if p.eof() || p.peek() != ']' {
+				return nil, p.errorf("expected ] after table name")
+			}
+			p.pos++
+			if err := p.endOfLine(); err != nil {
+				return nil, err
+			}
+			table = name
+			continue
+		}
+
+		key, err := p.key()
+		if err != nil {
+			return nil, err
+		}
+		p.skipInline()
+		if p.eof() || p.peek() != '=' {
+			return nil, p.errorf("expected = after %s", key)
+		}
+		p.pos++
+		value, err := p.value(true)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.endOfLine(); err != nil {
+			return nil, err
+		}
+		if table != "" {
+			key = table + "." + key
+		}
+		if _, ok := values[key]; ok {
+			return nil, p.errorf("%s is set twice", key)
+		}
+		values[key] = value
+	}
+}
+
+// tomlParser reads TOML from src, starting at pos.
+type tomlParser struct {
+	src string
+	pos int
+}
+
+func (p *tomlParser) eof() bool  { return p.pos >= len(p.src) }
+func (p *tomlParser) peek() byte { return p.src[p.pos] }
+
+func (p *tomlParser) errorf(format string, args ...interface{}) error {
+	line := strings.Count(p.src[:min(p.pos, len(p.src))], "\n") + 1
+	return fmt.Errorf("line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+// skipInline skips spaces and tabs.
+func (p *tomlParser) skipInline() {
+	for !p.eof() && (p.peek() == ' ' || p.peek() == '\t') {
+		p.pos++
+	}
+}
+
+// skipBlank skips whitespace, newlines and comments.
+func (p *tomlParser) skipBlank() {
+	for !p.eof() {
+		switch p.peek() {
+		case ' ', '\t', '\r', '\n':
+			p.pos++
+		case '#':
+			p.skipComment()
+		default:
+			return
+		}
+	}
+}
+
+func (p *tomlParser) skipComment() {
+	for !p.eof() && p.peek() != '\n' {
+		p.pos++
+	}
+}
+
+// endOfLine expects nothing but a comment before the next line.
+func (p *tomlParser) endOfLine() error {
+	p.skipInline()
+	if !p.eof() && p.peek() == '#' {
+		p.skipComment()
+	}
+	if p.eof() {
+		return nil
+	}
+	if p.peek() == '\r' {
+		p.pos++
+	}
+	if p.eof() || p.peek() != '\n' {
+		return p.errorf("unexpected %q after value", p.rest())
+	}
+	p.pos++
+	return nil
+}
+
+// rest returns the remainder of the current line, for error messages.
+func (p *tomlParser) rest() string {
+	rest := p.src[p.pos:]
+	if i := strings.IndexByte(rest, '\n'); i >= 0 {
+		rest = rest[:i]
+	}
+	return strings.TrimSpace(rest)
+}
+
+// key reads a bare, quoted or dotted key, such as posts.summarization or "api-url".
+func (p *tomlParser) key() (string, error) {
+	var parts []string
+	for {
+		p.skipInline()
+		if p.eof() {
+			return "", p.errorf("expected a key")
+		}
+		var part string
+		switch c := p.peek(); {
+		case c == '"' || c == '\'':
+			s, err := p.quoted()
+			if err != nil {
+				return "", err
+			}
+			part = s
+		default:
+			start := p.pos
+			for !p.eof() && isBareKeyChar(p.peek()) {
+				p.pos++
+			}
+			if start == p.pos {
+				return "", p.errorf("invalid key %q", p.rest())
+			}
+			part = p.src[start:p.pos]
+		}
+		parts = append(parts, part)
+		p.skipInline()
+		if p.eof() || p.peek() != '.' {
+			return strings.Join(parts, "."), nil
+		}
+		p.pos++
+	}
+}
+
+func isBareKeyChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+// quoted reads a basic "string" with escapes or a literal 'string'.
+func (p *tomlParser) quoted() (string, error) {
+	quote := p.peek()
+	end := p.pos + 1
+	for ; end < len(p.src) && p.src[end] != quote && p.src[end] != '\n'; end++ {
+		if quote == '"' && p.src[end] == '\\' {
+			end++
+		}
+	}
+	if end >= len(p.src) || p.src[end] != quote {
+		return "", p.errorf("unterminated string")
+	}
+	raw := p.src[p.pos : end+1]
+	p.pos = end + 1
+	if quote == '\'' {
+		return raw[1 : len(raw)-1], nil
+	}
+	s, err := strconv.Unquote(raw)
+	if err != nil {
+		return "", p.errorf("invalid string %s", raw)
+	}
+	return s, nil
+}
+
+// value reads a value; arrays are only allowed at the top level.
+func (p *tomlParser) value(allowArray bool) ([]string, error) {
+	p.skipInline()
+	if p.eof() || p.peek() == '\n' {
+		return nil, p.errorf("expected a value")
+	}
+	switch c := p.peek(); {
+	case c == '"' || c == '\'':
+		if strings.HasPrefix(p.src[p.pos:], `"""`) || strings.HasPrefix(p.src[p.pos:], "'''") {
+			return nil, p.errorf("multi-line strings are not supported")
+		}
+		s, err := p.quoted()
+		if err != nil {
+			return nil, err
+		}
+		return []string{s}, nil
+	case c == '[':
+		if !allowArray {
+			return nil, p.errorf("nested arrays are not supported")
+		}
+		return p.array()
+	case c == '{':
+		return nil, p.errorf("inline tables are not supported")
+	}
+
+	start := p.pos
+	for !p.eof() && !strings.ContainsRune(" \t\r\n,]#", rune(p.peek())) {
+		p.pos++
+	}
+	token := p.src[start:p.pos]
+	switch token {
+	case "true", "false":
+		return []string{token}, nil
+	}
+	number := strings.ReplaceAll(token, "_", "")
+	if _, err := strconv.ParseInt(number, 10, 64); err == nil {
+		return []string{strings.TrimPrefix(number, "+")}, nil
+	}
+	if _, err := strconv.ParseFloat(number, 64); err == nil {
+		return []string{number}, nil
+	}
+	return nil, p.errorf("invalid value %q; quote strings", token)
+}
+
+// array reads [a, b, c], which may span several lines.
+func (p *tomlParser) array() ([]string, error) {
+	p.pos++ // [
+	values := []string{}
+	for {
+		p.skipBlank()
+		if p.eof() {
+			return nil, p.errorf("unterminated array")
+		}
+		if p.peek() == ']' {
+			p.pos++
+			return values, nil
+		}
+		value, err := p.value(false)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value...)
+		p.skipBlank()
+		if !p.eof() && p.peek() == ',' {
+			p.pos++
+		} else if p.eof() || p.peek() != ']' {
+			return nil, p.errorf("expected , or ] in array")
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeConfigFile writes a config file named name into a temporary directory.
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	return path
+}
+
+// fakeEnv returns a LookupEnv function serving vars.
+func fakeEnv(vars map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		v, ok := vars[name]
+		return v, ok
+	}
+}
+
+func settingByKey(t *testing.T, c *Config, key string) ConfigSetting {
+	t.Helper()
+	for _, setting := range c.Settings() {
+		if setting.Key == key {
+			return setting
+		}
+	}
+	t.Fatalf("No setting %q", key)
+	return ConfigSetting{}
+}
+
+func TestLoadConfig_Layers(t *testing.T) {
+	file := writeConfigFile(t, "app.toml", `
+# Served behind a proxy
+[server]
+host = "0.0.0.0"
+port = 9000
+read_timeout = "2s"
+
+[db]
+path = "from-file.db"
+`)
+	cfg, err := LoadConfig(ConfigOptions{
+		File:      file,
+		EnvPrefix: "MYAPP",
+		LookupEnv: fakeEnv(map[string]string{"MYAPP_SERVER_PORT": "9100", "MYAPP_DB_PATH": "from-env.db"}),
+		Flags:     map[string]string{"db.path": "from-flag.db"},
+	})
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	if cfg.Server.Host != "0.0.0.0" || cfg.Server.Port != 9100 || cfg.DB.Path != "from-flag.db" {
+		t.Errorf("Unexpected values: host %q, port %d, db %q", cfg.Server.Host, cfg.Server.Port, cfg.DB.Path)
+	}
+	if cfg.Server.ReadTimeout != 2*time.Second || cfg.Server.WriteTimeout != 10*time.Second {
+		t.Errorf("Unexpected timeouts: read %s, write %s", cfg.Server.ReadTimeout, cfg.Server.WriteTimeout)
+	}
+
+	tests := []struct {
+		key, value, source, origin string
+	}{
+		{"server.host", "0.0.0.0", ConfigSourceFile, file},
+		{"server.port", "9100", ConfigSourceEnv, "MYAPP_SERVER_PORT"},
+		{"db.path", "from-flag.db", ConfigSourceFlag, ""},
+		{"server.write_timeout", "10s", ConfigSourceDefault, ""},
+	}
+	for _, tt := range tests {
+		setting := settingByKey(t, cfg, tt.key)
+		if setting.Value != tt.value || setting.Source != tt.source || setting.Origin != tt.origin {
+			t.Errorf("Expected %s = %q from %s %s, got %+v", tt.key, tt.value, tt.source, tt.origin, setting)
+		}
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected a valid config, got %v", err)
+	}
+}
+
+func TestLoadConfig_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want string
+	}{
+		{"out of range", map[string]string{"SERVER_PORT": "70000"}, "server"},
+		{"not a number", map[string]string{"SERVER_PORT": "http"}, "invalid integer"},
+		{"bad duration", map[string]string{"SERVER_IDLE_TIMEOUT": "2 minutes"}, "invalid duration"},
+		{"unknown log level", map[string]string{"LOG_LEVEL": "verbose"}, "invalid log level"},
+		{"required", map[string]string{"DB_PATH": ""}, "required"},
+		{"unknown trace exporter", map[string]string{"TRACE_EXPORTER": "jaeger"}, "invalid trace exporter"},
+		{"otlp without endpoint", map[string]string{"TRACE_EXPORTER": "otlp", "TRACE_ENDPOINT": ""}, "endpoint"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadConfig(ConfigOptions{LookupEnv: fakeEnv(tt.env)})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+type testFeatureConfig struct {
+	URL     string        `json:"url"`
+	Token   string        `json:"token" secret:"true"`
+	Retries int           `json:"retries" validate:"min=0,max=10"`
+	Tags    []string      `json:"tags"`
+	Timeout time.Duration `json:"timeout"`
+}
+
+func TestConfig_Section(t *testing.T) {
+	file := writeConfigFile(t, "app.json", `{
+		"server": {"port": 8081},
+		"posts": {"summarization": {"url": "https://example.com", "tags": ["a", "b"]}}
+	}`)
+	cfg, err := LoadConfig(ConfigOptions{
+		File:      file,
+		EnvPrefix: "MYAPP",
+		LookupEnv: fakeEnv(map[string]string{"MYAPP_POSTS_SUMMARIZATION_TOKEN": "s3cret"}),
+	})
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	feature := testFeatureConfig{Retries: 3, Timeout: time.Minute}
+	cfg.Section("posts.summarization", &feature)
+	want := testFeatureConfig{URL: "https://example.com", Token: "s3cret", Retries: 3, Tags: []string{"a", "b"}, Timeout: time.Minute}
+	if !reflect.DeepEqual(feature, want) {
+		t.Errorf("Expected %+v, got %+v", want, feature)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected a valid config, got %v", err)
+	}
+
+	if got := settingByKey(t, cfg, "posts.summarization.token").Value; got != "********" {
+		t.Errorf("Expected the token to be masked, got %q", got)
+	}
+	if got := settingByKey(t, cfg, "posts.summarization.tags").Value; got != "a, b" {
+		t.Errorf("Expected the tags to be listed, got %q", got)
+	}
+
+	cfg.Section("posts.summarization", &testFeatureConfig{})
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "loaded twice") {
+		t.Errorf("Expected an error for a section loaded twice, got %v", err)
+	}
+}
+
+func TestConfig_ValidateReportsFeatureErrorsAndUnknownKeys(t *testing.T) {
+	file := writeConfigFile(t, "app.toml", `
+[posts]
+retries = 20
+retires = 2
+`)
+	cfg, err := LoadConfig(ConfigOptions{File: file})
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	var feature testFeatureConfig
+	cfg.Section("posts", &feature)
+	err = cfg.Validate()
+	if err == nil {
+		t.Fatal("Expected validation errors")
+	}
+	for _, want := range []string{`invalid config section "posts"`, `unknown config key "posts.retires"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected %q in %v", want, err)
+		}
+	}
+}
+
+func TestConfig_Defaults(t *testing.T) {
+	cfg := DefaultConfig()
+	feature := testFeatureConfig{Retries: 2}
+	cfg.Section("posts", &feature)
+	if feature.Retries != 2 || cfg.Validate() != nil {
+		t.Errorf("Expected the defaults to be kept, got %+v", feature)
+	}
+}
+
+func TestParseTOMLConfig(t *testing.T) {
+	values, err := parseTOMLConfig([]byte(`
+top = 'literal \n'
+[a.b]  # comment
+"quoted-key" = "tab\tand \"quotes\" # not a comment"
+n = 1_000
+f = -1.5
+on = true
+list = [
+  "x", # first
+  2,
+]
+c.d = false
+`))
+	if err != nil {
+		t.Fatalf("parseTOMLConfig failed: %v", err)
+	}
+	want := map[string][]string{
+		"top":            {`literal \n`},
+		"a.b.quoted-key": {"tab\tand \"quotes\" # not a comment"},
+		"a.b.n":          {"1000"},
+		"a.b.f":          {"-1.5"},
+		"a.b.on":         {"true"},
+		"a.b.list":       {"x", "2"},
+		"a.b.c.d":        {"false"},
+	}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("Expected %v, got %v", want, values)
+	}
+
+	for _, invalid := range []string{
+		"key = bare",
+		"key = 1\nkey = 2",
+		"[[tables]]",
+		"key = {a = 1}",
+		"key = \"unterminated",
+		"key = 1 2",
+		"[section",
+		"key = [[1]]",
+	} {
+		if _, err := parseTOMLConfig([]byte(invalid)); err == nil {
+			t.Errorf("Expected an error for %q", invalid)
+		}
+	}
+}
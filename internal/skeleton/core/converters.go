@@ -109,6 +109,36 @@ func (c BasicConverter) ConvertSlice(values []string, targetType reflect.Type) (
 	return slice.Interface(), nil
 }
 
+// DurationConverter handles time.Duration, written like "1m30s"
+type DurationConverter struct{}
+
+func (c DurationConverter) CanConvert(targetType reflect.Type) bool {
+	return targetType == reflect.TypeOf(time.Duration(0))
+}
+
+func (c DurationConverter) Convert(value string, targetType reflect.Type) (interface{}, error) {
+	if value == "" {
+		return time.Duration(0), nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid duration: %s", value)
+	}
+	return d, nil
+}
+
+func (c DurationConverter) ConvertSlice(values []string, targetType reflect.Type) (interface{}, error) {
+	slice := make([]time.Duration, len(values))
+	for i, value := range values {
+		converted, err := c.Convert(value, targetType.Elem())
+		if err != nil {
+			return nil, fmt.Errorf("error converting duration element %d: %w", i, err)
+		}
+		slice[i] = converted.(time.Duration)
+	}
+	return slice, nil
+}
+
 // TimeConverter handles time.Time
 type TimeConverter struct {
 	Formats []string // Configurable time formats
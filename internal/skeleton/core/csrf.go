@@ -0,0 +1,122 @@
+package core
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/petrock/example_module_path/core/ui"
+)
+
+const (
+	// CSRFCookieName is the cookie holding the CSRF token.
+	CSRFCookieName = "csrf_token"
+	// CSRFFieldName is the form field ui.CSRFField renders the token into.
+	CSRFFieldName = "csrf_token"
+	// CSRFHeader is the header JSON clients send the token in.
+	CSRFHeader = "X-CSRF-Token"
+)
+
+// csrfTokenLength is the length of a base64url encoded 32-byte token.
+const csrfTokenLength = 43
+
+// CSRF protects against cross-site request forgery with a double-submit cookie. Every
+// request gets a token, kept in a cookie and put into the context for ui.CSRFField.
+// POST, PUT, PATCH and DELETE requests must send the token back in the csrf_token form
+// field or the X-CSRF-Token header; others are rejected with 403 and an error page.
+//
+// JSON requests and requests without a body, such as DELETE, pass with any non-empty
+// X-CSRF-Token header when the client has no CSRF cookie, as scripts calling the API
+// don't: browsers only let a page send custom headers to another origin after a CORS
+// preflight, which the application never approves. Requests whose path starts with one of the exempt
+// prefixes are not checked; use them for endpoints that authenticate every request
+// themselves, such as signed webhooks.
+func CSRF(exempt ...string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, hadCookie := csrfCookieToken(r)
+			if !hadCookie {
+				token = newCSRFToken()
+				http.SetCookie(w, &http.Cookie{
+					Name:     CSRFCookieName,
+					Value:    token,
+					Path:     "/",
+					HttpOnly: true,
+					Secure:   r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https",
+					SameSite: http.SameSiteLaxMode,
+				})
+			}
+			r = r.WithContext(ui.WithCSRFToken(r.Context(), token))
+
+			if csrfSafeMethod(r.Method) || csrfExempt(r.URL.Path, exempt) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			sent := r.Header.Get(CSRFHeader)
+			if !hadCookie && sent != "" && (isJSONRequest(r) || r.Header.Get("Content-Type") == "") {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if sent == "" {
+				sent = r.PostFormValue(CSRFFieldName)
+			}
+			if hadCookie && subtle.ConstantTimeCompare([]byte(sent), []byte(token)) == 1 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			WriteErrorPageMessage(w, r, http.StatusForbidden,
+				"This form has expired or was sent from another site. Go back, reload the page and try again.")
+		})
+	}
+}
+
+// csrfCookieToken returns the token from the CSRF cookie, if it holds a well-formed one.
+func csrfCookieToken(r *http.Request) (string, bool) {
+	cookie, err := r.Cookie(CSRFCookieName)
+	if err != nil || len(cookie.Value) != csrfTokenLength {
+		return "", false
+	}
+	if _, err := base64.RawURLEncoding.DecodeString(cookie.Value); err != nil {
+		return "", false
+	}
+	return cookie.Value, true
+}
+
+// newCSRFToken returns 32 random bytes, base64url encoded.
+func newCSRFToken() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic("failed to read random bytes for CSRF token: " + err.Error())
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// csrfSafeMethod reports whether method must not change state and so needs no token.
+func csrfSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+// csrfExempt reports whether path starts with one of the exempt prefixes.
+func csrfExempt(path string, exempt []string) bool {
+	for _, prefix := range exempt {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// isJSONRequest reports whether the request body is JSON.
+func isJSONRequest(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && (mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"))
+}
@@ -0,0 +1,158 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/petrock/example_module_path/core/ui"
+)
+
+// csrfHandler wraps an OK handler in CSRF and records the token the handler saw.
+func csrfHandler(seen *string, exempt ...string) http.Handler {
+	return CSRF(exempt...)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*seen = ui.CSRFToken(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}))
+}
+
+// csrfCookie fetches a page through h and returns the CSRF cookie it sets.
+func csrfCookie(t *testing.T, h http.Handler) *http.Cookie {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == CSRFCookieName {
+			return c
+		}
+	}
+	t.Fatal("Expected a CSRF cookie to be set")
+	return nil
+}
+
+func postForm(target string, form url.Values, cookie *http.Cookie) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, target, strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if cookie != nil {
+		r.AddCookie(cookie)
+	}
+	return r
+}
+
+func TestCSRF_GetSetsCookieAndContext(t *testing.T) {
+	var seen string
+	h := csrfHandler(&seen)
+	cookie := csrfCookie(t, h)
+	if !cookie.HttpOnly || cookie.SameSite != http.SameSiteLaxMode {
+		t.Errorf("Expected an HttpOnly SameSite=Lax cookie, got %+v", cookie)
+	}
+	if seen != cookie.Value {
+		t.Errorf("Expected context token %q, got %q", cookie.Value, seen)
+	}
+
+	// An existing cookie is kept
+	rec := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(cookie)
+	h.ServeHTTP(rec, r)
+	if len(rec.Result().Cookies()) != 0 {
+		t.Error("Expected no new cookie when the request has one")
+	}
+	if seen != cookie.Value {
+		t.Errorf("Expected context token %q, got %q", cookie.Value, seen)
+	}
+}
+
+func TestCSRF_FormPost(t *testing.T) {
+	var seen string
+	h := csrfHandler(&seen)
+	cookie := csrfCookie(t, h)
+
+	tests := []struct {
+		name   string
+		req    *http.Request
+		status int
+	}{
+		{"matching field", postForm("/", url.Values{CSRFFieldName: {cookie.Value}}, cookie), http.StatusOK},
+		{"missing field", postForm("/", url.Values{"name": {"x"}}, cookie), http.StatusForbidden},
+		{"wrong field", postForm("/", url.Values{CSRFFieldName: {newCSRFToken()}}, cookie), http.StatusForbidden},
+		{"no cookie", postForm("/", url.Values{CSRFFieldName: {cookie.Value}}, nil), http.StatusForbidden},
+		{"tampered cookie", postForm("/", url.Values{CSRFFieldName: {"short"}}, &http.Cookie{Name: CSRFCookieName, Value: "short"}), http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, tt.req)
+			if rec.Code != tt.status {
+				t.Errorf("Expected %d, got %d", tt.status, rec.Code)
+			}
+		})
+	}
+}
+
+func TestCSRF_Header(t *testing.T) {
+	var seen string
+	h := csrfHandler(&seen)
+	cookie := csrfCookie(t, h)
+
+	jsonPost := func(token string, cookie *http.Cookie) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/commands", strings.NewReader(`{}`))
+		r.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			r.Header.Set(CSRFHeader, token)
+		}
+		if cookie != nil {
+			r.AddCookie(cookie)
+		}
+		return r
+	}
+
+	bodyless := func(token string) *http.Request {
+		r := httptest.NewRequest(http.MethodDelete, "/api/items/1", nil)
+		if token != "" {
+			r.Header.Set(CSRFHeader, token)
+		}
+		return r
+	}
+
+	tests := []struct {
+		name   string
+		req    *http.Request
+		status int
+	}{
+		{"header matches cookie", jsonPost(cookie.Value, cookie), http.StatusOK},
+		{"header differs from cookie", jsonPost("api", cookie), http.StatusForbidden},
+		{"JSON client without cookie", jsonPost("api", nil), http.StatusOK},
+		{"JSON without header", jsonPost("", nil), http.StatusForbidden},
+		{"bodyless request without cookie", bodyless("api"), http.StatusOK},
+		{"bodyless request without header", bodyless(""), http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, tt.req)
+			if rec.Code != tt.status {
+				t.Errorf("Expected %d, got %d", tt.status, rec.Code)
+			}
+		})
+	}
+}
+
+func TestCSRF_Exempt(t *testing.T) {
+	var seen string
+	h := csrfHandler(&seen, "/webhooks/")
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, postForm("/webhooks/github", url.Values{}, nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected exempt path to pass, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, postForm("/webhook", url.Values{}, nil))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Expected other paths to be checked, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,133 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// HealthCheckTimeout bounds how long Readiness waits for each check.
+const HealthCheckTimeout = 2 * time.Second
+
+// HealthCheck reports whether a dependency the application needs is usable.
+// It returns nil when healthy and should return promptly when ctx is done.
+type HealthCheck func(ctx context.Context) error
+
+// namedHealthCheck is a HealthCheck registered with RegisterHealthCheck.
+type namedHealthCheck struct {
+	name  string
+	check HealthCheck
+}
+
+// CheckResult is the outcome of a single readiness check.
+type CheckResult struct {
+	Name     string        `json:"name"`
+	Healthy  bool          `json:"healthy"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration_ns"`
+}
+
+// Readiness reports whether the application can serve requests, see App.Readiness.
+type Readiness struct {
+	Ready      bool           `json:"ready"`
+	Replayed   bool           `json:"replayed"`    // The message log has been replayed into the state
+	LogVersion uint64         `json:"log_version"` // ID of the newest message in the log
+	Checks     []CheckResult  `json:"checks"`
+	Workers    []WorkerHealth `json:"workers"`
+}
+
+// WorkerHealth is the part of a WorkerStatus the readiness report includes. Errors,
+// positions and lease holders are only shown to admins, at GET /_/workers.
+type WorkerHealth struct {
+	Name    string `json:"name"`
+	State   string `json:"state"` // One of the WorkerState constants
+	Healthy bool   `json:"healthy"`
+}
+
+// RegisterHealthCheck adds a check to the readiness report. Features register checks for
+// the external services they depend on; the application is not ready while one fails.
+func (a *App) RegisterHealthCheck(name string, check HealthCheck) {
+	a.healthMu.Lock()
+	defer a.healthMu.Unlock()
+	a.healthChecks = append(a.healthChecks, namedHealthCheck{name: name, check: check})
+}
+
+// Replayed reports whether ReplayLog has completed.
+func (a *App) Replayed() bool {
+	return a.replayed.Load()
+}
+
+// Readiness runs the readiness checks: the database answers, the log has been replayed,
+// no registered worker has stopped, and every check added with RegisterHealthCheck passes.
+func (a *App) Readiness(ctx context.Context) Readiness {
+	readiness := Readiness{Replayed: a.Replayed(), Checks: []CheckResult{}, Workers: []WorkerHealth{}}
+
+	readiness.Checks = append(readiness.Checks, runHealthCheck(ctx, "database", func(ctx context.Context) error {
+		return a.DB.PingContext(ctx)
+	}))
+	readiness.Checks = append(readiness.Checks, runHealthCheck(ctx, "replay", func(ctx context.Context) error {
+		if !readiness.Replayed {
+			return errors.New("message log replay has not completed")
+		}
+		return nil
+	}))
+	readiness.Checks = append(readiness.Checks, runHealthCheck(ctx, "workers", func(ctx context.Context) error {
+		statuses, err := a.WorkerStatuses(ctx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			readiness.Workers = append(readiness.Workers, WorkerHealth{Name: status.Name, State: status.State, Healthy: status.Healthy})
+		}
+		// Failed cycles are reported in the statuses but don't make the app unready;
+		// a worker that stopped does
+		var stopped []string
+		for _, status := range statuses {
+			if status.State == WorkerStateStopped {
+				stopped = append(stopped, status.Name)
+			}
+		}
+		if len(stopped) > 0 {
+			return fmt.Errorf("workers not running: %v", stopped)
+		}
+		return nil
+	}))
+	if version, err := a.MessageLog.Version(ctx); err == nil {
+		readiness.LogVersion = version
+	}
+
+	a.healthMu.Lock()
+	checks := append([]namedHealthCheck(nil), a.healthChecks...)
+	a.healthMu.Unlock()
+	for _, c := range checks {
+		readiness.Checks = append(readiness.Checks, runHealthCheck(ctx, c.name, c.check))
+	}
+
+	readiness.Ready = true
+	for _, result := range readiness.Checks {
+		readiness.Ready = readiness.Ready && result.Healthy
+	}
+	return readiness
+}
+
+// runHealthCheck runs check with HealthCheckTimeout, turning a panic into a failure.
+func runHealthCheck(ctx context.Context, name string, check HealthCheck) (result CheckResult) {
+	ctx, cancel := context.WithTimeout(ctx, HealthCheckTimeout)
+	defer cancel()
+
+	result.Name = name
+	start := time.Now()
+	defer func() {
+		if r := recover(); r != nil {
+			result.Error = fmt.Sprintf("check panicked: %v", r)
+		}
+		result.Healthy = result.Error == ""
+		result.Duration = time.Since(start)
+	}()
+
+	if err := check(ctx); err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
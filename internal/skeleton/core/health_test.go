@@ -0,0 +1,71 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestApp_Readiness(t *testing.T) {
+	app := newTestApp(t)
+	ctx := context.Background()
+
+	readiness := app.Readiness(ctx)
+	if readiness.Ready || readiness.Replayed {
+		t.Fatalf("Expected app not to be ready before replay, got %+v", readiness)
+	}
+	if err := app.ReplayLog(); err != nil {
+		t.Fatalf("ReplayLog failed: %v", err)
+	}
+	if readiness = app.Readiness(ctx); !readiness.Ready {
+		t.Fatalf("Expected app to be ready after replay, got %+v", readiness)
+	}
+
+	var upstreamErr error
+	app.RegisterHealthCheck("upstream", func(ctx context.Context) error { return upstreamErr })
+	app.RegisterHealthCheck("flaky", func(ctx context.Context) error { panic("boom") })
+
+	readiness = app.Readiness(ctx)
+	if readiness.Ready {
+		t.Fatal("Expected a panicking check to make the app unready")
+	}
+	results := map[string]CheckResult{}
+	for _, result := range readiness.Checks {
+		results[result.Name] = result
+	}
+	if !results["database"].Healthy || !results["upstream"].Healthy || results["flaky"].Error != "check panicked: boom" {
+		t.Errorf("Unexpected check results: %+v", readiness.Checks)
+	}
+
+	upstreamErr = errors.New("connection refused")
+	readiness = app.Readiness(ctx)
+	for _, result := range readiness.Checks {
+		if result.Name == "upstream" && result.Error != "connection refused" {
+			t.Errorf("Expected upstream to report its error, got %+v", result)
+		}
+	}
+}
+
+func TestApp_ReadinessReportsStoppedWorkers(t *testing.T) {
+	app := newTestApp(t)
+	app.RegisterWorker(NewWorker("idle", "Never started", nil))
+	if err := app.ReplayLog(); err != nil {
+		t.Fatalf("ReplayLog failed: %v", err)
+	}
+
+	readiness := app.Readiness(context.Background())
+	if readiness.Ready || len(readiness.Workers) != 1 {
+		t.Fatalf("Expected a stopped worker to make the app unready, got %+v", readiness)
+	}
+	encoded, err := json.Marshal(readiness)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	for _, field := range []string{"last_error", "retrying", "lease", "position"} {
+		if strings.Contains(string(encoded), `"`+field+`"`) {
+			t.Errorf("Expected the public readiness report to leave out %s, got %s", field, encoded)
+		}
+	}
+}
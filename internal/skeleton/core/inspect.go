@@ -1,7 +1,9 @@
 package core
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
 	"reflect"
 	"strings"
 	"time"
@@ -48,6 +50,7 @@ type WorkerSchema struct {
 	Description string   `json:"description"` // Worker description if available
 	Type        string   `json:"type"`        // Go type name
 	Methods     []string `json:"methods"`     // Available methods
+	Schedule    string   `json:"schedule"`    // When Work() runs, e.g. "every 1h" or "cron 0 3 * * *"
 }
 
 // InspectResult holds application metadata
@@ -57,6 +60,7 @@ type InspectResult struct {
 	Routes   []string        `json:"routes"`   // List of all registered HTTP routes
 	Features []string        `json:"features"` // List of all registered features
 	Workers  []WorkerSchema  `json:"workers"`  // Schema of all registered workers
+	Leases   []Lease         `json:"leases"`   // Current worker leases and the processes holding them
 }
 
 // GetInspectResult gathers metadata about the application
@@ -83,7 +87,8 @@ func (a *App) GetInspectResult() *InspectResult {
 	for _, name := range queryNames {
 		queryType, found := a.QueryRegistry.GetQueryType(name)
 		if found {
-			schema := buildQuerySchema(name, queryType)
+			resultType, _ := a.QueryRegistry.GetResultType(name)
+			schema := buildQuerySchema(name, queryType, resultType)
 			result.Queries = append(result.Queries, schema)
 		}
 	}
@@ -95,6 +100,20 @@ func (a *App) GetInspectResult() *InspectResult {
 		result.Workers = append(result.Workers, schemas...)
 	}
 
+	// Report which processes hold worker leases
+	result.Leases = []Lease{}
+	if a.Leases != nil {
+		leases, err := a.Leases.List(context.Background())
+		if err != nil {
+			slog.Error("Failed to list leases", "error", err)
+		}
+		for _, lease := range leases {
+			if !lease.Expired(time.Now()) {
+				result.Leases = append(result.Leases, lease)
+			}
+		}
+	}
+
 	return result
 }
 
@@ -136,8 +155,9 @@ func buildCommandSchema(name string, cmdType reflect.Type) CommandSchema {
 	return schema
 }
 
-// buildQuerySchema creates a JSON schema from a query's reflect.Type
-func buildQuerySchema(name string, queryType reflect.Type) QuerySchema {
+// buildQuerySchema creates a JSON schema from a query's reflect.Type and, if the query
+// implements TypedQuery, the type of its result. resultType may be nil.
+func buildQuerySchema(name string, queryType, resultType reflect.Type) QuerySchema {
 	schema := QuerySchema{
 		Name:       name,
 		Type:       queryType.String(),
@@ -167,12 +187,30 @@ func buildQuerySchema(name string, queryType reflect.Type) QuerySchema {
 		schema.Required = append(schema.Required, fieldName)
 	}
 
-	// For now, we'll add a placeholder result schema
-	// In a real implementation, this would be derived from the query result type
+	// Queries that don't declare their result type get an empty result schema
 	schema.Result = ResultDef{
 		Type:       "object",
 		Properties: make(map[string]PropertyDef),
 	}
+	if resultType != nil && resultType.Kind() == reflect.Struct {
+		for i := 0; i < resultType.NumField(); i++ {
+			field := resultType.Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			fieldName := field.Name
+			if jsonTag := field.Tag.Get("json"); jsonTag != "" {
+				parts := strings.Split(jsonTag, ",")
+				if parts[0] == "-" {
+					continue
+				}
+				if parts[0] != "" {
+					fieldName = parts[0]
+				}
+			}
+			schema.Result.Properties[fieldName] = buildPropertyDef(field)
+		}
+	}
 
 	return schema
 }
@@ -203,7 +241,8 @@ func buildWorkerSchemas(worker Worker) []WorkerSchema {
 			}
 			
 			// Add standard worker methods
-			schema.Methods = []string{"Start", "Stop", "Work", "OnCommand", "Replay", "SetDependencies", "SetPeriodicWork", "State", "WorkerInfo"}
+			schema.Methods = []string{"Start", "Stop", "Work", "OnCommand", "Replay", "Reset", "SetDependencies", "SetPartitioning", "SetPeriodicWork", "SetRetryPolicy", "SetSchedule", "State", "WorkerInfo"}
+			schema.Schedule = describeSchedule(worker)
 			
 			schemas = append(schemas, schema)
 		}
@@ -218,7 +257,8 @@ func buildWorkerSchemas(worker Worker) []WorkerSchema {
 // buildSingleWorkerSchema creates a schema from a worker instance (original behavior)
 func buildSingleWorkerSchema(worker Worker) WorkerSchema {
 	schema := WorkerSchema{
-		Type: fmt.Sprintf("%T", worker),
+		Type:     fmt.Sprintf("%T", worker),
+		Schedule: describeSchedule(worker),
 	}
 
 	// Try to get WorkerInfo if implemented
@@ -1,23 +1,97 @@
 package core
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
+	"time"
 )
 
-// KVStore provides persistent key-value storage
+// KVSweepInterval is how often StartWorkers deletes expired keys from the KVStore and
+// trims its history to the retention limits.
+const KVSweepInterval = time.Minute
+
+// KVTombstoneRetention is how long the KVStore remembers the version of a deleted or
+// expired key. A key created again within that time continues from that version, so a
+// CompareAndSwap holding a version read before the delete can't succeed.
+const KVTombstoneRetention = 7 * 24 * time.Hour
+
+// ErrKeyNotFound is returned when a key doesn't exist or has expired.
+var ErrKeyNotFound = errors.New("key not found")
+
+// ErrVersionConflict is returned by CompareAndSwap when the key was changed since it was read.
+var ErrVersionConflict = errors.New("version conflict")
+
+// KVEntry is a stored key with its raw JSON value, as returned by Scan.
+type KVEntry struct {
+	Key       string          `json:"key"`
+	Value     json.RawMessage `json:"value"`
+	Version   uint64          `json:"version"`
+	ExpiresAt *time.Time      `json:"expires_at,omitempty"`
+}
+
+// KVStore provides persistent key-value storage.
+// Every write increments the key's version; expired keys behave as if they were deleted.
 type KVStore interface {
 	// Get retrieves a value by key and unmarshals it into dest
 	Get(key string, dest any) error
-	
+
 	// Set stores a value by key, marshaling it appropriately
 	Set(key string, value any) error
-	
+
 	// List returns all keys matching the glob pattern
 	List(glob string) ([]string, error)
+
+	// GetVersion retrieves a value like Get and also returns its version, for CompareAndSwap
+	GetVersion(key string, dest any) (uint64, error)
+
+	// Delete removes a key. Deleting a key that doesn't exist is not an error
+	Delete(key string) error
+
+	// CompareAndSwap stores a value only if the key is still at the given version, where
+	// version 0 means the key must not exist. It returns the new version, or an error
+	// wrapping ErrVersionConflict
+	CompareAndSwap(key string, version uint64, value any) (uint64, error)
+
+	// SetWithTTL stores a value that expires after ttl
+	SetWithTTL(key string, value any, ttl time.Duration) error
+
+	// Scan returns all entries whose key starts with prefix, ordered by key
+	Scan(prefix string) ([]KVEntry, error)
+
+	// Update calls fn in a transaction, committing its writes if fn returns nil and
+	// discarding them otherwise. fn must only use tx to access the store
+	Update(fn func(tx KVTx) error) error
+}
+
+// KVTx is the view of the KVStore inside Update.
+type KVTx interface {
+	Get(key string, dest any) error
+	Set(key string, value any) error
+	SetWithTTL(key string, value any, ttl time.Duration) error
+	Delete(key string) error
+}
+
+// KVLimitedScanner is implemented by KVStores that can stop a Scan early, so that
+// callers showing a page of entries don't read every matching key.
+type KVLimitedScanner interface {
+	// ScanLimit returns the first limit entries whose key starts with prefix, ordered by key
+	ScanLimit(prefix string, limit int) ([]KVEntry, error)
 }
 
+// kvQuerier is implemented by both *sql.DB and *sql.Tx.
+type kvQuerier interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// kvLive restricts a query to keys that haven't expired.
+const kvLive = "(expires_at IS NULL OR expires_at > ?)"
+
 // SQLiteKVStore implements KVStore using SQLite storage
 type SQLiteKVStore struct {
 	db *sql.DB
@@ -32,59 +106,146 @@ func NewSQLiteKVStore(db *sql.DB) (*SQLiteKVStore, error) {
 	return store, nil
 }
 
-// createTable creates the kv_store table if it doesn't exist
+// createTable creates the kv_store and kv_tombstones tables if they don't exist, and
+// adds the version, expires_at and updated_at columns to tables created by older versions
 func (s *SQLiteKVStore) createTable() error {
 	query := `
 		CREATE TABLE IF NOT EXISTS kv_store (
 			key TEXT PRIMARY KEY,
-			value TEXT NOT NULL
+			value TEXT NOT NULL,
+			version INTEGER NOT NULL DEFAULT 1,
+			expires_at INTEGER,
+			updated_at INTEGER
 		)
 	`
-	_, err := s.db.Exec(query)
-	return err
-}
+	if _, err := s.db.Exec(query); err != nil {
+		return err
+	}
 
-// Get retrieves a value by key and unmarshals it into dest
-func (s *SQLiteKVStore) Get(key string, dest any) error {
-	var valueJSON string
-	err := s.db.QueryRow("SELECT value FROM kv_store WHERE key = ?", key).Scan(&valueJSON)
+	columns := map[string]bool{}
+	rows, err := s.db.Query("SELECT name FROM pragma_table_info('kv_store')")
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return fmt.Errorf("key not found: %s", key)
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
 		}
-		return fmt.Errorf("failed to get value for key %s: %w", key, err)
+		columns[name] = true
 	}
-	
-	if err := json.Unmarshal([]byte(valueJSON), dest); err != nil {
-		return fmt.Errorf("failed to unmarshal value for key %s: %w", key, err)
+	if err := rows.Err(); err != nil {
+		return err
 	}
-	
-	return nil
+
+	for _, column := range []struct{ name, definition string }{
+		{"version", "INTEGER NOT NULL DEFAULT 1"},
+		{"expires_at", "INTEGER"},
+		{"updated_at", "INTEGER"},
+	} {
+		if columns[column.name] {
+			continue
+		}
+		if _, err := s.db.Exec("ALTER TABLE kv_store ADD COLUMN " + column.name + " " + column.definition); err != nil {
+			return err
+		}
+	}
+	if _, err := s.db.Exec("CREATE INDEX IF NOT EXISTS kv_store_expires_at ON kv_store (expires_at) WHERE expires_at IS NOT NULL"); err != nil {
+		return err
+	}
+	// The last version of removed keys, so that versions are never reused
+	if _, err := s.db.Exec("CREATE TABLE IF NOT EXISTS kv_tombstones (key TEXT PRIMARY KEY, version INTEGER NOT NULL, deleted_at INTEGER NOT NULL)"); err != nil {
+		return err
+	}
+	return s.createHistoryTables()
+}
+
+// Get retrieves a value by key and unmarshals it into dest
+func (s *SQLiteKVStore) Get(key string, dest any) error {
+	_, err := kvGet(s.db, key, dest)
+	return err
 }
 
-// Set stores a value by key, marshaling it appropriately  
+// GetVersion retrieves a value like Get and also returns its version
+func (s *SQLiteKVStore) GetVersion(key string, dest any) (uint64, error) {
+	return kvGet(s.db, key, dest)
+}
+
+// Set stores a value by key, marshaling it appropriately
 func (s *SQLiteKVStore) Set(key string, value any) error {
+	return s.inTx(func(tx *sql.Tx) error {
+		return kvSet(tx, key, value, nil)
+	})
+}
+
+// SetWithTTL stores a value that expires after ttl
+func (s *SQLiteKVStore) SetWithTTL(key string, value any, ttl time.Duration) error {
+	return s.inTx(func(tx *sql.Tx) error {
+		return kvSetWithTTL(tx, key, value, ttl)
+	})
+}
+
+// Delete removes a key
+func (s *SQLiteKVStore) Delete(key string) error {
+	return s.inTx(func(tx *sql.Tx) error {
+		return kvDelete(tx, key)
+	})
+}
+
+// CompareAndSwap stores a value only if the key is still at the given version
+func (s *SQLiteKVStore) CompareAndSwap(key string, version uint64, value any) (uint64, error) {
 	valueJSON, err := json.Marshal(value)
 	if err != nil {
-		return fmt.Errorf("failed to marshal value for key %s: %w", key, err)
+		return 0, fmt.Errorf("failed to marshal value for key %s: %w", key, err)
 	}
-	
-	_, err = s.db.Exec("INSERT OR REPLACE INTO kv_store (key, value) VALUES (?, ?)", key, string(valueJSON))
+
+	var newVersion uint64
+	err = s.inTx(func(tx *sql.Tx) error {
+		// Expired and deleted keys count as missing, but keep counting versions so an
+		// old version number can't match again
+		var stored, current uint64
+		var expiresAt sql.NullInt64
+		err := tx.QueryRow("SELECT version, expires_at FROM kv_store WHERE key = ?", key).Scan(&stored, &expiresAt)
+		switch {
+		case err == sql.ErrNoRows:
+			stored, err = kvTombstoneVersion(tx, key)
+		case err == nil && (!expiresAt.Valid || expiresAt.Int64 > time.Now().UnixNano()):
+			current = stored
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read version of key %s: %w", key, err)
+		}
+		if current != version {
+			return fmt.Errorf("%w: key %s is at version %d, expected %d", ErrVersionConflict, key, current, version)
+		}
+
+		now := time.Now()
+		if err := kvRecordHistory(tx, key, now, false); err != nil {
+			return err
+		}
+		newVersion = stored + 1
+		_, err = tx.Exec("INSERT OR REPLACE INTO kv_store (key, value, version, expires_at, updated_at) VALUES (?, ?, ?, NULL, ?)",
+			key, string(valueJSON), newVersion, now.UnixNano())
+		if err != nil {
+			return fmt.Errorf("failed to set value for key %s: %w", key, err)
+		}
+		return kvClearTombstone(tx, key)
+	})
 	if err != nil {
-		return fmt.Errorf("failed to set value for key %s: %w", key, err)
+		return 0, err
 	}
-	
-	return nil
+	return newVersion, nil
 }
 
 // List returns all keys matching the glob pattern
 func (s *SQLiteKVStore) List(glob string) ([]string, error) {
-	rows, err := s.db.Query("SELECT key FROM kv_store WHERE key GLOB ?", glob)
+	rows, err := s.db.Query("SELECT key FROM kv_store WHERE key GLOB ? AND "+kvLive, glob, time.Now().UnixNano())
 	if err != nil {
 		return nil, fmt.Errorf("failed to query keys with glob %s: %w", glob, err)
 	}
 	defer rows.Close()
-	
+
 	var keys []string
 	for rows.Next() {
 		var key string
@@ -93,10 +254,250 @@ func (s *SQLiteKVStore) List(glob string) ([]string, error) {
 		}
 		keys = append(keys, key)
 	}
-	
+
 	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("error during key iteration: %w", err)
 	}
-	
+
 	return keys, nil
 }
+
+// Scan returns all entries whose key starts with prefix, ordered by key
+func (s *SQLiteKVStore) Scan(prefix string) ([]KVEntry, error) {
+	return s.ScanLimit(prefix, -1)
+}
+
+// ScanLimit returns the first limit entries whose key starts with prefix, ordered by
+// key. A negative limit returns all of them.
+func (s *SQLiteKVStore) ScanLimit(prefix string, limit int) ([]KVEntry, error) {
+	rows, err := s.db.Query("SELECT key, value, version, expires_at FROM kv_store WHERE substr(key, 1, length(?)) = ? AND "+kvLive+" ORDER BY key LIMIT ?",
+		prefix, prefix, time.Now().UnixNano(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan keys with prefix %s: %w", prefix, err)
+	}
+	defer rows.Close()
+
+	entries := []KVEntry{}
+	for rows.Next() {
+		var entry KVEntry
+		var value string
+		var expiresAt sql.NullInt64
+		if err := rows.Scan(&entry.Key, &value, &entry.Version, &expiresAt); err != nil {
+			return nil, fmt.Errorf("failed to scan entry: %w", err)
+		}
+		entry.Value = json.RawMessage(value)
+		if expiresAt.Valid {
+			t := time.Unix(0, expiresAt.Int64)
+			entry.ExpiresAt = &t
+		}
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during entry iteration: %w", err)
+	}
+
+	return entries, nil
+}
+
+// Update calls fn in a transaction
+func (s *SQLiteKVStore) Update(fn func(tx KVTx) error) error {
+	return s.inTx(func(tx *sql.Tx) error {
+		return fn(sqliteKVTx{tx})
+	})
+}
+
+// DeleteExpired removes expired keys and returns how many were removed, keeping their
+// versions as tombstones. It also forgets tombstones older than KVTombstoneRetention.
+// Expired keys are already invisible to reads; this only reclaims their space.
+func (s *SQLiteKVStore) DeleteExpired() (int, error) {
+	var n int64
+	err := s.inTx(func(tx *sql.Tx) error {
+		now := time.Now().UnixNano()
+		_, err := tx.Exec(`
+			INSERT INTO kv_tombstones (key, version, deleted_at)
+			SELECT key, version, ? FROM kv_store WHERE expires_at <= ?
+			ON CONFLICT (key) DO UPDATE SET version = excluded.version, deleted_at = excluded.deleted_at
+		`, now, now)
+		if err != nil {
+			return fmt.Errorf("failed to keep versions of expired keys: %w", err)
+		}
+		result, err := tx.Exec("DELETE FROM kv_store WHERE expires_at <= ?", now)
+		if err != nil {
+			return fmt.Errorf("failed to delete expired keys: %w", err)
+		}
+		if n, err = result.RowsAffected(); err != nil {
+			return fmt.Errorf("failed to count expired keys: %w", err)
+		}
+		if _, err := tx.Exec("DELETE FROM kv_tombstones WHERE deleted_at <= ?", now-int64(KVTombstoneRetention)); err != nil {
+			return fmt.Errorf("failed to delete old tombstones: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return int(n), nil
+}
+
+// inTx runs fn in a transaction, committing if it returns nil
+func (s *SQLiteKVStore) inTx(fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin kv transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit kv transaction: %w", err)
+	}
+	return nil
+}
+
+// sqliteKVTx implements KVTx on a SQL transaction
+type sqliteKVTx struct {
+	tx *sql.Tx
+}
+
+func (t sqliteKVTx) Get(key string, dest any) error {
+	_, err := kvGet(t.tx, key, dest)
+	return err
+}
+
+func (t sqliteKVTx) Set(key string, value any) error {
+	return kvSet(t.tx, key, value, nil)
+}
+
+func (t sqliteKVTx) SetWithTTL(key string, value any, ttl time.Duration) error {
+	return kvSetWithTTL(t.tx, key, value, ttl)
+}
+
+func (t sqliteKVTx) Delete(key string) error {
+	return kvDelete(t.tx, key)
+}
+
+// kvGet reads and unmarshals a value and returns its version
+func kvGet(q kvQuerier, key string, dest any) (uint64, error) {
+	var valueJSON string
+	var version uint64
+	err := q.QueryRow("SELECT value, version FROM kv_store WHERE key = ? AND "+kvLive, key, time.Now().UnixNano()).Scan(&valueJSON, &version)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, fmt.Errorf("%w: %s", ErrKeyNotFound, key)
+		}
+		return 0, fmt.Errorf("failed to get value for key %s: %w", key, err)
+	}
+
+	if err := json.Unmarshal([]byte(valueJSON), dest); err != nil {
+		return 0, fmt.Errorf("failed to unmarshal value for key %s: %w", key, err)
+	}
+
+	return version, nil
+}
+
+// kvSet stores a value, incrementing the key's version and replacing its expiry.
+// The previous value is kept in the history if the key's prefix retains any.
+func kvSet(q kvQuerier, key string, value any, expiresAt *time.Time) error {
+	valueJSON, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal value for key %s: %w", key, err)
+	}
+
+	now := time.Now()
+	if err := kvRecordHistory(q, key, now, false); err != nil {
+		return err
+	}
+
+	var expires sql.NullInt64
+	if expiresAt != nil {
+		expires = sql.NullInt64{Int64: expiresAt.UnixNano(), Valid: true}
+	}
+	// A key created again continues from the version it had when it was removed
+	_, err = q.Exec(`
+		INSERT INTO kv_store (key, value, version, expires_at, updated_at)
+		VALUES (?1, ?2, COALESCE((SELECT version FROM kv_tombstones WHERE key = ?1), 0) + 1, ?3, ?4)
+		ON CONFLICT (key) DO UPDATE SET value = excluded.value, version = version + 1,
+			expires_at = excluded.expires_at, updated_at = excluded.updated_at
+	`, key, string(valueJSON), expires, now.UnixNano())
+	if err != nil {
+		return fmt.Errorf("failed to set value for key %s: %w", key, err)
+	}
+
+	return kvClearTombstone(q, key)
+}
+
+// kvSetWithTTL stores a value that expires after ttl
+func kvSetWithTTL(q kvQuerier, key string, value any, ttl time.Duration) error {
+	if ttl <= 0 {
+		return fmt.Errorf("failed to set value for key %s: ttl must be positive, got %s", key, ttl)
+	}
+	expiresAt := time.Now().Add(ttl)
+	return kvSet(q, key, value, &expiresAt)
+}
+
+// kvDelete removes a key if it exists, keeping its version as a tombstone and its
+// value in the history if the key's prefix retains any
+func kvDelete(q kvQuerier, key string) error {
+	now := time.Now()
+	if err := kvRecordHistory(q, key, now, true); err != nil {
+		return err
+	}
+	_, err := q.Exec(`
+		INSERT INTO kv_tombstones (key, version, deleted_at)
+		SELECT key, version, ? FROM kv_store WHERE key = ?
+		ON CONFLICT (key) DO UPDATE SET version = excluded.version, deleted_at = excluded.deleted_at
+	`, now.UnixNano(), key)
+	if err != nil {
+		return fmt.Errorf("failed to keep version of key %s: %w", key, err)
+	}
+	if _, err := q.Exec("DELETE FROM kv_store WHERE key = ?", key); err != nil {
+		return fmt.Errorf("failed to delete key %s: %w", key, err)
+	}
+	return nil
+}
+
+// kvTombstoneVersion returns the version a removed key had, or 0 if there is none.
+func kvTombstoneVersion(q kvQuerier, key string) (uint64, error) {
+	var version uint64
+	err := q.QueryRow("SELECT version FROM kv_tombstones WHERE key = ?", key).Scan(&version)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return version, err
+}
+
+// kvClearTombstone forgets the version of a removed key once it exists again.
+func kvClearTombstone(q kvQuerier, key string) error {
+	if _, err := q.Exec("DELETE FROM kv_tombstones WHERE key = ?", key); err != nil {
+		return fmt.Errorf("failed to clear tombstone of key %s: %w", key, err)
+	}
+	return nil
+}
+
+// sweepKVStore deletes expired keys and trims the history every KVSweepInterval until
+// ctx is cancelled.
+func sweepKVStore(ctx context.Context, store *SQLiteKVStore) {
+	ticker := time.NewTicker(KVSweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := store.DeleteExpired()
+			if err != nil {
+				slog.Error("Failed to sweep expired keys", "error", err)
+			} else if n > 0 {
+				slog.Debug("Swept expired keys", "count", n)
+			}
+			if n, err := store.PruneHistory(); err != nil {
+				slog.Error("Failed to prune KV history", "error", err)
+			} else if n > 0 {
+				slog.Debug("Pruned KV history", "count", n)
+			}
+		}
+	}
+}
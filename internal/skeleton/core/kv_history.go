@@ -0,0 +1,330 @@
+package core
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// DefaultWorkerKVHistory is how many previous values of each worker:* key a new database
+// keeps, so a worker's position and bookkeeping can be audited and rolled back.
+const DefaultWorkerKVHistory = 10
+
+// KVRevision is a previous value of a key, recorded when it was overwritten or deleted.
+type KVRevision struct {
+	Key        string          `json:"key"`
+	Value      json.RawMessage `json:"value"`
+	Version    uint64          `json:"version"`
+	SetAt      *time.Time      `json:"set_at,omitempty"` // Unknown for values written before history was kept
+	ReplacedAt time.Time       `json:"replaced_at"`
+	Deleted    bool            `json:"deleted"` // Ended by Delete or expiry rather than by a new value
+}
+
+// KVHistory is implemented by KVStores that keep previous values of keys. How many are
+// kept is configured per key prefix; the longest matching prefix applies, and keys
+// without a matching prefix keep no history.
+type KVHistory interface {
+	// History returns the recorded previous values of key, newest first
+	History(key string) ([]KVRevision, error)
+
+	// RestoreAt sets key back to the value it had at the given time
+	RestoreAt(key string, at time.Time) (KVRevision, error)
+
+	// RestoreVersion sets key back to the most recent recorded value with the given version
+	RestoreVersion(key string, version uint64) (KVRevision, error)
+
+	// SetHistoryRetention sets how many previous values keys starting with prefix keep.
+	// Zero turns history off for the prefix, overriding shorter prefixes
+	SetHistoryRetention(prefix string, keep int) error
+
+	// HistoryRetention returns the configured limits by prefix
+	HistoryRetention() (map[string]int, error)
+}
+
+// createHistoryTables creates the kv_history and kv_history_retention tables. A new
+// retention table starts with DefaultWorkerKVHistory for the worker: prefix.
+func (s *SQLiteKVStore) createHistoryTables() error {
+	query := `
+		CREATE TABLE IF NOT EXISTS kv_history (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			key TEXT NOT NULL,
+			value TEXT NOT NULL,
+			version INTEGER NOT NULL,
+			set_at INTEGER,
+			replaced_at INTEGER NOT NULL,
+			deleted INTEGER NOT NULL DEFAULT 0
+		);
+		CREATE INDEX IF NOT EXISTS kv_history_key ON kv_history (key, id);
+	`
+	if _, err := s.db.Exec(query); err != nil {
+		return err
+	}
+
+	var exists int
+	err := s.db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'kv_history_retention'").Scan(&exists)
+	if err != nil || exists > 0 {
+		return err
+	}
+	return s.inTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec("CREATE TABLE IF NOT EXISTS kv_history_retention (prefix TEXT PRIMARY KEY, keep INTEGER NOT NULL)"); err != nil {
+			return err
+		}
+		_, err := tx.Exec("INSERT OR IGNORE INTO kv_history_retention (prefix, keep) VALUES ('worker:', ?)", DefaultWorkerKVHistory)
+		return err
+	})
+}
+
+// History returns the recorded previous values of key, newest first
+func (s *SQLiteKVStore) History(key string) ([]KVRevision, error) {
+	rows, err := s.db.Query(`
+		SELECT key, value, version, set_at, replaced_at, deleted FROM kv_history
+		WHERE key = ? ORDER BY id DESC
+	`, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query history of key %s: %w", key, err)
+	}
+	defer rows.Close()
+
+	revisions := []KVRevision{}
+	for rows.Next() {
+		revision, err := scanKVRevision(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan history of key %s: %w", key, err)
+		}
+		revisions = append(revisions, revision)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during history iteration: %w", err)
+	}
+	return revisions, nil
+}
+
+// RestoreAt sets key back to the value it had at the given time. The value it replaces is
+// recorded in the history like any other write, so a restore can itself be undone. The
+// key keeps its current expiry time, if it has one.
+func (s *SQLiteKVStore) RestoreAt(key string, at time.Time) (KVRevision, error) {
+	var revision KVRevision
+	err := s.inTx(func(tx *sql.Tx) error {
+		var updatedAt, expiresAt sql.NullInt64
+		err := tx.QueryRow("SELECT updated_at, expires_at FROM kv_store WHERE key = ?", key).Scan(&updatedAt, &expiresAt)
+		if err != nil && err != sql.ErrNoRows {
+			return fmt.Errorf("failed to read key %s: %w", key, err)
+		}
+		if updatedAt.Valid && updatedAt.Int64 <= at.UnixNano() && (!expiresAt.Valid || expiresAt.Int64 > at.UnixNano()) {
+			return fmt.Errorf("key %s has not changed since %s", key, at.Format(time.RFC3339))
+		}
+
+		row := tx.QueryRow(`
+			SELECT key, value, version, set_at, replaced_at, deleted FROM kv_history
+			WHERE key = ? AND (set_at IS NULL OR set_at <= ?) AND replaced_at > ?
+			ORDER BY id DESC LIMIT 1
+		`, key, at.UnixNano(), at.UnixNano())
+		if revision, err = scanKVRevision(row); err != nil {
+			if err == sql.ErrNoRows {
+				return fmt.Errorf("%w: no recorded value of %s at %s", ErrKeyNotFound, key, at.Format(time.RFC3339))
+			}
+			return fmt.Errorf("failed to read history of key %s: %w", key, err)
+		}
+		return kvRestore(tx, key, revision.Value)
+	})
+	return revision, err
+}
+
+// RestoreVersion sets key back to the most recent recorded value with the given version.
+// The key keeps its current expiry time, if it has one.
+func (s *SQLiteKVStore) RestoreVersion(key string, version uint64) (KVRevision, error) {
+	var revision KVRevision
+	err := s.inTx(func(tx *sql.Tx) error {
+		var current uint64
+		err := tx.QueryRow("SELECT version FROM kv_store WHERE key = ? AND "+kvLive, key, time.Now().UnixNano()).Scan(&current)
+		if err != nil && err != sql.ErrNoRows {
+			return fmt.Errorf("failed to read key %s: %w", key, err)
+		}
+		if current == version {
+			return fmt.Errorf("key %s is already at version %d", key, version)
+		}
+
+		row := tx.QueryRow(`
+			SELECT key, value, version, set_at, replaced_at, deleted FROM kv_history
+			WHERE key = ? AND version = ? ORDER BY id DESC LIMIT 1
+		`, key, version)
+		if revision, err = scanKVRevision(row); err != nil {
+			if err == sql.ErrNoRows {
+				return fmt.Errorf("%w: no recorded version %d of %s", ErrKeyNotFound, version, key)
+			}
+			return fmt.Errorf("failed to read history of key %s: %w", key, err)
+		}
+		return kvRestore(tx, key, revision.Value)
+	})
+	return revision, err
+}
+
+// SetHistoryRetention sets how many previous values keys starting with prefix keep, and
+// drops recorded values beyond the new limit
+func (s *SQLiteKVStore) SetHistoryRetention(prefix string, keep int) error {
+	if keep < 0 {
+		return fmt.Errorf("failed to set history retention for prefix %s: keep must not be negative, got %d", prefix, keep)
+	}
+	_, err := s.db.Exec("INSERT OR REPLACE INTO kv_history_retention (prefix, keep) VALUES (?, ?)", prefix, keep)
+	if err != nil {
+		return fmt.Errorf("failed to set history retention for prefix %s: %w", prefix, err)
+	}
+	_, err = s.PruneHistory()
+	return err
+}
+
+// HistoryRetention returns the configured limits by prefix
+func (s *SQLiteKVStore) HistoryRetention() (map[string]int, error) {
+	rows, err := s.db.Query("SELECT prefix, keep FROM kv_history_retention")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query history retention: %w", err)
+	}
+	defer rows.Close()
+
+	retention := map[string]int{}
+	for rows.Next() {
+		var prefix string
+		var keep int
+		if err := rows.Scan(&prefix, &keep); err != nil {
+			return nil, fmt.Errorf("failed to scan history retention: %w", err)
+		}
+		retention[prefix] = keep
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during history retention iteration: %w", err)
+	}
+	return retention, nil
+}
+
+// PruneHistory drops recorded values beyond the retention limit of their key and returns
+// how many were dropped. Writes already prune the key they change; this catches keys
+// that were not written since their limit was lowered.
+func (s *SQLiteKVStore) PruneHistory() (int, error) {
+	rows, err := s.db.Query("SELECT DISTINCT key FROM kv_history")
+	if err != nil {
+		return 0, fmt.Errorf("failed to query history keys: %w", err)
+	}
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan history key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error during history key iteration: %w", err)
+	}
+
+	pruned := 0
+	for _, key := range keys {
+		err := s.inTx(func(tx *sql.Tx) error {
+			keep, err := kvHistoryRetention(tx, key)
+			if err != nil {
+				return err
+			}
+			n, err := kvTrimHistory(tx, key, keep)
+			pruned += n
+			return err
+		})
+		if err != nil {
+			return pruned, err
+		}
+	}
+	return pruned, nil
+}
+
+// kvRestore sets key to a recorded value. A key that hasn't expired keeps its expiry
+// time, since the history doesn't record it and a plain kvSet would drop it.
+func kvRestore(q kvQuerier, key string, value json.RawMessage) error {
+	var expires sql.NullInt64
+	err := q.QueryRow("SELECT expires_at FROM kv_store WHERE key = ? AND "+kvLive, key, time.Now().UnixNano()).Scan(&expires)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to read key %s: %w", key, err)
+	}
+	var expiresAt *time.Time
+	if expires.Valid {
+		t := time.Unix(0, expires.Int64)
+		expiresAt = &t
+	}
+	return kvSet(q, key, value, expiresAt)
+}
+
+// kvRecordHistory copies the current value of key into the history, if its prefix retains
+// any, and drops the oldest recorded values beyond the limit. A value that expired before
+// now is recorded as deleted at its expiry time.
+func kvRecordHistory(q kvQuerier, key string, now time.Time, deleted bool) error {
+	keep, err := kvHistoryRetention(q, key)
+	if err != nil || keep == 0 {
+		return err
+	}
+
+	_, err = q.Exec(`
+		INSERT INTO kv_history (key, value, version, set_at, replaced_at, deleted)
+		SELECT key, value, version, updated_at, MIN(?1, COALESCE(expires_at, ?1)),
+			CASE WHEN expires_at <= ?1 THEN 1 ELSE ?2 END
+		FROM kv_store WHERE key = ?3
+	`, now.UnixNano(), deleted, key)
+	if err != nil {
+		return fmt.Errorf("failed to record history of key %s: %w", key, err)
+	}
+	_, err = kvTrimHistory(q, key, keep)
+	return err
+}
+
+// kvHistoryRetention returns the number of previous values kept for key
+func kvHistoryRetention(q kvQuerier, key string) (int, error) {
+	var keep int
+	err := q.QueryRow(`
+		SELECT keep FROM kv_history_retention
+		WHERE substr(?, 1, length(prefix)) = prefix
+		ORDER BY length(prefix) DESC LIMIT 1
+	`, key).Scan(&keep)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to look up history retention of key %s: %w", key, err)
+	}
+	return keep, nil
+}
+
+// kvTrimHistory keeps only the newest keep recorded values of key
+func kvTrimHistory(q kvQuerier, key string, keep int) (int, error) {
+	result, err := q.Exec(`
+		DELETE FROM kv_history WHERE key = ?1 AND id NOT IN (
+			SELECT id FROM kv_history WHERE key = ?1 ORDER BY id DESC LIMIT ?2
+		)
+	`, key, keep)
+	if err != nil {
+		return 0, fmt.Errorf("failed to trim history of key %s: %w", key, err)
+	}
+	n, err := result.RowsAffected()
+	return int(n), err
+}
+
+// kvRevisionScanner is implemented by both *sql.Row and *sql.Rows.
+type kvRevisionScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanKVRevision reads a kv_history row selected as key, value, version, set_at, replaced_at, deleted
+func scanKVRevision(row kvRevisionScanner) (KVRevision, error) {
+	var revision KVRevision
+	var value string
+	var setAt sql.NullInt64
+	var replacedAt int64
+	if err := row.Scan(&revision.Key, &value, &revision.Version, &setAt, &replacedAt, &revision.Deleted); err != nil {
+		return KVRevision{}, err
+	}
+	revision.Value = json.RawMessage(value)
+	if setAt.Valid {
+		t := time.Unix(0, setAt.Int64)
+		revision.SetAt = &t
+	}
+	revision.ReplacedAt = time.Unix(0, replacedAt)
+	return revision, nil
+}
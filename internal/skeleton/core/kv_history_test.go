@@ -0,0 +1,105 @@
+package core
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSQLiteKVStore_HistoryAndRestore(t *testing.T) {
+	app := newTestApp(t)
+	store := app.KVStore.(*SQLiteKVStore)
+
+	if retention, err := store.HistoryRetention(); err != nil || retention["worker:"] != DefaultWorkerKVHistory {
+		t.Fatalf("Expected default worker retention, got %v, %v", retention, err)
+	}
+
+	key := "worker:w:position"
+	store.Set(key, 1)
+	time.Sleep(5 * time.Millisecond)
+	between := time.Now()
+	time.Sleep(5 * time.Millisecond)
+	store.Set(key, 2)
+	store.Set(key, 3)
+
+	history, err := store.History(key)
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+	if len(history) != 2 || string(history[0].Value) != "2" || string(history[1].Value) != "1" || history[1].Version != 1 {
+		t.Fatalf("Unexpected history: %+v", history)
+	}
+
+	revision, err := store.RestoreAt(key, between)
+	if err != nil || string(revision.Value) != "1" {
+		t.Fatalf("Expected to restore value 1, got %+v, %v", revision, err)
+	}
+	var position int
+	if version, _ := store.GetVersion(key, &position); position != 1 || version != 4 {
+		t.Errorf("Expected position 1 at version 4, got %d at %d", position, version)
+	}
+	if _, err := store.RestoreVersion(key, 3); err != nil {
+		t.Fatalf("RestoreVersion failed: %v", err)
+	}
+	if store.Get(key, &position); position != 3 {
+		t.Errorf("Expected position 3 after undoing the restore, got %d", position)
+	}
+	if _, err := store.RestoreAt(key, time.Now()); err == nil {
+		t.Error("Expected an error restoring the current value")
+	}
+	if _, err := store.RestoreAt(key, between.Add(-time.Hour)); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound before the key existed, got %v", err)
+	}
+
+	store.Delete(key)
+	if history, _ := store.History(key); len(history) == 0 || !history[0].Deleted {
+		t.Errorf("Expected the deleted value at the top of the history, got %+v", history)
+	}
+}
+
+func TestSQLiteKVStore_RestoreKeepsExpiry(t *testing.T) {
+	app := newTestApp(t)
+	store := app.KVStore.(*SQLiteKVStore)
+
+	key := "worker:w:lease"
+	store.SetWithTTL(key, "a", time.Hour)
+	store.SetWithTTL(key, "b", time.Hour)
+	if _, err := store.RestoreVersion(key, 1); err != nil {
+		t.Fatalf("RestoreVersion failed: %v", err)
+	}
+	entries, err := store.Scan(key)
+	if err != nil || len(entries) != 1 || string(entries[0].Value) != `"a"` || entries[0].ExpiresAt == nil {
+		t.Fatalf("Expected the restored value to keep its expiry, got %+v, %v", entries, err)
+	}
+}
+
+func TestSQLiteKVStore_HistoryRetention(t *testing.T) {
+	app := newTestApp(t)
+	store := app.KVStore.(*SQLiteKVStore)
+
+	for i := 0; i < 5; i++ {
+		store.Set("app:counter", i)
+	}
+	if history, _ := store.History("app:counter"); len(history) != 0 {
+		t.Errorf("Expected no history without a retention rule, got %+v", history)
+	}
+
+	if err := store.SetHistoryRetention("app:", 3); err != nil {
+		t.Fatalf("SetHistoryRetention failed: %v", err)
+	}
+	for i := 5; i < 10; i++ {
+		store.Set("app:counter", i)
+	}
+	history, _ := store.History("app:counter")
+	if len(history) != 3 || string(history[0].Value) != "8" {
+		t.Fatalf("Expected the last three previous values, got %+v", history)
+	}
+
+	// A longer prefix overrides a shorter one, and lowering a limit prunes at once
+	if err := store.SetHistoryRetention("app:count", 1); err != nil {
+		t.Fatalf("SetHistoryRetention failed: %v", err)
+	}
+	if history, _ := store.History("app:counter"); len(history) != 1 || string(history[0].Value) != "8" {
+		t.Errorf("Expected history to be pruned to one value, got %+v", history)
+	}
+}
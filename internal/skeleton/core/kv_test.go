@@ -0,0 +1,200 @@
+package core
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSQLiteKVStore_DeleteAndCompareAndSwap(t *testing.T) {
+	app := newTestApp(t)
+	kv := app.KVStore
+
+	version, err := kv.CompareAndSwap("counter", 0, 1)
+	if err != nil || version != 1 {
+		t.Fatalf("Expected create at version 1, got %d, %v", version, err)
+	}
+	if _, err := kv.CompareAndSwap("counter", 0, 1); !errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("Expected ErrVersionConflict creating an existing key, got %v", err)
+	}
+
+	var n int
+	version, err = kv.GetVersion("counter", &n)
+	if err != nil || version != 1 || n != 1 {
+		t.Fatalf("Unexpected GetVersion result: %d, %d, %v", n, version, err)
+	}
+	if err := kv.Set("counter", 5); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if _, err := kv.CompareAndSwap("counter", version, n+1); !errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("Expected ErrVersionConflict after a concurrent Set, got %v", err)
+	}
+	if version, err = kv.CompareAndSwap("counter", 2, 6); err != nil || version != 3 {
+		t.Fatalf("Expected swap to version 3, got %d, %v", version, err)
+	}
+
+	if err := kv.Delete("counter"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := kv.Delete("counter"); err != nil {
+		t.Errorf("Deleting a missing key should succeed, got %v", err)
+	}
+	if err := kv.Get("counter", &n); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound after Delete, got %v", err)
+	}
+}
+
+func TestSQLiteKVStore_VersionsAreNotReusedAfterDelete(t *testing.T) {
+	app := newTestApp(t)
+	store := app.KVStore.(*SQLiteKVStore)
+
+	if err := store.Set("flag", "a"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	var value string
+	stale, err := store.GetVersion("flag", &value)
+	if err != nil || stale != 1 {
+		t.Fatalf("Expected version 1, got %d, %v", stale, err)
+	}
+	if err := store.Delete("flag"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := store.Set("flag", "b"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if _, err := store.CompareAndSwap("flag", stale, "c"); !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("Expected a version read before the delete to conflict, got %v", err)
+	}
+
+	// Keys swept after expiring keep their version too
+	if err := store.SetWithTTL("flag", "d", 20*time.Millisecond); err != nil {
+		t.Fatalf("SetWithTTL failed: %v", err)
+	}
+	expired, err := store.GetVersion("flag", &value)
+	if err != nil {
+		t.Fatalf("GetVersion failed: %v", err)
+	}
+	time.Sleep(40 * time.Millisecond)
+	if _, err := store.DeleteExpired(); err != nil {
+		t.Fatalf("DeleteExpired failed: %v", err)
+	}
+	version, err := store.CompareAndSwap("flag", 0, "e")
+	if err != nil || version <= expired {
+		t.Errorf("Expected the re-created key to continue after version %d, got %d, %v", expired, version, err)
+	}
+}
+
+func TestSQLiteKVStore_TTL(t *testing.T) {
+	app := newTestApp(t)
+	store := app.KVStore.(*SQLiteKVStore)
+
+	if err := store.SetWithTTL("session:a", "short", 50*time.Millisecond); err != nil {
+		t.Fatalf("SetWithTTL failed: %v", err)
+	}
+	if err := store.SetWithTTL("session:b", "long", time.Hour); err != nil {
+		t.Fatalf("SetWithTTL failed: %v", err)
+	}
+	if err := store.SetWithTTL("session:c", "none", 0); err == nil {
+		t.Error("Expected an error for a zero TTL")
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	var value string
+	if err := store.Get("session:a", &value); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected expired key to be missing, got %q, %v", value, err)
+	}
+	if keys, _ := store.List("session:*"); len(keys) != 1 || keys[0] != "session:b" {
+		t.Errorf("Expected only the live key to be listed, got %v", keys)
+	}
+	if _, err := store.CompareAndSwap("session:a", 0, "again"); err != nil {
+		t.Errorf("Expected an expired key to count as missing for CompareAndSwap, got %v", err)
+	}
+
+	if err := store.SetWithTTL("session:d", "short", 10*time.Millisecond); err != nil {
+		t.Fatalf("SetWithTTL failed: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if n, err := store.DeleteExpired(); err != nil || n != 1 {
+		t.Errorf("Expected one expired key to be deleted, got %d, %v", n, err)
+	}
+}
+
+func TestSQLiteKVStore_ScanAndUpdate(t *testing.T) {
+	app := newTestApp(t)
+	kv := app.KVStore
+
+	kv.Set("a:1", map[string]int{"n": 1})
+	kv.Set("a:2", "two")
+	kv.Set("a_3", "not a:")
+	kv.Set("b:1", true)
+
+	entries, err := kv.Scan("a:")
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Key != "a:1" || string(entries[0].Value) != `{"n":1}` || entries[1].Key != "a:2" {
+		t.Errorf("Unexpected scan result: %+v", entries)
+	}
+
+	err = kv.Update(func(tx KVTx) error {
+		var value string
+		if err := tx.Get("a:2", &value); err != nil {
+			return err
+		}
+		if err := tx.Set("b:1", value); err != nil {
+			return err
+		}
+		return tx.Delete("a:2")
+	})
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	var moved string
+	if err := kv.Get("b:1", &moved); err != nil || moved != "two" {
+		t.Errorf("Expected b:1 to be two, got %q, %v", moved, err)
+	}
+
+	rollback := errors.New("rollback")
+	err = kv.Update(func(tx KVTx) error {
+		if err := tx.Delete("b:1"); err != nil {
+			return err
+		}
+		return rollback
+	})
+	if !errors.Is(err, rollback) {
+		t.Fatalf("Expected the error from fn, got %v", err)
+	}
+	if err := kv.Get("b:1", &moved); err != nil {
+		t.Errorf("Expected a failed Update to be rolled back, got %v", err)
+	}
+}
+
+func TestSQLiteKVStore_MigratesOldTable(t *testing.T) {
+	db, err := SetupDatabase(filepath.Join(t.TempDir(), "old.db"))
+	if err != nil {
+		t.Fatalf("SetupDatabase failed: %v", err)
+	}
+	defer db.Close()
+	mustExec(t, db, "CREATE TABLE kv_store (key TEXT PRIMARY KEY, value TEXT NOT NULL)")
+	mustExec(t, db, `INSERT INTO kv_store (key, value) VALUES ('old', '"value"')`)
+
+	store, err := NewSQLiteKVStore(db)
+	if err != nil {
+		t.Fatalf("NewSQLiteKVStore failed: %v", err)
+	}
+	var value string
+	version, err := store.GetVersion("old", &value)
+	if err != nil || value != "value" || version != 1 {
+		t.Errorf("Expected existing key at version 1, got %q, %d, %v", value, version, err)
+	}
+}
+
+func mustExec(t *testing.T, db *sql.DB, query string) {
+	t.Helper()
+	if _, err := db.Exec(query); err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+}
@@ -0,0 +1,269 @@
+package core
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// DefaultLeaseTTL is how long a worker lease stays valid without a heartbeat.
+// Holders renew their leases every third of the TTL.
+const DefaultLeaseTTL = 15 * time.Second
+
+// ErrLeaseHeld is returned when a lease is held by another process.
+var ErrLeaseHeld = errors.New("lease is held by another process")
+
+// ErrLeaseLost is returned when a lease expired or was taken over by another process.
+var ErrLeaseLost = errors.New("lease was lost")
+
+// Lease grants one process the exclusive right to run a worker until ExpiresAt.
+// Token is a fencing token: it increases every time the lease changes hands, so a
+// holder that was paused past expiry can be told apart from the current holder.
+type Lease struct {
+	Name       string    `json:"name"`
+	Holder     string    `json:"holder"`
+	Token      uint64    `json:"token"`
+	AcquiredAt time.Time `json:"acquired_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// Expired reports whether the lease has run out at the given time.
+func (l Lease) Expired(now time.Time) bool {
+	return !now.Before(l.ExpiresAt)
+}
+
+// LeaseManager stores leases in SQLite so that processes sharing a database
+// can agree on which of them runs each worker.
+type LeaseManager struct {
+	db     *sql.DB
+	holder string
+	ttl    time.Duration
+}
+
+// NewLeaseManager creates the lease table if needed and returns a manager acting
+// on behalf of holder. An empty holder is replaced by NewLeaseHolderID().
+func NewLeaseManager(db *sql.DB, holder string, ttl time.Duration) (*LeaseManager, error) {
+	if holder == "" {
+		holder = NewLeaseHolderID()
+	}
+	if ttl <= 0 {
+		ttl = DefaultLeaseTTL
+	}
+
+	m := &LeaseManager{db: db, holder: holder, ttl: ttl}
+	if err := m.createTable(); err != nil {
+		return nil, fmt.Errorf("failed to create leases table: %w", err)
+	}
+	return m, nil
+}
+
+// NewLeaseHolderID returns an identifier for the current process of the form host:pid:random.
+func NewLeaseHolderID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	return fmt.Sprintf("%s:%d:%s", host, os.Getpid(), hex.EncodeToString(suffix))
+}
+
+func (m *LeaseManager) createTable() error {
+	query := `
+		CREATE TABLE IF NOT EXISTS leases (
+			name TEXT PRIMARY KEY,
+			holder TEXT NOT NULL,
+			token INTEGER NOT NULL,
+			acquired_at INTEGER NOT NULL,
+			expires_at INTEGER NOT NULL
+		)
+	`
+	_, err := m.db.Exec(query)
+	return err
+}
+
+// Holder returns the identifier this manager acquires leases for.
+func (m *LeaseManager) Holder() string {
+	return m.holder
+}
+
+// TTL returns how long acquired leases stay valid without renewal.
+func (m *LeaseManager) TTL() time.Duration {
+	return m.ttl
+}
+
+// Acquire takes the named lease if it is free, expired or already held by this manager.
+// It returns ErrLeaseHeld if another process holds a valid lease.
+func (m *LeaseManager) Acquire(ctx context.Context, name string) (Lease, error) {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Lease{}, fmt.Errorf("failed to begin lease transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	current, found, err := scanLease(tx.QueryRowContext(ctx,
+		"SELECT name, holder, token, acquired_at, expires_at FROM leases WHERE name = ?", name))
+	if err != nil {
+		return Lease{}, fmt.Errorf("failed to read lease %s: %w", name, err)
+	}
+
+	lease := Lease{Name: name, Holder: m.holder, Token: 1, AcquiredAt: now, ExpiresAt: now.Add(m.ttl)}
+	if found {
+		switch {
+		case current.Holder == m.holder && !current.Expired(now):
+			// Still ours; keep the token so fencing checks stay valid
+			lease.Token = current.Token
+			lease.AcquiredAt = current.AcquiredAt
+		case !current.Expired(now):
+			return current, fmt.Errorf("%w: %s held by %s until %s", ErrLeaseHeld, name, current.Holder, current.ExpiresAt.Format(time.RFC3339))
+		default:
+			lease.Token = current.Token + 1
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO leases (name, holder, token, acquired_at, expires_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(name) DO UPDATE SET holder = excluded.holder, token = excluded.token,
+			acquired_at = excluded.acquired_at, expires_at = excluded.expires_at`,
+		lease.Name, lease.Holder, lease.Token, lease.AcquiredAt.UnixNano(), lease.ExpiresAt.UnixNano())
+	if err != nil {
+		return Lease{}, fmt.Errorf("failed to write lease %s: %w", name, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return Lease{}, fmt.Errorf("failed to commit lease %s: %w", name, err)
+	}
+	return lease, nil
+}
+
+// Renew extends a held lease by the manager's TTL. It returns ErrLeaseLost if the
+// lease expired or another process took it over since it was acquired.
+func (m *LeaseManager) Renew(ctx context.Context, lease Lease) (Lease, error) {
+	now := time.Now()
+	if lease.Expired(now) {
+		return lease, fmt.Errorf("%w: %s expired at %s", ErrLeaseLost, lease.Name, lease.ExpiresAt.Format(time.RFC3339))
+	}
+
+	expiresAt := now.Add(m.ttl)
+	result, err := m.db.ExecContext(ctx,
+		"UPDATE leases SET expires_at = ? WHERE name = ? AND holder = ? AND token = ?",
+		expiresAt.UnixNano(), lease.Name, lease.Holder, lease.Token)
+	if err != nil {
+		return lease, fmt.Errorf("failed to renew lease %s: %w", lease.Name, err)
+	}
+	if rows, err := result.RowsAffected(); err != nil || rows == 0 {
+		return lease, fmt.Errorf("%w: %s was taken over", ErrLeaseLost, lease.Name)
+	}
+
+	lease.ExpiresAt = expiresAt
+	return lease, nil
+}
+
+// Validate checks that lease is still the current lease for its name. Handlers can use it
+// as a fencing check right before a side effect that must not be performed twice.
+func (m *LeaseManager) Validate(ctx context.Context, lease Lease) error {
+	current, found, err := scanLease(m.db.QueryRowContext(ctx,
+		"SELECT name, holder, token, acquired_at, expires_at FROM leases WHERE name = ?", lease.Name))
+	if err != nil {
+		return fmt.Errorf("failed to read lease %s: %w", lease.Name, err)
+	}
+	if !found || current.Token != lease.Token || current.Holder != lease.Holder || current.Expired(time.Now()) {
+		return fmt.Errorf("%w: %s (token %d)", ErrLeaseLost, lease.Name, lease.Token)
+	}
+	return nil
+}
+
+// Release gives up a held lease so that a standby process can take over immediately.
+// The token is kept, so the next holder still receives a higher one.
+func (m *LeaseManager) Release(ctx context.Context, lease Lease) error {
+	_, err := m.db.ExecContext(ctx,
+		"UPDATE leases SET expires_at = ? WHERE name = ? AND holder = ? AND token = ?",
+		time.Now().UnixNano(), lease.Name, lease.Holder, lease.Token)
+	if err != nil {
+		return fmt.Errorf("failed to release lease %s: %w", lease.Name, err)
+	}
+	return nil
+}
+
+// List returns all leases, including expired ones, ordered by name.
+func (m *LeaseManager) List(ctx context.Context) ([]Lease, error) {
+	rows, err := m.db.QueryContext(ctx, "SELECT name, holder, token, acquired_at, expires_at FROM leases ORDER BY name")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list leases: %w", err)
+	}
+	defer rows.Close()
+
+	leases := []Lease{}
+	for rows.Next() {
+		lease, _, err := scanLease(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan lease: %w", err)
+		}
+		leases = append(leases, lease)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during lease iteration: %w", err)
+	}
+	return leases, nil
+}
+
+// scanLease reads a lease row, reporting found=false if the row does not exist.
+func scanLease(row interface{ Scan(...any) error }) (Lease, bool, error) {
+	var lease Lease
+	var acquiredAt, expiresAt int64
+	err := row.Scan(&lease.Name, &lease.Holder, &lease.Token, &acquiredAt, &expiresAt)
+	if err == sql.ErrNoRows {
+		return Lease{}, false, nil
+	}
+	if err != nil {
+		return Lease{}, false, err
+	}
+	lease.AcquiredAt = time.Unix(0, acquiredAt)
+	lease.ExpiresAt = time.Unix(0, expiresAt)
+	return lease, true, nil
+}
+
+type leaseContextKey struct{}
+
+// leaseContext is the value stored by WithLease and LeaseManager.WithLease.
+type leaseContext struct {
+	lease   Lease
+	manager *LeaseManager // Checks the lease in ValidateLease; nil for WithLease
+}
+
+// WithLease returns a context carrying the lease under which a worker runs.
+func WithLease(ctx context.Context, lease Lease) context.Context {
+	return context.WithValue(ctx, leaseContextKey{}, leaseContext{lease: lease})
+}
+
+// WithLease returns a context carrying the lease under which a worker runs, which
+// ValidateLease checks against this manager.
+func (m *LeaseManager) WithLease(ctx context.Context, lease Lease) context.Context {
+	return context.WithValue(ctx, leaseContextKey{}, leaseContext{lease: lease, manager: m})
+}
+
+// LeaseFromContext returns the lease of the worker a handler is running in.
+// Handlers can pass its Token to external systems as a fencing token.
+func LeaseFromContext(ctx context.Context) (Lease, bool) {
+	lc, ok := ctx.Value(leaseContextKey{}).(leaseContext)
+	return lc.lease, ok
+}
+
+// ValidateLease checks that the lease of the worker ctx belongs to is still current, if
+// the context was created by LeaseManager.WithLease. Workers call it before saving their
+// position and the outbox before recording or delivering a side effect, so a process that
+// lost its lease can't overwrite the work of the new holder. It returns ErrLeaseLost if
+// the lease changed hands or expired.
+func ValidateLease(ctx context.Context) error {
+	lc, ok := ctx.Value(leaseContextKey{}).(leaseContext)
+	if !ok || lc.manager == nil {
+		return nil
+	}
+	// A worker shutting down still saves its position
+	return lc.manager.Validate(context.WithoutCancel(ctx), lc.lease)
+}
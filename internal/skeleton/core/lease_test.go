@@ -0,0 +1,207 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLeaseManager_AcquireRenewTakeover(t *testing.T) {
+	app := newTestApp(t)
+	ctx := context.Background()
+
+	first, err := NewLeaseManager(app.DB, "first", 100*time.Millisecond)
+	if err != nil {
+		t.Fatalf("NewLeaseManager failed: %v", err)
+	}
+	second, err := NewLeaseManager(app.DB, "second", 100*time.Millisecond)
+	if err != nil {
+		t.Fatalf("NewLeaseManager failed: %v", err)
+	}
+
+	lease, err := first.Acquire(ctx, "job")
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	if lease.Token != 1 || lease.Holder != "first" {
+		t.Errorf("Unexpected lease: %+v", lease)
+	}
+
+	if _, err := second.Acquire(ctx, "job"); !errors.Is(err, ErrLeaseHeld) {
+		t.Fatalf("Expected ErrLeaseHeld, got %v", err)
+	}
+	if again, err := first.Acquire(ctx, "job"); err != nil || again.Token != 1 {
+		t.Errorf("Expected holder to re-acquire with the same token, got %+v, %v", again, err)
+	}
+	if lease, err = first.Renew(ctx, lease); err != nil {
+		t.Fatalf("Renew failed: %v", err)
+	}
+
+	// Once the lease expires the standby takes over with a higher fencing token
+	time.Sleep(150 * time.Millisecond)
+	taken, err := second.Acquire(ctx, "job")
+	if err != nil {
+		t.Fatalf("Expected takeover after expiry, got %v", err)
+	}
+	if taken.Token != 2 {
+		t.Errorf("Expected token 2 after takeover, got %d", taken.Token)
+	}
+	if _, err := first.Renew(ctx, lease); !errors.Is(err, ErrLeaseLost) {
+		t.Errorf("Expected ErrLeaseLost renewing a taken-over lease, got %v", err)
+	}
+	if err := first.Validate(ctx, lease); !errors.Is(err, ErrLeaseLost) {
+		t.Errorf("Expected stale lease to fail validation, got %v", err)
+	}
+	if err := second.Validate(ctx, taken); err != nil {
+		t.Errorf("Expected current lease to validate, got %v", err)
+	}
+
+	// Releasing hands the lease over immediately
+	if err := second.Release(ctx, taken); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+	if lease, err := first.Acquire(ctx, "job"); err != nil || lease.Token != 3 {
+		t.Errorf("Expected re-acquisition with token 3 after release, got %+v, %v", lease, err)
+	}
+}
+
+func TestStartWorkers_OnlyOneProcessRunsAWorker(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "shared.db")
+	ctx := context.Background()
+
+	newProcess := func(holder string) *App {
+		app, err := NewApp(dbPath)
+		if err != nil {
+			t.Fatalf("NewApp failed: %v", err)
+		}
+		t.Cleanup(func() { app.Close() })
+		if app.Leases, err = NewLeaseManager(app.DB, holder, 300*time.Millisecond); err != nil {
+			t.Fatalf("NewLeaseManager failed: %v", err)
+		}
+		worker := NewWorker("leased", "test worker", nil)
+		worker.SetSchedule(Every(10 * time.Millisecond))
+		app.RegisterWorker(worker)
+		return app
+	}
+
+	waitForState := func(app *App, want string) {
+		t.Helper()
+		deadline := time.Now().Add(3 * time.Second)
+		for time.Now().Before(deadline) {
+			statuses, err := app.WorkerStatuses(ctx)
+			if err != nil {
+				t.Fatalf("WorkerStatuses failed: %v", err)
+			}
+			if statuses[0].State == want {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("Timed out waiting for worker state %q", want)
+	}
+
+	first := newProcess("first")
+	if err := first.StartWorkers(ctx); err != nil {
+		t.Fatalf("StartWorkers failed: %v", err)
+	}
+	waitForState(first, WorkerStateRunning)
+
+	second := newProcess("second")
+	if err := second.StartWorkers(ctx); err != nil {
+		t.Fatalf("StartWorkers failed: %v", err)
+	}
+	waitForState(second, WorkerStateStandby)
+
+	statuses, _ := second.WorkerStatuses(ctx)
+	if statuses[0].Lease == nil || statuses[0].Lease.Holder != "first" {
+		t.Errorf("Expected standby to report the first process as lease holder, got %+v", statuses[0].Lease)
+	}
+	if err := second.RunWorkerOnce(ctx, "leased"); !errors.Is(err, ErrLeaseHeld) {
+		t.Errorf("Expected RunWorkerOnce to refuse a leased worker, got %v", err)
+	}
+
+	// Stopping the first process releases the lease and the standby takes over
+	if err := first.StopWorkers(ctx); err != nil {
+		t.Fatalf("StopWorkers failed: %v", err)
+	}
+	waitForState(second, WorkerStateRunning)
+}
+
+func TestValidateLease_FencesPositionSavesAndSideEffects(t *testing.T) {
+	app := newTestApp(t)
+	ctx := context.Background()
+
+	first, _ := NewLeaseManager(app.DB, "first", 100*time.Millisecond)
+	second, _ := NewLeaseManager(app.DB, "second", 100*time.Millisecond)
+	lease, err := first.Acquire(ctx, WorkerLeaseName("fenced"))
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	leaseCtx := first.WithLease(ctx, lease)
+	if err := ValidateLease(leaseCtx); err != nil {
+		t.Fatalf("Expected the held lease to validate, got %v", err)
+	}
+	if got, ok := LeaseFromContext(leaseCtx); !ok || got.Token != lease.Token {
+		t.Errorf("Expected the lease in the context, got %+v", got)
+	}
+
+	// Another process takes over while the first one is paused
+	time.Sleep(150 * time.Millisecond)
+	if _, err := second.Acquire(ctx, WorkerLeaseName("fenced")); err != nil {
+		t.Fatalf("Expected takeover after expiry, got %v", err)
+	}
+	if err := ValidateLease(leaseCtx); !errors.Is(err, ErrLeaseLost) {
+		t.Fatalf("Expected ErrLeaseLost, got %v", err)
+	}
+	if err := ValidateLease(WithLease(ctx, lease)); err != nil {
+		t.Errorf("Expected a context without a lease manager not to be checked, got %v", err)
+	}
+
+	worker := NewWorker("fenced", "test worker", nil)
+	worker.SetDependencies(app.MessageLog, app.Executor, app.KVStore)
+	if err := worker.Start(leaseCtx); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer worker.Stop(ctx)
+	if err := worker.Replay(leaseCtx); !errors.Is(err, ErrLeaseLost) {
+		t.Errorf("Expected the stale holder not to save its position, got %v", err)
+	}
+	var position uint64
+	if err := app.KVStore.Get(workerPositionKey("fenced"), &position); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected no saved position, got %d, %v", position, err)
+	}
+
+	item := OutboxItem{Key: "fenced-effect", Kind: OutboxHTTP, Payload: HTTPRequest{URL: "http://example.com"}}
+	if _, _, err := app.UseOutbox().Enqueue(leaseCtx, item); !errors.Is(err, ErrLeaseLost) {
+		t.Errorf("Expected the stale holder not to enqueue side effects, got %v", err)
+	}
+}
+
+func TestRunWorkerOnce_RenewsItsLease(t *testing.T) {
+	app := newTestApp(t)
+	ctx := context.Background()
+	var err error
+	if app.Leases, err = NewLeaseManager(app.DB, "once", 90*time.Millisecond); err != nil {
+		t.Fatalf("NewLeaseManager failed: %v", err)
+	}
+	standby, _ := NewLeaseManager(app.DB, "standby", 90*time.Millisecond)
+
+	worker := NewWorker("slow", "test worker", nil)
+	var takeover error
+	worker.SetPeriodicWork(func(ctx context.Context) error {
+		// Outlasts the TTL; the heartbeat keeps the lease
+		time.Sleep(200 * time.Millisecond)
+		_, takeover = standby.Acquire(ctx, WorkerLeaseName("slow"))
+		return nil
+	})
+	app.RegisterWorker(worker)
+
+	if err := app.RunWorkerOnce(ctx, "slow"); err != nil {
+		t.Fatalf("RunWorkerOnce failed: %v", err)
+	}
+	if !errors.Is(takeover, ErrLeaseHeld) {
+		t.Errorf("Expected the lease to be held throughout the run, got %v", takeover)
+	}
+}
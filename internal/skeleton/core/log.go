@@ -6,7 +6,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"math"
 	"reflect"
+	"strings"
+	"sync"
 	"time"
 	"iter"
 
@@ -38,6 +41,13 @@ type Message struct {
 	Timestamp time.Time
 	Type      string // String identifier for the concrete type of Data
 	Data      []byte // Serialized message data
+	TraceID   string // Trace of the span that appended the message, if it was traced
+	SpanID    string
+}
+
+// SpanContext identifies the span that appended the message, see Tracer.
+func (m Message) SpanContext() SpanContext {
+	return SpanContext{TraceID: m.TraceID, SpanID: m.SpanID}
 }
 
 // PersistedMessage combines a raw message with its decoded payload.
@@ -51,6 +61,11 @@ type MessageLog struct {
 	db           *sql.DB
 	encoder      Encoder
 	typeRegistry map[string]reflect.Type
+
+	changedMu sync.Mutex    // Guards changed
+	changed   chan struct{} // Closed and replaced after every Append
+
+	appendLatency *Histogram // Set by UseMetrics
 }
 
 // NewMessageLog creates a new MessageLog instance.
@@ -66,6 +81,7 @@ func NewMessageLog(db *sql.DB, encoder Encoder) (*MessageLog, error) {
 		db:           db,
 		encoder:      encoder,
 		typeRegistry: make(map[string]reflect.Type),
+		changed:      make(chan struct{}),
 	}
 	if err := log.setupSchema(context.Background()); err != nil {
 		return nil, fmt.Errorf("failed to setup log schema: %w", err)
@@ -88,6 +104,32 @@ func (l *MessageLog) setupSchema(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("failed to execute schema setup: %w", err)
 	}
+
+	// Logs created by older versions lack the trace columns
+	columns := map[string]bool{}
+	rows, err := l.db.QueryContext(ctx, "SELECT name FROM pragma_table_info('messages')")
+	if err != nil {
+		return fmt.Errorf("failed to read messages columns: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return fmt.Errorf("failed to read messages columns: %w", err)
+		}
+		columns[name] = true
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read messages columns: %w", err)
+	}
+	for _, column := range []string{"trace_id", "span_id"} {
+		if columns[column] {
+			continue
+		}
+		if _, err := l.db.ExecContext(ctx, "ALTER TABLE messages ADD COLUMN "+column+" TEXT NOT NULL DEFAULT ''"); err != nil {
+			return fmt.Errorf("failed to add column %s to messages: %w", column, err)
+		}
+	}
 	slog.Debug("Message log schema setup complete")
 	return nil
 }
@@ -140,8 +182,23 @@ func (l *MessageLog) RegisterType(instance interface{}) {
 	slog.Debug("Registered message type for decoding", "name", typeName, "type", instanceType)
 }
 
+// UseMetrics makes Append record its latency in m and reports the log version
+// whenever the metrics are written. NewApp calls it with App.Metrics.
+func (l *MessageLog) UseMetrics(m *Metrics) {
+	l.appendLatency = m.Histogram("petrock_log_append_duration_seconds", "Time taken to append a message to the log", nil)
+	m.GaugeFunc("petrock_log_version", "ID of the newest message in the log", func() float64 {
+		version, err := l.Version(context.Background())
+		if err != nil {
+			slog.Error("Failed to read log version for metrics", "error", err)
+			return math.NaN()
+		}
+		return float64(version)
+	})
+}
+
 // Append encodes the given message, determines its registered name string,
-// and inserts it as a new row into the 'messages' table.
+// and inserts it as a new row into the 'messages' table. The current span of ctx,
+// if any, is stored with the message so that workers can link to it.
 func (l *MessageLog) Append(ctx context.Context, msg interface{}) error {
 	// If no timeout set, create a context with sufficient timeout for database operations
 	var appendCtx context.Context
@@ -179,16 +236,38 @@ func (l *MessageLog) Append(ctx context.Context, msg interface{}) error {
 		return fmt.Errorf("failed to encode message type %s: %w", typeName, err)
 	}
 
-	query := `INSERT INTO messages (timestamp, type, data) VALUES (?, ?, ?)`
-	_, err = l.db.ExecContext(appendCtx, query, time.Now().UTC(), typeName, data)
+	query := `INSERT INTO messages (timestamp, type, data, trace_id, span_id) VALUES (?, ?, ?, ?, ?)`
+	span := SpanFromContext(ctx).SpanContext()
+	start := time.Now()
+	_, err = l.db.ExecContext(appendCtx, query, time.Now().UTC(), typeName, data, span.TraceID, span.SpanID)
+	l.appendLatency.ObserveSince(start)
 	if err != nil {
 		return fmt.Errorf("failed to insert message type %s into log: %w", typeName, err)
 	}
 
 	slog.Debug("Appended message to log", "name", typeName)
+	l.notifyChanged()
 	return nil
 }
 
+// Changed returns a channel that is closed the next time a message is appended
+// through this MessageLog. Callers must call Changed again after each notification.
+// Appends made by other processes sharing the database are not observed; callers
+// that care about them should additionally poll Version.
+func (l *MessageLog) Changed() <-chan struct{} {
+	l.changedMu.Lock()
+	defer l.changedMu.Unlock()
+	return l.changed
+}
+
+// notifyChanged wakes up everyone waiting on the current Changed channel.
+func (l *MessageLog) notifyChanged() {
+	l.changedMu.Lock()
+	defer l.changedMu.Unlock()
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
+
 // Version returns the highest message ID in the log (the current version).
 // Returns 0 if no messages exist.
 func (l *MessageLog) Version(ctx context.Context) (uint64, error) {
@@ -205,7 +284,7 @@ func (l *MessageLog) Version(ctx context.Context) (uint64, error) {
 // Uses Go 1.22's iter package for efficient iteration without loading everything into memory.
 func (l *MessageLog) After(ctx context.Context, startID uint64) iter.Seq[PersistedMessage] {
 	return func(yield func(PersistedMessage) bool) {
-		query := `SELECT id, timestamp, type, data FROM messages WHERE id > ? ORDER BY id ASC`
+		query := `SELECT id, timestamp, type, data, trace_id, span_id FROM messages WHERE id > ? ORDER BY id ASC`
 		rows, err := l.db.QueryContext(ctx, query, startID)
 		if err != nil {
 			if err == context.DeadlineExceeded || err == context.Canceled {
@@ -219,7 +298,7 @@ func (l *MessageLog) After(ctx context.Context, startID uint64) iter.Seq[Persist
 
 		for rows.Next() {
 			var m Message
-			if err := rows.Scan(&m.ID, &m.Timestamp, &m.Type, &m.Data); err != nil {
+			if err := rows.Scan(&m.ID, &m.Timestamp, &m.Type, &m.Data, &m.TraceID, &m.SpanID); err != nil {
 				slog.Error("Failed to scan message row", "error", err)
 				continue 
 			}
@@ -250,6 +329,86 @@ func (l *MessageLog) After(ctx context.Context, startID uint64) iter.Seq[Persist
 	}
 }
 
+// LogFilter selects the messages returned by Search.
+type LogFilter struct {
+	Type     string // Only messages of this type, if set
+	Text     string // Only messages whose type or encoded data contains this text, ignoring ASCII case
+	BeforeID uint64 // Only messages older than this ID, for paging; 0 starts at the newest
+	Limit    int    // Maximum number of messages; 0 means 50
+}
+
+// Search returns the messages matching filter, newest first. Messages of types that
+// aren't registered have a nil DecodedPayload.
+func (l *MessageLog) Search(ctx context.Context, filter LogFilter) ([]PersistedMessage, error) {
+	query := `SELECT id, timestamp, type, data, trace_id, span_id FROM messages WHERE 1 = 1`
+	var args []any
+	if filter.Type != "" {
+		query += ` AND type = ?`
+		args = append(args, filter.Type)
+	}
+	if filter.Text != "" {
+		pattern := "%" + likeEscaper.Replace(filter.Text) + "%"
+		query += ` AND (type LIKE ? ESCAPE '\' OR CAST(data AS TEXT) LIKE ? ESCAPE '\')`
+		args = append(args, pattern, pattern)
+	}
+	if filter.BeforeID > 0 {
+		query += ` AND id < ?`
+		args = append(args, filter.BeforeID)
+	}
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = 50
+	}
+	query += ` ORDER BY id DESC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := l.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search messages: %w", err)
+	}
+	defer rows.Close()
+
+	var messages []PersistedMessage
+	for rows.Next() {
+		var m Message
+		if err := rows.Scan(&m.ID, &m.Timestamp, &m.Type, &m.Data, &m.TraceID, &m.SpanID); err != nil {
+			return nil, fmt.Errorf("failed to scan message row: %w", err)
+		}
+		decoded, _ := l.Decode(m) // nil for unregistered types
+		messages = append(messages, PersistedMessage{Message: m, DecodedPayload: decoded})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to search messages: %w", err)
+	}
+	return messages, nil
+}
+
+// likeEscaper escapes the wildcards of a LIKE pattern, for use with ESCAPE '\'.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// TypeCounts returns the number of messages in the log by type.
+func (l *MessageLog) TypeCounts(ctx context.Context) (map[string]int, error) {
+	rows, err := l.db.QueryContext(ctx, `SELECT type, COUNT(*) FROM messages GROUP BY type`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count messages: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var typeName string
+		var count int
+		if err := rows.Scan(&typeName, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan message count: %w", err)
+		}
+		counts[typeName] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to count messages: %w", err)
+	}
+	return counts, nil
+}
+
 // Decode decodes the Data field of a raw Message into a concrete Go command/query type.
 // It uses the message.Type string to look up the reflect.Type in the typeRegistry,
 // creates a new instance, and uses the encoder to deserialize the Data into it.
@@ -0,0 +1,360 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Metric types as written in the exposition format.
+const (
+	MetricCounter   = "counter"
+	MetricGauge     = "gauge"
+	MetricHistogram = "histogram"
+)
+
+// DefaultBuckets are the histogram upper bounds, in seconds, used when a histogram is
+// registered without buckets. They suit latencies from a millisecond to ten seconds.
+var DefaultBuckets = []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+var metricNamePattern = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+
+// Metrics is a registry of counters, gauges and histograms, written in the
+// Prometheus text exposition format by WriteTo and HandleMetrics. NewApp creates
+// one for App.Metrics and instruments the core with it; features register their
+// own metrics while they are registered:
+//
+//	published := app.Metrics.Counter("posts_published_total", "Posts published", "author")
+//	published.Inc(author)
+//
+// Every label combination is its own series, so label values must come from a small
+// set, such as command names or route patterns, never from IDs or raw paths.
+type Metrics struct {
+	mu         sync.Mutex
+	families   map[string]*metricFamily
+	collectors []func()
+}
+
+// NewMetrics creates an empty registry.
+func NewMetrics() *Metrics {
+	return &Metrics{families: make(map[string]*metricFamily)}
+}
+
+// metricFamily holds all series of one metric.
+type metricFamily struct {
+	name    string
+	help    string
+	kind    string
+	labels  []string
+	buckets []float64      // Upper bounds of histogram buckets, ascending
+	fn      func() float64 // Computes the value of a gauge registered with GaugeFunc
+
+	mu     sync.Mutex
+	series map[string]*metricSeries // By label values joined with \xff
+}
+
+// metricSeries is the value of a metric for one combination of label values.
+type metricSeries struct {
+	labelValues []string
+	value       float64  // Counters and gauges
+	counts      []uint64 // Histogram observations per bucket, not cumulative
+	sum         float64
+	count       uint64
+}
+
+// Counter is a value that only goes up, such as the number of requests served.
+// Its methods do nothing on a nil Counter, so optional instrumentation needs no checks.
+type Counter struct{ family *metricFamily }
+
+// Inc adds one to the series with labelValues.
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds v, which must not be negative, to the series with labelValues.
+func (c *Counter) Add(v float64, labelValues ...string) {
+	if c == nil {
+		return
+	}
+	if v < 0 {
+		panic(fmt.Sprintf("counter %s cannot decrease", c.family.name))
+	}
+	c.family.update(labelValues, func(s *metricSeries) { s.value += v })
+}
+
+// Gauge is a value that can go up and down, such as a queue length.
+// Its methods do nothing on a nil Gauge.
+type Gauge struct{ family *metricFamily }
+
+// Set sets the series with labelValues to v.
+func (g *Gauge) Set(v float64, labelValues ...string) {
+	if g == nil {
+		return
+	}
+	g.family.update(labelValues, func(s *metricSeries) { s.value = v })
+}
+
+// Add adds v, which may be negative, to the series with labelValues.
+func (g *Gauge) Add(v float64, labelValues ...string) {
+	if g == nil {
+		return
+	}
+	g.family.update(labelValues, func(s *metricSeries) { s.value += v })
+}
+
+// Histogram counts observations, such as latencies, in buckets.
+// Its methods do nothing on a nil Histogram.
+type Histogram struct{ family *metricFamily }
+
+// Observe records v in the series with labelValues.
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	if h == nil {
+		return
+	}
+	bucket, _ := slices.BinarySearch(h.family.buckets, v)
+	h.family.update(labelValues, func(s *metricSeries) {
+		if bucket < len(s.counts) {
+			s.counts[bucket]++
+		}
+		s.sum += v
+		s.count++
+	})
+}
+
+// ObserveSince records the seconds elapsed since start.
+func (h *Histogram) ObserveSince(start time.Time, labelValues ...string) {
+	h.Observe(time.Since(start).Seconds(), labelValues...)
+}
+
+// Counter registers a counter with the given label names. Names should end in _total.
+func (m *Metrics) Counter(name, help string, labels ...string) *Counter {
+	return &Counter{m.register(&metricFamily{name: name, help: help, kind: MetricCounter, labels: labels})}
+}
+
+// Gauge registers a gauge with the given label names.
+func (m *Metrics) Gauge(name, help string, labels ...string) *Gauge {
+	return &Gauge{m.register(&metricFamily{name: name, help: help, kind: MetricGauge, labels: labels})}
+}
+
+// Histogram registers a histogram with the given bucket upper bounds, or
+// DefaultBuckets if buckets is nil. Names of latency histograms should end in _seconds.
+func (m *Metrics) Histogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+	if !slices.IsSorted(buckets) {
+		panic(fmt.Sprintf("buckets of histogram %s must be sorted", name))
+	}
+	return &Histogram{m.register(&metricFamily{name: name, help: help, kind: MetricHistogram, labels: labels, buckets: buckets})}
+}
+
+// GaugeFunc registers a gauge without labels whose value fn computes whenever the
+// metrics are written. fn must be safe for concurrent use.
+func (m *Metrics) GaugeFunc(name, help string, fn func() float64) {
+	m.register(&metricFamily{name: name, help: help, kind: MetricGauge, fn: fn})
+}
+
+// OnCollect adds fn to the functions called before the metrics are written, to
+// update gauges that are cheaper to compute on demand than to keep current.
+func (m *Metrics) OnCollect(fn func()) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.collectors = append(m.collectors, fn)
+}
+
+// register adds family, or returns the family already registered under its name if
+// that has the same type and labels. It panics on any other conflict or invalid name.
+func (m *Metrics) register(family *metricFamily) *metricFamily {
+	if !metricNamePattern.MatchString(family.name) {
+		panic(fmt.Sprintf("invalid metric name %q", family.name))
+	}
+	for _, label := range family.labels {
+		if !metricNamePattern.MatchString(label) || strings.Contains(label, ":") || label == "le" {
+			panic(fmt.Sprintf("invalid label name %q for metric %s", label, family.name))
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if existing, ok := m.families[family.name]; ok {
+		if existing.kind != family.kind || !slices.Equal(existing.labels, family.labels) ||
+			!slices.Equal(existing.buckets, family.buckets) || existing.fn != nil || family.fn != nil {
+			panic(fmt.Sprintf("metric %s is already registered differently", family.name))
+		}
+		return existing
+	}
+	family.series = make(map[string]*metricSeries)
+	if len(family.labels) == 0 && family.fn == nil {
+		// Metrics without labels are reported as zero until they change
+		family.update(nil, func(*metricSeries) {})
+	}
+	m.families[family.name] = family
+	return family
+}
+
+// update applies fn to the series with labelValues, creating it if needed.
+func (f *metricFamily) update(labelValues []string, fn func(s *metricSeries)) {
+	if len(labelValues) != len(f.labels) {
+		panic(fmt.Sprintf("metric %s takes %d label values, got %d", f.name, len(f.labels), len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s, ok := f.series[key]
+	if !ok {
+		s = &metricSeries{labelValues: slices.Clone(labelValues)}
+		if f.kind == MetricHistogram {
+			s.counts = make([]uint64, len(f.buckets))
+		}
+		f.series[key] = s
+	}
+	fn(s)
+}
+
+// WriteTo calls the OnCollect functions, then writes every metric in the Prometheus
+// text exposition format, sorted by name.
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	m.mu.Lock()
+	collectors := slices.Clone(m.collectors)
+	m.mu.Unlock()
+	for _, collect := range collectors {
+		collect()
+	}
+
+	m.mu.Lock()
+	families := make([]*metricFamily, 0, len(m.families))
+	for _, family := range m.families {
+		families = append(families, family)
+	}
+	m.mu.Unlock()
+	sort.Slice(families, func(i, j int) bool { return families[i].name < families[j].name })
+
+	var b strings.Builder
+	for _, family := range families {
+		family.write(&b)
+	}
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+func (f *metricFamily) write(b *strings.Builder) {
+	fmt.Fprintf(b, "# HELP %s %s\n", f.name, strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(f.help))
+	fmt.Fprintf(b, "# TYPE %s %s\n", f.name, f.kind)
+
+	if f.fn != nil {
+		fmt.Fprintf(b, "%s %s\n", f.name, formatMetricValue(f.fn()))
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	series := make([]*metricSeries, 0, len(f.series))
+	for _, s := range f.series {
+		series = append(series, s)
+	}
+	sort.Slice(series, func(i, j int) bool { return slices.Compare(series[i].labelValues, series[j].labelValues) < 0 })
+	for _, s := range series {
+		if f.kind != MetricHistogram {
+			fmt.Fprintf(b, "%s%s %s\n", f.name, formatLabels(f.labels, s.labelValues, ""), formatMetricValue(s.value))
+			continue
+		}
+		var cumulative uint64
+		for i, bound := range f.buckets {
+			cumulative += s.counts[i]
+			fmt.Fprintf(b, "%s_bucket%s %d\n", f.name, formatLabels(f.labels, s.labelValues, formatMetricValue(bound)), cumulative)
+		}
+		fmt.Fprintf(b, "%s_bucket%s %d\n", f.name, formatLabels(f.labels, s.labelValues, "+Inf"), s.count)
+		labels := formatLabels(f.labels, s.labelValues, "")
+		fmt.Fprintf(b, "%s_sum%s %s\n", f.name, labels, formatMetricValue(s.sum))
+		fmt.Fprintf(b, "%s_count%s %d\n", f.name, labels, s.count)
+	}
+}
+
+// formatLabels formats {name="value",...}, adding le for histogram buckets.
+func formatLabels(names, values []string, le string) string {
+	if len(names) == 0 && le == "" {
+		return ""
+	}
+	escape := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	pairs := make([]string, 0, len(names)+1)
+	for i, name := range names {
+		pairs = append(pairs, name+`="`+escape.Replace(values[i])+`"`)
+	}
+	if le != "" {
+		pairs = append(pairs, `le="`+le+`"`)
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatMetricValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// HandleMetrics serves the metrics in the Prometheus text exposition format.
+func HandleMetrics(m *Metrics) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		if _, err := m.WriteTo(w); err != nil {
+			slog.Error("Failed to write metrics", "error", err)
+		}
+	}
+}
+
+// routePatternKey is the context key of the route pattern recorded by RegisterRoute.
+type routePatternKey struct{}
+
+// recordRoutePattern tells HTTPMetrics and Tracing which route pattern served the request.
+func recordRoutePattern(pattern string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if route, ok := r.Context().Value(routePatternKey{}).(*string); ok {
+			*route = pattern
+		}
+		if span := SpanFromContext(r.Context()); span != nil && span.Kind == SpanKindServer {
+			span.SetName(pattern)
+			span.SetAttribute("http.route", pattern)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// HTTPMetrics counts requests by route pattern and status and records their latency
+// by route. Requests that no route registered with App.RegisterRoute matched are
+// counted under the route "unmatched", so that raw paths never become labels.
+func HTTPMetrics(m *Metrics) Middleware {
+	requests := m.Counter("petrock_http_requests_total", "HTTP requests by route pattern and status", "route", "status")
+	latency := m.Histogram("petrock_http_request_duration_seconds", "HTTP request latency by route pattern", nil, "route")
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			route := "unmatched"
+			rec := &responseRecorder{ResponseWriter: w}
+			next.ServeHTTP(rec, r.WithContext(context.WithValue(r.Context(), routePatternKey{}, &route)))
+
+			status := rec.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			requests.Inc(route, strconv.Itoa(status))
+			latency.ObserveSince(start, route)
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeMetrics(t *testing.T, m *Metrics) string {
+	t.Helper()
+	var b strings.Builder
+	if _, err := m.WriteTo(&b); err != nil {
+		t.Fatalf("WriteTo failed: %v", err)
+	}
+	return b.String()
+}
+
+func expectMetricLines(t *testing.T, output string, lines ...string) {
+	t.Helper()
+	for _, line := range lines {
+		if !strings.Contains(output, line+"\n") {
+			t.Errorf("Expected line %q in:\n%s", line, output)
+		}
+	}
+}
+
+func TestMetrics_Exposition(t *testing.T) {
+	m := NewMetrics()
+	requests := m.Counter("requests_total", "Requests served\nby path", "path")
+	requests.Inc("/a")
+	requests.Add(2, `/b"\`)
+	m.Counter("idle_total", "Never incremented")
+
+	queue := m.Gauge("queue_length", "Items queued")
+	queue.Set(5)
+	queue.Add(-1.5)
+
+	latency := m.Histogram("latency_seconds", "Latency", []float64{0.1, 1}, "op")
+	latency.Observe(0.05, "read")
+	latency.Observe(0.1, "read")
+	latency.Observe(3, "read")
+
+	collected := 0
+	m.OnCollect(func() { collected++ })
+	m.GaugeFunc("answer", "Computed on demand", func() float64 { return float64(40 + collected) })
+
+	output := writeMetrics(t, m)
+	expectMetricLines(t, output,
+		`# HELP requests_total Requests served\nby path`,
+		`# TYPE requests_total counter`,
+		`requests_total{path="/a"} 1`,
+		`requests_total{path="/b\"\\"} 2`,
+		`idle_total 0`,
+		`# TYPE queue_length gauge`,
+		`queue_length 3.5`,
+		`# TYPE latency_seconds histogram`,
+		`latency_seconds_bucket{op="read",le="0.1"} 2`,
+		`latency_seconds_bucket{op="read",le="1"} 2`,
+		`latency_seconds_bucket{op="read",le="+Inf"} 3`,
+		`latency_seconds_sum{op="read"} 3.15`,
+		`latency_seconds_count{op="read"} 3`,
+		`answer 41`,
+	)
+	if strings.Index(output, "# HELP answer") > strings.Index(output, "# HELP idle_total") {
+		t.Errorf("Expected metrics sorted by name:\n%s", output)
+	}
+}
+
+func TestMetrics_Registration(t *testing.T) {
+	m := NewMetrics()
+	first := m.Counter("events_total", "Events", "kind")
+	if again := m.Counter("events_total", "Events", "kind"); again.family != first.family {
+		t.Error("Expected registering the same counter twice to return it")
+	}
+
+	for name, register := range map[string]func(){
+		"different type":     func() { m.Gauge("events_total", "Events", "kind") },
+		"different labels":   func() { m.Counter("events_total", "Events") },
+		"invalid name":       func() { m.Counter("events-total", "Events") },
+		"reserved label":     func() { m.Histogram("sizes", "Sizes", nil, "le") },
+		"unsorted buckets":   func() { m.Histogram("sizes", "Sizes", []float64{2, 1}) },
+		"missing label":      func() { first.Inc() },
+		"decreasing counter": func() { first.Add(-1, "a") },
+	} {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("Expected a panic")
+				}
+			}()
+			register()
+		})
+	}
+
+	// Uninstrumented components hold nil metrics
+	var counter *Counter
+	var gauge *Gauge
+	var histogram *Histogram
+	counter.Inc("a")
+	gauge.Set(1)
+	histogram.Observe(1)
+}
+
+func TestMetrics_CoreInstrumentation(t *testing.T) {
+	app := newTestApp(t)
+	app.CommandRegistry.Register(&streamIncrementCommand{}, func(ctx context.Context, cmd Command, msg *Message, pctx *ProcessingContext) error {
+		return nil
+	}, streamAcceptAll{})
+	app.MessageLog.RegisterType(&streamIncrementCommand{})
+	registerBatchTestQueries(app)
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if err := app.Executor.Execute(ctx, &streamIncrementCommand{By: 1}); err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+	}
+	app.CommandRegistry.SetRateLimits("test/increment", RateLimit{Key: RateLimitByCommand, Requests: 1, Per: time.Minute})
+	httpCtx := WithClientIP(ctx, "192.0.2.1")
+	app.Executor.Execute(httpCtx, &streamIncrementCommand{By: 1})
+	if err := app.Executor.Execute(httpCtx, &streamIncrementCommand{By: 1}); err == nil {
+		t.Fatal("Expected the command to be rate limited")
+	}
+	if _, err := app.QueryRegistry.Dispatch(ctx, batchFailQuery{}); err == nil {
+		t.Fatal("Expected the query to fail")
+	}
+	if err := app.ReplayLog(); err != nil {
+		t.Fatalf("ReplayLog failed: %v", err)
+	}
+
+	output := writeMetrics(t, app.Metrics)
+	expectMetricLines(t, output,
+		`petrock_commands_executed_total{command="test/increment"} 3`,
+		`petrock_commands_failed_total{command="test/increment"} 1`,
+		`petrock_command_duration_seconds_count{command="test/increment"} 4`,
+		`petrock_log_append_duration_seconds_count 3`,
+		`petrock_log_version 3`,
+		`petrock_query_duration_seconds_count{query="test/fail"} 1`,
+	)
+	if !strings.Contains(output, "\npetrock_replay_duration_seconds ") {
+		t.Errorf("Expected the replay duration in:\n%s", output)
+	}
+}
+
+func TestHTTPMetrics_LabelsByRoutePattern(t *testing.T) {
+	app := newTestApp(t)
+	app.Mux = http.NewServeMux()
+	app.Use(HTTPMetrics(app.Metrics), RequestID())
+	app.RegisterRoute("GET /items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("id") == "missing" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("ok"))
+	})
+	app.RegisterRoute("GET /_/metrics", HandleMetrics(app.Metrics))
+
+	handler := app.Handler()
+	for _, path := range []string{"/items/1", "/items/2", "/items/missing", "/nowhere"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/_/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Unexpected content type %q", ct)
+	}
+	expectMetricLines(t, rec.Body.String(),
+		`petrock_http_requests_total{route="GET /items/{id}",status="200"} 2`,
+		`petrock_http_requests_total{route="GET /items/{id}",status="404"} 1`,
+		`petrock_http_requests_total{route="unmatched",status="404"} 1`,
+		`petrock_http_request_duration_seconds_count{route="GET /items/{id}"} 3`,
+	)
+}
+
+func TestMetrics_WorkerCycles(t *testing.T) {
+	app := newTestApp(t)
+	app.workerCycles.Inc("summarizer")
+	app.workerErrors.Inc("summarizer")
+	app.MessageLog.RegisterType(&streamIncrementCommand{})
+	if err := app.MessageLog.Append(context.Background(), &streamIncrementCommand{}); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+	app.workers = append(app.workers, &progressWorker{name: "summarizer"})
+	app.workerStats = append(app.workerStats, &workerStats{})
+
+	expectMetricLines(t, writeMetrics(t, app.Metrics),
+		`petrock_worker_cycles_total{worker="summarizer"} 1`,
+		`petrock_worker_cycle_errors_total{worker="summarizer"} 1`,
+		`petrock_worker_lag_messages{worker="summarizer"} 1`,
+	)
+}
+
+// progressWorker is a worker that has processed nothing.
+type progressWorker struct{ name string }
+
+func (w *progressWorker) Start(ctx context.Context) error  { return nil }
+func (w *progressWorker) Stop(ctx context.Context) error   { return nil }
+func (w *progressWorker) Work() error                      { return errors.New("not implemented") }
+func (w *progressWorker) Replay(ctx context.Context) error { return nil }
+func (w *progressWorker) WorkerInfo() *WorkerInfo          { return &WorkerInfo{Name: w.name} }
+func (w *progressWorker) Progress() WorkerProgress         { return WorkerProgress{} }
@@ -0,0 +1,366 @@
+package core
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andybalholm/brotli"
+)
+
+// RequestIDHeader is the header RequestID reads an incoming request ID from and writes
+// the request's ID to.
+const RequestIDHeader = "X-Request-ID"
+
+// DefaultMaxBodySize is the request body limit serve applies with MaxBodySize.
+const DefaultMaxBodySize = 10 << 20 // 10 MiB
+
+// DefaultSecurityHeaders are the headers SecurityHeaders sets when given none. There is
+// no Content-Security-Policy by default because the UI loads Tailwind from a CDN and
+// uses inline scripts; add one that fits the application.
+var DefaultSecurityHeaders = map[string]string{
+	"X-Content-Type-Options":     "nosniff",
+	"X-Frame-Options":            "DENY",
+	"Referrer-Policy":            "strict-origin-when-cross-origin",
+	"Cross-Origin-Opener-Policy": "same-origin",
+}
+
+// Middleware wraps an http.Handler with behavior that runs around it.
+type Middleware func(http.Handler) http.Handler
+
+// Chain wraps h in the given middleware. The first middleware is the outermost, so it
+// sees the request first and the response last.
+func Chain(h http.Handler, middleware ...Middleware) http.Handler {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		h = middleware[i](h)
+	}
+	return h
+}
+
+// Use adds middleware that wraps every route, in the order given. It takes effect in the
+// handler returned by Handler.
+func (a *App) Use(middleware ...Middleware) {
+	a.middleware = append(a.middleware, middleware...)
+}
+
+// Handler returns the application's mux wrapped in the middleware added with Use.
+func (a *App) Handler() http.Handler {
+	return Chain(a.Mux, a.middleware...)
+}
+
+// requestIDKey is the context key holding the request ID.
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of ctx carrying the given request ID.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the request ID set by RequestID, or "" outside a request.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// RequestID gives every request an ID, taken from the X-Request-ID header if the client
+// or a proxy sent a well-formed one and generated otherwise. The ID is stored in the
+// request context and echoed in the response header.
+func RequestID() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(RequestIDHeader)
+			if !validRequestID(id) {
+				id = newRequestID()
+			}
+			w.Header().Set(RequestIDHeader, id)
+			next.ServeHTTP(w, r.WithContext(WithRequestID(r.Context(), id)))
+		})
+	}
+}
+
+// validRequestID accepts IDs of up to 128 letters, digits and "-_.:".
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', strings.ContainsRune("-_.:", c):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID returns 16 random bytes as hex.
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// AccessLog logs one line per request with its method, path, status, response size,
+// duration and request ID. Server errors are logged at error level.
+func AccessLog(logger *slog.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rec := &responseRecorder{ResponseWriter: w}
+			next.ServeHTTP(rec, r)
+
+			status := rec.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			level := slog.LevelInfo
+			if status >= 500 {
+				level = slog.LevelError
+			}
+			logger.LogAttrs(r.Context(), level, "HTTP request",
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.Int("status", status),
+				slog.Int64("bytes", rec.bytes),
+				slog.Duration("duration", time.Since(start)),
+				slog.String("request_id", RequestIDFromContext(r.Context())),
+				slog.String("remote", r.RemoteAddr),
+			)
+		})
+	}
+}
+
+// Recover turns a panic in a handler into a 500 response: an error page for browsers
+// and plain text otherwise, both showing the request ID. The panic and its stack are
+// logged. If the handler had already started the response, it is cut off instead.
+func Recover() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rec := &responseRecorder{ResponseWriter: w}
+			defer func() {
+				v := recover()
+				if v == nil {
+					return
+				}
+				if v == http.ErrAbortHandler {
+					panic(v)
+				}
+				requestID := RequestIDFromContext(r.Context())
+				slog.Error("Panic while handling request", "method", r.Method, "path", r.URL.Path,
+					"request_id", requestID, "panic", v, "stack", string(debug.Stack()))
+				if rec.status != 0 {
+					panic(http.ErrAbortHandler)
+				}
+				WriteErrorPage(w, r, http.StatusInternalServerError)
+			}()
+			next.ServeHTTP(rec, r)
+		})
+	}
+}
+
+// SecurityHeaders sets the given response headers, or DefaultSecurityHeaders if headers
+// is nil, before the handler runs. Handlers can still override them.
+func SecurityHeaders(headers map[string]string) Middleware {
+	if headers == nil {
+		headers = DefaultSecurityHeaders
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for name, value := range headers {
+				w.Header().Set(name, value)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// MaxBodySize rejects requests whose body is larger than limit bytes: at once with 413
+// Request Entity Too Large if Content-Length says so, or with an error from the body's
+// Read once the handler has read past the limit.
+func MaxBodySize(limit int64) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > limit {
+				http.Error(w, "Request Entity Too Large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Compress compresses text, JSON, JavaScript, XML and SVG responses with brotli or gzip,
+// whichever the client accepts, preferring brotli. Event streams and responses that
+// already have a Content-Encoding are sent as they are.
+func Compress() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+			encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+			if encoding == "" || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+			cw := &compressWriter{ResponseWriter: w, encoding: encoding}
+			defer cw.Close()
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// negotiateEncoding picks "br" or "gzip" from an Accept-Encoding header, or "" for neither.
+func negotiateEncoding(header string) string {
+	accepted := map[string]bool{}
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if weight, err := strconv.ParseFloat(q, 64); err == nil && weight == 0 {
+				continue
+			}
+		}
+		accepted[strings.ToLower(strings.TrimSpace(name))] = true
+	}
+	switch {
+	case accepted["br"]:
+		return "br"
+	case accepted["gzip"]:
+		return "gzip"
+	}
+	return ""
+}
+
+// compressible reports whether responses of the given content type are worth compressing.
+func compressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch {
+	case mediaType == "text/event-stream":
+		return false
+	case strings.HasPrefix(mediaType, "text/"):
+		return true
+	}
+	switch mediaType {
+	case "application/json", "application/javascript", "application/xml", "image/svg+xml":
+		return true
+	}
+	return strings.HasSuffix(mediaType, "+json") || strings.HasSuffix(mediaType, "+xml")
+}
+
+// responseRecorder records the status and size of a response.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(p []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(p)
+	r.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// Flush implements http.Flusher for handlers that don't use http.ResponseController.
+func (r *responseRecorder) Flush() {
+	http.NewResponseController(r.ResponseWriter).Flush()
+}
+
+// compressWriter compresses the response once the handler's headers show it is compressible.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	writer   io.WriteCloser // nil until decided, or if the response is sent as it is
+	decided  bool
+}
+
+// decide chooses whether to compress, based on the headers and the first bytes written.
+func (c *compressWriter) decide(status int, p []byte) {
+	if c.decided {
+		return
+	}
+	c.decided = true
+
+	header := c.Header()
+	if status < 200 || status == http.StatusNoContent || status == http.StatusNotModified || header.Get("Content-Encoding") != "" {
+		return
+	}
+	if header.Get("Content-Type") == "" && len(p) > 0 {
+		header.Set("Content-Type", http.DetectContentType(p))
+	}
+	if !compressible(header.Get("Content-Type")) {
+		return
+	}
+
+	header.Del("Content-Length")
+	header.Set("Content-Encoding", c.encoding)
+	if c.encoding == "br" {
+		c.writer = brotli.NewWriter(c.ResponseWriter)
+	} else {
+		c.writer = gzip.NewWriter(c.ResponseWriter)
+	}
+}
+
+func (c *compressWriter) WriteHeader(status int) {
+	c.decide(status, nil)
+	c.ResponseWriter.WriteHeader(status)
+}
+
+func (c *compressWriter) Write(p []byte) (int, error) {
+	if !c.decided {
+		c.decide(http.StatusOK, p)
+	}
+	if c.writer == nil {
+		return c.ResponseWriter.Write(p)
+	}
+	return c.writer.Write(p)
+}
+
+// Flush sends what has been compressed so far.
+func (c *compressWriter) Flush() {
+	if flusher, ok := c.writer.(interface{ Flush() error }); ok {
+		flusher.Flush()
+	}
+	http.NewResponseController(c.ResponseWriter).Flush()
+}
+
+// Close finishes the compressed stream.
+func (c *compressWriter) Close() error {
+	if c.writer == nil {
+		return nil
+	}
+	return c.writer.Close()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (c *compressWriter) Unwrap() http.ResponseWriter {
+	return c.ResponseWriter
+}
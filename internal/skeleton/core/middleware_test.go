@@ -0,0 +1,200 @@
+package core
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+func TestChain_Order(t *testing.T) {
+	var order []string
+	mark := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	h := Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		order = append(order, "handler")
+	}), mark("outer"), mark("inner"))
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if got := strings.Join(order, ","); got != "outer,inner,handler" {
+		t.Errorf("Expected outer,inner,handler, got %s", got)
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	var seen string
+	h := RequestID()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = RequestIDFromContext(r.Context())
+	}))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if len(seen) != 32 || w.Header().Get(RequestIDHeader) != seen {
+		t.Errorf("Expected a generated ID in context and header, got %q and %q", seen, w.Header().Get(RequestIDHeader))
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(RequestIDHeader, "proxy-123")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	if seen != "proxy-123" {
+		t.Errorf("Expected the incoming ID to be kept, got %q", seen)
+	}
+
+	r.Header.Set(RequestIDHeader, "bad id\n")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	if seen == "bad id\n" {
+		t.Error("Expected a malformed incoming ID to be replaced")
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	h := Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down", http.StatusServiceUnavailable)
+	}), RequestID(), AccessLog(logger))
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/items", nil))
+	line := buf.String()
+	for _, want := range []string{`"level":"ERROR"`, `"method":"POST"`, `"path":"/items"`, `"status":503`, `"request_id":"`} {
+		if !strings.Contains(line, want) {
+			t.Errorf("Expected access log to contain %s, got %s", want, line)
+		}
+	}
+}
+
+func TestRecover(t *testing.T) {
+	h := Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}), RequestID(), Recover())
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept", "text/html")
+	r.Header.Set(RequestIDHeader, "req-1")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("Expected 500, got %d", w.Code)
+	}
+	if !strings.Contains(w.Header().Get("Content-Type"), "text/html") || !strings.Contains(w.Body.String(), "req-1") {
+		t.Errorf("Expected an HTML error page with the request ID, got %q", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusInternalServerError || strings.Contains(w.Body.String(), "<html") {
+		t.Errorf("Expected a plain text 500 for non-browser clients, got %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestSecurityHeaders(t *testing.T) {
+	h := SecurityHeaders(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Frame-Options", "SAMEORIGIN")
+	}))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Header().Get("X-Content-Type-Options") != "nosniff" {
+		t.Error("Expected default security headers to be set")
+	}
+	if w.Header().Get("X-Frame-Options") != "SAMEORIGIN" {
+		t.Error("Expected the handler to be able to override a security header")
+	}
+}
+
+func TestMaxBodySize(t *testing.T) {
+	var readErr error
+	h := MaxBodySize(4)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, readErr = io.ReadAll(r.Body)
+	}))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("too long")))
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected 413 for a large Content-Length, got %d", w.Code)
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/", io.NopCloser(strings.NewReader("too long")))
+	r.ContentLength = -1
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	if readErr == nil {
+		t.Error("Expected reading past the limit to fail")
+	}
+}
+
+func TestCompress(t *testing.T) {
+	body := strings.Repeat("hello petrock ", 100)
+	h := Compress()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/events" {
+			w.Header().Set("Content-Type", "text/event-stream")
+		}
+		io.WriteString(w, body)
+	}))
+
+	tests := []struct {
+		path, accept, encoding string
+		decode                 func(io.Reader) (io.Reader, error)
+	}{
+		{"/", "gzip, br", "br", func(r io.Reader) (io.Reader, error) { return brotli.NewReader(r), nil }},
+		{"/", "gzip", "gzip", func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) }},
+		{"/", "br;q=0, identity", "", nil},
+		{"/events", "gzip", "", nil},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		r.Header.Set("Accept-Encoding", tt.accept)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		if got := w.Header().Get("Content-Encoding"); got != tt.encoding {
+			t.Errorf("%s with %q: expected encoding %q, got %q", tt.path, tt.accept, tt.encoding, got)
+			continue
+		}
+		var reader io.Reader = w.Body
+		if tt.decode != nil {
+			var err error
+			if reader, err = tt.decode(w.Body); err != nil {
+				t.Fatalf("Failed to open %s body: %v", tt.encoding, err)
+			}
+		}
+		if got, _ := io.ReadAll(reader); string(got) != body {
+			t.Errorf("%s with %q: body did not round-trip", tt.path, tt.accept)
+		}
+	}
+}
+
+func TestApp_RouteMiddleware(t *testing.T) {
+	app := newTestApp(t)
+	app.Mux = http.NewServeMux()
+	tag := func(value string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Add("X-Tag", value)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	app.Use(tag("global"))
+	app.RegisterRoute("GET /scoped", func(w http.ResponseWriter, r *http.Request) {}, tag("route"))
+	app.RegisterRoute("GET /plain", func(w http.ResponseWriter, r *http.Request) {})
+
+	for path, want := range map[string]string{"/scoped": "global,route", "/plain": "global"} {
+		w := httptest.NewRecorder()
+		app.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if got := strings.Join(w.Header().Values("X-Tag"), ","); got != want {
+			t.Errorf("%s: expected tags %s, got %s", path, want, got)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package core
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// WantsJSON reports whether the client prefers JSON to HTML, according to its Accept
+// header: application/json, or a +json type, must be listed with a higher quality than
+// text/html. Browsers list text/html first, so they get HTML; so do clients that send
+// no Accept header or */*.
+func WantsJSON(r *http.Request) bool {
+	jsonQuality, htmlQuality := 0.0, 0.0
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		quality := 1.0
+		if q, err := strconv.ParseFloat(params["q"], 64); err == nil {
+			quality = q
+		}
+		switch {
+		case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+			jsonQuality = max(jsonQuality, quality)
+		case mediaType == "text/html":
+			htmlQuality = max(htmlQuality, quality)
+		}
+	}
+	return jsonQuality > htmlQuality
+}
@@ -0,0 +1,89 @@
+package core
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestWantsJSON(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   bool
+	}{
+		{"", false},
+		{"*/*", false},
+		{"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", false},
+		{"application/json", true},
+		{"application/problem+json", true},
+		{"application/json;q=0.5, text/html", false},
+		{"text/html;q=0.5, application/json", true},
+		{"application/json, text/html", false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept", tt.accept)
+		if got := WantsJSON(r); got != tt.want {
+			t.Errorf("WantsJSON(%q) = %v, want %v", tt.accept, got, tt.want)
+		}
+	}
+}
+
+type requestTestCommand struct {
+	ID   string `json:"id" validate:"required"`
+	Name string `json:"name" validate:"required,minlen=2"`
+	Rank int    `json:"rank"`
+}
+
+// pathRequest routes r through a mux so that the {id} path value is set.
+func pathRequest(t *testing.T, r *http.Request, target interface{}) error {
+	t.Helper()
+	var err error
+	mux := http.NewServeMux()
+	mux.HandleFunc("/items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		err = ParseFromRequest(r, target, "id")
+	})
+	mux.ServeHTTP(httptest.NewRecorder(), r)
+	return err
+}
+
+func TestParseFromRequest_JSON(t *testing.T) {
+	var cmd requestTestCommand
+	r := httptest.NewRequest(http.MethodPut, "/items/a1", strings.NewReader(`{"id":"other","name":"Widget","rank":3}`))
+	r.Header.Set("Content-Type", "application/json")
+	if err := pathRequest(t, r, &cmd); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cmd.ID != "a1" || cmd.Name != "Widget" || cmd.Rank != 3 {
+		t.Errorf("Expected the path ID and body fields, got %+v", cmd)
+	}
+
+	r = httptest.NewRequest(http.MethodPut, "/items/a1", strings.NewReader(`{"name":"W"}`))
+	r.Header.Set("Content-Type", "application/json")
+	var parseErrors *ParseErrors
+	if err := pathRequest(t, r, &requestTestCommand{}); !errors.As(err, &parseErrors) {
+		t.Errorf("Expected validation errors, got %v", err)
+	}
+
+	r = httptest.NewRequest(http.MethodPut, "/items/a1", strings.NewReader(`{"name":`))
+	r.Header.Set("Content-Type", "application/json")
+	if err := pathRequest(t, r, &requestTestCommand{}); err == nil || errors.As(err, &parseErrors) {
+		t.Errorf("Expected a decoding error, got %v", err)
+	}
+}
+
+func TestParseFromRequest_Form(t *testing.T) {
+	var cmd requestTestCommand
+	form := url.Values{"name": {"Widget"}, "rank": {"2"}}
+	r := httptest.NewRequest(http.MethodPost, "/items/b2?name=ignored", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if err := pathRequest(t, r, &cmd); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cmd.ID != "b2" || cmd.Name != "Widget" || cmd.Rank != 2 {
+		t.Errorf("Expected the path ID and form fields, got %+v", cmd)
+	}
+}
@@ -0,0 +1,463 @@
+package core
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// OpenAPIVersion is the version of the OpenAPI specification App.OpenAPI follows.
+const OpenAPIVersion = "3.1.0"
+
+// OpenAPIDocument is an OpenAPI description of the command and query API. Only the
+// parts of the specification the application uses are modelled.
+type OpenAPIDocument struct {
+	OpenAPI    string                     `json:"openapi"`
+	Info       OpenAPIInfo                `json:"info"`
+	Paths      map[string]OpenAPIPathItem `json:"paths"`
+	Components OpenAPIComponents          `json:"components"`
+}
+
+// OpenAPIInfo describes the API as a whole.
+type OpenAPIInfo struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// OpenAPIPathItem maps lowercase HTTP methods to the operations of a path.
+type OpenAPIPathItem map[string]*OpenAPIOperation
+
+// OpenAPIOperation describes a single API operation on a path.
+type OpenAPIOperation struct {
+	OperationID string                     `json:"operationId"`
+	Summary     string                     `json:"summary,omitempty"`
+	Description string                     `json:"description,omitempty"`
+	Tags        []string                   `json:"tags,omitempty"`
+	Parameters  []OpenAPIParameter         `json:"parameters,omitempty"`
+	RequestBody *OpenAPIRequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]OpenAPIResponse `json:"responses"`
+}
+
+// OpenAPIParameter describes a query string, header or path parameter.
+type OpenAPIParameter struct {
+	Name        string      `json:"name"`
+	In          string      `json:"in"`
+	Description string      `json:"description,omitempty"`
+	Required    bool        `json:"required,omitempty"`
+	Schema      *JSONSchema `json:"schema"`
+}
+
+// OpenAPIRequestBody describes the body of a request by media type.
+type OpenAPIRequestBody struct {
+	Required bool                        `json:"required"`
+	Content  map[string]OpenAPIMediaType `json:"content"`
+}
+
+// OpenAPIMediaType holds the schema of a body in one media type.
+type OpenAPIMediaType struct {
+	Schema *JSONSchema `json:"schema"`
+}
+
+// OpenAPIResponse describes a response by status code.
+type OpenAPIResponse struct {
+	Description string                      `json:"description"`
+	Headers     map[string]OpenAPIHeader    `json:"headers,omitempty"`
+	Content     map[string]OpenAPIMediaType `json:"content,omitempty"`
+}
+
+// OpenAPIHeader describes a response header.
+type OpenAPIHeader struct {
+	Description string      `json:"description,omitempty"`
+	Schema      *JSONSchema `json:"schema"`
+}
+
+// OpenAPIComponents holds the schemas operations refer to with $ref.
+type OpenAPIComponents struct {
+	Schemas map[string]*JSONSchema `json:"schemas"`
+}
+
+// OpenAPIDiscriminator tells clients which oneOf alternative a value is by one property.
+type OpenAPIDiscriminator struct {
+	PropertyName string            `json:"propertyName"`
+	Mapping      map[string]string `json:"mapping,omitempty"`
+}
+
+// JSONSchema is the subset of JSON Schema 2020-12 used to describe commands, queries
+// and their results. An empty schema accepts any value.
+type JSONSchema struct {
+	Ref                  string                 `json:"$ref,omitempty"`
+	Type                 any                    `json:"type,omitempty"` // A type name, or a list of them for nullable values
+	Format               string                 `json:"format,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Const                any                    `json:"const,omitempty"`
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	AdditionalProperties any                    `json:"additionalProperties,omitempty"` // false or a *JSONSchema
+	Items                *JSONSchema            `json:"items,omitempty"`
+	MinLength            *int                   `json:"minLength,omitempty"`
+	MaxLength            *int                   `json:"maxLength,omitempty"`
+	Minimum              *int64                 `json:"minimum,omitempty"`
+	Maximum              *int64                 `json:"maximum,omitempty"`
+	OneOf                []*JSONSchema          `json:"oneOf,omitempty"`
+	Discriminator        *OpenAPIDiscriminator  `json:"discriminator,omitempty"`
+}
+
+// OpenAPI describes POST /commands and GET /queries/{feature}/{query} for every
+// registered command and query. Payloads and query parameters are described from
+// their struct fields and json tags; validate tags become required properties and
+// constraints such as minLength. Query results are described when the query declares
+// its result type by implementing TypedQuery.
+func (a *App) OpenAPI(title, version string) *OpenAPIDocument {
+	doc := &OpenAPIDocument{
+		OpenAPI: OpenAPIVersion,
+		Info: OpenAPIInfo{
+			Title:       title,
+			Version:     version,
+			Description: "Commands change the application state through POST /commands; queries read it through GET /queries/{feature}/{query}.",
+		},
+		Paths:      map[string]OpenAPIPathItem{},
+		Components: OpenAPIComponents{Schemas: map[string]*JSONSchema{}},
+	}
+	schemas := doc.Components.Schemas
+	schemas["ValidationError"] = validationErrorSchema()
+
+	commandNames := a.CommandRegistry.PublicCommandNames()
+	sort.Strings(commandNames)
+	if len(commandNames) > 0 {
+		doc.Paths["/commands"] = OpenAPIPathItem{"post": commandOperation(commandNames, a.CommandRegistry, schemas)}
+	}
+
+	queryNames := a.QueryRegistry.RegisteredQueryNames()
+	sort.Strings(queryNames)
+	for _, name := range queryNames {
+		queryType, _ := a.QueryRegistry.GetQueryType(name)
+		resultType, _ := a.QueryRegistry.GetResultType(name)
+		doc.Paths["/queries/"+name] = OpenAPIPathItem{"get": queryOperation(name, queryType, resultType, schemas)}
+	}
+
+	return doc
+}
+
+// commandOperation describes POST /commands and adds the schemas of every command
+// payload and request envelope to schemas.
+func commandOperation(names []string, registry *CommandRegistry, schemas map[string]*JSONSchema) *OpenAPIOperation {
+	request := &JSONSchema{Discriminator: &OpenAPIDiscriminator{PropertyName: "type", Mapping: map[string]string{}}}
+	for _, name := range names {
+		cmdType, found := registry.GetCommandType(name)
+		if !found {
+			continue
+		}
+		payloadName := schemaName(name) + "Command"
+		requestName := payloadName + "Request"
+		schemas[payloadName] = structSchema(cmdType, true, map[reflect.Type]bool{})
+		schemas[requestName] = &JSONSchema{
+			Type: "object",
+			Properties: map[string]*JSONSchema{
+				"type":    {Type: "string", Const: name},
+				"payload": schemaRef(payloadName),
+			},
+			Required:             []string{"type", "payload"},
+			AdditionalProperties: false,
+		}
+		request.OneOf = append(request.OneOf, schemaRef(requestName))
+		request.Discriminator.Mapping[name] = schemaRef(requestName).Ref
+	}
+	schemas["CommandRequest"] = request
+
+	return &OpenAPIOperation{
+		OperationID: "executeCommand",
+		Summary:     "Execute a command",
+		Description: "Validates the command, appends it to the message log and applies it. The type field selects the command.",
+		Tags:        []string{"commands"},
+		Parameters: []OpenAPIParameter{{
+			Name:        CSRFHeader,
+			In:          "header",
+			Description: "Any non-empty value for clients without a CSRF cookie; otherwise the cookie's value.",
+			Required:    true,
+			Schema:      &JSONSchema{Type: "string"},
+		}},
+		RequestBody: &OpenAPIRequestBody{
+			Required: true,
+			Content:  map[string]OpenAPIMediaType{"application/json": {Schema: schemaRef("CommandRequest")}},
+		},
+		Responses: map[string]OpenAPIResponse{
+			"200": {
+				Description: "The command was executed",
+				Content: map[string]OpenAPIMediaType{"application/json": {Schema: &JSONSchema{
+					Type:       "object",
+					Properties: map[string]*JSONSchema{"status": {Type: "string", Const: "success"}},
+					Required:   []string{"status"},
+				}}},
+			},
+			"400": badRequestResponse(),
+			"403": plainResponse("The CSRF check failed"),
+			"415": plainResponse("The request body is not application/json"),
+			"429": {
+				Description: "A rate limit for the command was exceeded",
+				Headers: map[string]OpenAPIHeader{"Retry-After": {
+					Description: "Seconds to wait before trying again",
+					Schema:      &JSONSchema{Type: "integer"},
+				}},
+				Content: map[string]OpenAPIMediaType{"text/plain": {Schema: &JSONSchema{Type: "string"}}},
+			},
+			"500": plainResponse("The command could not be executed"),
+		},
+	}
+}
+
+// queryOperation describes GET /queries/{name}, adding the result schema to schemas.
+func queryOperation(name string, queryType, resultType reflect.Type, schemas map[string]*JSONSchema) *OpenAPIOperation {
+	params := structSchema(queryType, true, map[reflect.Type]bool{})
+	required := map[string]bool{}
+	for _, field := range params.Required {
+		required[field] = true
+	}
+	fields := make([]string, 0, len(params.Properties))
+	for field := range params.Properties {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	var parameters []OpenAPIParameter
+	for _, field := range fields {
+		schema := params.Properties[field]
+		description := schema.Description
+		schema.Description = ""
+		parameters = append(parameters, OpenAPIParameter{
+			Name:        field,
+			In:          "query",
+			Description: description,
+			Required:    required[field],
+			Schema:      schema,
+		})
+	}
+
+	result := &JSONSchema{}
+	if resultType != nil {
+		resultName := schemaName(name) + "Result"
+		schemas[resultName] = typeSchema(resultType, false, map[reflect.Type]bool{})
+		result = schemaRef(resultName)
+	}
+
+	feature, _, _ := strings.Cut(name, "/")
+	return &OpenAPIOperation{
+		OperationID: "query" + schemaName(name),
+		Summary:     "Run the " + name + " query",
+		Tags:        []string{feature},
+		Parameters:  parameters,
+		Responses: map[string]OpenAPIResponse{
+			"200": {
+				Description: "The query result",
+				Content:     map[string]OpenAPIMediaType{"application/json": {Schema: result}},
+			},
+			"400": badRequestResponse(),
+			"500": plainResponse("The query failed"),
+		},
+	}
+}
+
+// validationErrorSchema describes the body of 400 responses for invalid input.
+func validationErrorSchema() *JSONSchema {
+	return &JSONSchema{
+		Type: "object",
+		Properties: map[string]*JSONSchema{
+			"error": {Type: "string", Const: "Validation failed"},
+			"details": {Type: "array", Items: &JSONSchema{
+				Type: "object",
+				Properties: map[string]*JSONSchema{
+					"field":   {Type: "string"},
+					"message": {Type: "string"},
+					"code":    {Type: "string"},
+					"meta":    {Type: "object"},
+				},
+				Required: []string{"field", "message"},
+			}},
+		},
+		Required: []string{"error", "details"},
+	}
+}
+
+// badRequestResponse describes a 400 response: validation errors as JSON, malformed
+// requests as plain text.
+func badRequestResponse() OpenAPIResponse {
+	return OpenAPIResponse{
+		Description: "The input failed validation or could not be parsed",
+		Content: map[string]OpenAPIMediaType{
+			"application/json": {Schema: schemaRef("ValidationError")},
+			"text/plain":       {Schema: &JSONSchema{Type: "string"}},
+		},
+	}
+}
+
+// plainResponse describes a response with a plain text body.
+func plainResponse(description string) OpenAPIResponse {
+	return OpenAPIResponse{
+		Description: description,
+		Content:     map[string]OpenAPIMediaType{"text/plain": {Schema: &JSONSchema{Type: "string"}}},
+	}
+}
+
+// schemaRef refers to a schema in the document's components.
+func schemaRef(name string) *JSONSchema {
+	return &JSONSchema{Ref: "#/components/schemas/" + name}
+}
+
+// schemaName turns a message name such as "blog-posts/create" into "BlogPostsCreate".
+func schemaName(name string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	rawMessageType    = reflect.TypeOf(json.RawMessage{})
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// typeSchema describes how encoding/json represents values of type t. Input schemas
+// take required properties from validate tags; output schemas require every property
+// that isn't omitempty. seen guards against recursive types.
+func typeSchema(t reflect.Type, input bool, seen map[reflect.Type]bool) *JSONSchema {
+	switch {
+	case t == timeType:
+		return &JSONSchema{Type: "string", Format: "date-time"}
+	case t == rawMessageType:
+		return &JSONSchema{}
+	case t.Kind() != reflect.Pointer && (t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType)):
+		return &JSONSchema{}
+	case t.Kind() != reflect.Pointer && (t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType)):
+		return &JSONSchema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		schema := typeSchema(t.Elem(), input, seen)
+		if typeName, ok := schema.Type.(string); ok {
+			schema.Type = []string{typeName, "null"}
+		}
+		return schema
+	case reflect.String:
+		return &JSONSchema{Type: "string"}
+	case reflect.Bool:
+		return &JSONSchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &JSONSchema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &JSONSchema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &JSONSchema{Type: "string", Format: "byte"} // Base64, like encoding/json
+		}
+		return &JSONSchema{Type: "array", Items: typeSchema(t.Elem(), input, seen)}
+	case reflect.Map:
+		return &JSONSchema{Type: "object", AdditionalProperties: typeSchema(t.Elem(), input, seen)}
+	case reflect.Struct:
+		if seen[t] {
+			return &JSONSchema{Type: "object"}
+		}
+		seen[t] = true
+		defer delete(seen, t)
+		return structSchema(t, input, seen)
+	}
+	return &JSONSchema{}
+}
+
+// structSchema describes the exported fields of a struct the way encoding/json
+// marshals them, flattening embedded structs.
+func structSchema(t reflect.Type, input bool, seen map[reflect.Type]bool) *JSONSchema {
+	schema := &JSONSchema{Type: "object", Properties: map[string]*JSONSchema{}}
+	addStructFields(schema, t, input, seen)
+	sort.Strings(schema.Required)
+	return schema
+}
+
+// addStructFields adds the properties of t's fields to schema.
+func addStructFields(schema *JSONSchema, t reflect.Type, input bool, seen map[reflect.Type]bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				addStructFields(schema, embedded, input, seen)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		property := typeSchema(field.Type, input, seen)
+		property.Description = field.Tag.Get("description")
+		constraints := StandardTagParser{}.ParseTags(field)
+		applyConstraints(property, field.Type, constraints)
+		schema.Properties[name] = property
+
+		if input && constraints["required"] == "true" ||
+			!input && !strings.Contains(","+options+",", ",omitempty,") {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
+
+// applyConstraints turns the validate tags the parser enforces into JSON Schema keywords.
+func applyConstraints(schema *JSONSchema, t reflect.Type, constraints map[string]string) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch kind := t.Kind(); {
+	case kind == reflect.String:
+		if n, err := strconv.Atoi(constraints["minlen"]); err == nil {
+			schema.MinLength = &n
+		}
+		if n, err := strconv.Atoi(constraints["maxlen"]); err == nil {
+			schema.MaxLength = &n
+		}
+		if constraints["email"] == "true" {
+			schema.Format = "email"
+		}
+		// A required string must not be blank, so it has at least one character
+		if constraints["required"] == "true" && schema.MinLength == nil {
+			one := 1
+			schema.MinLength = &one
+		}
+	case kind >= reflect.Int && kind <= reflect.Float64:
+		if n, err := strconv.ParseInt(constraints["min"], 10, 64); err == nil {
+			schema.Minimum = &n
+		}
+		if n, err := strconv.ParseInt(constraints["max"], 10, 64); err == nil {
+			schema.Maximum = &n
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type openAPICreateCommand struct {
+	Name      string    `json:"name" validate:"required,minlen=2,maxlen=100"`
+	Email     string    `json:"email" validate:"email"`
+	Priority  int       `json:"priority" validate:"min=1,max=5"`
+	Tags      []string  `json:"tags"`
+	CreatedAt time.Time `json:"created_at"`
+	internal  string
+}
+
+func (c *openAPICreateCommand) CommandName() string { return "test-items/create" }
+
+type openAPIListQuery struct {
+	Page   int    `json:"page" validate:"min=1" description:"Page number"`
+	Filter string `json:"filter" validate:"required"`
+}
+
+func (q openAPIListQuery) QueryName() string { return "test-items/list" }
+
+func (q openAPIListQuery) NewResult() QueryResult { return &openAPIListResult{} }
+
+type openAPIPage struct {
+	Total int `json:"total"`
+}
+
+type openAPINode struct {
+	Children []openAPINode `json:"children"`
+}
+
+type openAPIListResult struct {
+	openAPIPage
+	Items    []openAPIItem `json:"items"`
+	Cursor   *string       `json:"cursor"`
+	Note     string        `json:"note,omitempty"`
+	Tree     openAPINode   `json:"tree"`
+	Metadata map[string]int
+	Skipped  string `json:"-"`
+}
+
+type openAPIItem struct {
+	ID string `json:"id"`
+}
+
+type openAPIUntypedQuery struct{}
+
+func (q openAPIUntypedQuery) QueryName() string { return "test-items/untyped" }
+
+func newOpenAPITestApp(t *testing.T) *App {
+	app := newTestApp(t)
+	app.CommandRegistry.Register(&openAPICreateCommand{}, func(ctx context.Context, cmd Command, msg *Message, pctx *ProcessingContext) error {
+		return nil
+	}, streamAcceptAll{})
+	handler := func(ctx context.Context, query Query) (QueryResult, error) { return nil, nil }
+	app.QueryRegistry.Register(openAPIListQuery{}, handler)
+	app.QueryRegistry.Register(openAPIUntypedQuery{}, handler)
+	return app
+}
+
+func TestOpenAPI_Commands(t *testing.T) {
+	doc := newOpenAPITestApp(t).OpenAPI("test", "1.0.0")
+	if doc.OpenAPI != "3.1.0" || doc.Info.Title != "test" {
+		t.Fatalf("Unexpected header: %s %+v", doc.OpenAPI, doc.Info)
+	}
+
+	op := doc.Paths["/commands"]["post"]
+	if op == nil {
+		t.Fatal("Expected POST /commands")
+	}
+	if got := op.RequestBody.Content["application/json"].Schema.Ref; got != "#/components/schemas/CommandRequest" {
+		t.Errorf("Unexpected request body schema %q", got)
+	}
+	if _, ok := op.Responses["429"].Headers["Retry-After"]; !ok {
+		t.Error("Expected a Retry-After header on 429")
+	}
+
+	request := doc.Components.Schemas["CommandRequest"]
+	if len(request.OneOf) != 1 || request.OneOf[0].Ref != "#/components/schemas/TestItemsCreateCommandRequest" {
+		t.Errorf("Unexpected oneOf %+v", request.OneOf)
+	}
+	if request.Discriminator.Mapping["test-items/create"] != "#/components/schemas/TestItemsCreateCommandRequest" {
+		t.Errorf("Unexpected discriminator %+v", request.Discriminator)
+	}
+	envelope := doc.Components.Schemas["TestItemsCreateCommandRequest"]
+	if envelope.Properties["type"].Const != "test-items/create" {
+		t.Errorf("Expected the type property to be the command name, got %v", envelope.Properties["type"].Const)
+	}
+
+	payload := doc.Components.Schemas["TestItemsCreateCommand"]
+	if !reflect.DeepEqual(payload.Required, []string{"name"}) {
+		t.Errorf("Expected only name to be required, got %v", payload.Required)
+	}
+	name := payload.Properties["name"]
+	if name.Type != "string" || *name.MinLength != 2 || *name.MaxLength != 100 {
+		t.Errorf("Unexpected name schema %+v", name)
+	}
+	if payload.Properties["email"].Format != "email" {
+		t.Error("Expected email format")
+	}
+	priority := payload.Properties["priority"]
+	if priority.Type != "integer" || *priority.Minimum != 1 || *priority.Maximum != 5 {
+		t.Errorf("Unexpected priority schema %+v", priority)
+	}
+	if tags := payload.Properties["tags"]; tags.Type != "array" || tags.Items.Type != "string" {
+		t.Errorf("Unexpected tags schema %+v", tags)
+	}
+	if payload.Properties["created_at"].Format != "date-time" {
+		t.Error("Expected created_at to be a date-time string")
+	}
+	if _, ok := payload.Properties["internal"]; ok {
+		t.Error("Expected unexported fields to be skipped")
+	}
+}
+
+func TestOpenAPI_Queries(t *testing.T) {
+	doc := newOpenAPITestApp(t).OpenAPI("test", "1.0.0")
+
+	op := doc.Paths["/queries/test-items/list"]["get"]
+	if op == nil {
+		t.Fatal("Expected GET /queries/test-items/list")
+	}
+	if op.OperationID != "queryTestItemsList" {
+		t.Errorf("Unexpected operation ID %q", op.OperationID)
+	}
+	params := map[string]OpenAPIParameter{}
+	for _, p := range op.Parameters {
+		params[p.Name] = p
+	}
+	if p := params["page"]; p.In != "query" || p.Required || *p.Schema.Minimum != 1 || p.Description != "Page number" {
+		t.Errorf("Unexpected page parameter %+v", p)
+	}
+	if !params["filter"].Required {
+		t.Error("Expected filter to be required")
+	}
+
+	if got := op.Responses["200"].Content["application/json"].Schema.Ref; got != "#/components/schemas/TestItemsListResult" {
+		t.Errorf("Unexpected result schema %q", got)
+	}
+	result := doc.Components.Schemas["TestItemsListResult"]
+	for _, name := range []string{"total", "items", "cursor", "note", "tree", "Metadata"} {
+		if _, ok := result.Properties[name]; !ok {
+			t.Errorf("Expected result property %q", name)
+		}
+	}
+	if _, ok := result.Properties["Skipped"]; ok {
+		t.Error(`Expected json:"-" fields to be skipped`)
+	}
+	if !reflect.DeepEqual(result.Properties["cursor"].Type, []string{"string", "null"}) {
+		t.Errorf("Expected a nullable cursor, got %v", result.Properties["cursor"].Type)
+	}
+	if result.Properties["items"].Items.Properties["id"].Type != "string" {
+		t.Error("Expected nested structs to be described")
+	}
+	for _, name := range result.Required {
+		if name == "note" {
+			t.Error("Expected omitempty fields not to be required in results")
+		}
+	}
+
+	untyped := doc.Paths["/queries/test-items/untyped"]["get"]
+	if schema := untyped.Responses["200"].Content["application/json"].Schema; schema.Ref != "" || schema.Type != nil {
+		t.Errorf("Expected an unconstrained result for untyped queries, got %+v", schema)
+	}
+
+	if _, err := json.Marshal(doc); err != nil {
+		t.Fatalf("Failed to encode document: %v", err)
+	}
+}
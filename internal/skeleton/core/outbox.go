@@ -0,0 +1,611 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Outbox entry statuses
+const (
+	OutboxPending   = "pending"   // Waiting for (another) delivery attempt
+	OutboxDelivered = "delivered" // Delivered successfully; Result holds the response
+	OutboxFailed    = "failed"    // Gave up after a permanent error or too many attempts
+)
+
+// OutboxHTTP is the effect kind of outbound HTTP requests, see EnqueueHTTP.
+const OutboxHTTP = "http"
+
+// OutboxEntry is a side effect recorded durably in the outbox.
+type OutboxEntry struct {
+	ID            uint64            `json:"id"`
+	Key           string            `json:"key"`    // Idempotency key; enqueuing the same key twice is a no-op
+	Kind          string            `json:"kind"`   // Effect that delivers the entry, e.g. OutboxHTTP
+	Source        string            `json:"source"` // Who enqueued it, e.g. a worker name
+	Payload       json.RawMessage   `json:"payload"`
+	Reply         string            `json:"reply,omitempty"` // Result handler to call once delivered or failed
+	Meta          map[string]string `json:"meta,omitempty"`  // Context for the result handler, e.g. IDs
+	Status        string            `json:"status"`
+	Attempts      int               `json:"attempts"`
+	NextAttemptAt time.Time         `json:"next_attempt_at"`
+	LastError     string            `json:"last_error,omitempty"`
+	Result        json.RawMessage   `json:"result,omitempty"`
+	Replied       bool              `json:"replied"` // The Reply handler's command has been executed
+	CreatedAt     time.Time         `json:"created_at"`
+	CompletedAt   *time.Time        `json:"completed_at,omitempty"`
+}
+
+// OutboxItem describes a side effect to enqueue.
+type OutboxItem struct {
+	Key     string // Required idempotency key, e.g. a request ID derived from the message
+	Kind    string // Registered effect kind
+	Source  string
+	Payload any    // Marshalled to JSON and passed to the effect
+	Reply   string // Optional name of a result handler registered with OnResult
+	Meta    map[string]string
+}
+
+// EffectFunc performs a side effect for an outbox entry and returns its result.
+// Errors are retried according to the outbox's retry policy unless wrapped with Permanent.
+type EffectFunc func(ctx context.Context, entry OutboxEntry) (json.RawMessage, error)
+
+// ResultHandler turns the outcome of a delivered or failed entry into a command that
+// feeds it back into the log. Returning a nil command records nothing.
+type ResultHandler func(ctx context.Context, entry OutboxEntry) (Command, error)
+
+// CredentialsFunc returns the headers that authenticate an HTTPRequest naming it in
+// Credentials, e.g. {"Authorization": "Bearer ..."}.
+type CredentialsFunc func(ctx context.Context) (map[string]string, error)
+
+// permanentError marks an effect error as not worth retrying.
+type permanentError struct{ err error }
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so the outbox fails the entry immediately instead of retrying it.
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// HTTPRequest is the payload of an OutboxHTTP entry.
+type HTTPRequest struct {
+	Method string            `json:"method"`
+	URL    string            `json:"url"`
+	Header map[string]string `json:"header,omitempty"`
+	Body   string            `json:"body,omitempty"`
+
+	// Credentials names a CredentialsFunc registered with RegisterCredentials. Its headers,
+	// such as Authorization, are added when the request is sent, so they are never stored
+	// in the outbox.
+	Credentials string `json:"credentials,omitempty"`
+}
+
+// HTTPResponse is the result recorded for an OutboxHTTP entry.
+type HTTPResponse struct {
+	StatusCode int               `json:"status_code"`
+	Header     map[string]string `json:"header,omitempty"`
+	Body       string            `json:"body,omitempty"`
+}
+
+// Outbox stores side effects in SQLite and delivers them with retries, so that an effect
+// requested by a worker is neither lost in a crash nor repeated when the worker retries.
+type Outbox struct {
+	db       *sql.DB
+	executor *Executor
+	Client   *http.Client // Used for OutboxHTTP entries
+	policy   RetryPolicy
+
+	mu          sync.RWMutex
+	effects     map[string]EffectFunc
+	handlers    map[string]ResultHandler
+	credentials map[string]CredentialsFunc
+}
+
+// NewOutbox creates the outbox table if needed. Commands returned by result handlers
+// are executed with executor.
+func NewOutbox(db *sql.DB, executor *Executor) (*Outbox, error) {
+	o := &Outbox{
+		db:          db,
+		executor:    executor,
+		Client:      &http.Client{Timeout: 30 * time.Second},
+		policy:      DefaultRetryPolicy(),
+		effects:     make(map[string]EffectFunc),
+		handlers:    make(map[string]ResultHandler),
+		credentials: make(map[string]CredentialsFunc),
+	}
+	o.effects[OutboxHTTP] = o.deliverHTTP
+
+	if err := o.createTable(); err != nil {
+		return nil, fmt.Errorf("failed to create outbox table: %w", err)
+	}
+	return o, nil
+}
+
+func (o *Outbox) createTable() error {
+	query := `
+		CREATE TABLE IF NOT EXISTS outbox (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			key TEXT NOT NULL UNIQUE,
+			kind TEXT NOT NULL,
+			source TEXT NOT NULL,
+			payload TEXT NOT NULL,
+			reply TEXT NOT NULL,
+			meta TEXT NOT NULL DEFAULT '{}',
+			status TEXT NOT NULL,
+			attempts INTEGER NOT NULL DEFAULT 0,
+			next_attempt_at INTEGER NOT NULL,
+			last_error TEXT NOT NULL DEFAULT '',
+			result TEXT NOT NULL DEFAULT '',
+			replied INTEGER NOT NULL DEFAULT 0,
+			created_at INTEGER NOT NULL,
+			completed_at INTEGER
+		)
+	`
+	if _, err := o.db.Exec(query); err != nil {
+		return err
+	}
+	_, err := o.db.Exec("CREATE INDEX IF NOT EXISTS outbox_due ON outbox (status, next_attempt_at)")
+	return err
+}
+
+// SetRetryPolicy sets how failed deliveries are retried. The default is DefaultRetryPolicy.
+func (o *Outbox) SetRetryPolicy(policy RetryPolicy) {
+	o.policy = policy
+}
+
+// RegisterEffect registers the function that delivers entries of the given kind.
+func (o *Outbox) RegisterEffect(kind string, fn EffectFunc) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.effects[kind] = fn
+}
+
+// OnResult registers a result handler that entries can name in OutboxItem.Reply.
+func (o *Outbox) OnResult(reply string, fn ResultHandler) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.handlers[reply] = fn
+}
+
+// RegisterCredentials registers the function that authenticates HTTP requests naming it
+// in HTTPRequest.Credentials.
+func (o *Outbox) RegisterCredentials(name string, fn CredentialsFunc) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.credentials[name] = fn
+}
+
+// Enqueue records a side effect for delivery. If an entry with the same key exists it is
+// returned unchanged with created=false, so handlers can enqueue safely when retried or
+// replayed.
+func (o *Outbox) Enqueue(ctx context.Context, item OutboxItem) (entry OutboxEntry, created bool, err error) {
+	if item.Key == "" {
+		return OutboxEntry{}, false, errors.New("outbox item requires an idempotency key")
+	}
+	// A worker that lost its lease must leave side effects to the new holder
+	if err := ValidateLease(ctx); err != nil {
+		return OutboxEntry{}, false, fmt.Errorf("failed to enqueue outbox entry %s: %w", item.Key, err)
+	}
+	o.mu.RLock()
+	_, known := o.effects[item.Kind]
+	o.mu.RUnlock()
+	if !known {
+		return OutboxEntry{}, false, fmt.Errorf("unknown outbox effect kind %q", item.Kind)
+	}
+
+	payload, err := json.Marshal(item.Payload)
+	if err != nil {
+		return OutboxEntry{}, false, fmt.Errorf("failed to marshal outbox payload: %w", err)
+	}
+	meta, err := json.Marshal(item.Meta)
+	if err != nil {
+		return OutboxEntry{}, false, fmt.Errorf("failed to marshal outbox metadata: %w", err)
+	}
+
+	now := time.Now()
+	result, err := o.db.ExecContext(ctx, `
+		INSERT INTO outbox (key, kind, source, payload, reply, meta, status, next_attempt_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(key) DO NOTHING`,
+		item.Key, item.Kind, item.Source, string(payload), item.Reply, string(meta), OutboxPending, now.UnixNano(), now.UnixNano())
+	if err != nil {
+		return OutboxEntry{}, false, fmt.Errorf("failed to enqueue outbox entry %s: %w", item.Key, err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return OutboxEntry{}, false, fmt.Errorf("failed to enqueue outbox entry %s: %w", item.Key, err)
+	}
+
+	entry, err = o.getBy(ctx, "key = ?", item.Key)
+	if err != nil {
+		return OutboxEntry{}, false, err
+	}
+	if rows > 0 {
+		slog.Debug("Enqueued outbox entry", "id", entry.ID, "key", entry.Key, "kind", entry.Kind)
+	}
+	return entry, rows > 0, nil
+}
+
+// EnqueueHTTP records an outbound HTTP request. The request carries the key in an
+// Idempotency-Key header so that the receiver can drop duplicate deliveries.
+func (o *Outbox) EnqueueHTTP(ctx context.Context, key, source string, req HTTPRequest, reply string, meta map[string]string) (OutboxEntry, bool, error) {
+	return o.Enqueue(ctx, OutboxItem{Key: key, Kind: OutboxHTTP, Source: source, Payload: req, Reply: reply, Meta: meta})
+}
+
+// Dispatch delivers all entries that are due and executes the commands of result
+// handlers for completed entries. It returns the number of delivery attempts made.
+// Called under a worker lease (see ValidateLease), it stops once the lease is lost.
+func (o *Outbox) Dispatch(ctx context.Context) (int, error) {
+	due, err := o.list(ctx, "status = ? AND next_attempt_at <= ? ORDER BY id LIMIT 100", OutboxPending, time.Now().UnixNano())
+	if err != nil {
+		return 0, err
+	}
+
+	for i, entry := range due {
+		if ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
+		// Stop delivering once another process has taken over the outbox worker
+		if err := ValidateLease(ctx); err != nil {
+			return i, fmt.Errorf("failed to dispatch outbox: %w", err)
+		}
+		o.deliver(ctx, entry)
+	}
+
+	// Feed outcomes back, including those whose reply failed in an earlier run
+	completed, err := o.list(ctx, "status IN (?, ?) AND replied = 0 ORDER BY id", OutboxDelivered, OutboxFailed)
+	if err != nil {
+		return len(due), err
+	}
+	for _, entry := range completed {
+		if err := o.reply(ctx, entry); err != nil {
+			slog.Error("Failed to feed back outbox result", "id", entry.ID, "key", entry.Key, "error", err)
+		}
+	}
+	return len(due), nil
+}
+
+// deliver makes one delivery attempt and records its outcome.
+func (o *Outbox) deliver(ctx context.Context, entry OutboxEntry) {
+	o.mu.RLock()
+	effect, found := o.effects[entry.Kind]
+	o.mu.RUnlock()
+
+	var result json.RawMessage
+	err := fmt.Errorf("no effect registered for kind %q", entry.Kind)
+	if found {
+		result, err = effect(ctx, entry)
+	}
+
+	if err != nil && ctx.Err() != nil {
+		// Shutting down; the attempt doesn't count
+		return
+	}
+
+	attempts := entry.Attempts + 1
+	now := time.Now()
+	if err == nil {
+		_, dbErr := o.db.ExecContext(ctx,
+			"UPDATE outbox SET status = ?, attempts = ?, last_error = '', result = ?, completed_at = ? WHERE id = ?",
+			OutboxDelivered, attempts, string(result), now.UnixNano(), entry.ID)
+		if dbErr != nil {
+			slog.Error("Failed to record outbox delivery", "id", entry.ID, "error", dbErr)
+			return
+		}
+		slog.Info("Delivered outbox entry", "id", entry.ID, "key", entry.Key, "kind", entry.Kind, "attempts", attempts)
+		return
+	}
+
+	var permanent *permanentError
+	if errors.As(err, &permanent) || attempts >= o.policy.MaxAttempts {
+		_, dbErr := o.db.ExecContext(ctx,
+			"UPDATE outbox SET status = ?, attempts = ?, last_error = ?, result = ?, completed_at = ? WHERE id = ?",
+			OutboxFailed, attempts, err.Error(), string(result), now.UnixNano(), entry.ID)
+		if dbErr != nil {
+			slog.Error("Failed to record outbox failure", "id", entry.ID, "error", dbErr)
+			return
+		}
+		slog.Error("Outbox entry failed", "id", entry.ID, "key", entry.Key, "kind", entry.Kind, "attempts", attempts, "error", err)
+		return
+	}
+
+	next := now.Add(o.policy.Backoff(attempts))
+	_, dbErr := o.db.ExecContext(ctx,
+		"UPDATE outbox SET attempts = ?, last_error = ?, result = ?, next_attempt_at = ? WHERE id = ?",
+		attempts, err.Error(), string(result), next.UnixNano(), entry.ID)
+	if dbErr != nil {
+		slog.Error("Failed to record outbox attempt", "id", entry.ID, "error", dbErr)
+		return
+	}
+	slog.Warn("Outbox delivery failed, will retry", "id", entry.ID, "key", entry.Key, "attempt", attempts, "nextAttemptAt", next, "error", err)
+}
+
+// reply runs the entry's result handler and executes the command it returns.
+func (o *Outbox) reply(ctx context.Context, entry OutboxEntry) error {
+	if entry.Reply != "" {
+		o.mu.RLock()
+		handler, found := o.handlers[entry.Reply]
+		o.mu.RUnlock()
+		if !found {
+			return fmt.Errorf("no result handler registered for %q", entry.Reply)
+		}
+
+		cmd, err := handler(ctx, entry)
+		if err != nil {
+			return fmt.Errorf("result handler %q failed: %w", entry.Reply, err)
+		}
+		if cmd != nil {
+			if err := o.executor.Execute(ctx, cmd); err != nil {
+				return fmt.Errorf("failed to execute %s for outbox entry %d: %w", cmd.CommandName(), entry.ID, err)
+			}
+		}
+	}
+
+	if _, err := o.db.ExecContext(ctx, "UPDATE outbox SET replied = 1 WHERE id = ?", entry.ID); err != nil {
+		return fmt.Errorf("failed to mark outbox entry %d as replied: %w", entry.ID, err)
+	}
+	return nil
+}
+
+// deliverHTTP is the effect for OutboxHTTP entries. 2xx responses succeed; 408, 429 and
+// 5xx responses are retried; other responses fail the entry permanently. The response
+// is recorded in every case.
+func (o *Outbox) deliverHTTP(ctx context.Context, entry OutboxEntry) (json.RawMessage, error) {
+	return o.sendHTTP(ctx, o.Client, entry)
+}
+
+// sendHTTP sends the HTTPRequest in the payload of entry with client and handles the
+// response like deliverHTTP.
+func (o *Outbox) sendHTTP(ctx context.Context, client *http.Client, entry OutboxEntry) (json.RawMessage, error) {
+	var spec HTTPRequest
+	if err := json.Unmarshal(entry.Payload, &spec); err != nil {
+		return nil, Permanent(fmt.Errorf("invalid HTTP request payload: %w", err))
+	}
+	method := spec.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, spec.URL, bytes.NewBufferString(spec.Body))
+	if err != nil {
+		return nil, Permanent(fmt.Errorf("failed to create request: %w", err))
+	}
+	for name, value := range spec.Header {
+		req.Header.Set(name, value)
+	}
+	if spec.Credentials != "" {
+		o.mu.RLock()
+		credentials, found := o.credentials[spec.Credentials]
+		o.mu.RUnlock()
+		if !found {
+			return nil, fmt.Errorf("no credentials registered for %q", spec.Credentials)
+		}
+		header, err := credentials(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get credentials %q: %w", spec.Credentials, err)
+		}
+		for name, value := range header {
+			req.Header.Set(name, value)
+		}
+	}
+	req.Header.Set("Idempotency-Key", entry.Key)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	response := HTTPResponse{StatusCode: resp.StatusCode, Header: map[string]string{}, Body: string(body)}
+	for name := range resp.Header {
+		response.Header[name] = resp.Header.Get(name)
+	}
+	result, err := json.Marshal(response)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode response: %w", err)
+	}
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return result, nil
+	case resp.StatusCode == http.StatusRequestTimeout, resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode >= 500:
+		return result, fmt.Errorf("%s %s returned %d", method, spec.URL, resp.StatusCode)
+	default:
+		return result, Permanent(fmt.Errorf("%s %s returned %d", method, spec.URL, resp.StatusCode))
+	}
+}
+
+// Get returns the entry with the given ID.
+func (o *Outbox) Get(ctx context.Context, id uint64) (OutboxEntry, error) {
+	return o.getBy(ctx, "id = ?", id)
+}
+
+// List returns entries with the given status, or all entries if status is empty, newest first.
+func (o *Outbox) List(ctx context.Context, status string, limit int) ([]OutboxEntry, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	if status == "" {
+		return o.list(ctx, "1 = 1 ORDER BY id DESC LIMIT ?", limit)
+	}
+	return o.list(ctx, "status = ? ORDER BY id DESC LIMIT ?", status, limit)
+}
+
+// Retry makes a failed or delivered entry pending again, with its attempts reset.
+func (o *Outbox) Retry(ctx context.Context, id uint64) error {
+	result, err := o.db.ExecContext(ctx,
+		"UPDATE outbox SET status = ?, attempts = 0, next_attempt_at = ?, replied = 0, completed_at = NULL WHERE id = ?",
+		OutboxPending, time.Now().UnixNano(), id)
+	if err != nil {
+		return fmt.Errorf("failed to retry outbox entry %d: %w", id, err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("outbox entry %d not found", id)
+	}
+	return nil
+}
+
+// Counts returns the number of entries by status.
+func (o *Outbox) Counts(ctx context.Context) (map[string]int, error) {
+	rows, err := o.db.QueryContext(ctx, "SELECT status, COUNT(*) FROM outbox GROUP BY status")
+	if err != nil {
+		return nil, fmt.Errorf("failed to count outbox entries: %w", err)
+	}
+	defer rows.Close()
+
+	counts := map[string]int{OutboxPending: 0, OutboxDelivered: 0, OutboxFailed: 0}
+	for rows.Next() {
+		var status string
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan outbox count: %w", err)
+		}
+		counts[status] = count
+	}
+	return counts, rows.Err()
+}
+
+const outboxColumns = `id, key, kind, source, payload, reply, meta, status, attempts, next_attempt_at,
+	last_error, result, replied, created_at, completed_at`
+
+func (o *Outbox) getBy(ctx context.Context, where string, args ...any) (OutboxEntry, error) {
+	entry, err := scanOutboxEntry(o.db.QueryRowContext(ctx, "SELECT "+outboxColumns+" FROM outbox WHERE "+where, args...))
+	if err == sql.ErrNoRows {
+		return OutboxEntry{}, fmt.Errorf("outbox entry not found")
+	}
+	if err != nil {
+		return OutboxEntry{}, fmt.Errorf("failed to read outbox entry: %w", err)
+	}
+	return entry, nil
+}
+
+func (o *Outbox) list(ctx context.Context, where string, args ...any) ([]OutboxEntry, error) {
+	rows, err := o.db.QueryContext(ctx, "SELECT "+outboxColumns+" FROM outbox WHERE "+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query outbox: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []OutboxEntry{}
+	for rows.Next() {
+		entry, err := scanOutboxEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan outbox entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during outbox iteration: %w", err)
+	}
+	return entries, nil
+}
+
+func scanOutboxEntry(row interface{ Scan(...any) error }) (OutboxEntry, error) {
+	var entry OutboxEntry
+	var payload, meta, result string
+	var nextAttemptAt, createdAt int64
+	var completedAt sql.NullInt64
+	err := row.Scan(&entry.ID, &entry.Key, &entry.Kind, &entry.Source, &payload, &entry.Reply, &meta, &entry.Status,
+		&entry.Attempts, &nextAttemptAt, &entry.LastError, &result, &entry.Replied, &createdAt, &completedAt)
+	if err != nil {
+		return OutboxEntry{}, err
+	}
+	entry.Payload = json.RawMessage(payload)
+	if err := json.Unmarshal([]byte(meta), &entry.Meta); err != nil {
+		return OutboxEntry{}, fmt.Errorf("invalid outbox metadata: %w", err)
+	}
+	if result != "" {
+		entry.Result = json.RawMessage(result)
+	}
+	entry.NextAttemptAt = time.Unix(0, nextAttemptAt)
+	entry.CreatedAt = time.Unix(0, createdAt)
+	if completedAt.Valid {
+		t := time.Unix(0, completedAt.Int64)
+		entry.CompletedAt = &t
+	}
+	return entry, nil
+}
+
+// OutboxWorkerName is the name of the worker that dispatches the outbox.
+const OutboxWorkerName = "outbox"
+
+// outboxWorker runs Outbox.Dispatch as a regular worker, so deliveries happen in the one
+// process holding its lease and show up on the worker status page.
+type outboxWorker struct {
+	outbox *Outbox
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func (w *outboxWorker) Start(ctx context.Context) error {
+	if w.ctx != nil {
+		return ErrWorkerAlreadyStarted
+	}
+	w.ctx, w.cancel = context.WithCancel(ctx)
+	return nil
+}
+
+func (w *outboxWorker) Stop(ctx context.Context) error {
+	if w.cancel != nil {
+		w.cancel()
+	}
+	w.ctx, w.cancel = nil, nil
+	return nil
+}
+
+func (w *outboxWorker) Replay(ctx context.Context) error { return nil }
+
+func (w *outboxWorker) Work() error {
+	if w.ctx == nil {
+		return ErrWorkerStopped
+	}
+	_, err := w.outbox.Dispatch(w.ctx)
+	return err
+}
+
+func (w *outboxWorker) Schedule() Schedule { return Every(time.Second) }
+
+func (w *outboxWorker) WorkerInfo() *WorkerInfo {
+	return &WorkerInfo{
+		Name:        OutboxWorkerName,
+		Description: "Delivers side effects recorded in the outbox and feeds their results back as commands",
+	}
+}
+
+// PendingCounts reports the entries still to be delivered: "pending" for those not tried yet
+// and "retrying" for those waiting for another attempt. Delivered and failed entries are not
+// pending. It reads the database and is safe to call at any time.
+func (w *outboxWorker) PendingCounts() map[string]int {
+	var pending, retrying int
+	err := w.outbox.db.QueryRowContext(context.Background(), `
+		SELECT COALESCE(SUM(attempts = 0), 0), COALESCE(SUM(attempts > 0), 0)
+		FROM outbox WHERE status = ?
+	`, OutboxPending).Scan(&pending, &retrying)
+	if err != nil {
+		slog.Error("Failed to count pending outbox entries", "error", err)
+		return nil
+	}
+	return map[string]int{"pending": pending, "retrying": retrying}
+}
+
+// UseOutbox returns the application's outbox and registers the worker that dispatches it.
+// Features call it when they register; calling it more than once is harmless.
+func (a *App) UseOutbox() *Outbox {
+	if _, found := a.FindWorker(OutboxWorkerName); !found {
+		a.RegisterWorker(&outboxWorker{outbox: a.Outbox})
+	}
+	return a.Outbox
+}
@@ -0,0 +1,172 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/petrock/example_module_path/core/outboxtest"
+)
+
+type outboxReplyCommand struct {
+	Key    string `json:"key"`
+	Status int    `json:"status"`
+	Failed bool   `json:"failed"`
+}
+
+func (c *outboxReplyCommand) CommandName() string { return "test/outbox-reply" }
+
+func TestOutbox_DeliversWithRetriesAndFeedsBackResults(t *testing.T) {
+	app := newTestApp(t)
+	ctx := context.Background()
+
+	var replies []*outboxReplyCommand
+	app.CommandRegistry.Register(&outboxReplyCommand{}, func(ctx context.Context, cmd Command, msg *Message, pctx *ProcessingContext) error {
+		replies = append(replies, cmd.(*outboxReplyCommand))
+		return nil
+	}, streamAcceptAll{})
+	app.MessageLog.RegisterType(&outboxReplyCommand{})
+
+	outbox := app.UseOutbox()
+	outbox.SetRetryPolicy(RetryPolicy{MaxAttempts: 3})
+	outbox.OnResult("test/reply", func(ctx context.Context, entry OutboxEntry) (Command, error) {
+		var response HTTPResponse
+		if err := json.Unmarshal(entry.Result, &response); err != nil {
+			return nil, err
+		}
+		return &outboxReplyCommand{Key: entry.Meta["key"], Status: response.StatusCode, Failed: entry.Status == OutboxFailed}, nil
+	})
+	if _, found := app.FindWorker(OutboxWorkerName); !found {
+		t.Fatal("Expected UseOutbox to register the dispatcher worker")
+	}
+
+	server := outboxtest.NewServer(t,
+		outboxtest.Response{Status: http.StatusServiceUnavailable},
+		outboxtest.Response{Status: http.StatusOK, Body: `{"ok":true}`},
+		outboxtest.Response{Status: http.StatusBadRequest, Body: "bad input"},
+	)
+
+	request := HTTPRequest{Method: http.MethodPost, URL: server.URL + "/hook", Body: `{"n":1}`}
+	entry, created, err := outbox.EnqueueHTTP(ctx, "first", "test", request, "test/reply", map[string]string{"key": "first"})
+	if err != nil || !created {
+		t.Fatalf("EnqueueHTTP failed: created=%v err=%v", created, err)
+	}
+	if _, created, _ := outbox.EnqueueHTTP(ctx, "first", "test", request, "test/reply", nil); created {
+		t.Error("Expected enqueuing the same key twice to be a no-op")
+	}
+
+	// 503 is retried, then delivered
+	for i := 0; i < 2; i++ {
+		if _, err := outbox.Dispatch(ctx); err != nil {
+			t.Fatalf("Dispatch failed: %v", err)
+		}
+	}
+	delivered, err := outbox.Get(ctx, entry.ID)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if delivered.Status != OutboxDelivered || delivered.Attempts != 2 || !delivered.Replied {
+		t.Fatalf("Unexpected entry after delivery: %+v", delivered)
+	}
+	requests := server.Requests()
+	if len(requests) != 2 || requests[1].Path != "/hook" || requests[1].Body != `{"n":1}` || requests[1].Header.Get("Idempotency-Key") != "first" {
+		t.Errorf("Unexpected requests: %+v", requests)
+	}
+	if len(replies) != 1 || replies[0].Key != "first" || replies[0].Status != http.StatusOK || replies[0].Failed {
+		t.Errorf("Unexpected replies: %+v", replies)
+	}
+
+	// 400 fails permanently on the first attempt, and the failure is fed back too
+	second, _, err := outbox.EnqueueHTTP(ctx, "second", "test", request, "test/reply", map[string]string{"key": "second"})
+	if err != nil {
+		t.Fatalf("EnqueueHTTP failed: %v", err)
+	}
+	if _, err := outbox.Dispatch(ctx); err != nil {
+		t.Fatalf("Dispatch failed: %v", err)
+	}
+	failed, _ := outbox.Get(ctx, second.ID)
+	if failed.Status != OutboxFailed || failed.Attempts != 1 {
+		t.Errorf("Expected permanent failure after one attempt, got %+v", failed)
+	}
+	if len(replies) != 2 || !replies[1].Failed || replies[1].Status != http.StatusBadRequest {
+		t.Errorf("Expected failure to be fed back, got %+v", replies)
+	}
+
+	counts, err := outbox.Counts(ctx)
+	if err != nil {
+		t.Fatalf("Counts failed: %v", err)
+	}
+	if counts[OutboxDelivered] != 1 || counts[OutboxFailed] != 1 || counts[OutboxPending] != 0 {
+		t.Errorf("Unexpected counts: %v", counts)
+	}
+	worker, _ := app.FindWorker(OutboxWorkerName)
+	if pending := worker.(*outboxWorker).PendingCounts(); pending["pending"] != 0 || pending["retrying"] != 0 || len(pending) != 2 {
+		t.Errorf("Expected delivered and failed entries not to be pending, got %v", pending)
+	}
+
+	// A retried entry is delivered again (the server keeps answering 400)
+	if err := outbox.Retry(ctx, second.ID); err != nil {
+		t.Fatalf("Retry failed: %v", err)
+	}
+	if pending, _ := outbox.List(ctx, OutboxPending, 10); len(pending) != 1 || pending[0].ID != second.ID {
+		t.Errorf("Expected retried entry to be pending, got %+v", pending)
+	}
+}
+
+func TestOutbox_BacksOffBetweenAttempts(t *testing.T) {
+	app := newTestApp(t)
+	ctx := context.Background()
+	outbox := app.Outbox
+	outbox.SetRetryPolicy(RetryPolicy{MaxAttempts: 5, InitialBackoff: time.Hour, Multiplier: 2})
+
+	server := outboxtest.NewServer(t, outboxtest.Response{Status: http.StatusInternalServerError})
+	if _, _, err := outbox.EnqueueHTTP(ctx, "slow", "test", HTTPRequest{URL: server.URL}, "", nil); err != nil {
+		t.Fatalf("EnqueueHTTP failed: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := outbox.Dispatch(ctx); err != nil {
+			t.Fatalf("Dispatch failed: %v", err)
+		}
+	}
+	if got := len(server.Requests()); got != 1 {
+		t.Errorf("Expected a single attempt before the backoff elapses, got %d", got)
+	}
+}
+
+func TestOutbox_AddsCredentialsWhenSending(t *testing.T) {
+	app := newTestApp(t)
+	ctx := context.Background()
+	outbox := app.UseOutbox()
+	outbox.RegisterCredentials("test/api", func(ctx context.Context) (map[string]string, error) {
+		return map[string]string{"Authorization": "Bearer secret-key"}, nil
+	})
+	server := outboxtest.NewServer(t, outboxtest.Response{Status: http.StatusOK})
+
+	request := HTTPRequest{Method: http.MethodPost, URL: server.URL, Credentials: "test/api"}
+	entry, _, err := outbox.EnqueueHTTP(ctx, "authenticated", "test", request, "", nil)
+	if err != nil {
+		t.Fatalf("EnqueueHTTP failed: %v", err)
+	}
+	if _, err := outbox.Dispatch(ctx); err != nil {
+		t.Fatalf("Dispatch failed: %v", err)
+	}
+	if requests := server.Requests(); len(requests) != 1 || requests[0].Header.Get("Authorization") != "Bearer secret-key" {
+		t.Errorf("Expected the credentials to be sent, got %+v", requests)
+	}
+	stored, _ := outbox.Get(ctx, entry.ID)
+	if strings.Contains(string(stored.Payload), "secret-key") {
+		t.Errorf("Expected the credentials not to be stored, got %s", stored.Payload)
+	}
+
+	// Requests naming unregistered credentials are retried, not sent without them
+	request.Credentials = "test/unknown"
+	entry, _, _ = outbox.EnqueueHTTP(ctx, "unknown", "test", request, "", nil)
+	outbox.Dispatch(ctx)
+	if pending, _ := outbox.Get(ctx, entry.ID); pending.Status != OutboxPending || len(server.Requests()) != 1 {
+		t.Errorf("Expected the request to wait for its credentials, got %+v", pending)
+	}
+}
@@ -0,0 +1,84 @@
+// Package outboxtest provides a local HTTP server for testing code that delivers
+// outbox entries, without reaching external services.
+package outboxtest
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// Request is a request received by the Server.
+type Request struct {
+	Method string
+	Path   string
+	Header http.Header
+	Body   string
+}
+
+// Response is a scripted answer of the Server.
+type Response struct {
+	Status int // Defaults to 200
+	Header map[string]string
+	Body   string
+}
+
+// Server is an httptest.Server that records every request and answers with scripted
+// responses in order. Once the script is exhausted, the last response is repeated;
+// without a script every request gets an empty 200 response.
+type Server struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	requests  []Request
+	responses []Response
+	last      Response
+}
+
+// NewServer starts a Server that answers with the given responses. It is closed when the test ends.
+func NewServer(t testing.TB, responses ...Response) *Server {
+	t.Helper()
+	s := &Server{responses: responses, last: Response{Status: http.StatusOK}}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Respond appends responses to the script.
+func (s *Server) Respond(responses ...Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responses = append(s.responses, responses...)
+}
+
+// Requests returns the requests received so far.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	s.mu.Lock()
+	s.requests = append(s.requests, Request{Method: r.Method, Path: r.URL.Path, Header: r.Header.Clone(), Body: string(body)})
+	if len(s.responses) > 0 {
+		s.last = s.responses[0]
+		s.responses = s.responses[1:]
+	}
+	response := s.last
+	s.mu.Unlock()
+
+	for name, value := range response.Header {
+		w.Header().Set(name, value)
+	}
+	status := response.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.WriteHeader(status)
+	io.WriteString(w, response.Body)
+}
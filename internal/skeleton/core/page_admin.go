@@ -0,0 +1,229 @@
+package core
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	g "maragu.dev/gomponents"
+	"maragu.dev/gomponents/html"
+
+	"github.com/petrock/example_module_path/core/ui"
+)
+
+// adminKVLimit is the number of KV entries the KV browser shows at most.
+const adminKVLimit = 500
+
+// AdminIndexPage renders the start page of the admin area: the size of the log and
+// links to a form for every registered command and query.
+func AdminIndexPage(version uint64, counts map[string]int, commandNames, queryNames []string) g.Node {
+	return ui.Container(ui.ContainerProps{Variant: "wide"},
+		adminNav(""),
+		ui.Section(ui.SectionProps{Heading: "Admin", Level: 1},
+			html.P(
+				ui.CSSClass("text-gray-600", "mb-6"),
+				g.Textf("The log holds %d messages of %d types. ", version, len(counts)),
+				html.A(html.Href(AdminPath+"/log"), ui.CSSClass("text-blue-600", "hover:underline"), g.Text("Browse the log")),
+				g.Text("."),
+			),
+			html.Div(
+				ui.CSSClass("grid", "grid-cols-1", "md:grid-cols-2", "gap-4"),
+				adminNameList("Commands", "No commands are registered.", AdminPath+"/commands/", commandNames, counts),
+				adminNameList("Queries", "No queries are registered.", AdminPath+"/queries/", queryNames, nil),
+			),
+		),
+	)
+}
+
+// adminNameList renders a card linking each name to prefix+name, with the number of
+// messages of that type if counts is set.
+func adminNameList(title, empty, prefix string, names []string, counts map[string]int) g.Node {
+	var body g.Node = ui.Alert(ui.AlertProps{Type: "info", Message: empty})
+	if len(names) > 0 {
+		items := make([]g.Node, len(names))
+		for i, name := range names {
+			items[i] = html.Li(
+				ui.CSSClass("flex", "items-center", "gap-2"),
+				html.A(html.Href(prefix+name), ui.CSSClass("text-blue-600", "hover:underline", "mr-auto"), html.Code(g.Text(name))),
+				ui.If(counts != nil, ui.Badge(ui.BadgeProps{Variant: "secondary", Size: "small"}, g.Textf("%d", counts[name]))),
+			)
+		}
+		body = html.Ul(ui.CSSClass("space-y-1", "text-sm"), g.Group(items))
+	}
+
+	return ui.Card(ui.CardProps{Variant: "outlined"},
+		ui.CardHeader(html.H2(ui.CSSClass("text-lg", "font-semibold"), g.Text(title))),
+		ui.CardBody(body),
+	)
+}
+
+// adminNav renders the links between the pages of the admin area. current is the
+// path below AdminPath of the page shown, such as "/log".
+func adminNav(current string) g.Node {
+	pages := []struct{ path, label string }{
+		{"", "Overview"},
+		{"/log", "Log"},
+		{"/kv", "KV store"},
+		{"/workers", "Workers"},
+		{"/webhooks", "Webhooks"},
+	}
+	links := make([]g.Node, len(pages))
+	for i, page := range pages {
+		classes := []string{"px-3", "py-1", "rounded-md", "text-sm"}
+		if page.path == current {
+			classes = append(classes, "bg-blue-600", "text-white")
+		} else {
+			classes = append(classes, "text-blue-600", "hover:bg-blue-50")
+		}
+		links[i] = html.A(html.Href(AdminPath+page.path), ui.CSSClass(classes...), g.Text(page.label))
+	}
+	return html.Nav(ui.CSSClass("flex", "flex-wrap", "gap-2", "mb-6"), g.Group(links))
+}
+
+// adminJSON renders v as indented JSON.
+func adminJSON(v any) g.Node {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return g.Textf("%v", v)
+	}
+	return adminPre(string(data))
+}
+
+// adminPre renders preformatted text such as JSON.
+func adminPre(text string) g.Node {
+	return html.Pre(ui.CSSClass("bg-gray-50", "border", "border-gray-200", "rounded", "p-2", "text-xs", "overflow-x-auto"), g.Text(text))
+}
+
+// adminTable renders a table with a header row.
+func adminTable(headers []string, rows []g.Node) g.Node {
+	headerCells := make([]g.Node, len(headers))
+	for i, header := range headers {
+		headerCells[i] = html.Th(ui.CSSClass("text-left", "font-medium", "text-gray-700", "px-2", "py-1"), g.Text(header))
+	}
+	return html.Table(
+		ui.CSSClass("w-full", "text-sm"),
+		html.THead(html.Tr(headerCells...)),
+		html.TBody(rows...),
+	)
+}
+
+// renderAdminPage writes page in the layout with the given status.
+func renderAdminPage(w http.ResponseWriter, status int, title string, page g.Node) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := ui.Layout(title+" - Admin - petrock_example_project_name", page).Render(w); err != nil {
+		slog.Error("Failed to render admin page", "title", title, "error", err)
+	}
+}
+
+// HandleAdminIndex creates an http.HandlerFunc for the start page of the admin area.
+func HandleAdminIndex(app *App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		version, err := app.MessageLog.Version(r.Context())
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Failed to read log version", "error", err)
+			WriteErrorPage(w, r, http.StatusInternalServerError)
+			return
+		}
+		counts, err := app.MessageLog.TypeCounts(r.Context())
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Failed to count messages", "error", err)
+			WriteErrorPage(w, r, http.StatusInternalServerError)
+			return
+		}
+
+		page := AdminIndexPage(version, counts, app.CommandRegistry.RegisteredCommandNames(), app.QueryRegistry.RegisteredQueryNames())
+		renderAdminPage(w, http.StatusOK, "Overview", page)
+	}
+}
+
+// AdminKVPage renders the KV entries whose key starts with prefix. truncated reports
+// that there are more entries than shown. Entries without a Value are secret and are
+// shown as hidden.
+func AdminKVPage(prefix string, entries []KVEntry, truncated bool) g.Node {
+	content := []g.Node{
+		html.Form(
+			html.Method("get"),
+			html.Action(AdminPath+"/kv"),
+			ui.CSSClass("flex", "gap-2", "mb-6"),
+			ui.TextInput(ui.TextInputProps{Type: "search", Name: "prefix", Value: prefix, Placeholder: "Key prefix, such as worker/"}),
+			ui.Button(ui.ButtonProps{Variant: "primary", Type: "submit"}, g.Text("Filter")),
+		),
+	}
+
+	if len(entries) == 0 {
+		content = append(content, ui.Alert(ui.AlertProps{Type: "info", Message: "No keys match."}))
+	} else {
+		rows := make([]g.Node, len(entries))
+		for i, entry := range entries {
+			expires := "never"
+			if entry.ExpiresAt != nil {
+				expires = entry.ExpiresAt.Format(time.DateTime)
+			}
+			rows[i] = html.Tr(
+				ui.CSSClass("border-t", "border-gray-200", "align-top"),
+				adminCell(html.Code(g.Text(entry.Key))),
+				adminCell(g.Textf("%d", entry.Version)),
+				adminCell(g.Text(expires)),
+				adminCell(adminKVValue(entry)),
+			)
+		}
+		content = append(content, adminTable([]string{"Key", "Version", "Expires", "Value"}, rows))
+	}
+	if truncated {
+		content = append(content, html.P(
+			ui.CSSClass("text-gray-600", "mt-4"),
+			g.Textf("Only the first %d keys are shown; use a longer prefix to see the others.", adminKVLimit),
+		))
+	}
+
+	return ui.Container(ui.ContainerProps{Variant: "wide"},
+		adminNav("/kv"),
+		ui.Section(ui.SectionProps{Heading: "KV store", Level: 1}, content...),
+	)
+}
+
+// adminKVValue renders the value of a KV entry, or a note for secret ones.
+func adminKVValue(entry KVEntry) g.Node {
+	if entry.Value == nil {
+		return html.Span(ui.CSSClass("text-gray-500", "italic"), g.Text("Secret, hidden"))
+	}
+	return adminJSON(entry.Value)
+}
+
+func adminCell(children ...g.Node) g.Node {
+	return html.Td(append([]g.Node{ui.CSSClass("px-2", "py-1")}, children...)...)
+}
+
+// HandleAdminKV creates an http.HandlerFunc for the KV store browser. The prefix query
+// parameter limits the keys shown. Values of keys registered with RegisterSecretKeys
+// are left out.
+func HandleAdminKV(app *App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		prefix := r.URL.Query().Get("prefix")
+		var entries []KVEntry
+		var err error
+		if scanner, ok := app.KVStore.(KVLimitedScanner); ok {
+			// One more than shown tells whether there are others
+			entries, err = scanner.ScanLimit(prefix, adminKVLimit+1)
+		} else {
+			entries, err = app.KVStore.Scan(prefix)
+		}
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Failed to scan KV store", "prefix", prefix, "error", err)
+			WriteErrorPage(w, r, http.StatusInternalServerError)
+			return
+		}
+		truncated := len(entries) > adminKVLimit
+		if truncated {
+			entries = entries[:adminKVLimit]
+		}
+		for i := range entries {
+			if app.secretKey(entries[i].Key) {
+				entries[i].Value = nil
+			}
+		}
+		renderAdminPage(w, http.StatusOK, "KV store", AdminKVPage(prefix, entries, truncated))
+	}
+}
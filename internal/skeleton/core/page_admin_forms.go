@@ -0,0 +1,268 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+
+	g "maragu.dev/gomponents"
+	"maragu.dev/gomponents/html"
+
+	"github.com/petrock/example_module_path/core/ui"
+)
+
+// adminField is a field of a command or query as set by the admin forms.
+type adminField struct {
+	Name  string      // Form and JSON name
+	Def   PropertyDef // Type and description, as in the inspect schema
+	Rules string      // The validate tag
+}
+
+// adminFormFields returns the fields of a command or query struct in declaration
+// order, named and described like in CommandSchema.
+func adminFormFields(t reflect.Type) []adminField {
+	var fields []adminField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name := field.Name
+		if jsonTag := field.Tag.Get("json"); jsonTag != "" {
+			parts := strings.Split(jsonTag, ",")
+			if parts[0] == "-" {
+				continue
+			}
+			if parts[0] != "" {
+				name = parts[0]
+			}
+		}
+		fields = append(fields, adminField{Name: name, Def: buildPropertyDef(field), Rules: field.Tag.Get("validate")})
+	}
+	return fields
+}
+
+// adminFormValues returns the values of fields submitted in form, splitting array
+// fields, which are edited one value per line, into their values.
+func adminFormValues(fields []adminField, form url.Values) url.Values {
+	values := url.Values{}
+	for _, field := range fields {
+		if field.Def.Type != "array" {
+			if vals, ok := form[field.Name]; ok {
+				values[field.Name] = vals
+			}
+			continue
+		}
+		for _, line := range strings.Split(form.Get(field.Name), "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				values.Add(field.Name, line)
+			}
+		}
+	}
+	return values
+}
+
+// adminFormErrors converts the field errors of a ParseErrors for the form inputs.
+func adminFormErrors(err error) []ui.ParseError {
+	var parseErrors *ParseErrors
+	if !errors.As(err, &parseErrors) {
+		return nil
+	}
+	fieldErrors := make([]ui.ParseError, len(parseErrors.Errors))
+	for i, e := range parseErrors.Errors {
+		fieldErrors[i] = ui.ParseError{Field: e.Field, Message: e.Message, Code: e.Code, Meta: e.Meta}
+	}
+	return fieldErrors
+}
+
+// adminFieldInputs renders an input for every field, filled in from form.
+func adminFieldInputs(fields []adminField, form *ui.FormData) g.Node {
+	inputs := make([]g.Node, len(fields))
+	for i, field := range fields {
+		help := field.Def.Type
+		if field.Def.Format != "" {
+			help += ", " + field.Def.Format
+		}
+		if field.Rules != "" {
+			help += ", " + field.Rules
+		}
+		if field.Def.Description != "" {
+			help = field.Def.Description + " (" + help + ")"
+		}
+
+		var input g.Node
+		switch field.Def.Type {
+		case "boolean":
+			input = ui.Checkbox(ui.CheckboxProps{
+				ID:      field.Name,
+				Name:    field.Name,
+				Value:   "true",
+				Label:   field.Name,
+				Checked: form.Get(field.Name) == "true",
+			})
+		case "array":
+			help += ", one value per line"
+			var value string
+			if form.Values != nil {
+				value = strings.Join(form.Values[field.Name], "\n")
+			}
+			input = ui.TextAreaWithValidation(form, ui.TextAreaProps{Name: field.Name, Value: value, Rows: 3})
+		case "integer":
+			input = ui.TextInputWithValidation(form, ui.TextInputProps{Type: "number", Name: field.Name})
+		default:
+			props := ui.TextInputProps{Name: field.Name}
+			if field.Def.Format == "date-time" {
+				props.Placeholder = "2006-01-02T15:04:05Z"
+			}
+			input = ui.TextInputWithValidation(form, props)
+		}
+		inputs[i] = ui.FormGroupWithValidation(form, field.Name, field.Name, input, help)
+	}
+	return g.Group(inputs)
+}
+
+// AdminCommandPage renders a form executing the command name, generated from the
+// fields of its type. outcome is shown above the form after it was submitted.
+func AdminCommandPage(ctx context.Context, name string, cmdType reflect.Type, form *ui.FormData, outcome g.Node) g.Node {
+	return ui.Container(ui.ContainerProps{Variant: "default"},
+		adminNav(""),
+		ui.Section(ui.SectionProps{Heading: "Command " + name, Level: 1},
+			html.P(
+				ui.CSSClass("text-gray-600", "mb-6"),
+				g.Text("Executed through the Executor like "),
+				html.Code(g.Text("POST /commands")),
+				g.Text(", so it is validated and logged as you."),
+			),
+			ui.If(outcome != nil, html.Div(ui.CSSClass("mb-6"), outcome)),
+			html.Form(
+				html.Method("post"),
+				html.Action(AdminPath+"/commands/"+name),
+				ui.CSSClass("space-y-4"),
+				ui.CSRFField(ctx),
+				adminFieldInputs(adminFormFields(cmdType), form),
+				ui.Button(ui.ButtonProps{Variant: "primary", Type: "submit"}, g.Text("Execute")),
+			),
+		),
+	)
+}
+
+// HandleAdminCommand creates an http.HandlerFunc for the form of the command named
+// by the name path value. GET shows the form; POST executes the command.
+func HandleAdminCommand(app *App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue("name")
+		cmdType, found := app.CommandRegistry.GetCommandType(name)
+		if !found {
+			WriteErrorPageMessage(w, r, http.StatusNotFound, fmt.Sprintf("There is no command %q.", name))
+			return
+		}
+		fields := adminFormFields(cmdType)
+		title := "Command " + name
+
+		if r.Method != http.MethodPost {
+			renderAdminPage(w, http.StatusOK, title, AdminCommandPage(r.Context(), name, cmdType, ui.NewFormData(nil, nil), nil))
+			return
+		}
+
+		if err := r.ParseForm(); err != nil {
+			WriteErrorPageMessage(w, r, http.StatusBadRequest, "The form could not be read.")
+			return
+		}
+		values := adminFormValues(fields, r.PostForm)
+		cmdPtr := reflect.New(cmdType)
+		if err := ParseFromURLValues(values, cmdPtr.Interface()); err != nil {
+			outcome := ui.Alert(ui.AlertProps{Type: "error", Title: "Invalid fields", Message: err.Error()})
+			page := AdminCommandPage(r.Context(), name, cmdType, ui.NewFormData(values, adminFormErrors(err)), outcome)
+			renderAdminPage(w, http.StatusBadRequest, title, page)
+			return
+		}
+		cmd, ok := cmdPtr.Interface().(Command)
+		if !ok {
+			slog.ErrorContext(r.Context(), "Registered command type does not implement core.Command", "name", name, "type", cmdType)
+			WriteErrorPage(w, r, http.StatusInternalServerError)
+			return
+		}
+
+		status := http.StatusOK
+		outcome := ui.Alert(ui.AlertProps{Type: "success", Title: "Executed", Message: name + " was executed and logged."})
+		if err := app.Executor.Execute(r.Context(), cmd); err != nil {
+			status = http.StatusInternalServerError
+			var validationErr *ValidationError
+			if _, limited := RateLimited(err); limited {
+				status = http.StatusTooManyRequests
+			} else if errors.As(err, &validationErr) {
+				status = http.StatusBadRequest
+			} else {
+				slog.ErrorContext(r.Context(), "Failed to execute command from the admin area", "name", name, "error", err)
+			}
+			outcome = ui.Alert(ui.AlertProps{Type: "error", Title: "Not executed", Message: err.Error()})
+		}
+		renderAdminPage(w, status, title, AdminCommandPage(r.Context(), name, cmdType, ui.NewFormData(values, nil), outcome))
+	}
+}
+
+// AdminQueryPage renders a form running the query name, generated from the fields of
+// its type, and the result of the last run if there is one.
+func AdminQueryPage(name string, queryType reflect.Type, form *ui.FormData, result g.Node) g.Node {
+	return ui.Container(ui.ContainerProps{Variant: "default"},
+		adminNav(""),
+		ui.Section(ui.SectionProps{Heading: "Query " + name, Level: 1},
+			html.Form(
+				html.Method("get"),
+				html.Action(AdminPath+"/queries/"+name),
+				ui.CSSClass("space-y-4", "mb-6"),
+				html.Input(html.Type("hidden"), html.Name("run"), html.Value("1")),
+				adminFieldInputs(adminFormFields(queryType), form),
+				ui.Button(ui.ButtonProps{Variant: "primary", Type: "submit"}, g.Text("Run")),
+			),
+			ui.If(result != nil, result),
+		),
+	)
+}
+
+// HandleAdminQuery creates an http.HandlerFunc for the form of the query named by the
+// name path value. The form is submitted with GET; the run parameter dispatches it.
+func HandleAdminQuery(app *App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue("name")
+		queryType, found := app.QueryRegistry.GetQueryType(name)
+		if !found {
+			WriteErrorPageMessage(w, r, http.StatusNotFound, fmt.Sprintf("There is no query %q.", name))
+			return
+		}
+		fields := adminFormFields(queryType)
+		title := "Query " + name
+
+		if !r.URL.Query().Has("run") {
+			renderAdminPage(w, http.StatusOK, title, AdminQueryPage(name, queryType, ui.NewFormData(nil, nil), nil))
+			return
+		}
+
+		values := adminFormValues(fields, r.URL.Query())
+		query, err := app.QueryRegistry.NewQuery(name, URLValuesSource{Values: values})
+		if err != nil {
+			result := ui.Alert(ui.AlertProps{Type: "error", Title: "Invalid fields", Message: err.Error()})
+			renderAdminPage(w, http.StatusBadRequest, title, AdminQueryPage(name, queryType, ui.NewFormData(values, adminFormErrors(err)), result))
+			return
+		}
+
+		status := http.StatusOK
+		var result g.Node
+		if queryResult, err := app.QueryRegistry.Dispatch(r.Context(), query); err != nil {
+			slog.ErrorContext(r.Context(), "Failed to run query from the admin area", "name", name, "error", err)
+			status = http.StatusInternalServerError
+			result = ui.Alert(ui.AlertProps{Type: "error", Title: "Query failed", Message: err.Error()})
+		} else {
+			result = html.Div(
+				html.H2(ui.CSSClass("text-lg", "font-semibold", "mb-2"), g.Text("Result")),
+				adminJSON(queryResult),
+			)
+		}
+		renderAdminPage(w, status, title, AdminQueryPage(name, queryType, ui.NewFormData(values, nil), result))
+	}
+}
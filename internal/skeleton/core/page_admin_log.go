@@ -0,0 +1,239 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	g "maragu.dev/gomponents"
+	"maragu.dev/gomponents/html"
+
+	"github.com/petrock/example_module_path/core/ui"
+)
+
+// adminLogPageSize is the number of messages the log browser shows per page.
+const adminLogPageSize = 50
+
+// AdminLogPage renders the messages matching filter, newest first, with the number
+// of messages of each type in the whole log. A link to older messages is shown if
+// the page is full.
+func AdminLogPage(filter LogFilter, counts map[string]int, messages []PersistedMessage) g.Node {
+	typeOptions := []g.Node{html.Option(html.Value(""), g.Text("All types"))}
+	countRows := make([]g.Node, 0, len(counts))
+	for _, typeName := range sortedTypeCounts(counts) {
+		typeOptions = append(typeOptions, html.Option(
+			html.Value(typeName),
+			ui.If(typeName == filter.Type, html.Selected()),
+			g.Textf("%s (%d)", typeName, counts[typeName]),
+		))
+		countRows = append(countRows, html.Tr(
+			ui.CSSClass("border-t", "border-gray-200"),
+			adminCell(html.A(
+				html.Href(adminLogURL(LogFilter{Type: typeName})),
+				ui.CSSClass("text-blue-600", "hover:underline"),
+				html.Code(g.Text(typeName)),
+			)),
+			adminCell(g.Textf("%d", counts[typeName])),
+		))
+	}
+
+	content := []g.Node{
+		html.Form(
+			html.Method("get"),
+			html.Action(AdminPath+"/log"),
+			ui.CSSClass("flex", "flex-wrap", "gap-2", "mb-6"),
+			html.Select(html.Name("type"), ui.CSSClass("px-3", "py-2", "border", "rounded-md", "text-sm"), g.Group(typeOptions)),
+			ui.TextInput(ui.TextInputProps{Type: "search", Name: "q", Value: filter.Text, Placeholder: "Search types and payloads"}),
+			ui.Button(ui.ButtonProps{Variant: "primary", Type: "submit"}, g.Text("Search")),
+		),
+	}
+
+	var results []g.Node
+	if len(messages) == 0 {
+		results = append(results, ui.Alert(ui.AlertProps{Type: "info", Message: "No messages match."}))
+	} else {
+		for _, msg := range messages {
+			results = append(results, adminMessageCard(msg))
+		}
+	}
+	if len(messages) == adminLogPageSize {
+		older := filter
+		older.BeforeID = messages[len(messages)-1].ID
+		results = append(results, html.A(
+			html.Href(adminLogURL(older)),
+			ui.CSSClass("text-blue-600", "hover:underline"),
+			g.Text("Older messages"),
+		))
+	}
+
+	content = append(content, html.Div(
+		ui.CSSClass("grid", "grid-cols-1", "lg:grid-cols-4", "gap-6"),
+		html.Div(ui.CSSClass("lg:col-span-3", "space-y-4"), g.Group(results)),
+		html.Div(
+			html.H2(ui.CSSClass("text-lg", "font-semibold", "mb-2"), g.Text("Messages by type")),
+			adminTable([]string{"Type", "Count"}, countRows),
+		),
+	))
+
+	return ui.Container(ui.ContainerProps{Variant: "wide"},
+		adminNav("/log"),
+		ui.Section(ui.SectionProps{Heading: "Message log", Level: 1}, content...),
+	)
+}
+
+// adminMessageCard renders a message with its metadata and payload. Payload fields
+// tagged secret:"true", such as password hashes, are masked. Messages of unregistered
+// types show the payload as stored.
+func adminMessageCard(msg PersistedMessage) g.Node {
+	payload := adminPre(string(msg.Data))
+	goType := "not registered"
+	if msg.DecodedPayload != nil {
+		payload = adminJSON(maskSecretFields(msg.DecodedPayload))
+		goType = fmt.Sprintf("%T", msg.DecodedPayload)
+	} else {
+		var indented bytes.Buffer
+		if json.Indent(&indented, msg.Data, "", "  ") == nil {
+			payload = adminPre(indented.String())
+		}
+	}
+
+	rows := []g.Node{
+		workerStatusRow("Time", g.Text(msg.Timestamp.Format(time.RFC3339Nano))),
+		workerStatusRow("Go type", html.Code(g.Text(goType))),
+	}
+	if msg.TraceID != "" {
+		rows = append(rows, workerStatusRow("Trace", html.Code(g.Textf("%s / %s", msg.TraceID, msg.SpanID))))
+	}
+
+	return ui.Card(ui.CardProps{Variant: "outlined"},
+		ui.CardHeader(
+			html.Div(
+				ui.CSSClass("flex", "items-center", "gap-2"),
+				html.H2(ui.CSSClass("text-lg", "font-semibold"), g.Textf("#%d", msg.ID)),
+				html.A(
+					html.Href(adminLogURL(LogFilter{Type: msg.Type})),
+					ui.Badge(ui.BadgeProps{Variant: "info", Size: "small"}, g.Text(msg.Type)),
+				),
+			),
+		),
+		ui.CardBody(
+			html.Dl(ui.CSSClass("grid", "grid-cols-2", "gap-x-4", "gap-y-1", "text-sm", "mb-4"), g.Group(rows)),
+			payload,
+		),
+	)
+}
+
+// maskSecretFields returns v as a JSON object with the values of its fields tagged
+// secret:"true" replaced by asterisks, or v itself if it has no such fields.
+func maskSecretFields(v any) any {
+	t := reflect.TypeOf(v)
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return v
+	}
+	var secret []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Tag.Get("secret") != "true" {
+			continue
+		}
+		name := field.Name
+		if jsonName, _, _ := strings.Cut(field.Tag.Get("json"), ","); jsonName != "" {
+			name = jsonName
+		}
+		secret = append(secret, name)
+	}
+	if len(secret) == 0 {
+		return v
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return v
+	}
+	for _, name := range secret {
+		if _, ok := fields[name]; ok {
+			fields[name] = "********"
+		}
+	}
+	return fields
+}
+
+// sortedTypeCounts returns the types of counts, most frequent first.
+func sortedTypeCounts(counts map[string]int) []string {
+	types := make([]string, 0, len(counts))
+	for typeName := range counts {
+		types = append(types, typeName)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		if counts[types[i]] != counts[types[j]] {
+			return counts[types[i]] > counts[types[j]]
+		}
+		return types[i] < types[j]
+	})
+	return types
+}
+
+// adminLogURL returns the URL of the log browser showing filter.
+func adminLogURL(filter LogFilter) string {
+	query := url.Values{}
+	if filter.Type != "" {
+		query.Set("type", filter.Type)
+	}
+	if filter.Text != "" {
+		query.Set("q", filter.Text)
+	}
+	if filter.BeforeID > 0 {
+		query.Set("before", strconv.FormatUint(filter.BeforeID, 10))
+	}
+	if len(query) == 0 {
+		return AdminPath + "/log"
+	}
+	return AdminPath + "/log?" + query.Encode()
+}
+
+// HandleAdminLog creates an http.HandlerFunc for the log browser. The type, q and
+// before query parameters set the Type, Text and BeforeID of the LogFilter.
+func HandleAdminLog(app *App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		filter := LogFilter{Type: query.Get("type"), Text: query.Get("q"), Limit: adminLogPageSize}
+		if before := query.Get("before"); before != "" {
+			id, err := strconv.ParseUint(before, 10, 64)
+			if err != nil {
+				WriteErrorPageMessage(w, r, http.StatusBadRequest, "before must be a message ID.")
+				return
+			}
+			filter.BeforeID = id
+		}
+
+		messages, err := app.MessageLog.Search(r.Context(), filter)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Failed to search the log", "error", err)
+			WriteErrorPage(w, r, http.StatusInternalServerError)
+			return
+		}
+		counts, err := app.MessageLog.TypeCounts(r.Context())
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Failed to count messages", "error", err)
+			WriteErrorPage(w, r, http.StatusInternalServerError)
+			return
+		}
+
+		renderAdminPage(w, http.StatusOK, "Log", AdminLogPage(filter, counts, messages))
+	}
+}
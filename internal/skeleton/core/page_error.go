@@ -0,0 +1,64 @@
+package core
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	g "maragu.dev/gomponents"
+	"maragu.dev/gomponents/html"
+
+	"github.com/petrock/example_module_path/core/ui"
+)
+
+// DefaultErrorMessage is the message WriteErrorPage shows.
+const DefaultErrorMessage = "Something went wrong while handling your request. Please try again later."
+
+// ErrorPage renders an error message with the request ID, for users to quote when they
+// report the problem.
+func ErrorPage(status int, message, requestID string) g.Node {
+	content := []g.Node{
+		ui.Alert(ui.AlertProps{Type: "error", Title: http.StatusText(status), Message: message}),
+	}
+	if requestID != "" {
+		content = append(content, html.P(
+			ui.CSSClass("text-gray-600", "mt-4"),
+			g.Text("Request ID: "),
+			html.Code(g.Text(requestID)),
+		))
+	}
+
+	return ui.Container(ui.ContainerProps{Variant: "narrow"},
+		ui.Section(ui.SectionProps{Heading: fmt.Sprintf("Error %d", status), Level: 1}, content...),
+	)
+}
+
+// WriteErrorPage answers with the given status, as an ErrorPage if the client accepts
+// HTML and as plain text otherwise.
+func WriteErrorPage(w http.ResponseWriter, r *http.Request, status int) {
+	WriteErrorPageMessage(w, r, status, DefaultErrorMessage)
+}
+
+// WriteErrorPageMessage is WriteErrorPage with a message explaining what went wrong.
+// Plain text responses include the message unless it is DefaultErrorMessage.
+func WriteErrorPageMessage(w http.ResponseWriter, r *http.Request, status int, message string) {
+	requestID := RequestIDFromContext(r.Context())
+	w.Header().Del("Content-Length")
+	w.Header().Del("Content-Encoding")
+
+	if !strings.Contains(r.Header.Get("Accept"), "text/html") {
+		text := http.StatusText(status)
+		if message != DefaultErrorMessage {
+			text += ": " + message
+		}
+		if requestID != "" {
+			text += " (request ID " + requestID + ")"
+		}
+		http.Error(w, text, status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	ui.Layout(fmt.Sprintf("Error %d - petrock_example_project_name", status), ErrorPage(status, message, requestID)).Render(w)
+}
@@ -27,6 +27,11 @@ func IndexPage(commandNames, queryNames []string) g.Node {
 				ui.CSSClass("text-lg", "mb-4"),
 				g.Text("Welcome to your Petrock-generated application!"),
 			),
+			html.P(
+				g.Text("Browse the message log, run commands and queries and inspect the KV store in the "),
+				html.A(html.Href(AdminPath), ui.CSSClass("text-blue-600", "hover:underline"), g.Text("admin area")),
+				g.Text("."),
+			),
 		),
 
 		ui.Section(ui.SectionProps{Heading: "Available Commands", Level: 2},
@@ -60,7 +65,7 @@ func IndexPage(commandNames, queryNames []string) g.Node {
 func HandleIndex(commandRegistry *CommandRegistry, queryRegistry *QueryRegistry) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Fetch registered names
-		commandNames := commandRegistry.RegisteredCommandNames()
+		commandNames := commandRegistry.PublicCommandNames()
 		queryNames := queryRegistry.RegisteredQueryNames()
 
 		component := IndexPage(commandNames, queryNames)
@@ -0,0 +1,180 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	g "maragu.dev/gomponents"
+	"maragu.dev/gomponents/html"
+
+	"github.com/petrock/example_module_path/core/ui"
+)
+
+// WebhooksPage renders the webhook subscriptions and their recent deliveries, each
+// with a button to deliver it again. filter is the subscription the deliveries are
+// limited to, if any.
+func WebhooksPage(ctx context.Context, subscriptions []WebhookSubscription, deliveries []WebhookDeliveryEntry, filter string) g.Node {
+	content := []g.Node{
+		html.P(
+			ui.CSSClass("text-gray-600", "mb-6"),
+			g.Text("Subscriptions are managed with the "),
+			html.Code(g.Text("webhooks/subscribe")),
+			g.Text(" and "),
+			html.Code(g.Text("webhooks/unsubscribe")),
+			g.Text(" commands. Deliveries are retried with backoff by the outbox worker."),
+		),
+	}
+
+	if len(subscriptions) == 0 {
+		content = append(content, ui.Alert(ui.AlertProps{Type: "info", Message: "There are no webhook subscriptions."}))
+	} else {
+		cards := make([]g.Node, 0, len(subscriptions))
+		for _, sub := range subscriptions {
+			cards = append(cards, webhookSubscriptionCard(sub))
+		}
+		content = append(content, html.Div(ui.CSSClass("space-y-4", "mb-8"), g.Group(cards)))
+	}
+
+	heading := "Recent deliveries"
+	if filter != "" {
+		heading = "Recent deliveries to " + filter
+	}
+	content = append(content, html.H2(ui.CSSClass("text-lg", "font-semibold", "mb-2"), g.Text(heading)))
+	if len(deliveries) == 0 {
+		content = append(content, ui.Alert(ui.AlertProps{Type: "info", Message: "No webhooks have been delivered yet."}))
+	} else {
+		content = append(content, webhookDeliveriesTable(ctx, deliveries))
+	}
+
+	return ui.Container(ui.ContainerProps{Variant: "wide"},
+		ui.Section(ui.SectionProps{Heading: "Webhooks", Level: 1}, content...),
+	)
+}
+
+// webhookSubscriptionCard renders a single subscription.
+func webhookSubscriptionCard(sub WebhookSubscription) g.Node {
+	return ui.Card(ui.CardProps{Variant: "outlined"},
+		ui.CardHeader(
+			html.Div(
+				ui.CSSClass("flex", "items-center", "gap-2"),
+				html.H2(ui.CSSClass("text-lg", "font-semibold", "mr-auto"), g.Text(sub.ID)),
+				html.A(
+					html.Href("/_/admin/webhooks?subscription="+url.QueryEscape(sub.ID)),
+					ui.CSSClass("text-blue-600", "hover:underline", "text-sm"),
+					g.Text("Deliveries"),
+				),
+			),
+		),
+		ui.CardBody(
+			ui.If(sub.Description != "", html.P(ui.CSSClass("text-gray-600", "mb-4"), g.Text(sub.Description))),
+			html.Dl(
+				ui.CSSClass("grid", "grid-cols-2", "gap-x-4", "gap-y-1", "text-sm"),
+				workerStatusRow("URL", html.Code(g.Text(sub.URL))),
+				workerStatusRow("Events", g.Text(strings.Join(sub.Events, ", "))),
+				workerStatusRow("Updated", g.Text(formatStatusTime(sub.UpdatedAt))),
+			),
+		),
+	)
+}
+
+// webhookDeliveriesTable renders deliveries as table rows with a redeliver form each.
+func webhookDeliveriesTable(ctx context.Context, deliveries []WebhookDeliveryEntry) g.Node {
+	rows := make([]g.Node, 0, len(deliveries))
+	for _, d := range deliveries {
+		statusVariant := "warning"
+		switch d.Status {
+		case OutboxDelivered:
+			statusVariant = "success"
+		case OutboxFailed:
+			statusVariant = "error"
+		}
+		rows = append(rows, html.Tr(
+			ui.CSSClass("border-t", "border-gray-200", "align-top"),
+			webhookCell(g.Textf("%d", d.ID)),
+			webhookCell(g.Text(d.Delivery.Subscription)),
+			webhookCell(html.Code(g.Text(d.Delivery.Event)), g.Textf(" (message %d)", d.Delivery.MessageID)),
+			webhookCell(ui.Badge(ui.BadgeProps{Variant: statusVariant, Size: "small"}, g.Text(d.Status))),
+			webhookCell(g.Textf("%d", d.Attempts)),
+			webhookCell(g.Text(d.LastError)),
+			webhookCell(g.Text(formatStatusTime(d.CreatedAt))),
+			webhookCell(html.Form(
+				html.Method("post"),
+				html.Action(fmt.Sprintf("/_/admin/webhooks/deliveries/%d/redeliver", d.ID)),
+				ui.CSRFField(ctx),
+				ui.Button(ui.ButtonProps{Variant: "secondary", Size: "small", Type: "submit"}, g.Text("Redeliver")),
+			)),
+		))
+	}
+
+	headers := []string{"ID", "Subscription", "Event", "Status", "Attempts", "Last error", "Created", ""}
+	headerCells := make([]g.Node, len(headers))
+	for i, header := range headers {
+		headerCells[i] = html.Th(ui.CSSClass("text-left", "font-medium", "text-gray-700", "px-2", "py-1"), g.Text(header))
+	}
+	return html.Table(
+		ui.CSSClass("w-full", "text-sm"),
+		html.THead(html.Tr(headerCells...)),
+		html.TBody(rows...),
+	)
+}
+
+func webhookCell(children ...g.Node) g.Node {
+	return html.Td(append([]g.Node{ui.CSSClass("px-2", "py-1")}, children...)...)
+}
+
+// HandleWebhooksPage creates an http.HandlerFunc for the webhooks admin page. The
+// subscription query parameter limits the deliveries shown to one subscription.
+func HandleWebhooksPage(app *App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if app.Webhooks == nil {
+			WriteErrorPageMessage(w, r, http.StatusNotFound, "Webhooks are not enabled; call app.UseWebhooks to enable them.")
+			return
+		}
+
+		filter := r.URL.Query().Get("subscription")
+		deliveries, err := app.Webhooks.Deliveries(r.Context(), filter, 50)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Failed to list webhook deliveries", "error", err)
+			WriteErrorPage(w, r, http.StatusInternalServerError)
+			return
+		}
+
+		page := WebhooksPage(r.Context(), app.Webhooks.Subscriptions(), deliveries, filter)
+		layout := ui.Layout("Webhooks - petrock_example_project_name", page)
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := layout.Render(w); err != nil {
+			http.Error(w, "Failed to render page", http.StatusInternalServerError)
+		}
+	}
+}
+
+// HandleWebhookRedeliver creates an http.HandlerFunc that delivers the delivery with
+// the {id} path value again and redirects back to the webhooks admin page.
+func HandleWebhookRedeliver(app *App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if app.Webhooks == nil {
+			WriteErrorPageMessage(w, r, http.StatusNotFound, "Webhooks are not enabled; call app.UseWebhooks to enable them.")
+			return
+		}
+		id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+		if err != nil {
+			WriteErrorPageMessage(w, r, http.StatusBadRequest, "Invalid delivery ID.")
+			return
+		}
+
+		entry, err := app.Webhooks.Redeliver(r.Context(), id)
+		if err != nil {
+			slog.WarnContext(r.Context(), "Failed to redeliver webhook", "id", id, "error", err)
+			WriteErrorPageMessage(w, r, http.StatusNotFound, err.Error())
+			return
+		}
+		slog.InfoContext(r.Context(), "Webhook delivery queued again", "id", id, "redelivery", entry.ID)
+		http.Redirect(w, r, "/_/admin/webhooks", http.StatusSeeOther)
+	}
+}
@@ -0,0 +1,155 @@
+package core
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	g "maragu.dev/gomponents"
+	"maragu.dev/gomponents/html"
+
+	"github.com/petrock/example_module_path/core/ui"
+)
+
+// WorkersPage renders the status of all workers as one card per worker.
+func WorkersPage(statuses []WorkerStatus) g.Node {
+	cards := make([]g.Node, 0, len(statuses))
+	for _, status := range statuses {
+		cards = append(cards, workerStatusCard(status))
+	}
+
+	content := []g.Node{
+		html.P(
+			ui.CSSClass("text-gray-600", "mb-6"),
+			g.Text("Live status of the application's background workers. The same data is available as JSON at "),
+			html.A(html.Href("/_/workers"), ui.CSSClass("text-blue-600", "hover:underline"), html.Code(g.Text("GET /_/workers"))),
+			g.Text("."),
+		),
+	}
+	if len(cards) == 0 {
+		content = append(content, ui.Alert(ui.AlertProps{Type: "info", Message: "No workers are registered."}))
+	} else {
+		content = append(content, html.Div(ui.CSSClass("space-y-4"), g.Group(cards)))
+	}
+
+	return ui.Container(ui.ContainerProps{Variant: "wide"},
+		ui.Section(ui.SectionProps{Heading: "Workers", Level: 1}, content...),
+	)
+}
+
+// workerStatusCard renders a single worker's status.
+func workerStatusCard(status WorkerStatus) g.Node {
+	stateVariant := "secondary"
+	switch status.State {
+	case WorkerStateRunning:
+		stateVariant = "success"
+	case WorkerStateReplaying:
+		stateVariant = "info"
+	case WorkerStatePaused:
+		stateVariant = "warning"
+	}
+
+	healthVariant, healthText := "error", "unhealthy"
+	if status.Healthy {
+		healthVariant, healthText = "success", "healthy"
+	}
+
+	lastSuccess := "never"
+	if status.LastSuccessAt != nil {
+		lastSuccess = formatStatusTime(*status.LastSuccessAt)
+	}
+
+	rows := []g.Node{
+		workerStatusRow("Schedule", g.Text(status.Schedule)),
+		workerStatusRow("Position", g.Textf("%d of %d (lag %d)", status.Position, status.Head, status.Lag)),
+		workerStatusRow("Cycles", g.Textf("%d (%d failed)", status.Cycles, status.Failures)),
+		workerStatusRow("Last success", g.Text(lastSuccess)),
+		workerStatusRow("Pending", g.Text(formatPendingCounts(status.Pending))),
+		workerStatusRow("Dead letters", g.Textf("%d", status.DeadLetters)),
+	}
+	for _, retry := range status.Retrying {
+		rows = append(rows, workerStatusRow("Retrying",
+			g.Textf("message %d, attempt %d, next at %s", retry.MessageID, retry.Attempts, retry.NextAttemptAt.Format(time.DateTime))))
+	}
+
+	var lastError g.Node
+	if status.LastErrorAt != nil {
+		lastError = ui.Alert(ui.AlertProps{
+			Type:    "error",
+			Title:   "Last error at " + formatStatusTime(*status.LastErrorAt),
+			Message: status.LastError,
+		})
+	}
+
+	return ui.Card(ui.CardProps{Variant: "outlined"},
+		ui.CardHeader(
+			html.Div(
+				ui.CSSClass("flex", "items-center", "gap-2"),
+				html.H2(ui.CSSClass("text-lg", "font-semibold", "mr-auto"), g.Text(status.Name)),
+				ui.Badge(ui.BadgeProps{Variant: stateVariant, Size: "small"}, g.Text(status.State)),
+				ui.Badge(ui.BadgeProps{Variant: healthVariant, Size: "small"}, g.Text(healthText)),
+			),
+		),
+		ui.CardBody(
+			ui.If(status.Description != "", html.P(ui.CSSClass("text-gray-600", "mb-4"), g.Text(status.Description))),
+			html.Dl(
+				ui.CSSClass("grid", "grid-cols-2", "gap-x-4", "gap-y-1", "text-sm"),
+				g.Group(rows),
+			),
+			ui.If(lastError != nil, html.Div(ui.CSSClass("mt-4"), lastError)),
+		),
+	)
+}
+
+// workerStatusRow renders a label/value pair of a worker's status.
+func workerStatusRow(label string, value g.Node) g.Node {
+	return g.Group([]g.Node{
+		html.Dt(ui.CSSClass("font-medium", "text-gray-700"), g.Text(label)),
+		html.Dd(ui.CSSClass("text-gray-900"), value),
+	})
+}
+
+// formatPendingCounts renders pending counts as "kind: n" pairs in a stable order.
+func formatPendingCounts(pending map[string]int) string {
+	if len(pending) == 0 {
+		return "none"
+	}
+	kinds := make([]string, 0, len(pending))
+	for kind := range pending {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+
+	parts := make([]string, len(kinds))
+	for i, kind := range kinds {
+		parts[i] = fmt.Sprintf("%s: %d", kind, pending[kind])
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatStatusTime renders a timestamp together with how long ago it was.
+func formatStatusTime(t time.Time) string {
+	return fmt.Sprintf("%s (%s ago)", t.Format(time.DateTime), time.Since(t).Round(time.Second))
+}
+
+// HandleWorkersPage creates an http.HandlerFunc for the worker status admin page.
+func HandleWorkersPage(app *App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		statuses, err := app.WorkerStatuses(r.Context())
+		if err != nil {
+			slog.Error("Failed to collect worker statuses", "error", err)
+			http.Error(w, "Failed to collect worker statuses", http.StatusInternalServerError)
+			return
+		}
+
+		layout := ui.Layout("Workers - petrock_example_project_name", WorkersPage(statuses))
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := layout.Render(w); err != nil {
+			http.Error(w, "Failed to render page", http.StatusInternalServerError)
+		}
+	}
+}
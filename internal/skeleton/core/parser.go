@@ -1,7 +1,9 @@
 package core
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/http"
 	"net/url"
 	"reflect"
 	"strconv"
@@ -298,7 +300,8 @@ func NewParser() *Parser {
 		tagParsers: make([]TagParser, 0),
 	}
 
-	// Register default components
+	// Register default components; the first converter that can handle a type is used
+	p.RegisterConverter(DurationConverter{})
 	p.RegisterConverter(BasicConverter{})
 	p.RegisterConverter(NewTimeConverter())
 
@@ -422,6 +425,38 @@ func ParseFromMap(data map[string]interface{}, target interface{}) error {
 	return DefaultParser.ParseFrom(MapSource{Data: data}, target)
 }
 
+// ParseFromRequest parses the body of r into target: a JSON object for requests with a
+// JSON Content-Type, form values otherwise. pathFields names path wildcards, such as
+// "id", whose values replace any sent in the body, so clients need not repeat them.
+// Malformed bodies give a plain error and invalid values *ParseErrors.
+func ParseFromRequest(r *http.Request, target interface{}, pathFields ...string) error {
+	if isJSONRequest(r) {
+		data := map[string]interface{}{}
+		if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+			return fmt.Errorf("failed to decode JSON body: %w", err)
+		}
+		if data == nil { // The body was null
+			data = map[string]interface{}{}
+		}
+		for _, name := range pathFields {
+			data[name] = r.PathValue(name)
+		}
+		return ParseFromMap(data, target)
+	}
+
+	if err := r.ParseForm(); err != nil {
+		return fmt.Errorf("failed to parse form: %w", err)
+	}
+	values := url.Values{}
+	for key, v := range r.PostForm {
+		values[key] = v
+	}
+	for _, name := range pathFields {
+		values.Set(name, r.PathValue(name))
+	}
+	return ParseFromURLValues(values, target)
+}
+
 // RegisterConverter adds a converter to the default parser
 func RegisterConverter(converter Converter) {
 	DefaultParser.RegisterConverter(converter)
@@ -2,10 +2,12 @@ package core
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"reflect"
 	"sync"
+	"time"
 )
 
 // Query is an interface for query messages.
@@ -16,14 +18,28 @@ type Query interface {
 // QueryResult is a marker interface for the data returned by a query handler.
 type QueryResult interface{}
 
+// TypedQuery is implemented by queries that declare the type of result their handler
+// returns, so that App.OpenAPI can describe it. NewResult returns an empty result,
+// e.g. &GetQueryResult{}.
+type TypedQuery interface {
+	Query
+	NewResult() QueryResult
+}
+
 // QueryHandler defines the function signature for handling queries.
 type QueryHandler func(ctx context.Context, query Query) (QueryResult, error)
 
+// ErrUnknownQuery is returned when a query name has no registered type.
+var ErrUnknownQuery = errors.New("unknown query type")
+
 // QueryRegistry maps query names (feature/Type) to their handlers and types.
 type QueryRegistry struct {
 	handlers map[string]QueryHandler // Key: "feature/TypeName"
 	types    map[string]reflect.Type // Key: "feature/TypeName"
+	results  map[string]reflect.Type // Key: "feature/TypeName", for queries implementing TypedQuery
 	mu       sync.RWMutex
+	latency  *Histogram // Set by UseMetrics
+	tracer   *Tracer    // Set by UseTracer
 }
 
 // NewQueryRegistry creates a new, initialized QueryRegistry.
@@ -31,6 +47,7 @@ func NewQueryRegistry() *QueryRegistry {
 	return &QueryRegistry{
 		handlers: make(map[string]QueryHandler),
 		types:    make(map[string]reflect.Type),
+		results:  make(map[string]reflect.Type),
 	}
 }
 
@@ -53,9 +70,29 @@ func (r *QueryRegistry) Register(query Query, handler QueryHandler) {
 
 	r.handlers[name] = handler
 	r.types[name] = queryType // Store the type for lookup
+	if typed, ok := query.(TypedQuery); ok {
+		if result := typed.NewResult(); result != nil {
+			resultType := reflect.TypeOf(result)
+			if resultType.Kind() == reflect.Ptr {
+				resultType = resultType.Elem()
+			}
+			r.results[name] = resultType
+		}
+	}
 	slog.Debug("Registered query handler", "name", name, "type", queryType)
 }
 
+// UseMetrics makes Dispatch record how long queries take by name in m.
+// NewApp calls it with App.Metrics.
+func (r *QueryRegistry) UseMetrics(m *Metrics) {
+	r.latency = m.Histogram("petrock_query_duration_seconds", "Time taken to answer queries by name", nil, "query")
+}
+
+// UseTracer makes Dispatch trace every query with a span. NewApp calls it with App.Tracer.
+func (r *QueryRegistry) UseTracer(tracer *Tracer) {
+	r.tracer = tracer
+}
+
 // Dispatch finds the handler for the given query's QueryName() and executes it.
 // It returns the result and an error if no handler is found or if the handler returns an error.
 func (r *QueryRegistry) Dispatch(ctx context.Context, query Query) (QueryResult, error) {
@@ -69,7 +106,14 @@ func (r *QueryRegistry) Dispatch(ctx context.Context, query Query) (QueryResult,
 	}
 
 	slog.Debug("Dispatching query", "name", name, "type", reflect.TypeOf(query))
-	return handler(ctx, query)
+	defer r.latency.ObserveSince(time.Now(), name)
+	ctx, span := r.tracer.Start(ctx, "query "+name)
+	span.SetAttribute("query", name)
+	defer span.Finish()
+
+	result, err := handler(ctx, query)
+	span.RecordError(err)
+	return result, err
 }
 
 // RegisteredQueryNames returns a slice of strings containing the full names
@@ -98,5 +142,37 @@ func (r *QueryRegistry) GetQueryType(name string) (reflect.Type, bool) {
 	return queryType, found
 }
 
+// GetResultType retrieves the type of result a registered query returns, if the query
+// declares it by implementing TypedQuery.
+func (r *QueryRegistry) GetResultType(name string) (reflect.Type, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	resultType, found := r.results[name]
+	return resultType, found
+}
+
+// NewQuery creates an instance of the query registered under name and populates it from source.
+// It returns an error wrapping ErrUnknownQuery if no such query exists, or *ParseErrors if
+// the source fails validation.
+func (r *QueryRegistry) NewQuery(name string, source FormSource) (Query, error) {
+	queryType, found := r.GetQueryType(name)
+	if !found {
+		return nil, fmt.Errorf("%w %q", ErrUnknownQuery, name)
+	}
+
+	// Parse into a pointer, then hand the value to Dispatch like the HTTP handlers do
+	queryPtr := reflect.New(queryType)
+	if err := ParseFromSource(source, queryPtr.Interface()); err != nil {
+		return nil, err
+	}
+
+	query, ok := queryPtr.Elem().Interface().(Query)
+	if !ok {
+		return nil, fmt.Errorf("registered type %s for %q does not implement core.Query", queryType, name)
+	}
+	return query, nil
+}
+
 // --- Global Registry (Optional - consider dependency injection instead) ---
 // var Queries = NewQueryRegistry()
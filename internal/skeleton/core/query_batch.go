@@ -0,0 +1,126 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+)
+
+// MaxBatchQueries is the largest number of queries a batch may contain. Each query
+// runs in its own goroutine, so larger batches are rejected rather than dispatched.
+const MaxBatchQueries = 50
+
+// BatchQuery is a single query inside a batch request.
+// Alias is chosen by the caller and used as the key for the query's result.
+type BatchQuery struct {
+	Alias  string                 `json:"alias"`
+	Type   string                 `json:"type"`   // Registered query name, e.g. "posts/list"
+	Params map[string]interface{} `json:"params"` // Query fields, parsed like a JSON command payload
+}
+
+// BatchQueryResult holds the outcome of one query in a batch.
+// Exactly one of Result or Error is set.
+type BatchQueryResult struct {
+	Result  QueryResult  `json:"result,omitempty"`
+	Error   string       `json:"error,omitempty"`
+	Details []ParseError `json:"details,omitempty"` // Validation errors for the query parameters
+}
+
+// BatchQueryResponse holds the results of a batch, keyed by alias.
+// All results were computed against the same log version.
+type BatchQueryResponse struct {
+	Version uint64                      `json:"version"`
+	Results map[string]BatchQueryResult `json:"results"`
+}
+
+// ValidateBatch checks that the batch has at most MaxBatchQueries queries, and that every
+// query has a unique, non-empty alias and a type.
+// Problems with individual query parameters are reported per query by DispatchBatch instead.
+func ValidateBatch(queries []BatchQuery) error {
+	if len(queries) > MaxBatchQueries {
+		return fmt.Errorf("batch has %d queries, at most %d are allowed", len(queries), MaxBatchQueries)
+	}
+	seen := make(map[string]bool, len(queries))
+	for i, q := range queries {
+		if q.Alias == "" {
+			return fmt.Errorf("query %d: alias is required", i)
+		}
+		if seen[q.Alias] {
+			return fmt.Errorf("query %d: duplicate alias %q", i, q.Alias)
+		}
+		seen[q.Alias] = true
+		if q.Type == "" {
+			return fmt.Errorf("query %q: type is required", q.Alias)
+		}
+	}
+	return nil
+}
+
+// DispatchBatch runs all queries in parallel while no command can be applied,
+// so every result reflects the same log version.
+// A failing query only sets the error of its own result; the batch as a whole
+// fails only if the batch is malformed or the log version cannot be read.
+func (a *App) DispatchBatch(ctx context.Context, queries []BatchQuery) (*BatchQueryResponse, error) {
+	if err := ValidateBatch(queries); err != nil {
+		return nil, err
+	}
+
+	response := &BatchQueryResponse{
+		Results: make(map[string]BatchQueryResult, len(queries)),
+	}
+
+	err := a.Executor.ReadConsistent(ctx, func(version uint64) error {
+		response.Version = version
+
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		for _, q := range queries {
+			wg.Add(1)
+			go func(q BatchQuery) {
+				defer wg.Done()
+				result := a.dispatchBatchQuery(ctx, q)
+
+				mu.Lock()
+				response.Results[q.Alias] = result
+				mu.Unlock()
+			}(q)
+		}
+		wg.Wait()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Debug("Dispatched query batch", "count", len(queries), "version", response.Version)
+	return response, nil
+}
+
+// dispatchBatchQuery builds and dispatches a single query, converting
+// every failure, including a panicking handler, into a BatchQueryResult.
+func (a *App) dispatchBatchQuery(ctx context.Context, q BatchQuery) (result BatchQueryResult) {
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Error("Query handler panicked in batch", "alias", q.Alias, "name", q.Type, "panic", r)
+			result = BatchQueryResult{Error: fmt.Sprintf("query %q panicked", q.Type)}
+		}
+	}()
+
+	query, err := a.QueryRegistry.NewQuery(q.Type, MapSource{Data: q.Params})
+	if err != nil {
+		var parseErrors *ParseErrors
+		if errors.As(err, &parseErrors) {
+			return BatchQueryResult{Error: "Validation failed", Details: parseErrors.Errors}
+		}
+		return BatchQueryResult{Error: err.Error()}
+	}
+
+	value, err := a.QueryRegistry.Dispatch(ctx, query)
+	if err != nil {
+		slog.Warn("Query failed in batch", "alias", q.Alias, "name", q.Type, "error", err)
+		return BatchQueryResult{Error: err.Error()}
+	}
+	return BatchQueryResult{Result: value}
+}
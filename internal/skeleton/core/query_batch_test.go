@@ -0,0 +1,125 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+// newTestApp creates an App backed by a SQLite file in a temporary directory.
+func newTestApp(t *testing.T) *App {
+	t.Helper()
+	app, err := NewApp(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewApp failed: %v", err)
+	}
+	t.Cleanup(func() { app.Close() })
+	return app
+}
+
+type batchEchoQuery struct {
+	Text string `json:"text" validate:"required"`
+}
+
+func (q batchEchoQuery) QueryName() string { return "test/echo" }
+
+type batchFailQuery struct{}
+
+func (q batchFailQuery) QueryName() string { return "test/fail" }
+
+type batchPanicQuery struct{}
+
+func (q batchPanicQuery) QueryName() string { return "test/panic" }
+
+func registerBatchTestQueries(app *App) {
+	app.QueryRegistry.Register(batchEchoQuery{}, func(ctx context.Context, query Query) (QueryResult, error) {
+		return query.(batchEchoQuery).Text, nil
+	})
+	app.QueryRegistry.Register(batchFailQuery{}, func(ctx context.Context, query Query) (QueryResult, error) {
+		return nil, errors.New("boom")
+	})
+	app.QueryRegistry.Register(batchPanicQuery{}, func(ctx context.Context, query Query) (QueryResult, error) {
+		panic("handler exploded")
+	})
+}
+
+func TestDispatchBatch_ResultsKeyedByAlias(t *testing.T) {
+	app := newTestApp(t)
+	registerBatchTestQueries(app)
+
+	response, err := app.DispatchBatch(context.Background(), []BatchQuery{
+		{Alias: "a", Type: "test/echo", Params: map[string]interface{}{"text": "hello"}},
+		{Alias: "b", Type: "test/echo", Params: map[string]interface{}{"text": "world"}},
+	})
+	if err != nil {
+		t.Fatalf("DispatchBatch failed: %v", err)
+	}
+
+	if got := response.Results["a"].Result; got != "hello" {
+		t.Errorf("Expected result 'hello' for alias a, got %v", got)
+	}
+	if got := response.Results["b"].Result; got != "world" {
+		t.Errorf("Expected result 'world' for alias b, got %v", got)
+	}
+	if response.Version != 0 {
+		t.Errorf("Expected version 0 for empty log, got %d", response.Version)
+	}
+}
+
+func TestDispatchBatch_ErrorsAreIsolated(t *testing.T) {
+	app := newTestApp(t)
+	registerBatchTestQueries(app)
+
+	response, err := app.DispatchBatch(context.Background(), []BatchQuery{
+		{Alias: "ok", Type: "test/echo", Params: map[string]interface{}{"text": "fine"}},
+		{Alias: "fail", Type: "test/fail"},
+		{Alias: "panic", Type: "test/panic"},
+		{Alias: "unknown", Type: "test/missing"},
+		{Alias: "invalid", Type: "test/echo"},
+	})
+	if err != nil {
+		t.Fatalf("DispatchBatch failed: %v", err)
+	}
+
+	if got := response.Results["ok"]; got.Result != "fine" || got.Error != "" {
+		t.Errorf("Expected successful result for alias ok, got %+v", got)
+	}
+	for _, alias := range []string{"fail", "panic", "unknown", "invalid"} {
+		if response.Results[alias].Error == "" {
+			t.Errorf("Expected error for alias %s, got %+v", alias, response.Results[alias])
+		}
+	}
+	if len(response.Results["invalid"].Details) == 0 {
+		t.Error("Expected validation details for alias invalid")
+	}
+}
+
+func TestValidateBatch(t *testing.T) {
+	var tooManyQueries []BatchQuery
+	for i := 0; i <= MaxBatchQueries; i++ {
+		tooManyQueries = append(tooManyQueries, BatchQuery{Alias: fmt.Sprintf("q%d", i), Type: "x/y"})
+	}
+
+	tests := []struct {
+		name    string
+		queries []BatchQuery
+		wantErr bool
+	}{
+		{"valid", []BatchQuery{{Alias: "a", Type: "x/y"}, {Alias: "b", Type: "x/y"}}, false},
+		{"missing alias", []BatchQuery{{Type: "x/y"}}, true},
+		{"duplicate alias", []BatchQuery{{Alias: "a", Type: "x/y"}, {Alias: "a", Type: "x/z"}}, true},
+		{"missing type", []BatchQuery{{Alias: "a"}}, true},
+		{"too many queries", tooManyQueries, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateBatch(tt.queries)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateBatch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
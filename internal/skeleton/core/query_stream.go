@@ -0,0 +1,89 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// DefaultStreamPollInterval is how often StreamQuery checks the log version
+// for messages appended by other processes.
+const DefaultStreamPollInterval = 2 * time.Second
+
+// QueryUpdate is a query result pushed to a subscriber by StreamQuery.
+type QueryUpdate struct {
+	Version uint64      `json:"version"` // Log version the result was computed at
+	Result  QueryResult `json:"result"`
+}
+
+// StreamQuery dispatches query once immediately and again every time the log advances,
+// calling send only when the JSON encoding of the result differs from the last one sent.
+// It blocks until ctx is cancelled or send returns an error.
+// Appends made through this process wake the stream up right away; appends made by
+// other processes are picked up by polling the log version every pollInterval.
+func (a *App) StreamQuery(ctx context.Context, query Query, pollInterval time.Duration, send func(QueryUpdate) error) error {
+	if pollInterval <= 0 {
+		pollInterval = DefaultStreamPollInterval
+	}
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	var lastVersion uint64
+	var lastEncoded []byte
+	first := true
+
+	for {
+		// Grab the notification channel before dispatching so no append is missed
+		changed := a.MessageLog.Changed()
+
+		// dispatched stays false when the log hasn't moved since the last dispatch
+		var update QueryUpdate
+		dispatched := false
+		err := a.Executor.ReadConsistent(ctx, func(version uint64) error {
+			if !first && version == lastVersion {
+				return nil
+			}
+			result, err := a.QueryRegistry.Dispatch(ctx, query)
+			if err != nil {
+				return err
+			}
+			update = QueryUpdate{Version: version, Result: result}
+			dispatched = true
+			return nil
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				// The client went away while the query was running
+				return nil
+			}
+			return fmt.Errorf("failed to dispatch streamed query %q: %w", query.QueryName(), err)
+		}
+
+		if dispatched {
+			encoded, err := json.Marshal(update.Result)
+			if err != nil {
+				return fmt.Errorf("failed to encode result of streamed query %q: %w", query.QueryName(), err)
+			}
+			lastVersion = update.Version
+
+			if first || !bytes.Equal(encoded, lastEncoded) {
+				lastEncoded = encoded
+				if err := send(update); err != nil {
+					return err
+				}
+				slog.Debug("Pushed query update", "name", query.QueryName(), "version", update.Version)
+			}
+			first = false
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-changed:
+		case <-ticker.C:
+		}
+	}
+}
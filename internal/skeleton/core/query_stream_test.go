@@ -0,0 +1,145 @@
+package core
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+type streamCounter struct {
+	mu    sync.Mutex
+	count int
+}
+
+type streamIncrementCommand struct {
+	By int `json:"by"`
+}
+
+func (c *streamIncrementCommand) CommandName() string { return "test/increment" }
+
+type streamCountQuery struct{}
+
+func (q streamCountQuery) QueryName() string { return "test/count" }
+
+type streamAcceptAll struct{}
+
+func (streamAcceptAll) ValidateCommand(ctx context.Context, cmd Command) error { return nil }
+
+// newStreamTestApp returns an app with the test/increment command and the test/count
+// query, which returns the sum of all increments.
+func newStreamTestApp(t *testing.T) *App {
+	t.Helper()
+	app := newTestApp(t)
+	counter := &streamCounter{}
+
+	app.CommandRegistry.Register(&streamIncrementCommand{}, func(ctx context.Context, cmd Command, msg *Message, pctx *ProcessingContext) error {
+		counter.mu.Lock()
+		defer counter.mu.Unlock()
+		counter.count += cmd.(*streamIncrementCommand).By
+		return nil
+	}, streamAcceptAll{})
+	app.MessageLog.RegisterType(&streamIncrementCommand{})
+	app.QueryRegistry.Register(streamCountQuery{}, func(ctx context.Context, query Query) (QueryResult, error) {
+		counter.mu.Lock()
+		defer counter.mu.Unlock()
+		return counter.count, nil
+	})
+	return app
+}
+
+func TestStreamQuery_PushesOnlyChangedResults(t *testing.T) {
+	app := newStreamTestApp(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	updates := make(chan QueryUpdate, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- app.StreamQuery(ctx, streamCountQuery{}, time.Hour, func(update QueryUpdate) error {
+			updates <- update
+			return nil
+		})
+	}()
+
+	expectUpdate := func(want int) QueryUpdate {
+		t.Helper()
+		select {
+		case update := <-updates:
+			if update.Result != want {
+				t.Fatalf("Expected result %d, got %v", want, update.Result)
+			}
+			return update
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Timed out waiting for result %d", want)
+		}
+		return QueryUpdate{}
+	}
+
+	expectUpdate(0)
+
+	if err := app.Executor.Execute(ctx, &streamIncrementCommand{By: 2}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	update := expectUpdate(2)
+	if update.Version != 1 {
+		t.Errorf("Expected version 1, got %d", update.Version)
+	}
+
+	// A command that leaves the result unchanged must not produce an update
+	if err := app.Executor.Execute(ctx, &streamIncrementCommand{By: 0}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if err := app.Executor.Execute(ctx, &streamIncrementCommand{By: 1}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	update = expectUpdate(3)
+	if update.Version != 3 {
+		t.Errorf("Expected version 3, got %d", update.Version)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("StreamQuery returned error: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("StreamQuery did not return after cancellation")
+	}
+}
+
+func TestStreamQuery_PollsWithoutPushingUnchangedVersions(t *testing.T) {
+	app := newStreamTestApp(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := app.Executor.Execute(ctx, &streamIncrementCommand{By: 2}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	var mu sync.Mutex
+	var updates []QueryUpdate
+	done := make(chan error, 1)
+	go func() {
+		done <- app.StreamQuery(ctx, streamCountQuery{}, time.Millisecond, func(update QueryUpdate) error {
+			mu.Lock()
+			defer mu.Unlock()
+			updates = append(updates, update)
+			return nil
+		})
+	}()
+
+	// Many poll ticks pass without the log moving
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("StreamQuery returned error: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(updates) != 1 || updates[0].Version != 1 || updates[0].Result != 2 {
+		t.Errorf("Expected only the initial update at version 1, got %+v", updates)
+	}
+}
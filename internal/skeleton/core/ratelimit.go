@@ -0,0 +1,347 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited is wrapped by the errors Executor.Execute returns for commands
+// rejected by a rate limit. Use RateLimited to get the time to wait.
+var ErrRateLimited = errors.New("rate limit exceeded")
+
+// rateLimitSweepInterval is how often the limiter forgets buckets that have refilled.
+const rateLimitSweepInterval = time.Minute
+
+// RateLimitKey chooses which requests share a token bucket.
+type RateLimitKey string
+
+const (
+	// RateLimitByIP gives every client IP its own bucket.
+	RateLimitByIP RateLimitKey = "ip"
+	// RateLimitByPrincipal gives every logged-in user their own bucket; anonymous
+	// requests share buckets by client IP.
+	RateLimitByPrincipal RateLimitKey = "principal"
+	// RateLimitByCommand uses one bucket for all clients.
+	RateLimitByCommand RateLimitKey = "command"
+)
+
+// RateLimit allows Requests requests per Per, with bursts of up to Burst requests.
+// Burst defaults to Requests.
+type RateLimit struct {
+	Key      RateLimitKey  `json:"key"`
+	Requests int           `json:"requests"`
+	Per      time.Duration `json:"per"`
+	Burst    int           `json:"burst,omitempty"`
+}
+
+// burst returns the bucket size.
+func (l RateLimit) burst() float64 {
+	if l.Burst > 0 {
+		return float64(l.Burst)
+	}
+	return float64(l.Requests)
+}
+
+// refillRate returns the number of tokens added per second.
+func (l RateLimit) refillRate() float64 {
+	return float64(l.Requests) / l.Per.Seconds()
+}
+
+// RateLimitError is returned when a rate limit rejects a request. It wraps ErrRateLimited.
+type RateLimitError struct {
+	Name       string        // The command or route the limit belongs to
+	RetryAfter time.Duration // How long until the next request is allowed
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("rate limit exceeded for %s, retry in %s", e.Name, e.RetryAfter.Round(time.Second))
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return ErrRateLimited
+}
+
+// RateLimited reports whether err was caused by a rate limit and how long the client
+// should wait before trying again.
+func RateLimited(err error) (time.Duration, bool) {
+	var rlErr *RateLimitError
+	if errors.As(err, &rlErr) {
+		return rlErr.RetryAfter, true
+	}
+	return 0, false
+}
+
+// WriteRateLimited responds with 429 Too Many Requests and a Retry-After header
+// rounded up to whole seconds: an error page for browsers, plain text otherwise.
+func WriteRateLimited(w http.ResponseWriter, r *http.Request, retryAfter time.Duration) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	WriteErrorPageMessage(w, r, http.StatusTooManyRequests,
+		fmt.Sprintf("Too many requests. Try again in %d seconds.", seconds))
+}
+
+// bucket is a token bucket. Tokens are refilled lazily from Updated when it is used.
+type bucket struct {
+	Tokens  float64   `json:"tokens"`
+	Updated time.Time `json:"updated"`
+}
+
+// refill adds the tokens earned since b.Updated, up to the bucket size.
+func (b *bucket) refill(now time.Time, limit RateLimit) {
+	if elapsed := now.Sub(b.Updated).Seconds(); elapsed > 0 {
+		b.Tokens = math.Min(limit.burst(), b.Tokens+elapsed*limit.refillRate())
+		b.Updated = now
+	}
+}
+
+// RateLimiter keeps token buckets for rate limits in memory. Once Persist is called,
+// buckets are also written to a KVStore, so limits survive restarts.
+type RateLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	limits    map[string]RateLimit // The limit each bucket was last used with, for sweeping
+	store     KVStore
+	now       func() time.Time
+	lastSweep time.Time
+}
+
+// NewRateLimiter creates a rate limiter that keeps its buckets in memory.
+func NewRateLimiter() *RateLimiter {
+	return &RateLimiter{
+		buckets: make(map[string]*bucket),
+		limits:  make(map[string]RateLimit),
+		now:     time.Now,
+	}
+}
+
+// Persist makes the limiter load buckets it doesn't know from store and write every
+// change back, under keys starting with "ratelimit:". This costs a write per limited
+// request. Buckets expire from the store once they have refilled.
+func (l *RateLimiter) Persist(store KVStore) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.store = store
+}
+
+// Allow takes a token from the bucket for key under limit. If the bucket is empty it
+// returns false and the time until a token is available. A limit with no requests
+// or no period never allows anything.
+func (l *RateLimiter) Allow(key string, limit RateLimit) (bool, time.Duration) {
+	return l.allowAll([]string{key}, []RateLimit{limit})
+}
+
+// allowAll takes a token from the bucket of every keys[i] under limits[i] if all of
+// them have one, and none otherwise, so that a request rejected by one limit doesn't
+// use up the others. When rejecting it returns the longest wait.
+func (l *RateLimiter) allowAll(keys []string, limits []RateLimit) (bool, time.Duration) {
+	for _, limit := range limits {
+		if limit.Requests <= 0 || limit.Per <= 0 {
+			return false, time.Hour
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	buckets := make([]*bucket, len(keys))
+	allowed := true
+	var retryAfter time.Duration
+	for i, key := range keys {
+		b := l.load(key, limits[i], now)
+		b.refill(now, limits[i])
+		l.limits[key] = limits[i]
+		if b.Tokens < 1 {
+			allowed = false
+			retryAfter = max(retryAfter, time.Duration((1-b.Tokens)/limits[i].refillRate()*float64(time.Second)))
+		}
+		buckets[i] = b
+	}
+
+	for i, b := range buckets {
+		if allowed {
+			b.Tokens--
+		}
+		l.save(keys[i], b, limits[i])
+	}
+	return allowed, retryAfter
+}
+
+// load returns the bucket for key, reading it from the store or creating a full one.
+func (l *RateLimiter) load(key string, limit RateLimit, now time.Time) *bucket {
+	if b, ok := l.buckets[key]; ok {
+		return b
+	}
+	b := &bucket{Tokens: limit.burst(), Updated: now}
+	if l.store != nil {
+		var stored bucket
+		err := l.store.Get(rateLimitStoreKey(key), &stored)
+		switch {
+		case err == nil:
+			b = &stored
+		case !errors.Is(err, ErrKeyNotFound):
+			slog.Warn("Failed to load rate limit bucket, starting with a full one", "key", key, "error", err)
+		}
+	}
+	l.buckets[key] = b
+	return b
+}
+
+// save writes b to the store, if the limiter persists its buckets.
+func (l *RateLimiter) save(key string, b *bucket, limit RateLimit) {
+	if l.store == nil {
+		return
+	}
+	// Once the bucket is full again it is no different from a missing one
+	ttl := time.Duration((limit.burst() - b.Tokens) / limit.refillRate() * float64(time.Second))
+	if ttl < time.Second {
+		ttl = time.Second
+	}
+	if err := l.store.SetWithTTL(rateLimitStoreKey(key), b, ttl); err != nil {
+		slog.Warn("Failed to persist rate limit bucket", "key", key, "error", err)
+	}
+}
+
+// sweep forgets buckets that have refilled, at most once per rateLimitSweepInterval.
+func (l *RateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < rateLimitSweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		limit := l.limits[key]
+		full := *b
+		full.refill(now, limit)
+		if full.Tokens >= limit.burst() {
+			delete(l.buckets, key)
+			delete(l.limits, key)
+		}
+	}
+}
+
+// rateLimitStoreKey returns the KVStore key of a persisted bucket.
+func rateLimitStoreKey(key string) string {
+	return "ratelimit:" + key
+}
+
+// bucketKey returns the bucket that a request for name, made by the client in ctx,
+// takes its token from under limit. Every limit of name has buckets of its own, even
+// limits with the same Key. It returns false if ctx doesn't identify the client the
+// limit is keyed by.
+func bucketKey(ctx context.Context, name string, limit RateLimit) (string, bool) {
+	prefix := fmt.Sprintf("%s|%s:%d/%s:%d|", name, limit.Key, limit.Requests, limit.Per, int(limit.burst()))
+	switch limit.Key {
+	case RateLimitByCommand:
+		return prefix + "command", true
+	case RateLimitByPrincipal:
+		if id := PrincipalID(ctx); id != "" {
+			return prefix + "principal:" + id, true
+		}
+	}
+	if ip := ClientIPFromContext(ctx); ip != "" {
+		return prefix + "ip:" + ip, true
+	}
+	return "", false
+}
+
+// Check takes a token from every bucket the limits for name apply to if all of them
+// have one, and otherwise takes none and returns a *RateLimitError with the longest
+// wait. Only requests that carry a client IP, set by the ClientIP middleware, are
+// limited; commands issued by workers and the outbox never are.
+func (l *RateLimiter) Check(ctx context.Context, name string, limits ...RateLimit) error {
+	if ClientIPFromContext(ctx) == "" {
+		return nil
+	}
+	var keys []string
+	var applied []RateLimit
+	for _, limit := range limits {
+		if key, ok := bucketKey(ctx, name, limit); ok {
+			keys = append(keys, key)
+			applied = append(applied, limit)
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	if allowed, retryAfter := l.allowAll(keys, applied); !allowed {
+		return &RateLimitError{Name: name, RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+// Limit returns route middleware enforcing limits under name, which keeps the route's
+// buckets apart from those of commands and other routes. Rejected requests get 429.
+//
+//	app.RegisterRoute("POST /login", handleLogin,
+//	    app.RateLimiter.Limit("login", core.RateLimit{Key: core.RateLimitByIP, Requests: 10, Per: time.Minute}))
+func (l *RateLimiter) Limit(name string, limits ...RateLimit) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if err := l.Check(r.Context(), name, limits...); err != nil {
+				retryAfter, _ := RateLimited(err)
+				slog.Warn("Request rate limited", "name", name, "retry_after", retryAfter,
+					"request_id", RequestIDFromContext(r.Context()))
+				WriteRateLimited(w, r, retryAfter)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// clientIPKey is the context key holding the client IP.
+type clientIPKey struct{}
+
+// WithClientIP returns a copy of ctx carrying the IP of the client a request came from.
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey{}, ip)
+}
+
+// ClientIPFromContext returns the client IP stored in ctx, or "" if there is none.
+func ClientIPFromContext(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey{}).(string)
+	return ip
+}
+
+// ClientIP puts the client's IP into the request context for rate limits and logs.
+// It is the remote address of the connection, unless trustProxy is set: then it is
+// the last address in X-Forwarded-For, the one added by the proxy in front of the
+// application. Only trust the header when every request passes through such a proxy.
+func ClientIP(trustProxy bool) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := remoteIP(r.RemoteAddr)
+			if trustProxy {
+				if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+					parts := strings.Split(forwarded, ",")
+					if last := strings.TrimSpace(parts[len(parts)-1]); net.ParseIP(last) != nil {
+						ip = last
+					}
+				}
+			}
+			next.ServeHTTP(w, r.WithContext(WithClientIP(r.Context(), ip)))
+		})
+	}
+}
+
+// remoteIP strips the port from a RemoteAddr.
+func remoteIP(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
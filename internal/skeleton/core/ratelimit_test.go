@@ -0,0 +1,222 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// fakeNow returns a clock function and a way to move it forward.
+func fakeNow() (func() time.Time, func(time.Duration)) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	return func() time.Time { return now }, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestRateLimiter_TokenBucket(t *testing.T) {
+	limiter := NewRateLimiter()
+	now, advance := fakeNow()
+	limiter.now = now
+	limit := RateLimit{Key: RateLimitByIP, Requests: 2, Per: time.Minute, Burst: 3}
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := limiter.Allow("k", limit); !ok {
+			t.Fatalf("Expected request %d of the burst to be allowed", i+1)
+		}
+	}
+	ok, retryAfter := limiter.Allow("k", limit)
+	if ok {
+		t.Fatal("Expected the request after the burst to be rejected")
+	}
+	if retryAfter != 30*time.Second {
+		t.Errorf("Expected to retry after 30s, got %s", retryAfter)
+	}
+
+	advance(30 * time.Second)
+	if ok, _ := limiter.Allow("k", limit); !ok {
+		t.Error("Expected a refilled token to be allowed")
+	}
+	if ok, _ := limiter.Allow("other", limit); !ok {
+		t.Error("Expected other keys to have their own bucket")
+	}
+}
+
+func TestRateLimiter_Persist(t *testing.T) {
+	app := newTestApp(t)
+	limit := RateLimit{Key: RateLimitByIP, Requests: 1, Per: time.Hour}
+
+	first := NewRateLimiter()
+	first.Persist(app.KVStore)
+	if ok, _ := first.Allow("k", limit); !ok {
+		t.Fatal("Expected the first request to be allowed")
+	}
+
+	// A new limiter, as after a restart, sees the empty bucket
+	second := NewRateLimiter()
+	second.Persist(app.KVStore)
+	if ok, _ := second.Allow("k", limit); ok {
+		t.Error("Expected the persisted bucket to still be empty")
+	}
+}
+
+func TestRateLimiter_CheckKeys(t *testing.T) {
+	limiter := NewRateLimiter()
+	one := func(key RateLimitKey) RateLimit { return RateLimit{Key: key, Requests: 1, Per: time.Hour} }
+	ip1 := WithClientIP(context.Background(), "10.0.0.1")
+	ip2 := WithClientIP(context.Background(), "10.0.0.2")
+	alice := WithPrincipal(ip1, Principal{UserID: "alice"})
+	aliceElsewhere := WithPrincipal(ip2, Principal{UserID: "alice"})
+
+	if err := limiter.Check(context.Background(), "test/cmd", one(RateLimitByCommand)); err != nil {
+		t.Errorf("Expected requests without a client IP not to be limited, got %v", err)
+	}
+
+	if err := limiter.Check(ip1, "by-ip", one(RateLimitByIP)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := limiter.Check(ip2, "by-ip", one(RateLimitByIP)); err != nil {
+		t.Errorf("Expected another IP to have its own bucket, got %v", err)
+	}
+
+	if err := limiter.Check(alice, "by-principal", one(RateLimitByPrincipal)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	err := limiter.Check(aliceElsewhere, "by-principal", one(RateLimitByPrincipal))
+	if !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Expected the same user from another IP to share a bucket, got %v", err)
+	}
+
+	if err := limiter.Check(ip1, "global", one(RateLimitByCommand)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, limited := RateLimited(limiter.Check(ip2, "global", one(RateLimitByCommand))); !limited {
+		t.Error("Expected all clients to share the command bucket")
+	}
+}
+
+func TestRateLimiter_CheckSpendsNothingWhenRejected(t *testing.T) {
+	limiter := NewRateLimiter()
+	ctx := WithClientIP(context.Background(), "10.0.0.1")
+	perIP := RateLimit{Key: RateLimitByIP, Requests: 2, Per: time.Hour}
+	global := RateLimit{Key: RateLimitByCommand, Requests: 1, Per: time.Hour}
+
+	if err := limiter.Check(ctx, "test/cmd", perIP, global); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := limiter.Check(ctx, "test/cmd", perIP, global); !errors.Is(err, ErrRateLimited) {
+			t.Fatalf("Expected the empty command bucket to reject the request, got %v", err)
+		}
+	}
+	key, _ := bucketKey(ctx, "test/cmd", perIP)
+	if ok, _ := limiter.Allow(key, perIP); !ok {
+		t.Error("Expected rejected requests not to use up the per-IP bucket")
+	}
+}
+
+func TestRateLimiter_LimitsWithTheSameKeyHaveTheirOwnBuckets(t *testing.T) {
+	now, _ := fakeNow()
+	perMinute := RateLimit{Key: RateLimitByIP, Requests: 10, Per: time.Minute}
+	perDay := RateLimit{Key: RateLimitByIP, Requests: 1000, Per: 24 * time.Hour}
+	anonymous := RateLimit{Key: RateLimitByPrincipal, Requests: 10, Per: time.Minute}
+	ctx := WithClientIP(context.Background(), "10.0.0.1")
+
+	for _, limits := range [][]RateLimit{{perMinute, perDay}, {perDay, perMinute}, {perMinute, anonymous}} {
+		limiter := NewRateLimiter()
+		limiter.now = now
+		allowed := 0
+		for i := 0; i < 100; i++ {
+			if limiter.Check(ctx, "test/cmd", limits...) == nil {
+				allowed++
+			}
+		}
+		if allowed != 10 {
+			t.Errorf("Expected %+v to allow 10 requests, got %d", limits, allowed)
+		}
+	}
+}
+
+func TestExecutor_RateLimits(t *testing.T) {
+	app := newTestApp(t)
+	applied := 0
+	app.CommandRegistry.Register(&streamIncrementCommand{}, func(ctx context.Context, cmd Command, msg *Message, pctx *ProcessingContext) error {
+		applied++
+		return nil
+	}, streamAcceptAll{})
+	app.MessageLog.RegisterType(&streamIncrementCommand{})
+	app.CommandRegistry.SetRateLimits("test/increment", RateLimit{Key: RateLimitByIP, Requests: 1, Per: time.Hour})
+
+	ctx := WithClientIP(context.Background(), "10.0.0.1")
+	if err := app.Executor.Execute(ctx, &streamIncrementCommand{By: 1}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	err := app.Executor.Execute(ctx, &streamIncrementCommand{By: 1})
+	if retryAfter, limited := RateLimited(err); !limited || retryAfter <= 0 {
+		t.Fatalf("Expected a rate limit error, got %v", err)
+	}
+	if err := app.Executor.Execute(context.Background(), &streamIncrementCommand{By: 1}); err != nil {
+		t.Errorf("Expected commands without a client IP to pass, got %v", err)
+	}
+	if applied != 2 {
+		t.Errorf("Expected 2 applied commands, got %d", applied)
+	}
+}
+
+func TestRateLimiter_LimitMiddleware(t *testing.T) {
+	limiter := NewRateLimiter()
+	h := Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}), ClientIP(false), limiter.Limit("login", RateLimit{Key: RateLimitByIP, Requests: 1, Per: time.Minute}))
+
+	request := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/login", nil)
+		r.RemoteAddr = "192.0.2.1:1234"
+		h.ServeHTTP(rec, r)
+		return rec
+	}
+
+	if rec := request(); rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected the first request to pass, got %d", rec.Code)
+	}
+	rec := request()
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "60" {
+		t.Errorf("Expected Retry-After 60, got %q", got)
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name       string
+		trustProxy bool
+		forwarded  string
+		want       string
+	}{
+		{"remote address", false, "", "192.0.2.1"},
+		{"forwarded header ignored", false, "203.0.113.9", "192.0.2.1"},
+		{"last forwarded address", true, "198.51.100.7, 203.0.113.9", "203.0.113.9"},
+		{"invalid forwarded address", true, "nonsense", "192.0.2.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			h := ClientIP(tt.trustProxy)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = ClientIPFromContext(r.Context())
+			}))
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = "192.0.2.1:1234"
+			if tt.forwarded != "" {
+				r.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			h.ServeHTTP(httptest.NewRecorder(), r)
+			if got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,380 @@
+package core
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Span kinds, as in OpenTelemetry.
+const (
+	SpanKindInternal = "internal"
+	SpanKindServer   = "server"   // Handles an HTTP request
+	SpanKindConsumer = "consumer" // Handles a message from the log
+)
+
+// SpanContext identifies a span within a trace.
+type SpanContext struct {
+	TraceID string `json:"trace_id"` // 32 hex digits
+	SpanID  string `json:"span_id"`  // 16 hex digits
+}
+
+// IsValid reports whether c identifies a span.
+func (c SpanContext) IsValid() bool {
+	return validTraceHex(c.TraceID, 32) && validTraceHex(c.SpanID, 16)
+}
+
+// Span is a timed operation within a trace. Spans are started with Tracer.Start and
+// exported when Finish is called. The methods of a nil Span do nothing, so code can be
+// traced whether or not a tracer is configured.
+type Span struct {
+	Name       string                 `json:"name"`
+	Kind       string                 `json:"kind"` // One of the SpanKind constants
+	TraceID    string                 `json:"trace_id"`
+	SpanID     string                 `json:"span_id"`
+	ParentID   string                 `json:"parent_id,omitempty"`
+	Start      time.Time              `json:"start"`
+	End        time.Time              `json:"end"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+	Links      []SpanContext          `json:"links,omitempty"` // Spans that caused this one outside its trace
+	Error      string                 `json:"error,omitempty"`
+
+	tracer *Tracer
+	mu     sync.Mutex
+	ended  bool
+}
+
+// SpanContext returns the identity of s, or the zero SpanContext for a nil span.
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return SpanContext{TraceID: s.TraceID, SpanID: s.SpanID}
+}
+
+// SetName renames s, e.g. once the route that serves a request is known.
+func (s *Span) SetName(name string) {
+	s.update(func() { s.Name = name })
+}
+
+// SetKind sets the kind of s to one of the SpanKind constants.
+func (s *Span) SetKind(kind string) {
+	s.update(func() { s.Kind = kind })
+}
+
+// SetAttribute records key with a string, number or boolean value.
+func (s *Span) SetAttribute(key string, value interface{}) {
+	s.update(func() {
+		if s.Attributes == nil {
+			s.Attributes = make(map[string]interface{})
+		}
+		s.Attributes[key] = value
+	})
+}
+
+// AddLink records that s was caused by the span c, typically in another trace.
+// Invalid span contexts are ignored.
+func (s *Span) AddLink(c SpanContext) {
+	if !c.IsValid() {
+		return
+	}
+	s.update(func() { s.Links = append(s.Links, c) })
+}
+
+// RecordError marks s as failed with err, if err is not nil.
+func (s *Span) RecordError(err error) {
+	if err == nil {
+		return
+	}
+	s.update(func() { s.Error = err.Error() })
+}
+
+// Finish ends s and hands it to the tracer's exporter. Later calls do nothing.
+func (s *Span) Finish() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.End = time.Now()
+	s.mu.Unlock()
+	s.tracer.export(s)
+}
+
+// update applies fn to s unless s is nil or has ended.
+func (s *Span) update(fn func()) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ended {
+		fn()
+	}
+}
+
+// spanKey is the context key of the current span.
+type spanKey struct{}
+
+// ContextWithSpan returns a copy of ctx in which span is the current span.
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// SpanFromContext returns the current span of ctx, or nil.
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// SpanExporter sends finished spans somewhere, such as a file or a collector.
+type SpanExporter interface {
+	ExportSpans(ctx context.Context, spans []*Span) error
+	Shutdown(ctx context.Context) error
+}
+
+// Span batching limits of the Tracer.
+const (
+	traceQueueSize     = 2048
+	traceBatchSize     = 256
+	traceFlushInterval = 2 * time.Second
+)
+
+// Tracer starts spans and exports them in batches in the background. Spans are
+// created even without an exporter, so their IDs still show up in logs.
+// NewApp creates one as App.Tracer, and NewAppFromConfig gives it the exporter
+// chosen in the [trace] config section.
+type Tracer struct {
+	mu       sync.RWMutex // Guards exporter and queue against Shutdown
+	exporter SpanExporter
+	queue    chan *Span
+	done     chan struct{}
+	dropped  atomic.Int64 // Spans discarded because the queue was full
+}
+
+// NewTracer creates a tracer that exports to exporter, or only creates spans if
+// exporter is nil.
+func NewTracer(exporter SpanExporter) *Tracer {
+	t := &Tracer{}
+	if exporter != nil {
+		t.SetExporter(exporter)
+	}
+	return t
+}
+
+// SetExporter starts exporting spans with exporter. Call it once, before the tracer is used.
+func (t *Tracer) SetExporter(exporter SpanExporter) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.exporter != nil {
+		panic("tracer already has an exporter")
+	}
+	t.exporter = exporter
+	t.queue = make(chan *Span, traceQueueSize)
+	t.done = make(chan struct{})
+	go t.run(exporter, t.queue, t.done)
+}
+
+// Start starts a span named name as a child of the current span of ctx, or as the
+// root of a new trace, and returns a context in which it is the current span.
+// Callers must call Finish on the span. On a nil tracer it returns ctx and a nil span.
+func (t *Tracer) Start(ctx context.Context, name string) (context.Context, *Span) {
+	return t.startSpan(ctx, name, SpanFromContext(ctx).SpanContext())
+}
+
+// startSpan starts a span whose parent is identified by parent, if it is valid.
+func (t *Tracer) startSpan(ctx context.Context, name string, parent SpanContext) (context.Context, *Span) {
+	if t == nil {
+		return ctx, nil
+	}
+	span := &Span{Name: name, Kind: SpanKindInternal, SpanID: newTraceHex(8), Start: time.Now(), tracer: t}
+	if parent.IsValid() {
+		span.TraceID, span.ParentID = parent.TraceID, parent.SpanID
+	} else {
+		span.TraceID = newTraceHex(16)
+	}
+	return ContextWithSpan(ctx, span), span
+}
+
+// export queues a finished span, dropping it if the exporter can't keep up.
+func (t *Tracer) export(span *Span) {
+	if t == nil {
+		return
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.queue == nil {
+		return
+	}
+	select {
+	case t.queue <- span:
+	default:
+		t.dropped.Add(1)
+	}
+}
+
+// run exports queued spans in batches until Shutdown closes the queue.
+func (t *Tracer) run(exporter SpanExporter, queue <-chan *Span, done chan<- struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(traceFlushInterval)
+	defer ticker.Stop()
+
+	var batch []*Span
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := exporter.ExportSpans(ctx, batch); err != nil {
+			slog.Error("Failed to export spans", "spans", len(batch), "error", err)
+		}
+		batch = nil
+	}
+	for {
+		select {
+		case span, ok := <-queue:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, span)
+			if len(batch) >= traceBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// Shutdown exports the queued spans and shuts the exporter down. Spans that end
+// afterwards are discarded. App.Close calls it.
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	queue, done, exporter := t.queue, t.done, t.exporter
+	t.queue = nil
+	t.mu.Unlock()
+	if queue == nil {
+		return nil
+	}
+	if dropped := t.dropped.Load(); dropped > 0 {
+		slog.Warn("Spans were dropped because the exporter could not keep up", "dropped", dropped)
+	}
+
+	close(queue)
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return fmt.Errorf("failed to export remaining spans: %w", ctx.Err())
+	}
+	return exporter.Shutdown(ctx)
+}
+
+// Tracing starts a server span for every request, continuing the trace of an
+// incoming W3C traceparent header. The span is named after the route pattern once
+// App.RegisterRoute's handler runs, and records the method, path, status and request ID.
+func Tracing(t *Tracer) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			parent, _ := parseTraceparent(r.Header.Get("traceparent"))
+			ctx, span := t.startSpan(r.Context(), r.Method+" unmatched", parent)
+			span.SetKind(SpanKindServer)
+			span.SetAttribute("http.request.method", r.Method)
+			span.SetAttribute("url.path", r.URL.Path)
+			if id := RequestIDFromContext(ctx); id != "" {
+				span.SetAttribute("request_id", id)
+			}
+
+			rec := &responseRecorder{ResponseWriter: w}
+			next.ServeHTTP(rec, r.WithContext(ctx))
+
+			status := rec.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			span.SetAttribute("http.response.status_code", status)
+			if status >= 500 {
+				span.RecordError(errors.New(http.StatusText(status)))
+			}
+			span.Finish()
+		})
+	}
+}
+
+// parseTraceparent reads the trace and parent span from a header such as
+// 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01.
+func parseTraceparent(header string) (SpanContext, bool) {
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return SpanContext{}, false
+	}
+	c := SpanContext{TraceID: parts[1], SpanID: parts[2]}
+	return c, c.IsValid()
+}
+
+// newTraceHex returns n random bytes as lowercase hex.
+func newTraceHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		// Fall back to the clock; IDs only need to be unique in practice
+		s := strconv.FormatInt(time.Now().UnixNano(), 16)
+		return strings.Repeat("0", 2*n-len(s)) + s
+	}
+	return hex.EncodeToString(b)
+}
+
+// validTraceHex reports whether s is n lowercase hex digits, not all zero.
+func validTraceHex(s string, n int) bool {
+	if len(s) != n || strings.Trim(s, "0") == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !(s[i] >= '0' && s[i] <= '9' || s[i] >= 'a' && s[i] <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// traceLogHandler adds the current span's IDs to log records.
+type traceLogHandler struct {
+	slog.Handler
+}
+
+// NewTraceLogHandler wraps h so that records logged with a context holding a span,
+// e.g. with slog.InfoContext, carry its trace_id and span_id.
+func NewTraceLogHandler(h slog.Handler) slog.Handler {
+	return traceLogHandler{h}
+}
+
+func (h traceLogHandler) Handle(ctx context.Context, record slog.Record) error {
+	if span := SpanFromContext(ctx); span != nil {
+		record.AddAttrs(slog.String("trace_id", span.TraceID), slog.String("span_id", span.SpanID))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h traceLogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return traceLogHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h traceLogHandler) WithGroup(name string) slog.Handler {
+	return traceLogHandler{h.Handler.WithGroup(name)}
+}
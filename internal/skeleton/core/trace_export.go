@@ -0,0 +1,264 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Trace exporters, as named in the [trace] config section.
+const (
+	TraceExporterNone = "none"
+	TraceExporterFile = "file"
+	TraceExporterOTLP = "otlp"
+)
+
+// NewSpanExporter creates the exporter chosen by c, or returns nil for none.
+func (c *TraceConfig) NewSpanExporter() SpanExporter {
+	service := c.ServiceName
+	if service == "" {
+		service = strings.TrimSuffix(filepath.Base(os.Args[0]), ".exe")
+	}
+	switch c.Exporter {
+	case TraceExporterFile:
+		return NewFileSpanExporter(c.File, service)
+	case TraceExporterOTLP:
+		return NewOTLPSpanExporter(c.Endpoint, service)
+	}
+	return nil
+}
+
+// FileSpanExporter appends spans to a file as JSON, one object per line, for
+// local debugging or shipping with a log collector.
+type FileSpanExporter struct {
+	path    string
+	service string
+
+	mu   sync.Mutex
+	file *os.File // Opened on the first export
+}
+
+// NewFileSpanExporter creates an exporter appending to path. Each span records service.
+func NewFileSpanExporter(path, service string) *FileSpanExporter {
+	return &FileSpanExporter{path: path, service: service}
+}
+
+// ExportSpans appends spans to the file.
+func (e *FileSpanExporter) ExportSpans(ctx context.Context, spans []*Span) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.file == nil {
+		file, err := os.OpenFile(e.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			return fmt.Errorf("failed to open trace file: %w", err)
+		}
+		e.file = file
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, span := range spans {
+		record := struct {
+			Service string `json:"service"`
+			*Span
+		}{e.service, span}
+		if err := encoder.Encode(record); err != nil {
+			return fmt.Errorf("failed to encode span: %w", err)
+		}
+	}
+	if _, err := e.file.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to write spans: %w", err)
+	}
+	return nil
+}
+
+// Shutdown closes the file.
+func (e *FileSpanExporter) Shutdown(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.file == nil {
+		return nil
+	}
+	err := e.file.Close()
+	e.file = nil
+	return err
+}
+
+// OTLPSpanExporter posts spans to an OpenTelemetry collector using OTLP over HTTP
+// with JSON encoding, as accepted by the OpenTelemetry Collector, Jaeger and most
+// tracing vendors.
+type OTLPSpanExporter struct {
+	url     string
+	service string
+	client  *http.Client
+}
+
+// NewOTLPSpanExporter creates an exporter posting to endpoint, such as
+// http://localhost:4318. The path /v1/traces is added unless endpoint ends with it.
+func NewOTLPSpanExporter(endpoint, service string) *OTLPSpanExporter {
+	url := strings.TrimSuffix(endpoint, "/")
+	if !strings.HasSuffix(url, "/v1/traces") {
+		url += "/v1/traces"
+	}
+	return &OTLPSpanExporter{url: url, service: service, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// ExportSpans posts spans to the collector.
+func (e *OTLPSpanExporter) ExportSpans(ctx context.Context, spans []*Span) error {
+	body, err := json.Marshal(e.request(spans))
+	if err != nil {
+		return fmt.Errorf("failed to encode spans: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create OTLP request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post spans to %s: %w", e.url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("collector at %s answered %s: %s", e.url, resp.Status, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
+
+// Shutdown does nothing; every export is a separate request.
+func (e *OTLPSpanExporter) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+// OTLP/JSON types, see https://opentelemetry.io/docs/specs/otlp/#json-protobuf-encoding
+type otlpTraceRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Links             []otlpLink     `json:"links,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"` // 64-bit integers are strings in OTLP/JSON
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+}
+
+type otlpLink struct {
+	TraceID string `json:"traceId"`
+	SpanID  string `json:"spanId"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"` // 0 unset, 1 ok, 2 error
+	Message string `json:"message,omitempty"`
+}
+
+var otlpSpanKinds = map[string]int{SpanKindInternal: 1, SpanKindServer: 2, SpanKindConsumer: 5}
+
+func (e *OTLPSpanExporter) request(spans []*Span) otlpTraceRequest {
+	out := make([]otlpSpan, len(spans))
+	for i, span := range spans {
+		s := otlpSpan{
+			TraceID:           span.TraceID,
+			SpanID:            span.SpanID,
+			ParentSpanID:      span.ParentID,
+			Name:              span.Name,
+			Kind:              otlpSpanKinds[span.Kind],
+			StartTimeUnixNano: strconv.FormatInt(span.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.End.UnixNano(), 10),
+			Attributes:        otlpAttributes(span.Attributes),
+		}
+		for _, link := range span.Links {
+			s.Links = append(s.Links, otlpLink{TraceID: link.TraceID, SpanID: link.SpanID})
+		}
+		if span.Error != "" {
+			s.Status = otlpStatus{Code: 2, Message: span.Error}
+		}
+		out[i] = s
+	}
+	return otlpTraceRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: otlpAttributes(map[string]interface{}{"service.name": e.service})},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: "petrock"}, Spans: out}},
+	}}}
+}
+
+// otlpAttributes converts attributes, sorted by key. Values of other types are
+// reported as strings.
+func otlpAttributes(attributes map[string]interface{}) []otlpKeyValue {
+	keys := make([]string, 0, len(attributes))
+	for key := range attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	kvs := make([]otlpKeyValue, 0, len(keys))
+	for _, key := range keys {
+		var value otlpAnyValue
+		switch v := attributes[key].(type) {
+		case bool:
+			value.BoolValue = &v
+		case int:
+			s := strconv.Itoa(v)
+			value.IntValue = &s
+		case int64:
+			s := strconv.FormatInt(v, 10)
+			value.IntValue = &s
+		case uint64:
+			s := strconv.FormatUint(v, 10)
+			value.IntValue = &s
+		case float64:
+			value.DoubleValue = &v
+		default:
+			s := fmt.Sprint(v)
+			value.StringValue = &s
+		}
+		kvs = append(kvs, otlpKeyValue{Key: key, Value: value})
+	}
+	return kvs
+}
@@ -0,0 +1,291 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingExporter keeps exported spans in memory.
+type recordingExporter struct {
+	mu    sync.Mutex
+	spans []*Span
+}
+
+func (e *recordingExporter) ExportSpans(ctx context.Context, spans []*Span) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+func (e *recordingExporter) Shutdown(ctx context.Context) error { return nil }
+
+// span returns the exported span named name.
+func (e *recordingExporter) span(t *testing.T, name string) *Span {
+	t.Helper()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, span := range e.spans {
+		if span.Name == name {
+			return span
+		}
+	}
+	t.Fatalf("No span named %q", name)
+	return nil
+}
+
+func shutdownTracer(t *testing.T, tracer *Tracer) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := tracer.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+}
+
+func TestTracer_CommandSpansAndWorkerLinks(t *testing.T) {
+	app := newTestApp(t)
+	exporter := &recordingExporter{}
+	app.Tracer.SetExporter(exporter)
+	app.CommandRegistry.Register(&streamIncrementCommand{}, func(ctx context.Context, cmd Command, msg *Message, pctx *ProcessingContext) error {
+		return nil
+	}, streamAcceptAll{})
+	app.MessageLog.RegisterType(&streamIncrementCommand{})
+
+	ctx, request := app.Tracer.Start(context.Background(), "request")
+	if err := app.Executor.Execute(ctx, &streamIncrementCommand{By: 1}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	request.Finish()
+
+	// The worker handles the logged command in a new trace linked to the append
+	var msg PersistedMessage
+	for m := range app.MessageLog.After(context.Background(), 0) {
+		msg = m
+	}
+	var handled *Span
+	worker := NewWorker("counter", "Counts", nil)
+	worker.OnCommand("test/increment", func(ctx context.Context, cmd Command, msg *Message, pctx *ProcessingContext) error {
+		handled = SpanFromContext(ctx)
+		return nil
+	})
+	app.RegisterWorker(worker)
+	worker.ctx = context.Background()
+	if err := worker.processMessage(msg); err != nil {
+		t.Fatalf("processMessage failed: %v", err)
+	}
+	shutdownTracer(t, app.Tracer)
+
+	command := exporter.span(t, "command test/increment")
+	if command.TraceID != request.TraceID || command.ParentID != request.SpanID {
+		t.Errorf("Expected the command span to be a child of the request, got %+v", command)
+	}
+	for _, step := range []string{"validate", "append", "apply"} {
+		if span := exporter.span(t, step); span.ParentID != command.SpanID {
+			t.Errorf("Expected %s to be a child of the command span, got parent %q", step, span.ParentID)
+		}
+	}
+
+	appendSpan := exporter.span(t, "append")
+	if msg.SpanContext() != appendSpan.SpanContext() {
+		t.Errorf("Expected the message to record the append span %+v, got %+v", appendSpan.SpanContext(), msg.SpanContext())
+	}
+
+	if handled == nil || handled != exporter.span(t, "worker counter test/increment") {
+		t.Fatal("Expected the handler to run in the exported worker span")
+	}
+	if handled.TraceID == request.TraceID || handled.Kind != SpanKindConsumer {
+		t.Errorf("Expected a consumer span in a new trace, got %+v", handled)
+	}
+	if len(handled.Links) != 1 || handled.Links[0] != appendSpan.SpanContext() {
+		t.Errorf("Expected a link to the append span, got %+v", handled.Links)
+	}
+}
+
+func TestTracing_Middleware(t *testing.T) {
+	exporter := &recordingExporter{}
+	tracer := NewTracer(exporter)
+	var logs bytes.Buffer
+	logger := slog.New(NewTraceLogHandler(slog.NewJSONHandler(&logs, nil)))
+
+	app := newTestApp(t)
+	app.Mux = http.NewServeMux()
+	app.Use(RequestID(), Tracing(tracer))
+	app.RegisterRoute("GET /items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		logger.InfoContext(r.Context(), "Loading item")
+		http.Error(w, "boom", http.StatusInternalServerError)
+	})
+
+	req := httptest.NewRequest("GET", "/items/1", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	app.Handler().ServeHTTP(httptest.NewRecorder(), req)
+	shutdownTracer(t, tracer)
+
+	span := exporter.span(t, "GET /items/{id}")
+	if span.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || span.ParentID != "00f067aa0ba902b7" {
+		t.Errorf("Expected the incoming trace to continue, got %+v", span)
+	}
+	if span.Kind != SpanKindServer || span.Attributes["http.response.status_code"] != 500 || span.Error == "" {
+		t.Errorf("Unexpected span %+v", span)
+	}
+	if span.Attributes["request_id"] == nil {
+		t.Error("Expected the request ID to be recorded")
+	}
+
+	var record map[string]interface{}
+	if err := json.Unmarshal(logs.Bytes(), &record); err != nil {
+		t.Fatalf("Failed to decode log record %q: %v", logs.String(), err)
+	}
+	if record["trace_id"] != span.TraceID || record["span_id"] != span.SpanID {
+		t.Errorf("Expected the log record to carry the span's IDs, got %v", record)
+	}
+}
+
+func TestParseTraceparent(t *testing.T) {
+	tests := []struct {
+		header string
+		valid  bool
+	}{
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true},
+		{"", false},
+		{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", false},
+		{"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", false},
+		{"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-01", false},
+	}
+	for _, tt := range tests {
+		if _, valid := parseTraceparent(tt.header); valid != tt.valid {
+			t.Errorf("parseTraceparent(%q): expected valid=%v", tt.header, tt.valid)
+		}
+	}
+}
+
+func TestTracer_NilIsNoop(t *testing.T) {
+	var tracer *Tracer
+	ctx, span := tracer.Start(context.Background(), "nothing")
+	span.SetAttribute("key", "value")
+	span.RecordError(os.ErrNotExist)
+	span.Finish()
+	if span != nil || SpanFromContext(ctx) != nil {
+		t.Error("Expected no span from a nil tracer")
+	}
+}
+
+func TestFileSpanExporter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces.jsonl")
+	tracer := NewTracer(NewFileSpanExporter(path, "blog"))
+	ctx, parent := tracer.Start(context.Background(), "parent")
+	_, child := tracer.Start(ctx, "child")
+	child.SetAttribute("items", 3)
+	child.Finish()
+	parent.Finish()
+	shutdownTracer(t, tracer)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read trace file: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 spans, got %q", data)
+	}
+	var record struct {
+		Service    string                 `json:"service"`
+		Name       string                 `json:"name"`
+		TraceID    string                 `json:"trace_id"`
+		ParentID   string                 `json:"parent_id"`
+		Attributes map[string]interface{} `json:"attributes"`
+	}
+	if err := json.Unmarshal([]byte(lines[0]), &record); err != nil {
+		t.Fatalf("Failed to decode span: %v", err)
+	}
+	if record.Service != "blog" || record.Name != "child" || record.TraceID != parent.TraceID ||
+		record.ParentID != parent.SpanID || record.Attributes["items"] != 3.0 {
+		t.Errorf("Unexpected span %+v", record)
+	}
+}
+
+func TestOTLPSpanExporter(t *testing.T) {
+	var body otlpTraceRequest
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Unexpected request %s %s", r.URL.Path, r.Header.Get("Content-Type"))
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	exporter := NewOTLPSpanExporter(server.URL, "blog")
+	link := SpanContext{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7"}
+	span := &Span{
+		Name: "worker summarizer posts/create", Kind: SpanKindConsumer,
+		TraceID: "0af7651916cd43dd8448eb211c80319c", SpanID: "b7ad6b7169203331",
+		Start: time.Unix(1, 0), End: time.Unix(2, 0),
+		Attributes: map[string]interface{}{"message.id": uint64(7), "worker": "summarizer"},
+		Links:      []SpanContext{link},
+		Error:      "boom",
+	}
+	if err := exporter.ExportSpans(context.Background(), []*Span{span}); err != nil {
+		t.Fatalf("ExportSpans failed: %v", err)
+	}
+
+	resource := body.ResourceSpans[0]
+	if v := resource.Resource.Attributes[0]; v.Key != "service.name" || *v.Value.StringValue != "blog" {
+		t.Errorf("Unexpected resource %+v", resource.Resource)
+	}
+	got := resource.ScopeSpans[0].Spans[0]
+	if got.TraceID != span.TraceID || got.Kind != 5 || got.StartTimeUnixNano != "1000000000" ||
+		got.Status.Code != 2 || len(got.Links) != 1 || got.Links[0].SpanID != link.SpanID {
+		t.Errorf("Unexpected span %+v", got)
+	}
+	if got.Attributes[0].Key != "message.id" || *got.Attributes[0].Value.IntValue != "7" {
+		t.Errorf("Unexpected attributes %+v", got.Attributes)
+	}
+
+	status = http.StatusBadRequest
+	if err := exporter.ExportSpans(context.Background(), []*Span{span}); err == nil {
+		t.Error("Expected an error when the collector rejects spans")
+	}
+}
+
+func TestMessageLog_AddsTraceColumns(t *testing.T) {
+	db, err := SetupDatabase(filepath.Join(t.TempDir(), "old.db"))
+	if err != nil {
+		t.Fatalf("SetupDatabase failed: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`CREATE TABLE messages (id INTEGER PRIMARY KEY AUTOINCREMENT, timestamp DATETIME NOT NULL, type TEXT NOT NULL, data BLOB NOT NULL);
+		INSERT INTO messages (timestamp, type, data) VALUES (CURRENT_TIMESTAMP, 'test/increment', '{"by":1}')`); err != nil {
+		t.Fatalf("Failed to create an old log: %v", err)
+	}
+
+	log, err := NewMessageLog(db, &JSONEncoder{})
+	if err != nil {
+		t.Fatalf("NewMessageLog failed: %v", err)
+	}
+	log.RegisterType(&streamIncrementCommand{})
+	count := 0
+	for msg := range log.After(context.Background(), 0) {
+		count++
+		if msg.SpanContext().IsValid() {
+			t.Errorf("Expected no span for an old message, got %+v", msg.SpanContext())
+		}
+	}
+	if count != 1 {
+		t.Errorf("Expected the old message to be read, got %d messages", count)
+	}
+}
@@ -1,6 +1,7 @@
 package ui
 
 import (
+	"context"
 	"net/url"
 
 	g "maragu.dev/gomponents"
@@ -224,16 +225,37 @@ func CSRFInput(token string) g.Node {
 	)
 }
 
+// csrfTokenKey is the context key holding the request's CSRF token.
+type csrfTokenKey struct{}
+
+// WithCSRFToken returns a copy of ctx carrying the request's CSRF token. core.CSRF
+// calls it for every request.
+func WithCSRFToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, csrfTokenKey{}, token)
+}
+
+// CSRFToken returns the CSRF token of the request ctx belongs to, or "" without core.CSRF.
+func CSRFToken(ctx context.Context) string {
+	token, _ := ctx.Value(csrfTokenKey{}).(string)
+	return token
+}
+
+// CSRFField renders the hidden CSRF input for the request ctx belongs to. Every form
+// that POSTs to the application needs it; core.CSRF rejects submissions without it.
+func CSRFField(ctx context.Context) g.Node {
+	return CSRFInput(CSRFToken(ctx))
+}
+
 // Example usage:
 //
 // Creating a complete form with validation integration:
 //
-//   func MyFormHandler(formData *ui.FormData) g.Node {
+//   func MyFormHandler(ctx context.Context, formData *ui.FormData) g.Node {
 //     return ui.Layout("My Form", 
 //       ui.Page("Contact Form",
 //         html.Form(
 //           html.Method("POST"),
-//           ui.CSRFInput("csrf-token-here"),
+//           ui.CSRFField(ctx),
 //           
 //           // Text input with integrated validation
 //           ui.FormGroupWithValidation(formData, "name", "Full Name",
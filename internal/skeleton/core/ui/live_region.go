@@ -0,0 +1,49 @@
+package ui
+
+import (
+	g "maragu.dev/gomponents"
+	"maragu.dev/gomponents/html"
+)
+
+// LiveRegionProps defines the properties for the LiveRegion component
+type LiveRegionProps struct {
+	ID        string // element id, must be unique on the page and stable across renders
+	StreamURL string // query stream endpoint, e.g. /queries/posts/list/stream?page=1
+}
+
+// liveRegionScript subscribes to the stream given in data-live-stream and, whenever
+// a changed result arrives, re-fetches the current page and swaps in the region's
+// freshly rendered content. The first event only confirms the initial render.
+const liveRegionScript = `(function(region){
+  if (!region || !window.EventSource) { return; }
+  var initial = true;
+  var source = new EventSource(region.dataset.liveStream);
+  source.addEventListener("result", function() {
+    if (initial) { initial = false; return; }
+    fetch(window.location.href, {headers: {"Accept": "text/html"}})
+      .then(function(response) { return response.text(); })
+      .then(function(text) {
+        var doc = new DOMParser().parseFromString(text, "text/html");
+        var fresh = doc.getElementById(region.id);
+        if (fresh) { region.innerHTML = fresh.innerHTML; }
+      });
+  });
+  source.addEventListener("error", function() {
+    if (source.readyState === EventSource.CLOSED) { region.removeAttribute("data-live"); }
+  });
+})(document.currentScript.previousElementSibling);`
+
+// LiveRegion wraps content that is re-rendered in place whenever the result of the
+// query behind StreamURL changes. The page itself stays server-rendered; the region
+// simply reloads its own markup from the current URL.
+func LiveRegion(props LiveRegionProps, children ...g.Node) g.Node {
+	return g.Group([]g.Node{
+		html.Div(
+			html.ID(props.ID),
+			g.Attr("data-live", "true"),
+			g.Attr("data-live-stream", props.StreamURL),
+			g.Group(children),
+		),
+		html.Script(g.Raw(liveRegionScript)),
+	})
+}
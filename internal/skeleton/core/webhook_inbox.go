@@ -0,0 +1,422 @@
+package core
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// InboundWebhookPrefix is the path prefix of inbound webhook routes. serve exempts it
+// from CSRF checks, as every inbound webhook verifies its signature instead.
+const InboundWebhookPrefix = "/webhooks/"
+
+// Inbox entry statuses
+const (
+	InboxReceived  = "received"  // Verified and recorded; its command is being executed
+	InboxProcessed = "processed" // The mapped command was executed
+	InboxIgnored   = "ignored"   // The mapping returned no command
+	InboxFailed    = "failed"    // Mapping or executing failed; the provider's retry processes it again
+)
+
+// DefaultWebhookTolerance is how far the timestamp of a signed request may be from the
+// current time when HMACVerifier.Tolerance is not set.
+const DefaultWebhookTolerance = 5 * time.Minute
+
+// InboxClaimTimeout is how long an event stays claimed by the request processing it. A
+// received entry whose claim is older was abandoned, e.g. by a process that crashed, and
+// the provider's next retry processes it again.
+const InboxClaimTimeout = time.Minute
+
+// maxInboundWebhookBody limits the size of inbound webhook payloads.
+const maxInboundWebhookBody = 1 << 20
+
+// WebhookVerifier checks that a request comes from the provider it claims to be from.
+type WebhookVerifier interface {
+	Verify(r *http.Request, body []byte, now time.Time) error
+}
+
+// WebhookVerifierFunc adapts a function to a WebhookVerifier, for providers with their
+// own signature schemes.
+type WebhookVerifierFunc func(r *http.Request, body []byte, now time.Time) error
+
+func (f WebhookVerifierFunc) Verify(r *http.Request, body []byte, now time.Time) error {
+	return f(r, body, now)
+}
+
+// HMACVerifier checks an HMAC-SHA256 signature of the body sent in a header, the scheme
+// most providers use. With a TimestampHeader, the signed content is the timestamp, a
+// '.' and the body, and requests whose timestamp is off by more than Tolerance are
+// rejected, so that recorded requests can't be replayed later.
+type HMACVerifier struct {
+	Secret          string
+	Header          string        // Header holding the signature, e.g. X-Hub-Signature-256
+	Prefix          string        // Removed from the header value before decoding, e.g. "sha256="
+	Base64          bool          // Signatures are base64 rather than hex encoded
+	TimestampHeader string        // Header holding the Unix time of signing, if the provider sends one
+	Tolerance       time.Duration // Defaults to DefaultWebhookTolerance
+}
+
+// NewWebhookSignatureVerifier returns a verifier for deliveries of outbound webhooks
+// from another petrock application, signed with secret (see SignWebhook).
+func NewWebhookSignatureVerifier(secret string) *HMACVerifier {
+	return &HMACVerifier{
+		Secret:          secret,
+		Header:          WebhookSignatureHeader,
+		Prefix:          "sha256=",
+		TimestampHeader: WebhookTimestampHeader,
+	}
+}
+
+// Verify checks the signature of the request. Without a Secret every request is
+// rejected, as anyone could sign it.
+func (v *HMACVerifier) Verify(r *http.Request, body []byte, now time.Time) error {
+	if v.Secret == "" {
+		return errors.New("no secret configured")
+	}
+	signature := r.Header.Get(v.Header)
+	if signature == "" {
+		return fmt.Errorf("missing %s header", v.Header)
+	}
+	if !strings.HasPrefix(signature, v.Prefix) {
+		return fmt.Errorf("%s header does not start with %q", v.Header, v.Prefix)
+	}
+	var sent []byte
+	var err error
+	if v.Base64 {
+		sent, err = base64.StdEncoding.DecodeString(strings.TrimPrefix(signature, v.Prefix))
+	} else {
+		sent, err = hex.DecodeString(strings.TrimPrefix(signature, v.Prefix))
+	}
+	if err != nil {
+		return fmt.Errorf("malformed %s header: %w", v.Header, err)
+	}
+
+	mac := hmac.New(sha256.New, []byte(v.Secret))
+	if v.TimestampHeader != "" {
+		value := r.Header.Get(v.TimestampHeader)
+		timestamp, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("missing or malformed %s header", v.TimestampHeader)
+		}
+		tolerance := v.Tolerance
+		if tolerance <= 0 {
+			tolerance = DefaultWebhookTolerance
+		}
+		if age := now.Sub(time.Unix(timestamp, 0)); age > tolerance || age < -tolerance {
+			return fmt.Errorf("timestamp %s is outside the tolerance of %s", value, tolerance)
+		}
+		mac.Write([]byte(value))
+		mac.Write([]byte("."))
+	}
+	mac.Write(body)
+	if !hmac.Equal(sent, mac.Sum(nil)) {
+		return errors.New("signature does not match")
+	}
+	return nil
+}
+
+// InboundWebhook turns a provider's callbacks into commands.
+type InboundWebhook struct {
+	Provider string          // Name of the provider, e.g. stripe; event IDs are unique per provider
+	Path     string          // Route of the callbacks; must start with InboundWebhookPrefix
+	Verifier WebhookVerifier // Required
+
+	// EventID returns the provider's ID of the event, which deduplicates deliveries.
+	// Without it, the SHA-256 of the body is used.
+	EventID func(r *http.Request, body []byte) (string, error)
+
+	// Map turns a verified payload into the command to execute. Returning a nil command
+	// acknowledges events the application doesn't care about.
+	Map func(ctx context.Context, r *http.Request, body []byte) (Command, error)
+}
+
+// InboxEntry is a verified inbound webhook payload recorded in the inbox.
+type InboxEntry struct {
+	ID         uint64            `json:"id"`
+	Provider   string            `json:"provider"`
+	EventID    string            `json:"event_id"`
+	Header     map[string]string `json:"header,omitempty"`
+	Body       string            `json:"body"`
+	Status     string            `json:"status"`
+	Command    string            `json:"command,omitempty"` // Name of the mapped command
+	Attempts   int               `json:"attempts"`
+	LastError  string            `json:"last_error,omitempty"`
+	ReceivedAt time.Time         `json:"received_at"`
+	UpdatedAt  time.Time         `json:"updated_at"`
+}
+
+// WebhookInbox records inbound webhook payloads in SQLite and executes the commands
+// they map to, once per provider event ID.
+type WebhookInbox struct {
+	db       *sql.DB
+	executor *Executor
+}
+
+// NewWebhookInbox creates the inbox table if needed. Mapped commands are executed
+// with executor.
+func NewWebhookInbox(db *sql.DB, executor *Executor) (*WebhookInbox, error) {
+	query := `
+		CREATE TABLE IF NOT EXISTS webhook_inbox (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			provider TEXT NOT NULL,
+			event_id TEXT NOT NULL,
+			header TEXT NOT NULL DEFAULT '{}',
+			body BLOB NOT NULL,
+			status TEXT NOT NULL,
+			command TEXT NOT NULL DEFAULT '',
+			attempts INTEGER NOT NULL DEFAULT 0,
+			last_error TEXT NOT NULL DEFAULT '',
+			received_at INTEGER NOT NULL,
+			updated_at INTEGER NOT NULL,
+			UNIQUE (provider, event_id)
+		)
+	`
+	if _, err := db.Exec(query); err != nil {
+		return nil, fmt.Errorf("failed to create webhook inbox table: %w", err)
+	}
+	return &WebhookInbox{db: db, executor: executor}, nil
+}
+
+// RegisterInboundWebhook validates hook and registers its route, answering:
+//
+//   - 401 if the signature doesn't verify,
+//   - 200 once the mapped command was executed, or for an event processed before,
+//   - 409 while the same event is being processed by another request,
+//   - 422 if the payload can't be mapped, 400 if the command is invalid, 429 if it is
+//     rate limited and 500 if it can't be executed.
+//
+// Providers retry deliveries that didn't get a 2xx answer; failed events are
+// processed again when they do.
+func (a *App) RegisterInboundWebhook(hook InboundWebhook) {
+	if hook.Provider == "" || hook.Verifier == nil || hook.Map == nil {
+		panic(fmt.Sprintf("inbound webhook %q requires a provider, a verifier and a mapping", hook.Path))
+	}
+	if v, ok := hook.Verifier.(*HMACVerifier); ok && v.Secret == "" {
+		panic(fmt.Sprintf("inbound webhook %q requires a verifier secret", hook.Path))
+	}
+	if !strings.HasPrefix(hook.Path, InboundWebhookPrefix) {
+		panic(fmt.Sprintf("inbound webhook path %q must start with %s", hook.Path, InboundWebhookPrefix))
+	}
+	a.RegisterRoute("POST "+hook.Path, a.WebhookInbox.Handler(hook))
+}
+
+// Handler returns the http.HandlerFunc receiving hook's callbacks, see App.RegisterInboundWebhook.
+func (in *WebhookInbox) Handler(hook InboundWebhook) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxInboundWebhookBody+1))
+		if err != nil || len(body) > maxInboundWebhookBody {
+			http.Error(w, "Failed to read payload", http.StatusBadRequest)
+			return
+		}
+		if err := hook.Verifier.Verify(r, body, Now(ctx)); err != nil {
+			slog.WarnContext(ctx, "Rejected inbound webhook", "provider", hook.Provider, "error", err)
+			http.Error(w, "Invalid signature", http.StatusUnauthorized)
+			return
+		}
+
+		eventID := ""
+		if hook.EventID != nil {
+			eventID, err = hook.EventID(r, body)
+			if err != nil || eventID == "" {
+				slog.WarnContext(ctx, "Inbound webhook without event ID", "provider", hook.Provider, "error", err)
+				http.Error(w, "Missing event ID", http.StatusUnprocessableEntity)
+				return
+			}
+		} else {
+			sum := sha256.Sum256(body)
+			eventID = hex.EncodeToString(sum[:])
+		}
+
+		entry, claimed, err := in.claim(ctx, hook.Provider, eventID, r.Header, body)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to record inbound webhook", "provider", hook.Provider, "event", eventID, "error", err)
+			http.Error(w, "Failed to record payload", http.StatusInternalServerError)
+			return
+		}
+		if !claimed {
+			if entry.Status == InboxReceived {
+				http.Error(w, "Event is being processed", http.StatusConflict)
+				return
+			}
+			slog.InfoContext(ctx, "Duplicate inbound webhook", "provider", hook.Provider, "event", eventID, "status", entry.Status)
+			writeInboxStatus(w, entry.Status)
+			return
+		}
+
+		status, name, err := in.process(ctx, hook, r, body)
+		in.finish(ctx, entry.ID, status, name, err)
+		if err == nil {
+			slog.InfoContext(ctx, "Processed inbound webhook", "provider", hook.Provider, "event", eventID, "status", status, "command", name)
+			writeInboxStatus(w, status)
+			return
+		}
+
+		slog.WarnContext(ctx, "Failed to process inbound webhook", "provider", hook.Provider, "event", eventID, "error", err)
+		var mapErr *inboxMapError
+		var validationErr *ValidationError
+		switch retryAfter, limited := RateLimited(err); {
+		case limited:
+			WriteRateLimited(w, r, retryAfter)
+		case errors.As(err, &mapErr):
+			http.Error(w, "Unprocessable payload", http.StatusUnprocessableEntity)
+		case errors.As(err, &validationErr):
+			http.Error(w, "Invalid command", http.StatusBadRequest)
+		default:
+			http.Error(w, "Failed to process payload", http.StatusInternalServerError)
+		}
+	}
+}
+
+// inboxMapError marks an error of InboundWebhook.Map.
+type inboxMapError struct{ err error }
+
+func (e *inboxMapError) Error() string { return e.err.Error() }
+func (e *inboxMapError) Unwrap() error { return e.err }
+
+// process maps the payload and executes the command, returning the entry's new status.
+func (in *WebhookInbox) process(ctx context.Context, hook InboundWebhook, r *http.Request, body []byte) (string, string, error) {
+	cmd, err := hook.Map(ctx, r, body)
+	if err != nil {
+		return InboxFailed, "", &inboxMapError{err}
+	}
+	if cmd == nil {
+		return InboxIgnored, "", nil
+	}
+	if err := in.executor.Execute(ctx, cmd); err != nil {
+		return InboxFailed, cmd.CommandName(), err
+	}
+	return InboxProcessed, cmd.CommandName(), nil
+}
+
+func writeInboxStatus(w http.ResponseWriter, status string) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": status})
+}
+
+// claim records the payload, or finds the entry of an earlier delivery of the event.
+// It returns claimed=true if the caller should process the event: it is new, failed
+// before or was claimed more than InboxClaimTimeout ago.
+func (in *WebhookInbox) claim(ctx context.Context, provider, eventID string, header http.Header, body []byte) (InboxEntry, bool, error) {
+	headers := make(map[string]string, len(header))
+	for name := range header {
+		if name != "Authorization" && name != "Cookie" {
+			headers[name] = header.Get(name)
+		}
+	}
+	encoded, err := json.Marshal(headers)
+	if err != nil {
+		return InboxEntry{}, false, err
+	}
+
+	now := time.Now().UnixNano()
+	result, err := in.db.ExecContext(ctx, `
+		INSERT INTO webhook_inbox (provider, event_id, header, body, status, attempts, received_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, 1, ?, ?)
+		ON CONFLICT (provider, event_id) DO UPDATE SET
+			header = excluded.header, body = excluded.body, status = excluded.status,
+			attempts = attempts + 1, updated_at = excluded.updated_at
+		WHERE status = ? OR (status = ? AND updated_at <= ?)`,
+		provider, eventID, string(encoded), body, InboxReceived, now, now,
+		InboxFailed, InboxReceived, now-int64(InboxClaimTimeout))
+	if err != nil {
+		return InboxEntry{}, false, fmt.Errorf("failed to record inbound webhook: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return InboxEntry{}, false, fmt.Errorf("failed to record inbound webhook: %w", err)
+	}
+
+	entry, err := in.getBy(ctx, "provider = ? AND event_id = ?", provider, eventID)
+	return entry, rows > 0, err
+}
+
+// finish records the outcome of processing an entry.
+func (in *WebhookInbox) finish(ctx context.Context, id uint64, status, command string, err error) {
+	lastError := ""
+	if err != nil {
+		lastError = err.Error()
+	}
+	// The request may have been cancelled; the outcome must be recorded anyway
+	_, dbErr := in.db.ExecContext(context.WithoutCancel(ctx),
+		"UPDATE webhook_inbox SET status = ?, command = ?, last_error = ?, updated_at = ? WHERE id = ?",
+		status, command, lastError, time.Now().UnixNano(), id)
+	if dbErr != nil {
+		slog.ErrorContext(ctx, "Failed to record inbound webhook outcome", "id", id, "error", dbErr)
+	}
+}
+
+// Get returns the entry with the given ID.
+func (in *WebhookInbox) Get(ctx context.Context, id uint64) (InboxEntry, error) {
+	return in.getBy(ctx, "id = ?", id)
+}
+
+// List returns the entries of provider, or of all providers if provider is empty, newest first.
+func (in *WebhookInbox) List(ctx context.Context, provider string, limit int) ([]InboxEntry, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	where, args := "1 = 1", []any{}
+	if provider != "" {
+		where, args = "provider = ?", []any{provider}
+	}
+	rows, err := in.db.QueryContext(ctx, "SELECT "+inboxColumns+" FROM webhook_inbox WHERE "+where+" ORDER BY id DESC LIMIT ?", append(args, limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhook inbox: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []InboxEntry{}
+	for rows.Next() {
+		entry, err := scanInboxEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook inbox entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+const inboxColumns = "id, provider, event_id, header, body, status, command, attempts, last_error, received_at, updated_at"
+
+func (in *WebhookInbox) getBy(ctx context.Context, where string, args ...any) (InboxEntry, error) {
+	entry, err := scanInboxEntry(in.db.QueryRowContext(ctx, "SELECT "+inboxColumns+" FROM webhook_inbox WHERE "+where, args...))
+	if err == sql.ErrNoRows {
+		return InboxEntry{}, fmt.Errorf("webhook inbox entry not found")
+	}
+	if err != nil {
+		return InboxEntry{}, fmt.Errorf("failed to read webhook inbox entry: %w", err)
+	}
+	return entry, nil
+}
+
+func scanInboxEntry(row interface{ Scan(...any) error }) (InboxEntry, error) {
+	var entry InboxEntry
+	var header string
+	var body []byte
+	var receivedAt, updatedAt int64
+	err := row.Scan(&entry.ID, &entry.Provider, &entry.EventID, &header, &body, &entry.Status, &entry.Command,
+		&entry.Attempts, &entry.LastError, &receivedAt, &updatedAt)
+	if err != nil {
+		return InboxEntry{}, err
+	}
+	if err := json.Unmarshal([]byte(header), &entry.Header); err != nil {
+		return InboxEntry{}, fmt.Errorf("invalid webhook inbox headers: %w", err)
+	}
+	entry.Body = string(body)
+	entry.ReceivedAt = time.Unix(0, receivedAt)
+	entry.UpdatedAt = time.Unix(0, updatedAt)
+	return entry, nil
+}
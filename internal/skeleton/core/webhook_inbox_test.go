@@ -0,0 +1,205 @@
+package core
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// inboxValidator rejects commands while reject is set.
+type inboxValidator struct{ reject *bool }
+
+func (v inboxValidator) ValidateCommand(ctx context.Context, cmd Command) error {
+	if *v.reject {
+		return errors.New("rejected")
+	}
+	return nil
+}
+
+func TestWebhookInbox_VerifiesDeduplicatesAndExecutes(t *testing.T) {
+	app := newTestApp(t)
+	app.Mux = http.NewServeMux()
+	app.Use(CSRF(InboundWebhookPrefix))
+
+	applied := 0
+	reject := false
+	app.CommandRegistry.Register(&streamIncrementCommand{}, func(ctx context.Context, cmd Command, msg *Message, pctx *ProcessingContext) error {
+		applied += cmd.(*streamIncrementCommand).By
+		return nil
+	}, inboxValidator{&reject})
+	app.MessageLog.RegisterType(&streamIncrementCommand{})
+
+	type payload struct {
+		ID   string `json:"id"`
+		Type string `json:"type"`
+		By   int    `json:"by"`
+	}
+	app.RegisterInboundWebhook(InboundWebhook{
+		Provider: "crm",
+		Path:     "/webhooks/crm",
+		Verifier: NewWebhookSignatureVerifier(testWebhookSecret),
+		EventID: func(r *http.Request, body []byte) (string, error) {
+			var p payload
+			err := json.Unmarshal(body, &p)
+			return p.ID, err
+		},
+		Map: func(ctx context.Context, r *http.Request, body []byte) (Command, error) {
+			var p payload
+			if err := json.Unmarshal(body, &p); err != nil {
+				return nil, err
+			}
+			switch p.Type {
+			case "increment":
+				return &streamIncrementCommand{By: p.By}, nil
+			case "unknown":
+				return nil, errors.New("unknown event type")
+			}
+			return nil, nil
+		},
+	})
+
+	send := func(body string, timestamp time.Time, secret string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest("POST", "/webhooks/crm", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if secret != "" {
+			req.Header.Set(WebhookTimestampHeader, strconv.FormatInt(timestamp.Unix(), 10))
+			req.Header.Set(WebhookSignatureHeader, SignWebhook(secret, timestamp.Unix(), []byte(body)))
+		}
+		rec := httptest.NewRecorder()
+		app.Handler().ServeHTTP(rec, req)
+		return rec
+	}
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		body    string
+		at      time.Time
+		secret  string
+		status  int
+		applied int
+	}{
+		{"unsigned", `{"id":"evt_1","type":"increment","by":1}`, now, "", http.StatusUnauthorized, 0},
+		{"wrong secret", `{"id":"evt_1","type":"increment","by":1}`, now, "another secret!!", http.StatusUnauthorized, 0},
+		{"stale timestamp", `{"id":"evt_1","type":"increment","by":1}`, now.Add(-time.Hour), testWebhookSecret, http.StatusUnauthorized, 0},
+		{"signed", `{"id":"evt_1","type":"increment","by":1}`, now, testWebhookSecret, http.StatusOK, 1},
+		{"duplicate", `{"id":"evt_1","type":"increment","by":1}`, now, testWebhookSecret, http.StatusOK, 1},
+		{"ignored", `{"id":"evt_2","type":"ping"}`, now, testWebhookSecret, http.StatusOK, 1},
+		{"unmappable", `{"id":"evt_3","type":"unknown"}`, now, testWebhookSecret, http.StatusUnprocessableEntity, 1},
+		{"missing event ID", `{"type":"increment","by":1}`, now, testWebhookSecret, http.StatusUnprocessableEntity, 1},
+	}
+	for _, tt := range tests {
+		if rec := send(tt.body, tt.at, tt.secret); rec.Code != tt.status || applied != tt.applied {
+			t.Errorf("%s: expected %d with %d applied, got %d with %d applied: %s", tt.name, tt.status, tt.applied, rec.Code, applied, rec.Body.String())
+		}
+	}
+
+	// A rejected command fails the event; the provider's retry processes it again
+	reject = true
+	if rec := send(`{"id":"evt_4","type":"increment","by":10}`, now, testWebhookSecret); rec.Code != http.StatusBadRequest {
+		t.Fatalf("Expected 400 for a rejected command, got %d", rec.Code)
+	}
+	var validationErr *ValidationError
+	if err := app.Executor.Execute(context.Background(), &streamIncrementCommand{By: 1}); !errors.As(err, &validationErr) || validationErr.Err.Error() != "rejected" {
+		t.Errorf("Expected a ValidationError from the Executor, got %v", err)
+	}
+	reject = false
+	if rec := send(`{"id":"evt_4","type":"increment","by":10}`, now, testWebhookSecret); rec.Code != http.StatusOK || applied != 11 {
+		t.Fatalf("Expected the retry to be processed, got %d with %d applied", rec.Code, applied)
+	}
+
+	entries, err := app.WebhookInbox.List(context.Background(), "crm", 0)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	statuses := map[string]string{}
+	for _, entry := range entries {
+		statuses[entry.EventID] = entry.Status
+	}
+	want := map[string]string{"evt_1": InboxProcessed, "evt_2": InboxIgnored, "evt_3": InboxFailed, "evt_4": InboxProcessed}
+	for id, status := range want {
+		if statuses[id] != status {
+			t.Errorf("Expected %s to be %s, got %q", id, status, statuses[id])
+		}
+	}
+	if entries[0].EventID != "evt_4" || entries[0].Attempts != 2 || entries[0].Command != "test/increment" ||
+		entries[0].Header[WebhookSignatureHeader] == "" || !strings.Contains(entries[0].Body, `"by":10`) {
+		t.Errorf("Unexpected entry %+v", entries[0])
+	}
+}
+
+func TestHMACVerifier_Base64WithoutTimestamp(t *testing.T) {
+	verifier := &HMACVerifier{Secret: "secret", Header: "X-Signature", Base64: true}
+	body := []byte(`{"event":"paid"}`)
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write(body)
+
+	req := httptest.NewRequest("POST", "/webhooks/pay", nil)
+	req.Header.Set("X-Signature", base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+	if err := verifier.Verify(req, body, time.Now()); err != nil {
+		t.Errorf("Expected the signature to verify, got %v", err)
+	}
+	if err := verifier.Verify(req, []byte(`{"event":"refunded"}`), time.Now()); err == nil {
+		t.Error("Expected a changed body to fail verification")
+	}
+}
+
+func TestHMACVerifier_RejectsEmptySecret(t *testing.T) {
+	verifier := &HMACVerifier{Header: "X-Signature"}
+	body := []byte(`{"event":"paid"}`)
+	mac := hmac.New(sha256.New, nil)
+	mac.Write(body)
+
+	req := httptest.NewRequest("POST", "/webhooks/pay", nil)
+	req.Header.Set("X-Signature", hex.EncodeToString(mac.Sum(nil)))
+	if err := verifier.Verify(req, body, time.Now()); err == nil {
+		t.Error("Expected a verifier without a secret to reject every request")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected registering a webhook without a secret to panic")
+		}
+	}()
+	newTestApp(t).RegisterInboundWebhook(InboundWebhook{
+		Provider: "pay",
+		Path:     "/webhooks/pay",
+		Verifier: verifier,
+		Map:      func(ctx context.Context, r *http.Request, body []byte) (Command, error) { return nil, nil },
+	})
+}
+
+func TestWebhookInbox_ReclaimsAbandonedEvents(t *testing.T) {
+	app := newTestApp(t)
+	ctx := context.Background()
+	inbox := app.WebhookInbox
+
+	entry, claimed, err := inbox.claim(ctx, "crm", "evt_1", http.Header{}, []byte(`{}`))
+	if err != nil || !claimed {
+		t.Fatalf("Expected a new event to be claimed, got %v, %v", claimed, err)
+	}
+	if _, claimed, _ := inbox.claim(ctx, "crm", "evt_1", http.Header{}, []byte(`{}`)); claimed {
+		t.Error("Expected an event being processed not to be claimed again")
+	}
+
+	// The request processing it never finished
+	abandoned := time.Now().Add(-InboxClaimTimeout - time.Second).UnixNano()
+	if _, err := inbox.db.Exec("UPDATE webhook_inbox SET updated_at = ? WHERE id = ?", abandoned, entry.ID); err != nil {
+		t.Fatalf("Failed to age the claim: %v", err)
+	}
+	entry, claimed, err = inbox.claim(ctx, "crm", "evt_1", http.Header{}, []byte(`{}`))
+	if err != nil || !claimed || entry.Status != InboxReceived || entry.Attempts != 2 {
+		t.Errorf("Expected the abandoned event to be claimed again, got %+v, %v, %v", entry, claimed, err)
+	}
+}
@@ -0,0 +1,497 @@
+package core
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// WebhooksWorkerName is the name of the worker that turns logged commands into webhook deliveries.
+const WebhooksWorkerName = "webhooks"
+
+// OutboxWebhook is the effect kind of webhook deliveries in the outbox.
+const OutboxWebhook = "webhook"
+
+// Headers sent with every webhook delivery.
+const (
+	WebhookEventHeader     = "X-Webhook-Event"     // Name of the command, e.g. posts/publish
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"  // ID of the outbox entry
+	WebhookTimestampHeader = "X-Webhook-Timestamp" // Unix time the request was signed at
+	WebhookSignatureHeader = "X-Webhook-Signature" // sha256=<hex HMAC of "<timestamp>.<body>">
+)
+
+// minWebhookSecretLength is the shortest secret a subscription accepts.
+const minWebhookSecretLength = 16
+
+// WebhookSecretKeyPrefix starts the KV keys of the subscription secrets, which are kept
+// out of the message log.
+const WebhookSecretKeyPrefix = "webhooks:secret:"
+
+// WebhookSubscribeCommand creates the subscription with ID, or replaces it. The secret
+// is stored in the KV store when the command is executed and is not logged.
+type WebhookSubscribeCommand struct {
+	ID          string   `json:"id"`
+	URL         string   `json:"url"`
+	Events      []string `json:"events"`                         // Command name patterns such as posts/publish or posts/*
+	Secret      string   `json:"secret,omitempty" secret:"true"` // Key of the HMAC-SHA256 signature of every delivery
+	Description string   `json:"description,omitempty"`
+}
+
+func (c *WebhookSubscribeCommand) CommandName() string { return "webhooks/subscribe" }
+
+// MarshalJSON encodes the command without its secret, so that it never reaches the log.
+func (c WebhookSubscribeCommand) MarshalJSON() ([]byte, error) {
+	type logged WebhookSubscribeCommand
+	c.Secret = ""
+	return json.Marshal(logged(c))
+}
+
+// WebhookUnsubscribeCommand removes the subscription with ID. Its pending deliveries fail.
+type WebhookUnsubscribeCommand struct {
+	ID string `json:"id"`
+}
+
+func (c *WebhookUnsubscribeCommand) CommandName() string { return "webhooks/unsubscribe" }
+
+// WebhookSubscription receives the commands whose names match one of its Events
+// patterns, using path.Match syntax.
+type WebhookSubscription struct {
+	ID          string    `json:"id"`
+	URL         string    `json:"url"`
+	Events      []string  `json:"events"`
+	Description string    `json:"description,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Matches reports whether the subscription receives commands named commandName.
+// The commands managing subscriptions are never delivered, as they carry secrets.
+func (s *WebhookSubscription) Matches(commandName string) bool {
+	if strings.HasPrefix(commandName, "webhooks/") {
+		return false
+	}
+	for _, pattern := range s.Events {
+		if matched, _ := path.Match(pattern, commandName); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// webhookSubscriptions holds the subscriptions as of a position in the log.
+type webhookSubscriptions map[string]*WebhookSubscription
+
+// Reset forgets all subscriptions, so that a worker replay rebuilds them from the log.
+func (s webhookSubscriptions) Reset() {
+	for id := range s {
+		delete(s, id)
+	}
+}
+
+// apply updates the subscriptions with a subscription command logged at the given time.
+func (s webhookSubscriptions) apply(cmd Command, at time.Time) {
+	switch c := cmd.(type) {
+	case *WebhookSubscribeCommand:
+		sub := &WebhookSubscription{
+			ID:          c.ID,
+			URL:         c.URL,
+			Events:      append([]string(nil), c.Events...),
+			Description: c.Description,
+			CreatedAt:   at,
+			UpdatedAt:   at,
+		}
+		if existing, found := s[c.ID]; found {
+			sub.CreatedAt = existing.CreatedAt
+		}
+		s[c.ID] = sub
+	case *WebhookUnsubscribeCommand:
+		delete(s, c.ID)
+	}
+}
+
+// WebhookEvent is the JSON body posted to subscribers.
+type WebhookEvent struct {
+	ID        uint64          `json:"id"`   // ID of the message in the log
+	Type      string          `json:"type"` // Name of the command
+	Timestamp time.Time       `json:"timestamp"`
+	Data      json.RawMessage `json:"data"` // The command as logged
+}
+
+// WebhookDelivery is the payload of an OutboxWebhook entry.
+type WebhookDelivery struct {
+	Subscription string `json:"subscription"`
+	Event        string `json:"event"`
+	MessageID    uint64 `json:"message_id"`
+	Body         string `json:"body"` // Encoded WebhookEvent
+}
+
+// WebhookDeliveryEntry is an outbox entry of a webhook delivery with its decoded payload.
+type WebhookDeliveryEntry struct {
+	OutboxEntry
+	Delivery WebhookDelivery `json:"delivery"`
+}
+
+// Webhooks delivers logged commands to external systems that subscribed to them.
+// Subscriptions are managed with the webhooks/subscribe and webhooks/unsubscribe
+// commands. The webhooks worker enqueues a delivery in the outbox for every command
+// that matches a subscription; the outbox signs and posts it, retrying with backoff
+// and keeping the delivery history. Enable it with App.UseWebhooks.
+//
+// Subscriptions may only point at public hosts: URLs naming loopback, private or
+// link-local addresses are rejected, and deliveries refuse to connect to them.
+type Webhooks struct {
+	outbox *Outbox
+	kv     KVStore      // Holds the subscription secrets
+	client *http.Client // Used for deliveries; refuses internal addresses
+
+	allowInternal bool // Lets tests deliver to servers on the loopback interface
+
+	mu            sync.RWMutex
+	subscriptions webhookSubscriptions // As of the last applied command
+}
+
+// UseWebhooks enables outbound webhooks: it registers the subscription commands, the
+// webhooks worker and the outbox worker. The subscription commands are admin-only, so
+// they can't be run through the public command API. Call it before the log is
+// replayed; calling it more than once is harmless.
+func (a *App) UseWebhooks() *Webhooks {
+	if a.Webhooks != nil {
+		return a.Webhooks
+	}
+	h := &Webhooks{outbox: a.UseOutbox(), kv: a.KVStore, subscriptions: webhookSubscriptions{}}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil // A proxy would connect to internal addresses on our behalf
+	transport.DialContext = (&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, Control: h.checkDial}).DialContext
+	h.client = &http.Client{Timeout: 30 * time.Second, Transport: transport}
+
+	h.outbox.RegisterEffect(OutboxWebhook, h.deliver)
+	for _, cmd := range []Command{&WebhookSubscribeCommand{}, &WebhookUnsubscribeCommand{}} {
+		a.CommandRegistry.Register(cmd, h.handleCommand, h)
+		a.CommandRegistry.SetAdminOnly(cmd.CommandName())
+		a.MessageLog.RegisterType(cmd)
+	}
+	a.RegisterSecretKeys(WebhookSecretKeyPrefix)
+	a.RegisterWorker(h.newWorker())
+	a.Webhooks = h
+	return h
+}
+
+// ValidateCommand checks subscription commands against the current subscriptions.
+func (h *Webhooks) ValidateCommand(ctx context.Context, cmd Command) error {
+	switch c := cmd.(type) {
+	case *WebhookSubscribeCommand:
+		if err := validateWebhookID(c.ID); err != nil {
+			return err
+		}
+		u, err := url.Parse(c.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("url must be an absolute http or https URL, got %q", c.URL)
+		}
+		if err := h.checkHost(u.Hostname()); err != nil {
+			return err
+		}
+		if len(c.Events) == 0 {
+			return errors.New("events must list at least one command name pattern")
+		}
+		for _, pattern := range c.Events {
+			if _, err := path.Match(pattern, ""); err != nil || pattern == "" {
+				return fmt.Errorf("invalid event pattern %q", pattern)
+			}
+		}
+		if len(c.Secret) < minWebhookSecretLength {
+			return fmt.Errorf("secret must be at least %d characters long", minWebhookSecretLength)
+		}
+	case *WebhookUnsubscribeCommand:
+		if _, found := h.Subscription(c.ID); !found {
+			return fmt.Errorf("webhook subscription %q not found", c.ID)
+		}
+	default:
+		return fmt.Errorf("unexpected command %s", cmd.CommandName())
+	}
+	return nil
+}
+
+// validateWebhookID accepts IDs of letters, digits, '.', '_' and '-', as they become
+// part of outbox keys.
+func validateWebhookID(id string) error {
+	if id == "" {
+		return errors.New("id is required")
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '_' || r == '-') {
+			return fmt.Errorf("id %q may only contain letters, digits, '.', '_' and '-'", id)
+		}
+	}
+	return nil
+}
+
+// handleCommand applies a subscription command to the current subscriptions. When the
+// command is executed rather than replayed, it also stores or deletes the secret.
+func (h *Webhooks) handleCommand(ctx context.Context, cmd Command, msg *Message, pctx *ProcessingContext) error {
+	at := Now(ctx)
+	if msg != nil {
+		at = msg.Timestamp
+	} else {
+		switch c := cmd.(type) {
+		case *WebhookSubscribeCommand:
+			if err := h.kv.Set(WebhookSecretKeyPrefix+c.ID, c.Secret); err != nil {
+				return fmt.Errorf("failed to store the secret of webhook subscription %s: %w", c.ID, err)
+			}
+		case *WebhookUnsubscribeCommand:
+			if err := h.kv.Delete(WebhookSecretKeyPrefix + c.ID); err != nil && !errors.Is(err, ErrKeyNotFound) {
+				return fmt.Errorf("failed to delete the secret of webhook subscription %s: %w", c.ID, err)
+			}
+		}
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.subscriptions.apply(cmd, at)
+	return nil
+}
+
+// secret returns the secret of the subscription with the given ID.
+func (h *Webhooks) secret(id string) (string, error) {
+	var secret string
+	if err := h.kv.Get(WebhookSecretKeyPrefix+id, &secret); err != nil {
+		return "", fmt.Errorf("failed to get the secret of webhook subscription %s: %w", id, err)
+	}
+	return secret, nil
+}
+
+// checkHost rejects hosts that are, or obviously name, loopback, private or link-local
+// addresses. Other names are checked against the addresses they resolve to on delivery.
+func (h *Webhooks) checkHost(host string) error {
+	if h.allowInternal {
+		return nil
+	}
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("url must not point at an internal host, got %q", host)
+	}
+	if ip := net.ParseIP(host); ip != nil && internalIP(ip) {
+		return fmt.Errorf("url must not point at an internal address, got %s", ip)
+	}
+	return nil
+}
+
+// checkDial is the Control function of the delivery dialer. It sees the resolved
+// address of every connection, including those of redirects.
+func (h *Webhooks) checkDial(network, address string, _ syscall.RawConn) error {
+	if h.allowInternal {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || internalIP(ip) {
+		return fmt.Errorf("webhook deliveries to internal address %s are not allowed", host)
+	}
+	return nil
+}
+
+// internalIP reports whether ip is a loopback, private, link-local or unspecified address.
+func internalIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast()
+}
+
+// Subscriptions returns the current subscriptions ordered by ID.
+func (h *Webhooks) Subscriptions() []WebhookSubscription {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	subs := make([]WebhookSubscription, 0, len(h.subscriptions))
+	for _, sub := range h.subscriptions {
+		subs = append(subs, *sub)
+	}
+	sort.Slice(subs, func(i, j int) bool { return subs[i].ID < subs[j].ID })
+	return subs
+}
+
+// Subscription returns the current subscription with the given ID.
+func (h *Webhooks) Subscription(id string) (WebhookSubscription, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	sub, found := h.subscriptions[id]
+	if !found {
+		return WebhookSubscription{}, false
+	}
+	return *sub, true
+}
+
+// newWorker creates the worker that enqueues deliveries. It keeps its own copy of the
+// subscriptions as of the message it handles, so that a command is delivered to the
+// subscriptions that existed when it was logged, even when the worker lags behind.
+func (h *Webhooks) newWorker() *CommandWorker {
+	subs := webhookSubscriptions{}
+	worker := NewWorker(WebhooksWorkerName, "Delivers logged commands to webhook subscribers through the outbox", subs)
+	worker.SetSchedule(OnEvents())
+	worker.OnAnyCommand(func(ctx context.Context, cmd Command, msg *Message, pctx *ProcessingContext) error {
+		switch cmd.(type) {
+		case *WebhookSubscribeCommand, *WebhookUnsubscribeCommand:
+			subs.apply(cmd, msg.Timestamp)
+			return nil
+		}
+		if pctx.IsReplay {
+			return nil
+		}
+		return h.enqueue(ctx, subs, cmd, msg)
+	})
+	return worker
+}
+
+// enqueue records a delivery for every subscription matching cmd. The outbox key makes
+// this idempotent, so the worker can retry the message safely.
+func (h *Webhooks) enqueue(ctx context.Context, subs webhookSubscriptions, cmd Command, msg *Message) error {
+	name := cmd.CommandName()
+	var body []byte
+	for _, sub := range subs {
+		if !sub.Matches(name) {
+			continue
+		}
+		if body == nil {
+			var err error
+			body, err = json.Marshal(WebhookEvent{ID: msg.ID, Type: name, Timestamp: msg.Timestamp, Data: json.RawMessage(msg.Data)})
+			if err != nil {
+				return fmt.Errorf("failed to encode webhook event: %w", err)
+			}
+		}
+		_, _, err := h.outbox.Enqueue(ctx, OutboxItem{
+			Key:     fmt.Sprintf("webhook/%s/%d", sub.ID, msg.ID),
+			Kind:    OutboxWebhook,
+			Source:  WebhooksWorkerName,
+			Payload: WebhookDelivery{Subscription: sub.ID, Event: name, MessageID: msg.ID, Body: string(body)},
+			Meta:    map[string]string{"subscription": sub.ID, "event": name},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to enqueue webhook delivery to %s: %w", sub.ID, err)
+		}
+	}
+	return nil
+}
+
+// deliver is the effect for OutboxWebhook entries. It signs the body with the current
+// secret of the subscription and posts it to the subscription's current URL with the
+// guarded client; responses are handled like those of OutboxHTTP entries.
+func (h *Webhooks) deliver(ctx context.Context, entry OutboxEntry) (json.RawMessage, error) {
+	var delivery WebhookDelivery
+	if err := json.Unmarshal(entry.Payload, &delivery); err != nil {
+		return nil, Permanent(fmt.Errorf("invalid webhook delivery payload: %w", err))
+	}
+	sub, found := h.Subscription(delivery.Subscription)
+	if !found {
+		return nil, Permanent(fmt.Errorf("webhook subscription %q no longer exists", delivery.Subscription))
+	}
+	secret, err := h.secret(sub.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	timestamp := time.Now().Unix()
+	request, err := json.Marshal(HTTPRequest{
+		Method: http.MethodPost,
+		URL:    sub.URL,
+		Header: map[string]string{
+			"Content-Type":         "application/json",
+			WebhookEventHeader:     delivery.Event,
+			WebhookDeliveryHeader:  strconv.FormatUint(entry.ID, 10),
+			WebhookTimestampHeader: strconv.FormatInt(timestamp, 10),
+			WebhookSignatureHeader: SignWebhook(secret, timestamp, []byte(delivery.Body)),
+		},
+		Body: delivery.Body,
+	})
+	if err != nil {
+		return nil, Permanent(fmt.Errorf("failed to encode webhook request: %w", err))
+	}
+	entry.Payload = request
+	return h.outbox.sendHTTP(ctx, h.client, entry)
+}
+
+// SignWebhook returns the X-Webhook-Signature value of a delivery: "sha256=" followed by
+// the hex encoded HMAC-SHA256 of the timestamp, a '.' and the body, keyed with secret.
+// Receivers recompute it to check that a delivery is authentic.
+func SignWebhook(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Deliveries returns the deliveries to the given subscription, or to all subscriptions
+// if subscription is empty, newest first.
+func (h *Webhooks) Deliveries(ctx context.Context, subscription string, limit int) ([]WebhookDeliveryEntry, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	var entries []OutboxEntry
+	var err error
+	if subscription == "" {
+		entries, err = h.outbox.list(ctx, "kind = ? ORDER BY id DESC LIMIT ?", OutboxWebhook, limit)
+	} else {
+		prefix := "webhook/" + subscription + "/"
+		entries, err = h.outbox.list(ctx, "kind = ? AND substr(key, 1, ?) = ? ORDER BY id DESC LIMIT ?",
+			OutboxWebhook, len(prefix), prefix, limit)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := make([]WebhookDeliveryEntry, len(entries))
+	for i, entry := range entries {
+		deliveries[i].OutboxEntry = entry
+		if err := json.Unmarshal(entry.Payload, &deliveries[i].Delivery); err != nil {
+			return nil, fmt.Errorf("invalid payload of webhook delivery %d: %w", entry.ID, err)
+		}
+	}
+	return deliveries, nil
+}
+
+// Redeliver enqueues a new delivery of the same event to the same subscription as the
+// delivery with the given ID, whatever its status. It has a new idempotency key, so
+// receivers that dropped the original as a duplicate process it again.
+func (h *Webhooks) Redeliver(ctx context.Context, id uint64) (OutboxEntry, error) {
+	entry, err := h.outbox.Get(ctx, id)
+	if err != nil {
+		return OutboxEntry{}, err
+	}
+	if entry.Kind != OutboxWebhook {
+		return OutboxEntry{}, fmt.Errorf("outbox entry %d is not a webhook delivery", id)
+	}
+	var delivery WebhookDelivery
+	if err := json.Unmarshal(entry.Payload, &delivery); err != nil {
+		return OutboxEntry{}, fmt.Errorf("invalid payload of webhook delivery %d: %w", id, err)
+	}
+
+	meta := map[string]string{"redelivery_of": strconv.FormatUint(id, 10)}
+	for key, value := range entry.Meta {
+		if key != "redelivery_of" {
+			meta[key] = value
+		}
+	}
+	redelivery, _, err := h.outbox.Enqueue(ctx, OutboxItem{
+		Key:     fmt.Sprintf("webhook/%s/%d/redelivery/%d", delivery.Subscription, delivery.MessageID, time.Now().UnixNano()),
+		Kind:    OutboxWebhook,
+		Source:  entry.Source,
+		Payload: delivery,
+		Meta:    meta,
+	})
+	if err != nil {
+		return OutboxEntry{}, fmt.Errorf("failed to redeliver webhook delivery %d: %w", id, err)
+	}
+	return redelivery, nil
+}
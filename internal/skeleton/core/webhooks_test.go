@@ -0,0 +1,256 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/petrock/example_module_path/core/outboxtest"
+)
+
+const testWebhookSecret = "0123456789abcdef"
+
+func TestWebhooks_DeliversSignedCommandsWithRetries(t *testing.T) {
+	app := newTestApp(t)
+	ctx := context.Background()
+	app.CommandRegistry.Register(&streamIncrementCommand{}, func(ctx context.Context, cmd Command, msg *Message, pctx *ProcessingContext) error {
+		return nil
+	}, streamAcceptAll{})
+	app.MessageLog.RegisterType(&streamIncrementCommand{})
+
+	hooks := app.UseWebhooks()
+	if app.UseWebhooks() != hooks {
+		t.Fatal("Expected UseWebhooks to return the same instance")
+	}
+	hooks.allowInternal = true
+	app.Outbox.SetRetryPolicy(RetryPolicy{MaxAttempts: 3})
+	server := outboxtest.NewServer(t,
+		outboxtest.Response{Status: http.StatusServiceUnavailable},
+		outboxtest.Response{Status: http.StatusOK},
+	)
+
+	for _, cmd := range []Command{
+		&WebhookSubscribeCommand{ID: "crm", URL: server.URL + "/hook", Events: []string{"test/*"}, Secret: testWebhookSecret},
+		&WebhookSubscribeCommand{ID: "other", URL: server.URL + "/other", Events: []string{"posts/publish"}, Secret: testWebhookSecret},
+	} {
+		if err := app.Executor.Execute(ctx, cmd); err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+	}
+	if subs := hooks.Subscriptions(); len(subs) != 2 || subs[0].ID != "crm" {
+		t.Fatalf("Unexpected subscriptions %+v", subs)
+	}
+
+	// The secret is kept in the KV store, not in the log
+	if secret, err := hooks.secret("crm"); err != nil || secret != testWebhookSecret {
+		t.Fatalf("Expected the stored secret, got %q, %v", secret, err)
+	}
+	for msg := range app.MessageLog.After(ctx, 0) {
+		if strings.Contains(string(msg.Data), testWebhookSecret) {
+			t.Errorf("Expected no secret in the log, got %s", msg.Data)
+		}
+	}
+	if !app.secretKey(WebhookSecretKeyPrefix + "crm") {
+		t.Error("Expected the secrets to be hidden in the admin area")
+	}
+
+	// The first run treats the existing log as history
+	if err := app.RunWorkerOnce(ctx, WebhooksWorkerName); err != nil {
+		t.Fatalf("RunWorkerOnce failed: %v", err)
+	}
+	if err := app.Executor.Execute(ctx, &streamIncrementCommand{By: 2}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if err := app.RunWorkerOnce(ctx, WebhooksWorkerName); err != nil {
+		t.Fatalf("RunWorkerOnce failed: %v", err)
+	}
+
+	// 503 is retried, then delivered
+	for i := 0; i < 2; i++ {
+		if _, err := app.Outbox.Dispatch(ctx); err != nil {
+			t.Fatalf("Dispatch failed: %v", err)
+		}
+	}
+
+	requests := server.Requests()
+	if len(requests) != 2 {
+		t.Fatalf("Expected 2 requests, got %+v", requests)
+	}
+	req := requests[1]
+	var event WebhookEvent
+	if err := json.Unmarshal([]byte(req.Body), &event); err != nil {
+		t.Fatalf("Failed to decode webhook body %q: %v", req.Body, err)
+	}
+	if req.Path != "/hook" || event.Type != "test/increment" || string(event.Data) != `{"by":2}` || event.ID != 3 {
+		t.Errorf("Unexpected delivery %+v with event %+v", req, event)
+	}
+	timestamp, err := strconv.ParseInt(req.Header.Get(WebhookTimestampHeader), 10, 64)
+	if err != nil {
+		t.Fatalf("Invalid timestamp header: %v", err)
+	}
+	if got := req.Header.Get(WebhookSignatureHeader); got != SignWebhook(testWebhookSecret, timestamp, []byte(req.Body)) {
+		t.Errorf("Signature %q does not match the body", got)
+	}
+	if req.Header.Get(WebhookEventHeader) != "test/increment" || req.Header.Get("Idempotency-Key") != "webhook/crm/3" {
+		t.Errorf("Unexpected headers %v", req.Header)
+	}
+
+	deliveries, err := hooks.Deliveries(ctx, "crm", 0)
+	if err != nil {
+		t.Fatalf("Deliveries failed: %v", err)
+	}
+	if len(deliveries) != 1 || deliveries[0].Status != OutboxDelivered || deliveries[0].Attempts != 2 || deliveries[0].Delivery.MessageID != 3 {
+		t.Fatalf("Unexpected deliveries %+v", deliveries)
+	}
+	if other, _ := hooks.Deliveries(ctx, "other", 0); len(other) != 0 {
+		t.Errorf("Expected no deliveries to a subscription that doesn't match, got %+v", other)
+	}
+
+	// A redelivery is a new entry with its own idempotency key
+	redelivery, err := hooks.Redeliver(ctx, deliveries[0].ID)
+	if err != nil {
+		t.Fatalf("Redeliver failed: %v", err)
+	}
+	if _, err := app.Outbox.Dispatch(ctx); err != nil {
+		t.Fatalf("Dispatch failed: %v", err)
+	}
+	requests = server.Requests()
+	if len(requests) != 3 || requests[2].Body != req.Body || requests[2].Header.Get("Idempotency-Key") != redelivery.Key {
+		t.Errorf("Unexpected redelivery %+v", requests[len(requests)-1])
+	}
+	if all, _ := hooks.Deliveries(ctx, "", 0); len(all) != 2 || all[0].Meta["redelivery_of"] != strconv.FormatUint(deliveries[0].ID, 10) {
+		t.Errorf("Expected the redelivery in the history, got %+v", all)
+	}
+
+	// The admin page lists both and the redeliver form works through the handler
+	rec := httptest.NewRecorder()
+	HandleWebhooksPage(app).ServeHTTP(rec, httptest.NewRequest("GET", "/_/admin/webhooks", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "/_/admin/webhooks/deliveries/1/redeliver") {
+		t.Errorf("Unexpected admin page %d: %s", rec.Code, rec.Body.String())
+	}
+	post := httptest.NewRequest("POST", "/_/admin/webhooks/deliveries/1/redeliver", nil)
+	post.SetPathValue("id", "1")
+	rec = httptest.NewRecorder()
+	HandleWebhookRedeliver(app).ServeHTTP(rec, post)
+	if rec.Code != http.StatusSeeOther {
+		t.Errorf("Expected a redirect after redelivering, got %d", rec.Code)
+	}
+}
+
+func TestWebhooks_UnsubscribeFailsPendingDeliveries(t *testing.T) {
+	app := newTestApp(t)
+	ctx := context.Background()
+	hooks := app.UseWebhooks()
+	hooks.allowInternal = true
+	server := outboxtest.NewServer(t)
+
+	subscribe := &WebhookSubscribeCommand{ID: "crm", URL: server.URL, Events: []string{"*/*"}, Secret: testWebhookSecret}
+	if err := app.Executor.Execute(ctx, subscribe); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	entry, _, err := app.Outbox.Enqueue(ctx, OutboxItem{
+		Key: "webhook/crm/1", Kind: OutboxWebhook, Source: WebhooksWorkerName,
+		Payload: WebhookDelivery{Subscription: "crm", Event: "test/increment", MessageID: 1, Body: "{}"},
+	})
+	if err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	if err := app.Executor.Execute(ctx, &WebhookUnsubscribeCommand{ID: "crm"}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if _, err := app.Outbox.Dispatch(ctx); err != nil {
+		t.Fatalf("Dispatch failed: %v", err)
+	}
+
+	failed, _ := app.Outbox.Get(ctx, entry.ID)
+	if failed.Status != OutboxFailed || len(server.Requests()) != 0 || len(hooks.Subscriptions()) != 0 {
+		t.Errorf("Expected the delivery to fail without a request, got %+v", failed)
+	}
+	if _, err := hooks.secret("crm"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected the secret to be deleted, got %v", err)
+	}
+	if (&WebhookSubscription{Events: []string{"*/*"}}).Matches("webhooks/subscribe") {
+		t.Error("Expected subscription commands never to be delivered")
+	}
+}
+
+func TestWebhooks_ValidateCommand(t *testing.T) {
+	app := newTestApp(t)
+	hooks := app.UseWebhooks()
+	valid := WebhookSubscribeCommand{ID: "crm", URL: "https://example.com/hook", Events: []string{"posts/*"}, Secret: testWebhookSecret}
+
+	tests := []struct {
+		name   string
+		modify func(c *WebhookSubscribeCommand)
+	}{
+		{"missing id", func(c *WebhookSubscribeCommand) { c.ID = "" }},
+		{"id with slash", func(c *WebhookSubscribeCommand) { c.ID = "a/b" }},
+		{"relative url", func(c *WebhookSubscribeCommand) { c.URL = "/hook" }},
+		{"ftp url", func(c *WebhookSubscribeCommand) { c.URL = "ftp://example.com" }},
+		{"no events", func(c *WebhookSubscribeCommand) { c.Events = nil }},
+		{"bad pattern", func(c *WebhookSubscribeCommand) { c.Events = []string{"posts/["} }},
+		{"short secret", func(c *WebhookSubscribeCommand) { c.Secret = "short" }},
+		{"localhost", func(c *WebhookSubscribeCommand) { c.URL = "http://localhost:8080/hook" }},
+		{"loopback", func(c *WebhookSubscribeCommand) { c.URL = "http://127.0.0.1/hook" }},
+		{"ipv6 loopback", func(c *WebhookSubscribeCommand) { c.URL = "http://[::1]/hook" }},
+		{"private", func(c *WebhookSubscribeCommand) { c.URL = "https://10.0.0.5/hook" }},
+		{"link-local", func(c *WebhookSubscribeCommand) { c.URL = "http://169.254.169.254/latest" }},
+		{"unspecified", func(c *WebhookSubscribeCommand) { c.URL = "http://0.0.0.0/hook" }},
+	}
+	if err := hooks.ValidateCommand(context.Background(), &valid); err != nil {
+		t.Fatalf("Expected a valid command, got %v", err)
+	}
+	for _, tt := range tests {
+		cmd := valid
+		tt.modify(&cmd)
+		if err := hooks.ValidateCommand(context.Background(), &cmd); err == nil {
+			t.Errorf("%s: expected a validation error", tt.name)
+		}
+	}
+	if err := hooks.ValidateCommand(context.Background(), &WebhookUnsubscribeCommand{ID: "unknown"}); err == nil {
+		t.Error("Expected unsubscribing an unknown subscription to fail")
+	}
+}
+
+func TestWebhooks_AreAdminOnlyAndRefuseInternalAddresses(t *testing.T) {
+	app := newTestApp(t)
+	ctx := context.Background()
+	hooks := app.UseWebhooks()
+
+	for _, name := range app.CommandRegistry.PublicCommandNames() {
+		if strings.HasPrefix(name, "webhooks/") {
+			t.Errorf("Expected %s to be left out of the public commands", name)
+		}
+	}
+	if !app.CommandRegistry.AdminOnly("webhooks/subscribe") || !app.CommandRegistry.AdminOnly("webhooks/unsubscribe") {
+		t.Error("Expected the subscription commands to be admin-only")
+	}
+
+	// Deliveries are refused when the host turns out to be internal, e.g. after a DNS change
+	server := outboxtest.NewServer(t)
+	hooks.allowInternal = true
+	subscribe := &WebhookSubscribeCommand{ID: "crm", URL: server.URL, Events: []string{"*/*"}, Secret: testWebhookSecret}
+	if err := app.Executor.Execute(ctx, subscribe); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	hooks.allowInternal = false
+	entry, _, err := app.Outbox.Enqueue(ctx, OutboxItem{
+		Key: "webhook/crm/1", Kind: OutboxWebhook, Source: WebhooksWorkerName,
+		Payload: WebhookDelivery{Subscription: "crm", Event: "test/increment", MessageID: 1, Body: "{}"},
+	})
+	if err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	if _, err := app.Outbox.Dispatch(ctx); err != nil {
+		t.Fatalf("Dispatch failed: %v", err)
+	}
+	refused, _ := app.Outbox.Get(ctx, entry.ID)
+	if refused.Status == OutboxDelivered || len(server.Requests()) != 0 || !strings.Contains(refused.LastError, "internal address") {
+		t.Errorf("Expected the delivery to be refused, got %+v", refused)
+	}
+}
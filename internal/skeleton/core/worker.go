@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"sync"
 )
 
 // WorkerError represents an error that occurred during worker operations.
@@ -111,11 +112,23 @@ type CommandWorker struct {
 	description  string
 	state        interface{}
 	handlers     map[string]CommandHandler
+	anyHandler   CommandHandler // Set by OnAnyCommand
 	periodicWork func(context.Context) error
+	schedule     Schedule
+	retryPolicy  *RetryPolicy
+	retries      map[uint64]RetryState // Failed messages awaiting another attempt, by message ID
+	retryMu      sync.Mutex
+	progress     WorkerProgress
+	progressMu   sync.Mutex
+	replayed     bool // State reflects the log up to the follower position
+	partitionKey func(Command) string
+	partitions   int
+	completed    map[uint64]bool // Messages handled out of order, beyond the follower position
 	log          *MessageLog
 	executor     *Executor
 	follower     LogFollower
 	kvStore      KVStore
+	tracer       *Tracer // Set by UseTracer
 	ctx          context.Context
 	cancel       context.CancelFunc
 	started      bool
@@ -138,11 +151,52 @@ func (w *CommandWorker) OnCommand(commandName string, handler CommandHandler) {
 	w.handlers[commandName] = handler
 }
 
+// OnAnyCommand registers a handler for every command that has no handler of its own,
+// for workers that react to commands of other features, such as the webhooks worker.
+func (w *CommandWorker) OnAnyCommand(handler CommandHandler) {
+	w.anyHandler = handler
+}
+
+// handlerFor returns the handler registered for the command name, falling back to the
+// one registered with OnAnyCommand.
+func (w *CommandWorker) handlerFor(commandName string) (CommandHandler, bool) {
+	if handler, found := w.handlers[commandName]; found {
+		return handler, true
+	}
+	return w.anyHandler, w.anyHandler != nil
+}
+
 // SetPeriodicWork sets the periodic work function that gets called during Work() cycles
 func (w *CommandWorker) SetPeriodicWork(fn func(context.Context) error) {
 	w.periodicWork = fn
 }
 
+// SetSchedule declares when the App runs this worker's Work cycles, e.g.
+// core.Every(time.Hour), core.MustCron("0 3 * * *") or core.OnEvents().
+// Workers without a schedule run on DefaultSchedule.
+func (w *CommandWorker) SetSchedule(schedule Schedule) {
+	w.schedule = schedule
+}
+
+// SetRetryPolicy sets how often and how quickly a message whose handler fails is retried
+// before it is moved to the dead-letter list. Workers without a policy use DefaultRetryPolicy.
+func (w *CommandWorker) SetRetryPolicy(policy RetryPolicy) {
+	w.retryPolicy = &policy
+}
+
+// RetryPolicy returns the worker's retry policy
+func (w *CommandWorker) RetryPolicy() RetryPolicy {
+	if w.retryPolicy != nil {
+		return *w.retryPolicy
+	}
+	return DefaultRetryPolicy()
+}
+
+// Schedule returns the schedule declared with SetSchedule, or nil if none was set
+func (w *CommandWorker) Schedule() Schedule {
+	return w.schedule
+}
+
 // State returns the worker's current state
 func (w *CommandWorker) State() interface{} {
 	return w.state
@@ -164,9 +218,15 @@ func (w *CommandWorker) SetDependencies(log *MessageLog, executor *Executor, kvS
 	w.kvStore = kvStore
 }
 
+// UseTracer makes the worker trace the handling of every message with a span that
+// links to the span that appended the message. App.RegisterWorker calls it.
+func (w *CommandWorker) UseTracer(tracer *Tracer) {
+	w.tracer = tracer
+}
+
 // positionKey returns the KVStore key for this worker's position
 func (w *CommandWorker) positionKey() string {
-	return fmt.Sprintf("worker:%s:position", w.name)
+	return workerPositionKey(w.name)
 }
 
 // Start initializes the worker
@@ -182,27 +242,56 @@ func (w *CommandWorker) Start(ctx context.Context) error {
 	return nil
 }
 
+// savePosition stores the follower's position, unless the worker's lease was lost and
+// another process may have moved the position on since.
+func (w *CommandWorker) savePosition() error {
+	follower, ok := w.follower.(*SimpleLogFollower)
+	if !ok {
+		return nil
+	}
+	if err := ValidateLease(w.ctx); err != nil {
+		return err
+	}
+	return follower.SavePosition(w.kvStore, w.positionKey())
+}
+
 // Replay processes all messages from the beginning to reconstruct worker state
 func (w *CommandWorker) Replay(ctx context.Context) error {
 	if w.kvStore == nil {
 		return fmt.Errorf("kvStore not set for worker %s", w.name)
 	}
 
+	// A worker that ran before in this process (e.g. until it lost its lease) already has
+	// state for the messages up to its position and only needs to catch up from there
+	replayedThrough := uint64(0)
+	if w.replayed {
+		replayedThrough = w.follower.LogPosition()
+	}
+
 	// Load last known position from KV store - cast the follower to access methods
+	resuming := false
 	if follower, ok := w.follower.(*SimpleLogFollower); ok {
 		if err := follower.LoadPosition(w.kvStore, w.positionKey()); err != nil {
 			// If no position found, start from beginning
 			w.follower.LogSeek(0)
+		} else {
+			resuming = true
 		}
 	}
+	w.loadRetryState()
 
 	startPosition := w.follower.LogPosition()
 	slog.Info("Starting message replay", "worker", w.name, "fromPosition", startPosition)
 
-	// Replay ALL messages from beginning to reconstruct state
+	// Replay messages from the beginning to reconstruct state. A worker resuming from a
+	// saved position stops there, leaving later messages (including one that is being
+	// retried) to Work(); a new worker treats the whole existing log as history.
 	messageCount := 0
 	var lastMessageID uint64
-	for msg := range w.log.After(ctx, 0) { // Always start from beginning for state reconstruction
+	for msg := range w.log.After(ctx, replayedThrough) {
+		if resuming && msg.ID > startPosition {
+			break
+		}
 		if err := w.replayMessage(msg); err != nil {
 			slog.Error("Failed to replay message", "worker", w.name, "id", msg.ID, "error", err)
 			continue
@@ -213,21 +302,50 @@ func (w *CommandWorker) Replay(ctx context.Context) error {
 	}
 
 	// Set position to latest message ID to avoid reprocessing
-	if messageCount > 0 {
+	if messageCount > 0 && !resuming {
 		w.follower.LogSeek(lastMessageID)
 	}
+	// Messages this instance already handled live must not be handled again
+	if replayedThrough > w.follower.LogPosition() {
+		w.follower.LogSeek(replayedThrough)
+	}
+	w.replayed = true
 
 	// Save final position
-	if follower, ok := w.follower.(*SimpleLogFollower); ok {
-		if err := follower.SavePosition(w.kvStore, w.positionKey()); err != nil {
-			return fmt.Errorf("failed to save final position: %w", err)
-		}
+	if err := w.savePosition(); err != nil {
+		return fmt.Errorf("failed to save final position: %w", err)
 	}
 
+	w.recordProgress()
 	slog.Info("Message replay completed", "worker", w.name, "messagesProcessed", messageCount, "finalPosition", w.follower.LogPosition())
 	return nil
 }
 
+// HandleMessage passes a single message to the worker's handler for its command, the way
+// Replay (replay=true) or Work would, but without reading the log, saving the position or
+// retrying. The worker must be started. It is meant for test harnesses such as core/workertest.
+func (w *CommandWorker) HandleMessage(msg PersistedMessage, replay bool) error {
+	if !w.started {
+		return ErrWorkerStopped
+	}
+	if replay {
+		return w.replayMessage(msg)
+	}
+	return w.processMessage(msg)
+}
+
+// RunPeriodicWork runs the function set with SetPeriodicWork once, without processing new
+// messages. The worker must be started. It is meant for test harnesses such as core/workertest.
+func (w *CommandWorker) RunPeriodicWork() error {
+	if !w.started {
+		return ErrWorkerStopped
+	}
+	if w.periodicWork == nil {
+		return nil
+	}
+	return w.periodicWork(w.ctx)
+}
+
 // replayMessage processes a message for state reconstruction only (no side effects)
 func (w *CommandWorker) replayMessage(msg PersistedMessage) error {
 	cmd, ok := msg.DecodedPayload.(Command)
@@ -235,7 +353,7 @@ func (w *CommandWorker) replayMessage(msg PersistedMessage) error {
 		return nil
 	}
 
-	handler, found := w.handlers[cmd.CommandName()]
+	handler, found := w.handlerFor(cmd.CommandName())
 	if !found {
 		return nil
 	}
@@ -267,25 +385,25 @@ func (w *CommandWorker) Work() error {
 		return ErrWorkerStopped
 	}
 
+	// Give requeued dead letters another attempt before moving on
+	w.retryDeadLetters()
+
 	// Process only new messages (after current position)
 	currentPosition := w.follower.LogPosition()
-	messagesProcessed := 0
-
-	for msg := range w.log.After(w.ctx, currentPosition) {
-		if err := w.processMessage(msg); err != nil {
-			slog.Error("Failed to process message", "worker", w.name, "id", msg.ID, "error", err)
-			continue
-		}
-
-		w.follower.LogSeek(msg.ID)
-		messagesProcessed++
+	var messagesProcessed int
+	if w.partitionKey != nil {
+		messagesProcessed = w.workPartitioned(currentPosition)
+	} else {
+		messagesProcessed = w.workSequential(currentPosition)
 	}
 
 	// Persist position if we processed any messages
 	if messagesProcessed > 0 && w.kvStore != nil {
-		if follower, ok := w.follower.(*SimpleLogFollower); ok {
-			if err := follower.SavePosition(w.kvStore, w.positionKey()); err != nil {
-				slog.Error("Failed to save worker position", "worker", w.name, "error", err)
+		if err := w.savePosition(); err != nil {
+			slog.Error("Failed to save worker position", "worker", w.name, "error", err)
+			if errors.Is(err, ErrLeaseLost) {
+				w.recordProgress()
+				return err
 			}
 		}
 	}
@@ -293,13 +411,39 @@ func (w *CommandWorker) Work() error {
 	// Execute periodic work if defined
 	if w.periodicWork != nil {
 		if err := w.periodicWork(w.ctx); err != nil {
+			w.recordProgress()
 			return fmt.Errorf("periodic work failed: %w", err)
 		}
 	}
 
+	w.recordProgress()
 	return nil
 }
 
+// workSequential handles the messages after position one at a time, in log order.
+// It stops at a message that failed and is waiting to be retried.
+func (w *CommandWorker) workSequential(position uint64) int {
+	messagesProcessed := 0
+	for msg := range w.log.After(w.ctx, position) {
+		if w.backingOff(msg.ID) {
+			// Still backing off; keep the position so ordering is preserved
+			break
+		}
+
+		if err := w.processMessage(msg); err != nil {
+			if !w.recordFailure(msg, err) {
+				break
+			}
+		} else {
+			w.clearRetryState(msg.ID)
+		}
+
+		w.follower.LogSeek(msg.ID)
+		messagesProcessed++
+	}
+	return messagesProcessed
+}
+
 // WorkerInfo returns worker information
 func (w *CommandWorker) WorkerInfo() *WorkerInfo {
 	return &WorkerInfo{
@@ -315,7 +459,7 @@ func (w *CommandWorker) processMessage(msg PersistedMessage) error {
 		return nil
 	}
 
-	handler, found := w.handlers[cmd.CommandName()]
+	handler, found := w.handlerFor(cmd.CommandName())
 	if !found {
 		return nil
 	}
@@ -323,5 +467,16 @@ func (w *CommandWorker) processMessage(msg PersistedMessage) error {
 	// Create normal processing context (allows side effects)
 	normalCtx := &ProcessingContext{IsReplay: false}
 
-	return handler(w.ctx, cmd, &msg.Message, normalCtx)
+	// Handling a message starts a new trace linked to the span that appended it,
+	// so that commands the handler executes can be followed back to their cause
+	ctx, span := w.tracer.Start(w.ctx, "worker "+w.name+" "+cmd.CommandName())
+	span.SetKind(SpanKindConsumer)
+	span.SetAttribute("worker", w.name)
+	span.SetAttribute("message.id", msg.ID)
+	span.AddLink(msg.SpanContext())
+	defer span.Finish()
+
+	err := handler(ctx, cmd, &msg.Message, normalCtx)
+	span.RecordError(err)
+	return err
 }
\ No newline at end of file
@@ -0,0 +1,336 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// WorkerControlPollInterval is how often running and paused workers check their
+// WorkerControl for changes made by the CLI or by other processes.
+const WorkerControlPollInterval = time.Second
+
+// errWorkerControlChanged stops a running worker after it was paused, sought or replayed.
+var errWorkerControlChanged = errors.New("worker control changed")
+
+// WorkerControl is the operator-set state of a worker. It is stored in the KVStore, so
+// every process sharing the database honors it, including after a restart.
+type WorkerControl struct {
+	Paused   bool       `json:"paused"`
+	PausedAt *time.Time `json:"paused_at,omitempty"`
+	// Generation is incremented by SeekWorker and ReplayWorker. A process that ran the
+	// worker under an older generation discards its state and replays from scratch.
+	Generation uint64 `json:"generation"`
+}
+
+// Resetter is implemented by workers, and by CommandWorker states, that can discard what
+// they built up so that the next Replay rebuilds it from the beginning of the log.
+// SeekWorker and ReplayWorker refuse workers whose state doesn't implement it.
+type Resetter interface {
+	Reset()
+}
+
+// workerControlKey returns the KVStore key holding the worker's WorkerControl.
+func workerControlKey(workerName string) string {
+	return fmt.Sprintf("worker:%s:control", workerName)
+}
+
+// workerPositionKey returns the KVStore key holding the ID of the last message a
+// CommandWorker processed.
+func workerPositionKey(workerName string) string {
+	return fmt.Sprintf("worker:%s:position", workerName)
+}
+
+// LoadWorkerControl returns the control state of the named worker.
+// A worker that was never paused, sought or replayed has the zero WorkerControl.
+func LoadWorkerControl(kv KVStore, workerName string) (WorkerControl, error) {
+	var control WorkerControl
+	if err := kv.Get(workerControlKey(workerName), &control); err != nil {
+		if errors.Is(err, ErrKeyNotFound) {
+			return WorkerControl{}, nil
+		}
+		return control, fmt.Errorf("failed to load control state of worker %s: %w", workerName, err)
+	}
+	return control, nil
+}
+
+// saveWorkerControl replaces the control state of the named worker.
+func saveWorkerControl(kv KVStore, workerName string, control WorkerControl) error {
+	if err := kv.Set(workerControlKey(workerName), control); err != nil {
+		return fmt.Errorf("failed to save control state of worker %s: %w", workerName, err)
+	}
+	return nil
+}
+
+// LoadWorkerPosition returns the saved position of the named worker and whether it has one.
+func LoadWorkerPosition(kv KVStore, workerName string) (uint64, bool, error) {
+	var position uint64
+	if err := kv.Get(workerPositionKey(workerName), &position); err != nil {
+		if errors.Is(err, ErrKeyNotFound) {
+			return 0, false, nil
+		}
+		return 0, false, fmt.Errorf("failed to load position of worker %s: %w", workerName, err)
+	}
+	return position, true, nil
+}
+
+// PauseWorker stops the named worker in whichever process runs it, within about
+// WorkerControlPollInterval, and keeps it stopped in all processes until ResumeWorker.
+func (a *App) PauseWorker(ctx context.Context, name string) error {
+	return a.updateWorkerControl(name, func(control *WorkerControl) error {
+		if control.Paused {
+			return nil
+		}
+		now := time.Now()
+		control.Paused = true
+		control.PausedAt = &now
+		return nil
+	})
+}
+
+// ResumeWorker lets a paused worker run again. A process waiting on the pause picks it
+// up within about WorkerControlPollInterval.
+func (a *App) ResumeWorker(ctx context.Context, name string) error {
+	return a.updateWorkerControl(name, func(control *WorkerControl) error {
+		control.Paused = false
+		control.PausedAt = nil
+		return nil
+	})
+}
+
+// SeekWorker sets the position of the named CommandWorker: the next time it runs, its state
+// is rebuilt from the messages up to position and every message after it is handled again,
+// with side effects. Pending retries are dropped. The worker must be paused or not running.
+func (a *App) SeekWorker(ctx context.Context, name string, position uint64) error {
+	w, err := a.controlledWorker(name)
+	if err != nil {
+		return err
+	}
+	if _, ok := w.(*CommandWorker); !ok {
+		return &WorkerError{Op: "seek", Err: fmt.Errorf("worker %q does not follow the message log", name)}
+	}
+	if err := checkResettable(w); err != nil {
+		return &WorkerError{Op: "seek", Err: err}
+	}
+
+	head, err := a.MessageLog.Version(ctx)
+	if err != nil {
+		return &WorkerError{Op: "seek", Err: fmt.Errorf("failed to read log version: %w", err)}
+	}
+	if position > head {
+		return &WorkerError{Op: "seek", Err: fmt.Errorf("position %d is beyond the newest message %d", position, head)}
+	}
+
+	return a.withIdleWorker(ctx, "seek", name, func() error {
+		err := a.KVStore.Update(func(tx KVTx) error {
+			if err := tx.Set(workerPositionKey(name), position); err != nil {
+				return fmt.Errorf("failed to save position of worker %s: %w", name, err)
+			}
+			if err := tx.Delete(retryKey(name)); err != nil {
+				return fmt.Errorf("failed to clear retry state of worker %s: %w", name, err)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		slog.Info("Worker position changed", "worker", name, "position", position)
+		return nil
+	})
+}
+
+// ReplayWorker makes the named worker discard its state and rebuild it from the messages up
+// to its saved position the next time it runs. Replayed messages are handled with
+// ProcessingContext.IsReplay set, so handlers skip their side effects. The worker must be
+// paused or not running.
+func (a *App) ReplayWorker(ctx context.Context, name string) error {
+	w, err := a.controlledWorker(name)
+	if err != nil {
+		return err
+	}
+	if err := checkResettable(w); err != nil {
+		return &WorkerError{Op: "replay", Err: err}
+	}
+	return a.withIdleWorker(ctx, "replay", name, func() error {
+		slog.Info("Worker replay requested", "worker", name)
+		return nil
+	})
+}
+
+// checkResettable fails for workers whose state can't be discarded before a replay, as
+// replaying the log on top of it would apply every message twice.
+func checkResettable(w Worker) error {
+	if cw, ok := w.(*CommandWorker); ok {
+		if _, ok := cw.state.(Resetter); cw.state != nil && !ok {
+			return fmt.Errorf("worker %q can't rebuild its state: %T doesn't implement core.Resetter", cw.name, cw.state)
+		}
+		return nil
+	}
+	if _, ok := w.(Resetter); !ok {
+		return fmt.Errorf("worker %q can't rebuild its state: it doesn't implement core.Resetter", workerName(w))
+	}
+	return nil
+}
+
+// controlledWorker returns the named worker, failing if there is no KVStore to keep its
+// control state in.
+func (a *App) controlledWorker(name string) (Worker, error) {
+	w, found := a.FindWorker(name)
+	if !found {
+		return nil, &WorkerError{Op: "control", Err: fmt.Errorf("no worker named %q", name)}
+	}
+	if a.KVStore == nil {
+		return nil, &WorkerError{Op: "control", Err: errors.New("kvStore not set")}
+	}
+	return w, nil
+}
+
+// updateWorkerControl applies fn to the persisted control state of the named worker.
+func (a *App) updateWorkerControl(name string, fn func(*WorkerControl) error) error {
+	if _, err := a.controlledWorker(name); err != nil {
+		return err
+	}
+	control, err := LoadWorkerControl(a.KVStore, name)
+	if err != nil {
+		return err
+	}
+	if err := fn(&control); err != nil {
+		return err
+	}
+	return saveWorkerControl(a.KVStore, name, control)
+}
+
+// withIdleWorker holds the worker's lease while calling fn and then increments the
+// worker's generation, so a process that ran the worker before rebuilds its state.
+// A paused worker may take up to WorkerControlPollInterval to give up its lease; a worker
+// that is running and not paused makes withIdleWorker fail.
+func (a *App) withIdleWorker(ctx context.Context, op, name string, fn func() error) error {
+	if a.Leases != nil {
+		lease, err := a.acquireIdleWorkerLease(ctx, name)
+		if err != nil {
+			return &WorkerError{Op: op, Err: err}
+		}
+		defer a.Leases.Release(context.Background(), lease)
+	}
+
+	if err := fn(); err != nil {
+		return &WorkerError{Op: op, Err: err}
+	}
+	return a.updateWorkerControl(name, func(control *WorkerControl) error {
+		control.Generation++
+		return nil
+	})
+}
+
+// acquireIdleWorkerLease takes the worker's lease, waiting for a paused worker to stop.
+func (a *App) acquireIdleWorkerLease(ctx context.Context, name string) (Lease, error) {
+	deadline := time.Now().Add(a.Leases.TTL())
+	for {
+		// This process's own lease would be granted again, so check its workers directly
+		err := a.errIfRunningHere(name)
+		if err == nil {
+			var lease Lease
+			lease, err = a.Leases.Acquire(ctx, WorkerLeaseName(name))
+			if err == nil || !errors.Is(err, ErrLeaseHeld) {
+				return lease, err
+			}
+		}
+
+		control, controlErr := LoadWorkerControl(a.KVStore, name)
+		if controlErr != nil {
+			return Lease{}, controlErr
+		}
+		if !control.Paused {
+			return Lease{}, fmt.Errorf("worker %q is running, pause it first: %w", name, err)
+		}
+		if time.Now().After(deadline) {
+			return Lease{}, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return Lease{}, ctx.Err()
+		case <-time.After(WorkerControlPollInterval / 4):
+		}
+	}
+}
+
+// errIfRunningHere returns ErrLeaseHeld if this process is replaying or running the named worker.
+func (a *App) errIfRunningHere(name string) error {
+	for i, w := range a.workers {
+		if workerName(w) != name {
+			continue
+		}
+		var status WorkerStatus
+		a.workerStats[i].fill(&status)
+		if status.State == WorkerStateRunning || status.State == WorkerStateReplaying {
+			return fmt.Errorf("%w: %s is running in this process", ErrLeaseHeld, name)
+		}
+	}
+	return nil
+}
+
+// awaitWorkerResumed blocks while the worker is paused and returns its control state once it
+// may run. It returns false if the worker context is cancelled first.
+func (a *App) awaitWorkerResumed(index int, name string) (WorkerControl, bool) {
+	if a.KVStore == nil {
+		return WorkerControl{}, a.workerCtx.Err() == nil
+	}
+
+	paused := false
+	for {
+		control, err := LoadWorkerControl(a.KVStore, name)
+		if err != nil {
+			slog.Error("Failed to load worker control state", "worker", name, "error", err)
+		} else if !control.Paused {
+			if paused {
+				slog.Info("Worker resumed", "worker", name)
+			}
+			return control, a.workerCtx.Err() == nil
+		} else if !paused {
+			slog.Info("Worker is paused", "worker", name)
+			a.workerStats[index].setState(WorkerStatePaused)
+			paused = true
+		}
+
+		select {
+		case <-a.workerCtx.Done():
+			return WorkerControl{}, false
+		case <-time.After(WorkerControlPollInterval):
+		}
+	}
+}
+
+// workerControlChanged reports whether the worker was paused, sought or replayed since it
+// was started with the given control state.
+func (a *App) workerControlChanged(name string, started WorkerControl) bool {
+	if a.KVStore == nil {
+		return false
+	}
+	control, err := LoadWorkerControl(a.KVStore, name)
+	if err != nil {
+		slog.Error("Failed to load worker control state", "worker", name, "error", err)
+		return false
+	}
+	return control.Paused || control.Generation != started.Generation
+}
+
+// Reset discards the worker's progress and retry counters so that the next Replay rebuilds
+// its state from the beginning of the log, and resets the state if it implements Resetter.
+// It must only be called while the worker is stopped.
+func (w *CommandWorker) Reset() {
+	w.replayed = false
+	w.follower.LogSeek(0)
+	w.completed = nil
+
+	w.retryMu.Lock()
+	w.retries = map[uint64]RetryState{}
+	w.retryMu.Unlock()
+
+	if resetter, ok := w.state.(Resetter); ok {
+		resetter.Reset()
+	}
+	w.recordProgress()
+}
@@ -0,0 +1,185 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type controlTestCommand struct {
+	N int `json:"n"`
+}
+
+func (c *controlTestCommand) CommandName() string { return "test/control" }
+
+// controlTestState records which messages a worker handled, and how
+type controlTestState struct {
+	mu       sync.Mutex
+	replayed []uint64
+	live     []uint64
+	resets   int
+}
+
+func (s *controlTestState) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.replayed, s.live = nil, nil
+	s.resets++
+}
+
+func (s *controlTestState) snapshot() (replayed, live []uint64, resets int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]uint64(nil), s.replayed...), append([]uint64(nil), s.live...), s.resets
+}
+
+func TestWorkerControl_PauseSeekReplay(t *testing.T) {
+	app := newTestApp(t)
+	ctx := context.Background()
+	app.MessageLog.RegisterType(&controlTestCommand{})
+
+	state := &controlTestState{}
+	worker := NewWorker("controlled", "test worker", state)
+	worker.SetSchedule(Every(10 * time.Millisecond))
+	worker.OnCommand("test/control", func(ctx context.Context, cmd Command, msg *Message, pctx *ProcessingContext) error {
+		state.mu.Lock()
+		defer state.mu.Unlock()
+		if pctx.IsReplay {
+			state.replayed = append(state.replayed, msg.ID)
+		} else {
+			state.live = append(state.live, msg.ID)
+		}
+		return nil
+	})
+	app.RegisterWorker(worker)
+
+	appendMessage := func(n int) {
+		t.Helper()
+		if err := app.MessageLog.Append(ctx, &controlTestCommand{N: n}); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+	eventually := func(what string, cond func() bool) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			if cond() {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("Timed out waiting for %s", what)
+	}
+	inState := func(want string) func() bool {
+		return func() bool {
+			statuses, err := app.WorkerStatuses(ctx)
+			return err == nil && statuses[0].State == want
+		}
+	}
+
+	appendMessage(1)
+	appendMessage(2)
+
+	// A worker paused before the process starts stays paused
+	if err := app.PauseWorker(ctx, "controlled"); err != nil {
+		t.Fatalf("PauseWorker failed: %v", err)
+	}
+	if err := app.StartWorkers(ctx); err != nil {
+		t.Fatalf("StartWorkers failed: %v", err)
+	}
+	defer app.StopWorkers(ctx)
+	eventually("paused state", inState(WorkerStatePaused))
+	if err := app.RunWorkerOnce(ctx, "controlled"); err == nil {
+		t.Error("Expected RunWorkerOnce to refuse a paused worker")
+	}
+
+	appendMessage(3)
+	time.Sleep(50 * time.Millisecond)
+	if _, live, _ := state.snapshot(); len(live) != 0 {
+		t.Fatalf("Expected a paused worker to handle nothing, got %v", live)
+	}
+
+	// Resuming starts it; a new worker treats the existing log as history
+	if err := app.ResumeWorker(ctx, "controlled"); err != nil {
+		t.Fatalf("ResumeWorker failed: %v", err)
+	}
+	eventually("running state", inState(WorkerStateRunning))
+	appendMessage(4)
+	eventually("message 4 to be handled", func() bool {
+		_, live, _ := state.snapshot()
+		return reflect.DeepEqual(live, []uint64{4})
+	})
+	if replayed, _, _ := state.snapshot(); !reflect.DeepEqual(replayed, []uint64{1, 2, 3}) {
+		t.Errorf("Expected messages 1 to 3 to be replayed, got %v", replayed)
+	}
+
+	// A running worker must be paused before it can be sought
+	if err := app.SeekWorker(ctx, "controlled", 1); !errors.Is(err, ErrLeaseHeld) {
+		t.Fatalf("Expected SeekWorker to refuse a running worker, got %v", err)
+	}
+
+	// Seeking rebuilds the state up to the new position and handles the rest again
+	if err := app.PauseWorker(ctx, "controlled"); err != nil {
+		t.Fatalf("PauseWorker failed: %v", err)
+	}
+	if err := app.SeekWorker(ctx, "controlled", 1); err != nil {
+		t.Fatalf("SeekWorker failed: %v", err)
+	}
+	if position, _, _ := LoadWorkerPosition(app.KVStore, "controlled"); position != 1 {
+		t.Errorf("Expected saved position 1, got %d", position)
+	}
+	if err := app.ResumeWorker(ctx, "controlled"); err != nil {
+		t.Fatalf("ResumeWorker failed: %v", err)
+	}
+	eventually("messages 2 to 4 to be handled again", func() bool {
+		_, live, _ := state.snapshot()
+		return reflect.DeepEqual(live, []uint64{2, 3, 4})
+	})
+	if replayed, _, resets := state.snapshot(); resets != 1 || !reflect.DeepEqual(replayed, []uint64{1}) {
+		t.Errorf("Expected one reset and message 1 replayed, got resets=%d replayed=%v", resets, replayed)
+	}
+
+	// Replaying rebuilds the state without handling anything live
+	if err := app.PauseWorker(ctx, "controlled"); err != nil {
+		t.Fatalf("PauseWorker failed: %v", err)
+	}
+	if err := app.ReplayWorker(ctx, "controlled"); err != nil {
+		t.Fatalf("ReplayWorker failed: %v", err)
+	}
+	if err := app.ResumeWorker(ctx, "controlled"); err != nil {
+		t.Fatalf("ResumeWorker failed: %v", err)
+	}
+	eventually("the replay", func() bool {
+		replayed, _, resets := state.snapshot()
+		return resets == 2 && reflect.DeepEqual(replayed, []uint64{1, 2, 3, 4})
+	})
+	eventually("running state", inState(WorkerStateRunning))
+	if _, live, _ := state.snapshot(); len(live) != 0 {
+		t.Errorf("Expected no live handling after a replay, got %v", live)
+	}
+}
+
+func TestWorkerControl_RefusesToReplayStateWithoutReset(t *testing.T) {
+	app := newTestApp(t)
+	ctx := context.Background()
+	counts := map[string]int{}
+	app.RegisterWorker(NewWorker("counting", "test worker", counts))
+	app.RegisterWorker(NewWorker("stateless", "test worker", nil))
+
+	if err := app.ReplayWorker(ctx, "counting"); err == nil || !strings.Contains(err.Error(), "Resetter") {
+		t.Errorf("Expected ReplayWorker to refuse a state without Reset, got %v", err)
+	}
+	if err := app.SeekWorker(ctx, "counting", 0); err == nil || !strings.Contains(err.Error(), "Resetter") {
+		t.Errorf("Expected SeekWorker to refuse a state without Reset, got %v", err)
+	}
+	if control, err := LoadWorkerControl(app.KVStore, "counting"); err != nil || control.Generation != 0 {
+		t.Errorf("Expected a refused replay to leave the worker alone, got %+v, %v", control, err)
+	}
+	if err := app.ReplayWorker(ctx, "stateless"); err != nil {
+		t.Errorf("Expected a worker without state to be replayable, got %v", err)
+	}
+}
@@ -0,0 +1,115 @@
+package core
+
+import (
+	"log/slog"
+	"sync"
+)
+
+// SetPartitioning makes the worker handle messages concurrently. key maps each command
+// to a partition, e.g. the ID of the item it concerns. Messages with the same key are
+// handled one at a time in log order; messages with different keys are handled by a
+// pool of concurrency goroutines. Handlers must then be safe for concurrent use across
+// keys. Each cycle handles at most partitionWindow messages.
+//
+// A message that fails and is waiting to be retried holds up later messages with the
+// same key only. The saved position never moves past a message that has not been fully
+// handled, so after a restart messages handled out of order may be handled again.
+func (w *CommandWorker) SetPartitioning(key func(Command) string, concurrency int) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	w.partitionKey = key
+	w.partitions = concurrency
+}
+
+// partitionWindow is the most messages after the saved position a partitioned worker
+// reads per cycle, so that a backlog or a message waiting for a retry never puts the
+// rest of the log into memory.
+const partitionWindow = 1000
+
+// workPartitioned handles up to partitionWindow messages after position with a pool of
+// w.partitions goroutines taking keys from a queue, and advances the follower to the
+// last message before the first one not yet handled.
+func (w *CommandWorker) workPartitioned(position uint64) int {
+	if w.completed == nil {
+		w.completed = make(map[uint64]bool)
+	}
+
+	// Read the window up front so no cursor is held while handlers run
+	var order []uint64
+	var keys []string
+	queues := make(map[string][]PersistedMessage)
+	for msg := range w.log.After(w.ctx, position) {
+		order = append(order, msg.ID)
+		if !w.completed[msg.ID] {
+			if cmd, ok := msg.DecodedPayload.(Command); ok {
+				key := w.partitionKey(cmd)
+				if _, seen := queues[key]; !seen {
+					keys = append(keys, key)
+				}
+				queues[key] = append(queues[key], msg)
+			} else {
+				w.completed[msg.ID] = true
+			}
+		}
+		if len(order) == partitionWindow {
+			break
+		}
+	}
+
+	pending := make(chan string, len(keys))
+	for _, key := range keys {
+		pending <- key
+	}
+	close(pending)
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < min(w.partitions, len(keys)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for key := range pending {
+				w.handlePartition(queues[key], &mu)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Advance over the contiguous run of handled messages
+	messagesProcessed := 0
+	for _, id := range order {
+		if !w.completed[id] {
+			break
+		}
+		delete(w.completed, id)
+		w.follower.LogSeek(id)
+		messagesProcessed++
+	}
+	if len(w.completed) > 0 {
+		slog.Debug("Messages handled ahead of the saved position", "worker", w.name, "count", len(w.completed), "position", w.follower.LogPosition())
+	}
+	return messagesProcessed
+}
+
+// handlePartition handles the messages of one key in log order, stopping at the first
+// one that is waiting for a retry. mu guards w.completed.
+func (w *CommandWorker) handlePartition(queue []PersistedMessage, mu *sync.Mutex) {
+	for _, msg := range queue {
+		if w.ctx.Err() != nil || w.backingOff(msg.ID) {
+			return
+		}
+		if err := w.processMessage(msg); err != nil {
+			if !w.recordFailure(msg, err) {
+				// Later messages with this key wait for the retry
+				return
+			}
+		} else {
+			w.clearRetryState(msg.ID)
+		}
+
+		mu.Lock()
+		w.completed[msg.ID] = true
+		mu.Unlock()
+	}
+}
@@ -0,0 +1,165 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+type partitionTestCommand struct {
+	Key string `json:"key"`
+}
+
+func (c *partitionTestCommand) CommandName() string { return "test/partition" }
+
+func TestCommandWorker_PartitionedProcessing(t *testing.T) {
+	app := newTestApp(t)
+	ctx := context.Background()
+	app.MessageLog.RegisterType(&partitionTestCommand{})
+
+	var mu sync.Mutex
+	handled := map[uint64]int{}
+	var orderA []uint64
+	bStarted := make(chan struct{})
+	failC := true
+
+	worker := NewWorker("partitioned", "test worker", nil)
+	worker.SetRetryPolicy(RetryPolicy{MaxAttempts: 3})
+	worker.SetPartitioning(func(cmd Command) string { return cmd.(*partitionTestCommand).Key }, 4)
+	worker.OnCommand("test/partition", func(ctx context.Context, cmd Command, msg *Message, pctx *ProcessingContext) error {
+		if pctx.IsReplay {
+			return nil
+		}
+		switch cmd.(*partitionTestCommand).Key {
+		case "a":
+			if msg.ID == 1 {
+				// Only finishes if key "b" is handled concurrently
+				select {
+				case <-bStarted:
+				case <-time.After(2 * time.Second):
+					return errors.New("partitions were not handled concurrently")
+				}
+			}
+		case "b":
+			if msg.ID == 2 {
+				close(bStarted)
+			}
+		case "c":
+			mu.Lock()
+			fail := failC
+			failC = false
+			mu.Unlock()
+			if fail {
+				return errors.New("transient")
+			}
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		handled[msg.ID]++
+		if cmd.(*partitionTestCommand).Key == "a" {
+			orderA = append(orderA, msg.ID)
+		}
+		return nil
+	})
+	worker.SetDependencies(app.MessageLog, app.Executor, app.KVStore)
+	if err := worker.Start(ctx); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer worker.Stop(ctx)
+	if err := worker.Replay(ctx); err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+
+	for _, key := range []string{"a", "b", "a", "c", "b"} {
+		if err := app.MessageLog.Append(ctx, &partitionTestCommand{Key: key}); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+
+	if err := worker.Work(); err != nil {
+		t.Fatalf("Work failed: %v", err)
+	}
+	if got := worker.Progress().Position; got != 3 {
+		t.Errorf("Expected position to stop before the failed message 4, got %d", got)
+	}
+	if handled[5] != 1 {
+		t.Errorf("Expected message 5 to be handled despite message 4 failing, got %v", handled)
+	}
+
+	if err := worker.Work(); err != nil {
+		t.Fatalf("Work failed: %v", err)
+	}
+	if got := worker.Progress().Position; got != 5 {
+		t.Errorf("Expected position 5 after the retry succeeded, got %d", got)
+	}
+	for id := uint64(1); id <= 5; id++ {
+		if handled[id] != 1 {
+			t.Errorf("Expected message %d to be handled exactly once, got %d", id, handled[id])
+		}
+	}
+	if len(orderA) != 2 || orderA[0] != 1 || orderA[1] != 3 {
+		t.Errorf("Expected key a handled in log order, got %v", orderA)
+	}
+}
+
+func TestCommandWorker_PartitionedProcessingIsBounded(t *testing.T) {
+	app := newTestApp(t)
+	ctx := context.Background()
+	app.MessageLog.RegisterType(&partitionTestCommand{})
+
+	var mu sync.Mutex
+	running, maxRunning, handled := 0, 0, 0
+	worker := NewWorker("bounded", "test worker", nil)
+	worker.SetPartitioning(func(cmd Command) string { return cmd.(*partitionTestCommand).Key }, 3)
+	worker.OnCommand("test/partition", func(ctx context.Context, cmd Command, msg *Message, pctx *ProcessingContext) error {
+		if pctx.IsReplay {
+			return nil
+		}
+		mu.Lock()
+		running++
+		maxRunning = max(maxRunning, running)
+		mu.Unlock()
+		time.Sleep(time.Microsecond)
+		mu.Lock()
+		running--
+		handled++
+		mu.Unlock()
+		return nil
+	})
+	worker.SetDependencies(app.MessageLog, app.Executor, app.KVStore)
+	if err := worker.Start(ctx); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer worker.Stop(ctx)
+	if err := worker.Replay(ctx); err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+
+	// Every message has a key of its own
+	total := partitionWindow + 10
+	for i := 0; i < total; i++ {
+		if err := app.MessageLog.Append(ctx, &partitionTestCommand{Key: fmt.Sprint(i)}); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+
+	if err := worker.Work(); err != nil {
+		t.Fatalf("Work failed: %v", err)
+	}
+	if got := worker.Progress().Position; got != partitionWindow {
+		t.Errorf("Expected one cycle to handle a window of %d messages, got position %d", partitionWindow, got)
+	}
+	if err := worker.Work(); err != nil {
+		t.Fatalf("Work failed: %v", err)
+	}
+	if got := worker.Progress().Position; got != uint64(total) {
+		t.Errorf("Expected the next cycle to handle the rest, got position %d", got)
+	}
+	if handled != total || maxRunning > 3 {
+		t.Errorf("Expected %d messages handled by at most 3 goroutines, got %d by %d", total, handled, maxRunning)
+	}
+}
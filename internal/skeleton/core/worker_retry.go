@@ -0,0 +1,347 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// RetryPolicy controls how a CommandWorker retries a message whose handler returned an error.
+// While a message is being retried the worker does not move past it, so ordering is kept.
+// Once MaxAttempts is reached the message is moved to the worker's dead-letter list and
+// processing continues with the next message.
+type RetryPolicy struct {
+	MaxAttempts    int           // Total attempts including the first; 1 disables retries
+	InitialBackoff time.Duration // Delay before the first retry
+	MaxBackoff     time.Duration // Upper bound for the delay between attempts; 0 means maxRetryBackoff
+	Multiplier     float64       // Factor applied to the delay after each failed attempt
+	Jitter         float64       // Random fraction (0-1) added to or removed from each delay
+}
+
+// DefaultRetryPolicy returns the policy used by workers that don't set their own:
+// five attempts with exponential backoff starting at one second, capped at five minutes.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    5,
+		InitialBackoff: time.Second,
+		MaxBackoff:     5 * time.Minute,
+		Multiplier:     2,
+		Jitter:         0.2,
+	}
+}
+
+// maxRetryBackoff caps the delay of policies without a MaxBackoff, so that the delay
+// of a message failing many times can't overflow time.Duration and become negative.
+const maxRetryBackoff = 24 * time.Hour
+
+// Backoff returns the delay before the next attempt after the given number of failed attempts.
+func (p RetryPolicy) Backoff(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	maxBackoff := p.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = maxRetryBackoff
+	}
+
+	// math.Pow returns +Inf rather than overflowing, which the comparison below caps
+	delay := float64(p.InitialBackoff) * math.Pow(multiplier, float64(attempts-1))
+	if delay > float64(maxBackoff) {
+		delay = float64(maxBackoff)
+	}
+	if p.Jitter > 0 {
+		delay += delay * p.Jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(delay)
+}
+
+// RetryState is the persisted retry counter of a message whose handler failed.
+type RetryState struct {
+	MessageID     uint64    `json:"message_id"`
+	Attempts      int       `json:"attempts"`
+	LastError     string    `json:"last_error"`
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+}
+
+// DeadLetter records a message a worker gave up on after exhausting its retry policy.
+type DeadLetter struct {
+	MessageID uint64    `json:"message_id"`
+	Type      string    `json:"type"` // Command name of the message
+	Attempts  int       `json:"attempts"`
+	LastError string    `json:"last_error"`
+	FailedAt  time.Time `json:"failed_at"`
+	Requeued  bool      `json:"requeued"` // Set by RequeueDeadLetter; retried on the next Work cycle
+}
+
+// retryKey returns the KVStore key holding the worker's RetryStates.
+func retryKey(workerName string) string {
+	return fmt.Sprintf("worker:%s:retry", workerName)
+}
+
+// deadLetterKey returns the KVStore key holding the worker's dead-letter list.
+func deadLetterKey(workerName string) string {
+	return fmt.Sprintf("worker:%s:dead-letters", workerName)
+}
+
+// LoadDeadLetters returns the dead-letter list of the named worker.
+// A worker that never dead-lettered a message has an empty list.
+func LoadDeadLetters(kv KVStore, workerName string) ([]DeadLetter, error) {
+	letters := []DeadLetter{}
+	if err := kv.Get(deadLetterKey(workerName), &letters); err != nil && !errors.Is(err, ErrKeyNotFound) {
+		return nil, fmt.Errorf("failed to load dead letters for worker %s: %w", workerName, err)
+	}
+	return letters, nil
+}
+
+// saveDeadLetters replaces the dead-letter list of the named worker, deleting its key
+// once the list is empty.
+func saveDeadLetters(kv KVTx, workerName string, letters []DeadLetter) error {
+	var err error
+	if len(letters) == 0 {
+		err = kv.Delete(deadLetterKey(workerName))
+	} else {
+		err = kv.Set(deadLetterKey(workerName), letters)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save dead letters for worker %s: %w", workerName, err)
+	}
+	return nil
+}
+
+// updateDeadLetters atomically replaces the dead-letter list of the named worker with the
+// list returned by fn.
+func updateDeadLetters(kv KVStore, workerName string, fn func([]DeadLetter) ([]DeadLetter, error)) error {
+	return kv.Update(func(tx KVTx) error {
+		letters := []DeadLetter{}
+		if err := tx.Get(deadLetterKey(workerName), &letters); err != nil && !errors.Is(err, ErrKeyNotFound) {
+			return fmt.Errorf("failed to load dead letters for worker %s: %w", workerName, err)
+		}
+		letters, err := fn(letters)
+		if err != nil {
+			return err
+		}
+		return saveDeadLetters(tx, workerName, letters)
+	})
+}
+
+// RequeueDeadLetter marks a dead-lettered message to be handled again by the worker's
+// next Work cycle. If that attempt fails the message stays in the dead-letter list.
+func RequeueDeadLetter(kv KVStore, workerName string, messageID uint64) error {
+	return updateDeadLetters(kv, workerName, func(letters []DeadLetter) ([]DeadLetter, error) {
+		for i := range letters {
+			if letters[i].MessageID == messageID {
+				letters[i].Requeued = true
+				return letters, nil
+			}
+		}
+		return nil, fmt.Errorf("message %d is not in the dead-letter list of worker %s", messageID, workerName)
+	})
+}
+
+// DropDeadLetter removes a message from the worker's dead-letter list without handling it.
+func DropDeadLetter(kv KVStore, workerName string, messageID uint64) error {
+	return updateDeadLetters(kv, workerName, func(letters []DeadLetter) ([]DeadLetter, error) {
+		for i := range letters {
+			if letters[i].MessageID == messageID {
+				return append(letters[:i], letters[i+1:]...), nil
+			}
+		}
+		return nil, fmt.Errorf("message %d is not in the dead-letter list of worker %s", messageID, workerName)
+	})
+}
+
+// loadRetryState restores the retry counters persisted by a previous run of the worker.
+func (w *CommandWorker) loadRetryState() {
+	w.retryMu.Lock()
+	defer w.retryMu.Unlock()
+
+	w.retries = map[uint64]RetryState{}
+	if w.kvStore == nil {
+		return
+	}
+	var states []RetryState
+	if err := w.kvStore.Get(retryKey(w.name), &states); err != nil {
+		if errors.Is(err, ErrKeyNotFound) {
+			return
+		}
+		slog.Error("Failed to load worker retry state", "worker", w.name, "error", err)
+		return
+	}
+	for _, state := range states {
+		w.retries[state.MessageID] = state
+	}
+}
+
+// saveRetryState persists the current retry counters. The caller must hold retryMu.
+func (w *CommandWorker) saveRetryState() {
+	if w.kvStore == nil {
+		return
+	}
+	var err error
+	if len(w.retries) == 0 {
+		err = w.kvStore.Delete(retryKey(w.name))
+	} else {
+		err = w.kvStore.Set(retryKey(w.name), w.retryStates())
+	}
+	if err != nil {
+		slog.Error("Failed to save worker retry state", "worker", w.name, "error", err)
+	}
+}
+
+// retryStates returns the retry counters ordered by message ID. The caller must hold retryMu.
+func (w *CommandWorker) retryStates() []RetryState {
+	states := make([]RetryState, 0, len(w.retries))
+	for _, state := range w.retries {
+		states = append(states, state)
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].MessageID < states[j].MessageID })
+	return states
+}
+
+// backingOff reports whether the message failed before and must not be retried yet.
+func (w *CommandWorker) backingOff(messageID uint64) bool {
+	w.retryMu.Lock()
+	defer w.retryMu.Unlock()
+	state, found := w.retries[messageID]
+	return found && time.Now().Before(state.NextAttemptAt)
+}
+
+// clearRetryState forgets the retry counter of a message that was handled successfully.
+func (w *CommandWorker) clearRetryState(messageID uint64) {
+	w.retryMu.Lock()
+	defer w.retryMu.Unlock()
+	if _, found := w.retries[messageID]; !found {
+		return
+	}
+	delete(w.retries, messageID)
+	w.saveRetryState()
+}
+
+// recordFailure counts a failed attempt at handling msg and reports whether the worker
+// should move past it. It returns false while attempts remain, and true once the message
+// has been moved to the dead-letter list.
+func (w *CommandWorker) recordFailure(msg PersistedMessage, err error) bool {
+	policy := w.RetryPolicy()
+
+	w.retryMu.Lock()
+	defer w.retryMu.Unlock()
+
+	attempts := w.retries[msg.ID].Attempts + 1
+	if attempts < policy.MaxAttempts {
+		state := RetryState{
+			MessageID:     msg.ID,
+			Attempts:      attempts,
+			LastError:     err.Error(),
+			NextAttemptAt: time.Now().Add(policy.Backoff(attempts)),
+		}
+		w.retries[msg.ID] = state
+		w.saveRetryState()
+		slog.Warn("Failed to process message, will retry", "worker", w.name, "id", msg.ID,
+			"attempt", attempts, "maxAttempts", policy.MaxAttempts, "nextAttemptAt", state.NextAttemptAt, "error", err)
+		return false
+	}
+
+	slog.Error("Failed to process message, moving to dead-letter list", "worker", w.name, "id", msg.ID,
+		"attempts", attempts, "error", err)
+	w.deadLetter(DeadLetter{
+		MessageID: msg.ID,
+		Type:      msg.Type,
+		Attempts:  attempts,
+		LastError: err.Error(),
+		FailedAt:  time.Now(),
+	})
+	delete(w.retries, msg.ID)
+	w.saveRetryState()
+	return true
+}
+
+// deadLetter appends a message to the worker's dead-letter list. The caller must hold retryMu.
+func (w *CommandWorker) deadLetter(letter DeadLetter) {
+	if w.kvStore == nil {
+		return
+	}
+	err := updateDeadLetters(w.kvStore, w.name, func(letters []DeadLetter) ([]DeadLetter, error) {
+		return append(letters, letter), nil
+	})
+	if err != nil {
+		slog.Error("Failed to record dead letter", "worker", w.name, "id", letter.MessageID, "error", err)
+	}
+}
+
+// retryDeadLetters handles every requeued dead letter once. Messages that succeed are
+// removed from the list; messages that fail again stay in it with an updated error.
+func (w *CommandWorker) retryDeadLetters() {
+	if w.kvStore == nil {
+		return
+	}
+	letters, err := LoadDeadLetters(w.kvStore, w.name)
+	if err != nil {
+		slog.Error("Failed to load dead letters", "worker", w.name, "error", err)
+		return
+	}
+
+	// Outcome of each requeued message: the updated letter, or nil once it is handled
+	retried := map[uint64]*DeadLetter{}
+	for _, letter := range letters {
+		if !letter.Requeued {
+			continue
+		}
+		retried[letter.MessageID] = nil
+
+		msg, found := w.messageByID(letter.MessageID)
+		if !found {
+			slog.Warn("Dropping requeued dead letter for missing message", "worker", w.name, "id", letter.MessageID)
+			continue
+		}
+		if err := w.processMessage(msg); err != nil {
+			slog.Error("Requeued message failed again", "worker", w.name, "id", letter.MessageID, "error", err)
+			letter.Attempts++
+			letter.LastError = err.Error()
+			letter.FailedAt = time.Now()
+			letter.Requeued = false
+			retried[letter.MessageID] = &letter
+			continue
+		}
+		slog.Info("Requeued message processed", "worker", w.name, "id", letter.MessageID)
+	}
+
+	if len(retried) == 0 {
+		return
+	}
+	// Apply the outcomes to the current list, which may have changed while handling them
+	err = updateDeadLetters(w.kvStore, w.name, func(current []DeadLetter) ([]DeadLetter, error) {
+		remaining := current[:0]
+		for _, letter := range current {
+			updated, done := retried[letter.MessageID]
+			switch {
+			case !done:
+				remaining = append(remaining, letter)
+			case updated != nil:
+				remaining = append(remaining, *updated)
+			}
+		}
+		return remaining, nil
+	})
+	if err != nil {
+		slog.Error("Failed to update dead letters", "worker", w.name, "error", err)
+	}
+}
+
+// messageByID reads a single message from the log.
+func (w *CommandWorker) messageByID(id uint64) (PersistedMessage, bool) {
+	if id == 0 {
+		return PersistedMessage{}, false
+	}
+	for msg := range w.log.After(w.ctx, id-1) {
+		return msg, msg.ID == id
+	}
+	return PersistedMessage{}, false
+}
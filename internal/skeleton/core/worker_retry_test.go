@@ -0,0 +1,132 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type retryTestCommand struct {
+	Fail bool `json:"fail"`
+}
+
+func (c *retryTestCommand) CommandName() string { return "test/retry" }
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 5, InitialBackoff: time.Second, MaxBackoff: 5 * time.Second, Multiplier: 2}
+
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second}
+	for i, w := range want {
+		if got := policy.Backoff(i + 1); got != w {
+			t.Errorf("Backoff(%d) = %v, want %v", i+1, got, w)
+		}
+	}
+
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if got := policy.Backoff(1); got < 500*time.Millisecond || got > 1500*time.Millisecond {
+			t.Fatalf("Backoff with jitter out of range: %v", got)
+		}
+	}
+}
+
+func TestRetryPolicy_BackoffDoesNotOverflow(t *testing.T) {
+	policy := RetryPolicy{InitialBackoff: time.Second, Multiplier: 2}
+	for _, attempts := range []int{40, 64, 100, 10000} {
+		if got := policy.Backoff(attempts); got != maxRetryBackoff {
+			t.Errorf("Backoff(%d) without MaxBackoff = %v, want %v", attempts, got, maxRetryBackoff)
+		}
+	}
+
+	policy.Jitter = 0.2
+	if got := policy.Backoff(1 << 30); got <= 0 || got > maxRetryBackoff+maxRetryBackoff/5 {
+		t.Errorf("Backoff with jitter after many attempts out of range: %v", got)
+	}
+}
+
+func TestCommandWorker_RetriesThenDeadLetters(t *testing.T) {
+	app := newTestApp(t)
+	ctx := context.Background()
+	app.MessageLog.RegisterType(&retryTestCommand{})
+
+	healed := false
+	var handled []uint64
+	worker := NewWorker("retrying", "test worker", nil)
+	worker.SetRetryPolicy(RetryPolicy{MaxAttempts: 3, Multiplier: 2})
+	worker.OnCommand("test/retry", func(ctx context.Context, cmd Command, msg *Message, pctx *ProcessingContext) error {
+		if cmd.(*retryTestCommand).Fail && !healed {
+			return errors.New("boom")
+		}
+		handled = append(handled, msg.ID)
+		return nil
+	})
+	worker.SetDependencies(app.MessageLog, app.Executor, app.KVStore)
+	if err := worker.Start(ctx); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer worker.Stop(ctx)
+	if err := worker.Replay(ctx); err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+
+	for _, cmd := range []*retryTestCommand{{Fail: true}, {Fail: false}} {
+		if err := app.MessageLog.Append(ctx, cmd); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+
+	// The failing message blocks the worker until its attempts are exhausted
+	for attempt := 1; attempt <= 2; attempt++ {
+		if err := worker.Work(); err != nil {
+			t.Fatalf("Work failed: %v", err)
+		}
+		if len(handled) != 0 {
+			t.Fatalf("Expected no messages handled after attempt %d, got %v", attempt, handled)
+		}
+		var states []RetryState
+		if err := app.KVStore.Get(retryKey("retrying"), &states); err != nil {
+			t.Fatalf("Failed to load retry state: %v", err)
+		}
+		if len(states) != 1 {
+			t.Fatalf("Expected one retry state after attempt %d, got %+v", attempt, states)
+		}
+		if state := states[0]; state.MessageID != 1 || state.Attempts != attempt || state.LastError != "boom" {
+			t.Errorf("Unexpected retry state after attempt %d: %+v", attempt, state)
+		}
+	}
+
+	if err := worker.Work(); err != nil {
+		t.Fatalf("Work failed: %v", err)
+	}
+	if len(handled) != 1 || handled[0] != 2 {
+		t.Fatalf("Expected message 2 handled after dead-lettering message 1, got %v", handled)
+	}
+
+	letters, err := LoadDeadLetters(app.KVStore, "retrying")
+	if err != nil {
+		t.Fatalf("LoadDeadLetters failed: %v", err)
+	}
+	if len(letters) != 1 || letters[0].MessageID != 1 || letters[0].Attempts != 3 || letters[0].Type != "test/retry" {
+		t.Fatalf("Unexpected dead letters: %+v", letters)
+	}
+
+	// A requeued message is retried on the next cycle and leaves the list on success
+	if err := RequeueDeadLetter(app.KVStore, "retrying", 1); err != nil {
+		t.Fatalf("RequeueDeadLetter failed: %v", err)
+	}
+	healed = true
+	if err := worker.Work(); err != nil {
+		t.Fatalf("Work failed: %v", err)
+	}
+	if len(handled) != 2 || handled[1] != 1 {
+		t.Errorf("Expected requeued message 1 to be handled, got %v", handled)
+	}
+	if letters, _ := LoadDeadLetters(app.KVStore, "retrying"); len(letters) != 0 {
+		t.Errorf("Expected empty dead-letter list, got %+v", letters)
+	}
+
+	if err := DropDeadLetter(app.KVStore, "retrying", 1); err == nil {
+		t.Error("Expected error dropping a message that is not dead-lettered")
+	}
+}
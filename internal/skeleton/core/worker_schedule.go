@@ -0,0 +1,259 @@
+package core
+
+import (
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule decides when the App calls a worker's Work method.
+type Schedule interface {
+	// Next returns the next time Work should run after the given time.
+	// A zero time means the schedule never fires on its own.
+	Next(after time.Time) time.Time
+
+	// EventDriven reports whether Work should also run whenever the message log advances.
+	EventDriven() bool
+
+	// String describes the schedule for introspection, e.g. "every 1s" or "cron 0 3 * * *".
+	String() string
+}
+
+// ScheduledWorker is implemented by workers that declare their own schedule.
+// Workers that don't implement it run on DefaultSchedule.
+type ScheduledWorker interface {
+	Schedule() Schedule
+}
+
+// intervalSchedule fires at a fixed interval, offset by a constant jitter chosen
+// between 0 and maxJitter.
+type intervalSchedule struct {
+	interval  time.Duration
+	jitter    time.Duration
+	maxJitter time.Duration
+}
+
+// Every returns a schedule that runs Work at a fixed interval. Intervals that aren't
+// positive are clamped to one second.
+func Every(interval time.Duration) Schedule {
+	if interval <= 0 {
+		slog.Warn("Schedule interval must be positive, using 1s", "interval", interval)
+		interval = time.Second
+	}
+	return intervalSchedule{interval: interval}
+}
+
+// DefaultSchedule returns the schedule used for workers that don't declare one:
+// every second plus a random jitter of up to one second, chosen once per call.
+func DefaultSchedule() Schedule {
+	return intervalSchedule{
+		interval:  time.Second,
+		jitter:    time.Duration(rand.Intn(1000)) * time.Millisecond,
+		maxJitter: time.Second,
+	}
+}
+
+func (s intervalSchedule) Next(after time.Time) time.Time {
+	return after.Add(s.interval + s.jitter)
+}
+
+func (s intervalSchedule) EventDriven() bool { return false }
+
+func (s intervalSchedule) String() string {
+	if s.maxJitter > 0 {
+		return fmt.Sprintf("every %s (+ up to %s jitter)", s.interval, s.maxJitter)
+	}
+	return fmt.Sprintf("every %s", s.interval)
+}
+
+// eventSchedule never fires on its own; Work only runs when the log advances.
+type eventSchedule struct{}
+
+// OnEvents returns a schedule that runs Work only when new messages are appended to the log.
+func OnEvents() Schedule {
+	return eventSchedule{}
+}
+
+func (eventSchedule) Next(after time.Time) time.Time { return time.Time{} }
+func (eventSchedule) EventDriven() bool              { return true }
+func (eventSchedule) String() string                 { return "on events" }
+
+// cronSchedule fires at the times matched by a standard five-field cron expression.
+// Each field is a bit set of the values it matches.
+type cronSchedule struct {
+	expr                          string
+	minute, hour, dom, month, dow uint64
+	domRestricted, dowRestricted  bool
+	location                      *time.Location
+}
+
+// cronDescriptors maps the supported @-shortcuts to their five-field equivalents.
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Cron parses a five-field cron expression (minute hour day-of-month month day-of-week)
+// into a schedule evaluated in local time. Fields support "*", single values, ranges
+// ("1-5"), steps ("*/15", "0-30/10") and comma-separated lists. The @hourly, @daily,
+// @weekly, @monthly and @yearly shortcuts are also accepted.
+func Cron(expr string) (Schedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) == 1 {
+		if expanded, ok := cronDescriptors[fields[0]]; ok {
+			fields = strings.Fields(expanded)
+		}
+	}
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid cron expression %q: expected 5 fields, got %d", expr, len(fields))
+	}
+
+	s := &cronSchedule{expr: expr, location: time.Local}
+	var err error
+	if s.minute, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: minute: %w", expr, err)
+	}
+	if s.hour, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: hour: %w", expr, err)
+	}
+	if s.dom, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: day of month: %w", expr, err)
+	}
+	if s.month, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: month: %w", expr, err)
+	}
+	if s.dow, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: day of week: %w", expr, err)
+	}
+	// Both 0 and 7 mean Sunday
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	s.domRestricted = fields[2] != "*"
+	s.dowRestricted = fields[4] != "*"
+	return s, nil
+}
+
+// MustCron is like Cron but panics if the expression is invalid.
+// It is intended for schedules declared in code.
+func MustCron(expr string) Schedule {
+	s, err := Cron(expr)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// parseCronField parses one comma-separated cron field into a bit set of matching values.
+func parseCronField(field string, min, max int) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			rangePart, step = part[:i], n
+		}
+
+		lo, hi := min, max
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err1, err2 error
+			lo, err1 = strconv.Atoi(bounds[0])
+			hi, err2 = strconv.Atoi(bounds[1])
+			if err1 != nil || err2 != nil {
+				return 0, fmt.Errorf("invalid range %q", rangePart)
+			}
+		default:
+			n, err := strconv.Atoi(rangePart)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value %q", rangePart)
+			}
+			lo = n
+			hi = n
+			if step > 1 {
+				// "5/15" means starting at 5 through the maximum
+				hi = max
+			}
+		}
+
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("value %q out of range %d-%d", part, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			set |= 1 << uint(v)
+		}
+	}
+	return set, nil
+}
+
+func (s *cronSchedule) Next(after time.Time) time.Time {
+	t := after.In(s.location).Truncate(time.Minute).Add(time.Minute)
+
+	// Give up after five years; an expression like "0 0 30 2 *" never matches
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, s.location)
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, s.location)
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, s.location)
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// dayMatches applies cron's rule that when both day fields are restricted,
+// a day matches if either of them does.
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domRestricted && s.dowRestricted {
+		return domMatch || dowMatch
+	}
+	return domMatch && dowMatch
+}
+
+func (s *cronSchedule) EventDriven() bool { return false }
+
+func (s *cronSchedule) String() string {
+	return "cron " + s.expr
+}
+
+// workerSchedule returns the schedule declared by the worker or DefaultSchedule.
+func workerSchedule(w Worker) Schedule {
+	if sw, ok := w.(ScheduledWorker); ok {
+		if s := sw.Schedule(); s != nil {
+			return s
+		}
+	}
+	return DefaultSchedule()
+}
+
+// describeSchedule returns a human-readable description of the worker's schedule.
+func describeSchedule(w Worker) string {
+	return workerSchedule(w).String()
+}
@@ -0,0 +1,138 @@
+package core
+
+import (
+	"context"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCron_Next(t *testing.T) {
+	base := time.Date(2025, time.March, 14, 10, 17, 30, 0, time.Local) // a Friday
+
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2025, time.March, 14, 10, 18, 0, 0, time.Local)},
+		{"*/15 * * * *", time.Date(2025, time.March, 14, 10, 30, 0, 0, time.Local)},
+		{"0 3 * * *", time.Date(2025, time.March, 15, 3, 0, 0, 0, time.Local)},
+		{"0 9-17/4 * * *", time.Date(2025, time.March, 14, 13, 0, 0, 0, time.Local)},
+		{"0 0 1 * *", time.Date(2025, time.April, 1, 0, 0, 0, 0, time.Local)},
+		{"0 12 * * 1", time.Date(2025, time.March, 17, 12, 0, 0, 0, time.Local)},
+		{"0 12 * * 7", time.Date(2025, time.March, 16, 12, 0, 0, 0, time.Local)},
+		{"0 0 20 * 6", time.Date(2025, time.March, 15, 0, 0, 0, 0, time.Local)}, // day-of-month OR day-of-week
+		{"@hourly", time.Date(2025, time.March, 14, 11, 0, 0, 0, time.Local)},
+		{"@yearly", time.Date(2026, time.January, 1, 0, 0, 0, 0, time.Local)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			schedule, err := Cron(tt.expr)
+			if err != nil {
+				t.Fatalf("Cron(%q) failed: %v", tt.expr, err)
+			}
+			if got := schedule.Next(base); !got.Equal(tt.want) {
+				t.Errorf("Next() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCron_Invalid(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "*/0 * * * *", "a * * * *", "5-1 * * * *"} {
+		if _, err := Cron(expr); err == nil {
+			t.Errorf("Expected error for cron expression %q", expr)
+		}
+	}
+}
+
+func TestCron_NeverMatches(t *testing.T) {
+	schedule := MustCron("0 0 30 2 *")
+	if next := schedule.Next(time.Now()); !next.IsZero() {
+		t.Errorf("Expected zero time for impossible expression, got %v", next)
+	}
+}
+
+func TestSchedules(t *testing.T) {
+	now := time.Now()
+
+	if got := Every(time.Hour).Next(now); !got.Equal(now.Add(time.Hour)) {
+		t.Errorf("Every(1h).Next() = %v, want %v", got, now.Add(time.Hour))
+	}
+	if Every(time.Hour).EventDriven() {
+		t.Error("Expected interval schedule not to be event driven")
+	}
+	if got := Every(0).Next(now); !got.Equal(now.Add(time.Second)) {
+		t.Errorf("Expected Every(0) to be clamped to 1s, got %v", got.Sub(now))
+	}
+
+	events := OnEvents()
+	if !events.Next(now).IsZero() {
+		t.Error("Expected event schedule to never fire on its own")
+	}
+	if !events.EventDriven() {
+		t.Error("Expected event schedule to be event driven")
+	}
+
+	worker := NewWorker("scheduled", "test worker", nil)
+	if got := describeSchedule(worker); got != DefaultSchedule().String() {
+		t.Errorf("Unexpected default schedule description %q", got)
+	}
+	worker.SetSchedule(MustCron("0 3 * * *"))
+	if got := describeSchedule(worker); got != "cron 0 3 * * *" {
+		t.Errorf("Unexpected schedule description %q", got)
+	}
+}
+
+func TestRunWorkerSchedule_RunsDespiteControlPolls(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "shared.db")
+	ctx := context.Background()
+
+	app, err := NewApp(dbPath)
+	if err != nil {
+		t.Fatalf("NewApp failed: %v", err)
+	}
+	t.Cleanup(func() { app.Close() })
+
+	// Runs less often than the control state is polled
+	var timed, evented atomic.Int32
+	timedWorker := NewWorker("timed", "test worker", nil)
+	timedWorker.SetSchedule(Every(WorkerControlPollInterval + WorkerControlPollInterval/2))
+	timedWorker.SetPeriodicWork(func(ctx context.Context) error { timed.Add(1); return nil })
+	app.RegisterWorker(timedWorker)
+
+	eventWorker := NewWorker("evented", "test worker", nil)
+	eventWorker.SetSchedule(OnEvents())
+	eventWorker.SetPeriodicWork(func(ctx context.Context) error { evented.Add(1); return nil })
+	app.RegisterWorker(eventWorker)
+
+	if err := app.StartWorkers(ctx); err != nil {
+		t.Fatalf("StartWorkers failed: %v", err)
+	}
+	t.Cleanup(func() { app.StopWorkers(ctx) })
+
+	// Another process appends to the shared log
+	other, err := NewApp(dbPath)
+	if err != nil {
+		t.Fatalf("NewApp failed: %v", err)
+	}
+	t.Cleanup(func() { other.Close() })
+	other.MessageLog.RegisterType(&streamIncrementCommand{})
+	time.Sleep(100 * time.Millisecond)
+	if err := other.MessageLog.Append(ctx, &streamIncrementCommand{By: 1}); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+
+	deadline := time.Now().Add(3 * DefaultStreamPollInterval)
+	for time.Now().Before(deadline) && (timed.Load() == 0 || evented.Load() == 0) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	if timed.Load() == 0 {
+		t.Error("Expected the interval worker to run")
+	}
+	if evented.Load() == 0 {
+		t.Error("Expected the event-driven worker to notice the other process's append")
+	}
+}
@@ -0,0 +1,202 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Worker states reported in WorkerStatus.State
+const (
+	WorkerStateStandby   = "standby" // Waiting for another process to give up the worker's lease
+	WorkerStatePaused    = "paused"  // Paused by an operator, see App.PauseWorker
+	WorkerStateReplaying = "replaying"
+	WorkerStateRunning   = "running"
+	WorkerStateStopped   = "stopped"
+)
+
+// WorkerStatus is a point-in-time report of what a registered worker is doing.
+type WorkerStatus struct {
+	Name          string         `json:"name"`
+	Description   string         `json:"description"`
+	State         string         `json:"state"` // One of the WorkerState constants
+	Schedule      string         `json:"schedule"`
+	Position      uint64         `json:"position"` // ID of the last message the worker processed
+	Head          uint64         `json:"head"`     // ID of the newest message in the log
+	Lag           uint64         `json:"lag"`      // Messages the worker has yet to process
+	Cycles        uint64         `json:"cycles"`   // Work cycles run since the worker started
+	Failures      uint64         `json:"failures"` // Work cycles that returned an error
+	LastSuccessAt *time.Time     `json:"last_success_at,omitempty"`
+	LastErrorAt   *time.Time     `json:"last_error_at,omitempty"`
+	LastError     string         `json:"last_error,omitempty"`
+	Pending       map[string]int `json:"pending,omitempty"`  // Outstanding items by kind, see PendingCounter
+	Retrying      []RetryState   `json:"retrying,omitempty"` // Messages currently being retried
+	DeadLetters   int            `json:"dead_letters"`
+	Lease         *Lease         `json:"lease,omitempty"` // Lease of the process running the worker
+	Healthy       bool           `json:"healthy"`
+}
+
+// WorkerProgress describes how far a worker has processed the log.
+type WorkerProgress struct {
+	Position uint64
+	Pending  map[string]int
+	Retrying []RetryState
+}
+
+// ProgressReporter is implemented by workers that can report their progress.
+// It must be safe to call while the worker is running. CommandWorker implements it.
+type ProgressReporter interface {
+	Progress() WorkerProgress
+}
+
+// PendingCounter can be implemented by a CommandWorker's state to report outstanding
+// items, e.g. {"summaries": 3}. The counts are taken after every Work cycle, from the
+// worker's own goroutine, so implementations don't need locking. Workers that don't
+// implement ProgressReporter can implement PendingCounter themselves; it is then called
+// while reporting status and must be safe for concurrent use.
+type PendingCounter interface {
+	PendingCounts() map[string]int
+}
+
+// workerStats tracks the outcome of a worker's cycles as observed by the App.
+type workerStats struct {
+	mu            sync.Mutex
+	state         string
+	cycles        uint64
+	failures      uint64
+	lastSuccessAt time.Time
+	lastErrorAt   time.Time
+	lastError     string
+}
+
+func (s *workerStats) setState(state string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state = state
+}
+
+// recordCycle records the result of a single Work call.
+func (s *workerStats) recordCycle(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cycles++
+	if err != nil {
+		s.failures++
+		s.lastErrorAt = time.Now()
+		s.lastError = err.Error()
+		return
+	}
+	s.lastSuccessAt = time.Now()
+}
+
+// fill copies the recorded statistics into status.
+func (s *workerStats) fill(status *WorkerStatus) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status.State = s.state
+	if status.State == "" {
+		status.State = WorkerStateStopped
+	}
+	status.Cycles = s.cycles
+	status.Failures = s.failures
+	if !s.lastSuccessAt.IsZero() {
+		t := s.lastSuccessAt
+		status.LastSuccessAt = &t
+	}
+	if !s.lastErrorAt.IsZero() {
+		t := s.lastErrorAt
+		status.LastErrorAt = &t
+		status.LastError = s.lastError
+	}
+	// A running worker is healthy unless its most recent cycle failed; a standby
+	// worker is healthy because another process is running it, and a paused one
+	// because an operator stopped it on purpose
+	status.Healthy = s.state == WorkerStateStandby || s.state == WorkerStatePaused ||
+		s.state == WorkerStateRunning && !s.lastErrorAt.After(s.lastSuccessAt)
+}
+
+// Progress returns the position and pending counts recorded after the last Replay or Work
+func (w *CommandWorker) Progress() WorkerProgress {
+	w.progressMu.Lock()
+	defer w.progressMu.Unlock()
+	return w.progress
+}
+
+// recordProgress snapshots the worker's position, retry state and pending counts so that
+// Progress can be called from other goroutines without touching the worker's state.
+func (w *CommandWorker) recordProgress() {
+	progress := WorkerProgress{Position: w.follower.LogPosition()}
+	if counter, ok := w.state.(PendingCounter); ok {
+		progress.Pending = counter.PendingCounts()
+	}
+	w.retryMu.Lock()
+	if len(w.retries) > 0 {
+		progress.Retrying = w.retryStates()
+	}
+	w.retryMu.Unlock()
+
+	w.progressMu.Lock()
+	defer w.progressMu.Unlock()
+	w.progress = progress
+}
+
+// WorkerStatuses reports the status of every registered worker in registration order.
+func (a *App) WorkerStatuses(ctx context.Context) ([]WorkerStatus, error) {
+	head, err := a.MessageLog.Version(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read log version: %w", err)
+	}
+
+	leases := map[string]Lease{}
+	if a.Leases != nil {
+		all, err := a.Leases.List(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, lease := range all {
+			leases[lease.Name] = lease
+		}
+	}
+
+	statuses := make([]WorkerStatus, 0, len(a.workers))
+	for i, w := range a.workers {
+		status := WorkerStatus{
+			Name:     workerName(w),
+			Schedule: describeSchedule(w),
+			Head:     head,
+		}
+		if info := w.WorkerInfo(); info != nil {
+			status.Description = info.Description
+		}
+		a.workerStats[i].fill(&status)
+		if lease, ok := leases[WorkerLeaseName(status.Name)]; ok && !lease.Expired(time.Now()) {
+			status.Lease = &lease
+		}
+
+		if reporter, ok := w.(ProgressReporter); ok {
+			progress := reporter.Progress()
+			status.Position = progress.Position
+			status.Pending = progress.Pending
+			status.Retrying = progress.Retrying
+			if head > progress.Position {
+				status.Lag = head - progress.Position
+			}
+		} else if counter, ok := w.(PendingCounter); ok {
+			status.Pending = counter.PendingCounts()
+		}
+
+		if a.KVStore != nil {
+			letters, err := LoadDeadLetters(a.KVStore, status.Name)
+			if err != nil {
+				slog.Error("Failed to count dead letters", "worker", status.Name, "error", err)
+			}
+			status.DeadLetters = len(letters)
+		}
+
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
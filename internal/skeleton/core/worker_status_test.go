@@ -0,0 +1,82 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type statusTestState struct {
+	seen int
+}
+
+func (s *statusTestState) PendingCounts() map[string]int {
+	return map[string]int{"seen": s.seen}
+}
+
+func TestWorkerStatuses(t *testing.T) {
+	app := newTestApp(t)
+	ctx := context.Background()
+	app.MessageLog.RegisterType(&retryTestCommand{})
+
+	state := &statusTestState{}
+	worker := NewWorker("status", "reports its status", state)
+	worker.SetSchedule(Every(10 * time.Millisecond))
+	worker.OnCommand("test/retry", func(ctx context.Context, cmd Command, msg *Message, pctx *ProcessingContext) error {
+		state.seen++
+		return nil
+	})
+	app.RegisterWorker(worker)
+
+	if err := app.MessageLog.Append(ctx, &retryTestCommand{}); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+
+	statuses, err := app.WorkerStatuses(ctx)
+	if err != nil {
+		t.Fatalf("WorkerStatuses failed: %v", err)
+	}
+	if len(statuses) != 1 {
+		t.Fatalf("Expected 1 status, got %d", len(statuses))
+	}
+	if s := statuses[0]; s.State != WorkerStateStopped || s.Healthy || s.Head != 1 || s.Lag != 1 {
+		t.Errorf("Unexpected status before start: %+v", s)
+	}
+
+	if err := app.StartWorkers(ctx); err != nil {
+		t.Fatalf("StartWorkers failed: %v", err)
+	}
+	defer app.StopWorkers(ctx)
+
+	// The existing message is history for a new worker; the next one is processed live
+	if err := app.MessageLog.Append(ctx, &retryTestCommand{}); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	var status WorkerStatus
+	for time.Now().Before(deadline) {
+		statuses, err := app.WorkerStatuses(ctx)
+		if err != nil {
+			t.Fatalf("WorkerStatuses failed: %v", err)
+		}
+		status = statuses[0]
+		if status.Healthy && status.Lag == 0 && status.LastSuccessAt != nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if status.State != WorkerStateRunning || !status.Healthy {
+		t.Fatalf("Expected running healthy worker, got %+v", status)
+	}
+	if status.Position != 2 || status.Head != 2 || status.Lag != 0 {
+		t.Errorf("Expected worker caught up at 2, got position=%d head=%d lag=%d", status.Position, status.Head, status.Lag)
+	}
+	if status.Pending["seen"] != 2 {
+		t.Errorf("Expected pending count from state, got %v", status.Pending)
+	}
+	if status.Schedule != "every 10ms" || status.Description != "reports its status" {
+		t.Errorf("Unexpected schedule or description: %+v", status)
+	}
+}
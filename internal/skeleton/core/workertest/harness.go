@@ -0,0 +1,207 @@
+// Package workertest drives a core.CommandWorker deterministically in tests. Messages are
+// fed to the worker directly instead of being read from a log, time only moves when the
+// test advances it, and commands the worker executes are captured instead of run.
+package workertest
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/petrock/example_module_path/core"
+)
+
+// Epoch is the time a Harness's clock starts at.
+var Epoch = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Clock is a core.Clock that only moves when told to.
+type Clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewClock returns a Clock stopped at start.
+func NewClock(start time.Time) *Clock {
+	return &Clock{now: start}
+}
+
+// Now returns the clock's current time.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward by d.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// Set moves the clock to t.
+func (c *Clock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = t
+}
+
+// Harness runs a single CommandWorker. Pass its Executor to the worker's constructor so
+// that the commands the worker executes are captured, then Start the worker and script
+// its input with Replay, Live, Advance and Work.
+type Harness struct {
+	Clock    *Clock
+	Executor *core.Executor // Captures commands instead of executing them
+
+	t        testing.TB
+	worker   *core.CommandWorker
+	nextID   uint64
+	mu       sync.Mutex
+	executed []core.Command
+	failures map[string]error
+}
+
+// New creates a harness whose clock starts at Epoch.
+func New(t testing.TB) *Harness {
+	h := &Harness{
+		Clock:    NewClock(Epoch),
+		t:        t,
+		failures: make(map[string]error),
+	}
+	h.Executor = core.NewCapturingExecutor(h.capture)
+	return h
+}
+
+func (h *Harness) capture(ctx context.Context, cmd core.Command) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err, found := h.failures[cmd.CommandName()]; found {
+		return err
+	}
+	h.executed = append(h.executed, cmd)
+	return nil
+}
+
+// Start starts worker with a context carrying the harness clock, so that handlers and
+// periodic work calling core.Now(ctx) see the fake time. The worker is stopped when the
+// test ends.
+func (h *Harness) Start(worker *core.CommandWorker) {
+	h.t.Helper()
+	if err := worker.Start(core.WithClock(context.Background(), h.Clock)); err != nil {
+		h.t.Fatalf("Failed to start worker: %v", err)
+	}
+	h.worker = worker
+	h.t.Cleanup(func() { worker.Stop(context.Background()) })
+}
+
+// Replay feeds commands to the worker as Replay would on startup: handlers see
+// ProcessingContext.IsReplay and must not cause side effects.
+func (h *Harness) Replay(cmds ...core.Command) {
+	h.t.Helper()
+	for _, cmd := range cmds {
+		if err := h.handle(cmd, true); err != nil {
+			h.t.Fatalf("Replaying %s failed: %v", cmd.CommandName(), err)
+		}
+	}
+}
+
+// Live feeds commands to the worker as Work would after they were appended to the log.
+func (h *Harness) Live(cmds ...core.Command) {
+	h.t.Helper()
+	for _, cmd := range cmds {
+		if err := h.LiveErr(cmd); err != nil {
+			h.t.Fatalf("Handling %s failed: %v", cmd.CommandName(), err)
+		}
+	}
+}
+
+// LiveErr feeds a single command to the worker like Live and returns the handler's error.
+func (h *Harness) LiveErr(cmd core.Command) error {
+	return h.handle(cmd, false)
+}
+
+// handle wraps cmd in a message with the next ID and the current fake time.
+func (h *Harness) handle(cmd core.Command, replay bool) error {
+	if h.worker == nil {
+		h.t.Fatalf("Harness has no worker; call Start first")
+	}
+	data, err := json.Marshal(cmd)
+	if err != nil {
+		h.t.Fatalf("Failed to encode %s: %v", cmd.CommandName(), err)
+	}
+
+	h.nextID++
+	msg := core.PersistedMessage{
+		Message: core.Message{
+			ID:        h.nextID,
+			Timestamp: h.Clock.Now(),
+			Type:      cmd.CommandName(),
+			Data:      data,
+		},
+		DecodedPayload: cmd,
+	}
+	return h.worker.HandleMessage(msg, replay)
+}
+
+// Advance moves the harness clock forward by d.
+func (h *Harness) Advance(d time.Duration) {
+	h.Clock.Advance(d)
+}
+
+// Work runs the worker's periodic work once and fails the test if it returns an error.
+func (h *Harness) Work() {
+	h.t.Helper()
+	if err := h.WorkErr(); err != nil {
+		h.t.Fatalf("Periodic work failed: %v", err)
+	}
+}
+
+// WorkErr runs the worker's periodic work once and returns its error.
+func (h *Harness) WorkErr() error {
+	if h.worker == nil {
+		h.t.Fatalf("Harness has no worker; call Start first")
+	}
+	return h.worker.RunPeriodicWork()
+}
+
+// FailCommands makes the executor return err for every command with the given name
+// instead of capturing it.
+func (h *Harness) FailCommands(commandName string, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.failures[commandName] = err
+}
+
+// Executed returns the commands captured so far, in the order the worker executed them.
+func (h *Harness) Executed() []core.Command {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]core.Command(nil), h.executed...)
+}
+
+// TakeExecuted returns the captured commands and forgets them, so the next call only
+// returns commands executed after this one.
+func (h *Harness) TakeExecuted() []core.Command {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	executed := h.executed
+	h.executed = nil
+	return executed
+}
+
+// Loopback feeds the captured commands back to the worker as live messages, as the log
+// would once they were executed, and returns them. Commands executed by the worker
+// while handling them are captured for the next call.
+func (h *Harness) Loopback() []core.Command {
+	h.t.Helper()
+	executed := h.TakeExecuted()
+	h.Live(executed...)
+	return executed
+}
+
+// State returns the worker's state for assertions.
+func (h *Harness) State() any {
+	return h.worker.State()
+}
@@ -10,6 +10,12 @@ import (
 	"github.com/petrock/example_module_path/petrock_example_feature_name/state" // Import state package
 )
 
+// Errors wrapped by Validate methods, whose state parameter hides the state package.
+var (
+	ErrItemNotFound = state.ErrItemNotFound
+	ErrItemExists   = state.ErrItemExists
+)
+
 // Validator defines an interface for commands that require stateful validation.
 // The feature's Executor will call this method if implemented by a command.
 type Validator interface {
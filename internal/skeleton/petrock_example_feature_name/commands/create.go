@@ -59,7 +59,7 @@ func (c *CreateCommand) Validate(state *state.State) error {
 	items, _ := state.ListItems(1, 1000, "")
 	for _, item := range items {
 		if item.Name == trimmedName {
-			return fmt.Errorf("item with name %q already exists", trimmedName)
+			return fmt.Errorf("%w with name %q", ErrItemExists, trimmedName)
 		}
 	}
 
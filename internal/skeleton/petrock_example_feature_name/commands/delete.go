@@ -42,7 +42,7 @@ func (c *DeleteCommand) Validate(state *state.State) error {
 	_, found := state.GetItem(trimmedID) // GetItem handles locking
 	if !found {
 		// Decide if deleting a non-existent item is an error or idempotent success
-		return fmt.Errorf("item with ID %q not found", trimmedID) // Return error
+		return fmt.Errorf("%w with ID %q", ErrItemNotFound, trimmedID) // Return error
 		// return nil // Alternative: Treat as success
 	}
 	// Add other validation rules (e.g., check if item is deletable based on status)
@@ -38,7 +38,7 @@ func (c *RequestSummaryGenerationCommand) Validate(state *state.State) error {
 	// Verify the item exists
 	_, found := state.GetItem(c.ID)
 	if !found {
-		return fmt.Errorf("item with ID %q not found", c.ID)
+		return fmt.Errorf("%w with ID %q", ErrItemNotFound, c.ID)
 	}
 	return nil
 }
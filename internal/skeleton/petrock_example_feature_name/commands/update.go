@@ -59,7 +59,7 @@ func (c *UpdateCommand) Validate(state *state.State) error {
 	// Example stateful validation: Check if the item exists
 	_, found := state.GetItem(trimmedID) // GetItem handles locking
 	if !found {
-		return fmt.Errorf("item with ID %q not found", trimmedID)
+		return fmt.Errorf("%w with ID %q", ErrItemNotFound, trimmedID)
 	}
 	// Example: Check if updating the name conflicts with another existing item's name
 	// state.mu.RLock()
@@ -2,12 +2,17 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"strconv"
 
 	"github.com/petrock/example_module_path/core"
 	"github.com/petrock/example_module_path/core/ui"
+	"github.com/petrock/example_module_path/petrock_example_feature_name/queries"
+	"github.com/petrock/example_module_path/petrock_example_feature_name/state"
 	g "maragu.dev/gomponents"
 	"maragu.dev/gomponents/html"
 )
@@ -52,6 +57,78 @@ func RespondJSON(w http.ResponseWriter, status int, payload interface{}) {
 	}
 }
 
+// APIPath is where registerAPIRoutes serves the feature's JSON API.
+const APIPath = "/api/petrock_example_feature_name"
+
+// RespondParseError answers a request whose body could not be parsed with 400 Bad
+// Request: validation errors are listed like POST /commands lists them.
+func RespondParseError(w http.ResponseWriter, err error) {
+	var parseErrors *core.ParseErrors
+	if errors.As(err, &parseErrors) {
+		RespondJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error":   "Validation failed",
+			"details": parseErrors.Errors,
+		})
+		return
+	}
+	RespondJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+}
+
+// RespondCommandError answers a request whose command failed: 429 when it was rate
+// limited, 404 when it was rejected because the item doesn't exist, 409 when it was
+// rejected because the item already exists, 400 for other validation errors and 500
+// otherwise.
+func RespondCommandError(w http.ResponseWriter, r *http.Request, err error) {
+	if retryAfter, limited := core.RateLimited(err); limited {
+		core.WriteRateLimited(w, r, retryAfter)
+		return
+	}
+
+	var validationErr *core.ValidationError
+	if !errors.As(err, &validationErr) {
+		slog.Error("Command failed", "feature", "petrock_example_feature_name", "error", err,
+			"request_id", core.RequestIDFromContext(r.Context()))
+		RespondJSON(w, http.StatusInternalServerError, map[string]string{"error": "Internal Server Error"})
+		return
+	}
+
+	status := http.StatusBadRequest
+	switch {
+	case errors.Is(validationErr.Err, state.ErrItemNotFound):
+		status = http.StatusNotFound
+	case errors.Is(validationErr.Err, state.ErrItemExists):
+		status = http.StatusConflict
+	}
+	// Leave out the "validation failed for command" prefix added by the Executor
+	RespondJSON(w, status, map[string]string{"error": validationErr.Err.Error()})
+}
+
+// respondItem looks up an item and responds with it as JSON. Items that were just
+// created get 201 Created with their URL in the Location header.
+func (fs *FeatureServer) respondItem(w http.ResponseWriter, r *http.Request, status int, id string) {
+	result, err := fs.querier.HandleGet(r.Context(), queries.GetQuery{ID: id})
+	if err != nil {
+		if errors.Is(err, state.ErrItemNotFound) {
+			RespondJSON(w, http.StatusNotFound, map[string]string{"error": "Item not found"})
+			return
+		}
+		slog.Error("Error handling GetQuery", "error", err, "id", id)
+		RespondJSON(w, http.StatusInternalServerError, map[string]string{"error": "Internal Server Error"})
+		return
+	}
+	getResult, ok := result.(*queries.GetQueryResult)
+	if !ok {
+		slog.Error("Invalid result type for GetQuery", "type", fmt.Sprintf("%T", result))
+		RespondJSON(w, http.StatusInternalServerError, map[string]string{"error": "Internal Server Error"})
+		return
+	}
+
+	if status == http.StatusCreated {
+		w.Header().Set("Location", APIPath+"/"+url.PathEscape(id))
+	}
+	RespondJSON(w, status, getResult.Item)
+}
+
 // parseIntParam is a helper to parse integer query parameters with a default value.
 func ParseIntParam(param string, defaultValue int) int {
 	if param == "" {
@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/petrock/example_module_path/core"
+	"github.com/petrock/example_module_path/petrock_example_feature_name/state"
+)
+
+func TestRespondCommandError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"missing item", &core.ValidationError{Command: "c", Err: fmt.Errorf("%w with ID %q", state.ErrItemNotFound, "1")}, http.StatusNotFound},
+		{"existing item", &core.ValidationError{Command: "c", Err: fmt.Errorf("%w with name %q", state.ErrItemExists, "a")}, http.StatusConflict},
+		{"other validation error", &core.ValidationError{Command: "c", Err: errors.New("item name cannot be empty")}, http.StatusBadRequest},
+		{"internal error mentioning not found", fmt.Errorf("failed to persist command: %w", errors.New("table not found")), http.StatusInternalServerError},
+		{"internal error wrapping a state error", fmt.Errorf("failed to apply: %w", state.ErrItemNotFound), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		RespondCommandError(w, httptest.NewRequest(http.MethodPost, APIPath, nil), tt.err)
+		if w.Code != tt.want {
+			t.Errorf("%s: expected %d, got %d: %s", tt.name, tt.want, w.Code, w.Body)
+		}
+	}
+}
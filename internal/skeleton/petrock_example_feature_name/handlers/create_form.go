@@ -1,9 +1,9 @@
 package handlers
 
 import (
+	"errors"
 	"log/slog"
 	"net/http"
-	"strings"
 	"time"
 
 	"github.com/petrock/example_module_path/core"
@@ -19,15 +19,12 @@ func (fs *FeatureServer) HandleNewForm(w http.ResponseWriter, r *http.Request) {
 	// Create empty form data
 	formData := ui.NewFormData(nil, nil)
 
-	// Get CSRF token
-	csrfToken := "token" // Replace with actual CSRF token generation
-
 	// Render the form
 	// Create page title
 	pageTitle := "Create New Item"
 
 	// Render the page with our helper
-	if err := RenderPage(w, pageTitle, ItemForm(formData, nil, csrfToken)); err != nil {
+	if err := RenderPage(w, pageTitle, ItemForm(r.Context(), formData, nil)); err != nil {
 		slog.Error("Error rendering new item form", "error", err)
 		http.Error(w, "Error rendering form", http.StatusInternalServerError)
 	}
@@ -38,6 +35,12 @@ func (fs *FeatureServer) HandleNewForm(w http.ResponseWriter, r *http.Request) {
 func (fs *FeatureServer) HandleCreateForm(w http.ResponseWriter, r *http.Request) {
 	slog.Debug("HandleCreateForm called", "feature", "petrock_example_feature_name", "method", r.Method, "url", r.URL.String())
 
+	// API clients get the JSON response of the REST endpoint instead of a redirect
+	if core.WantsJSON(r) {
+		fs.HandleCreateItem(w, r)
+		return
+	}
+
 	// Parse the form
 	if err := r.ParseForm(); err != nil {
 		slog.Error("Failed to parse form", "error", err)
@@ -70,9 +73,8 @@ func (fs *FeatureServer) HandleCreateForm(w http.ResponseWriter, r *http.Request
 
 			// Render the form with validation errors
 			pageTitle := "Create New Item"
-			csrfToken := "token" // Replace with actual CSRF token
 
-			if err := RenderPage(w, pageTitle, ItemForm(formData, nil, csrfToken)); err != nil {
+			if err := RenderPage(w, pageTitle, ItemForm(r.Context(), formData, nil)); err != nil {
 				slog.Error("Error rendering form with validation errors", "error", err)
 				http.Error(w, "Error rendering form", http.StatusInternalServerError)
 			}
@@ -86,7 +88,7 @@ func (fs *FeatureServer) HandleCreateForm(w http.ResponseWriter, r *http.Request
 	}
 
 	// Set additional fields not from form
-	cmd.CreatedBy = "user" // Replace with actual user ID if authentication is implemented
+	cmd.CreatedBy = core.PrincipalID(r.Context()) // Empty for anonymous requests
 	cmd.CreatedAt = time.Now().UTC()
 
 	slog.Debug("Command parsed successfully", "command", cmd)
@@ -95,9 +97,14 @@ func (fs *FeatureServer) HandleCreateForm(w http.ResponseWriter, r *http.Request
 	slog.Debug("Executing command", "command", cmd)
 	err := fs.app.Executor.Execute(r.Context(), &cmd)
 	if err != nil {
+		if retryAfter, limited := core.RateLimited(err); limited {
+			core.WriteRateLimited(w, r, retryAfter)
+			return
+		}
 		slog.Error("Command execution failed", "error", err, "command", cmd)
 		// Check if it's a validation error
-		if strings.Contains(err.Error(), "validation failed") || strings.Contains(err.Error(), "already exists") {
+		var validationErr *core.ValidationError
+		if errors.As(err, &validationErr) {
 			// Create FormData with validation error
 			uiErrors := []ui.ParseError{
 				{
@@ -110,10 +117,9 @@ func (fs *FeatureServer) HandleCreateForm(w http.ResponseWriter, r *http.Request
 
 			// Create page title for validation error
 			pageTitle := "Create New Item"
-			csrfToken := "token" // Replace with actual CSRF token
 
 			// Render the page with validation errors
-			if err := RenderPage(w, pageTitle, ItemForm(formData, nil, csrfToken)); err != nil {
+			if err := RenderPage(w, pageTitle, ItemForm(r.Context(), formData, nil)); err != nil {
 				slog.Error("Error rendering form with validation errors", "error", err)
 				http.Error(w, "Error rendering form", http.StatusInternalServerError)
 			}
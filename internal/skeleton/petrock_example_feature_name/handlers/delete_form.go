@@ -1,14 +1,16 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
-	"strings"
 	"time"
 
+	"github.com/petrock/example_module_path/core"
 	"github.com/petrock/example_module_path/petrock_example_feature_name/commands"
 	"github.com/petrock/example_module_path/petrock_example_feature_name/queries"
+	"github.com/petrock/example_module_path/petrock_example_feature_name/state"
 )
 
 // HandleDeleteForm handles requests to display a confirmation form for deleting an item.
@@ -25,7 +27,7 @@ func (fs *FeatureServer) HandleDeleteForm(w http.ResponseWriter, r *http.Request
 	query := queries.GetQuery{ID: itemID}
 	result, err := fs.querier.HandleGet(r.Context(), query)
 	if err != nil {
-		if strings.Contains(err.Error(), "not found") {
+		if errors.Is(err, state.ErrItemNotFound) {
 			http.Error(w, "Item not found", http.StatusNotFound)
 			return
 		}
@@ -42,15 +44,12 @@ func (fs *FeatureServer) HandleDeleteForm(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	// Get CSRF token
-	csrfToken := "token" // Replace with actual CSRF token generation
-
 	// Render the delete confirmation view
 	// Create page title
 	pageTitle := fmt.Sprintf("Delete %s", item.Item.Name)
 
 	// Render the page with our helper
-	if err := RenderPage(w, pageTitle, DeleteConfirmForm(&item.Item, csrfToken)); err != nil {
+	if err := RenderPage(w, pageTitle, DeleteConfirmForm(r.Context(), &item.Item)); err != nil {
 		slog.Error("Error rendering delete confirmation", "error", err)
 		http.Error(w, "Error rendering confirmation", http.StatusInternalServerError)
 	}
@@ -66,27 +65,34 @@ func (fs *FeatureServer) HandleDeleteConfirm(w http.ResponseWriter, r *http.Requ
 	}
 	slog.Debug("HandleDeleteConfirm called", "feature", "petrock_example_feature_name", "id", itemID)
 
-	// Parse form to get CSRF token (if needed)
+	// API clients get the JSON response of the REST endpoint instead of a redirect
+	if core.WantsJSON(r) {
+		fs.HandleDeleteItem(w, r)
+		return
+	}
+
+	// Parse form (the CSRF token was already checked by core.CSRF)
 	if err := r.ParseForm(); err != nil {
 		slog.Error("Failed to parse form", "error", err)
 		http.Error(w, "Invalid form submission", http.StatusBadRequest)
 		return
 	}
 
-	// Verify CSRF token (if implemented)
-	// ...
-
 	// Create the delete command
 	cmd := &commands.DeleteCommand{
 		ID:        itemID,
-		DeletedBy: "user", // Replace with actual user ID if authentication is implemented
+		DeletedBy: core.PrincipalID(r.Context()), // Empty for anonymous requests
 		DeletedAt: time.Now().UTC(),
 	}
 
 	// Execute the command
 	err := fs.app.Executor.Execute(r.Context(), cmd)
 	if err != nil {
-		if strings.Contains(err.Error(), "not found") {
+		if retryAfter, limited := core.RateLimited(err); limited {
+			core.WriteRateLimited(w, r, retryAfter)
+			return
+		}
+		if errors.Is(err, state.ErrItemNotFound) {
 			// Could redirect with a message that the item was already deleted
 			w.Header().Set("Location", "/petrock_example_feature_name")
 			w.WriteHeader(http.StatusSeeOther)
@@ -1,12 +1,14 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
-	"strings"
 
+	"github.com/petrock/example_module_path/core"
 	"github.com/petrock/example_module_path/petrock_example_feature_name/queries"
+	"github.com/petrock/example_module_path/petrock_example_feature_name/state"
 )
 
 // HandleGetItem handles requests to retrieve a single item.
@@ -19,6 +21,11 @@ func (fs *FeatureServer) HandleGetItem(w http.ResponseWriter, r *http.Request) {
 	}
 	slog.Debug("HandleGetItem called", "feature", "petrock_example_feature_name", "id", itemID)
 
+	if core.WantsJSON(r) {
+		fs.respondItem(w, r, http.StatusOK, itemID)
+		return
+	}
+
 	// Check for success message in query parameters
 	successAction := r.URL.Query().Get("success")
 	var successMsg string
@@ -33,7 +40,7 @@ func (fs *FeatureServer) HandleGetItem(w http.ResponseWriter, r *http.Request) {
 	result, err := fs.querier.HandleGet(r.Context(), query)
 	if err != nil {
 		// Handle not found error
-		if strings.Contains(err.Error(), "not found") {
+		if errors.Is(err, state.ErrItemNotFound) {
 			slog.Warn("Item not found", "feature", "petrock_example_feature_name", "id", itemID)
 			http.Error(w, "Not Found", http.StatusNotFound)
 			return
@@ -62,3 +69,9 @@ func (fs *FeatureServer) HandleGetItem(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Error rendering view", http.StatusInternalServerError)
 	}
 }
+
+// HandleGetItemAPI handles API requests to retrieve a single item as JSON.
+// Example route: GET /api/petrock_example_feature_name/{id}
+func (fs *FeatureServer) HandleGetItemAPI(w http.ResponseWriter, r *http.Request) {
+	fs.respondItem(w, r, http.StatusOK, r.PathValue("id"))
+}
@@ -5,6 +5,7 @@ import (
 	"log/slog"
 	"net/http"
 
+	"github.com/petrock/example_module_path/core"
 	"github.com/petrock/example_module_path/petrock_example_feature_name/queries"
 )
 
@@ -13,6 +14,11 @@ import (
 func (fs *FeatureServer) HandleListItems(w http.ResponseWriter, r *http.Request) {
 	slog.Debug("HandleListItems called", "feature", "petrock_example_feature_name")
 
+	if core.WantsJSON(r) {
+		fs.HandleListItemsAPI(w, r)
+		return
+	}
+
 	// Parse query parameters for filtering/pagination (example)
 	page := ParseIntParam(r.URL.Query().Get("page"), 1)
 	pageSize := ParseIntParam(r.URL.Query().Get("pageSize"), 20)
@@ -58,8 +64,35 @@ func (fs *FeatureServer) HandleListItems(w http.ResponseWriter, r *http.Request)
 	pageTitle := "All Items"
 
 	// Render the page with our helper and success message if present
-	if err := RenderPageWithSuccess(w, pageTitle, ItemsListView(*listResult), successMsg); err != nil {
+	if err := RenderPageWithSuccess(w, pageTitle, ItemsListView(*listResult, filter), successMsg); err != nil {
 		slog.Error("Error rendering list view", "error", err)
 		http.Error(w, "Error rendering view", http.StatusInternalServerError)
 	}
 }
+
+// HandleListItemsAPI handles API requests to list items as JSON. It takes the fields
+// of ListQuery as query parameters, with page 1 and 20 items per page by default.
+// Example route: GET /api/petrock_example_feature_name
+func (fs *FeatureServer) HandleListItemsAPI(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	if params.Get("page") == "" {
+		params.Set("page", "1")
+	}
+	if params.Get("page_size") == "" {
+		params.Set("page_size", "20")
+	}
+
+	var query queries.ListQuery
+	if err := core.ParseFromURLValues(params, &query); err != nil {
+		RespondParseError(w, err)
+		return
+	}
+
+	result, err := fs.querier.HandleList(r.Context(), query)
+	if err != nil {
+		slog.Error("Error handling ListQuery", "error", err)
+		RespondJSON(w, http.StatusInternalServerError, map[string]string{"error": "Internal Server Error"})
+		return
+	}
+	RespondJSON(w, http.StatusOK, result)
+}
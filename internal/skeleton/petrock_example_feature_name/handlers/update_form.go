@@ -1,16 +1,17 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
-	"strings"
 	"time"
 
 	"github.com/petrock/example_module_path/core"
 	"github.com/petrock/example_module_path/core/ui"
 	"github.com/petrock/example_module_path/petrock_example_feature_name/commands"
 	"github.com/petrock/example_module_path/petrock_example_feature_name/queries"
+	"github.com/petrock/example_module_path/petrock_example_feature_name/state"
 )
 
 // HandleEditForm handles requests to display a form for editing an existing item.
@@ -27,7 +28,7 @@ func (fs *FeatureServer) HandleEditForm(w http.ResponseWriter, r *http.Request)
 	query := queries.GetQuery{ID: itemID}
 	result, err := fs.querier.HandleGet(r.Context(), query)
 	if err != nil {
-		if strings.Contains(err.Error(), "not found") {
+		if errors.Is(err, state.ErrItemNotFound) {
 			http.Error(w, "Item not found", http.StatusNotFound)
 			return
 		}
@@ -39,9 +40,6 @@ func (fs *FeatureServer) HandleEditForm(w http.ResponseWriter, r *http.Request)
 	// Create empty form data
 	formData := ui.NewFormData(nil, nil)
 
-	// Get CSRF token
-	csrfToken := "token" // Replace with actual CSRF token generation
-
 	// Cast the result to the correct type
 	item, ok := result.(*queries.GetQueryResult)
 	if !ok {
@@ -55,7 +53,7 @@ func (fs *FeatureServer) HandleEditForm(w http.ResponseWriter, r *http.Request)
 	pageTitle := fmt.Sprintf("Edit %s", item.Item.Name)
 
 	// Render the page with our helper
-	if err := RenderPage(w, pageTitle, ItemForm(formData, &item.Item, csrfToken)); err != nil {
+	if err := RenderPage(w, pageTitle, ItemForm(r.Context(), formData, &item.Item)); err != nil {
 		slog.Error("Error rendering edit form", "error", err)
 		http.Error(w, "Error rendering form", http.StatusInternalServerError)
 	}
@@ -71,6 +69,12 @@ func (fs *FeatureServer) HandleUpdateForm(w http.ResponseWriter, r *http.Request
 	}
 	slog.Debug("HandleUpdateForm called", "feature", "petrock_example_feature_name", "id", itemID)
 
+	// API clients get the JSON response of the REST endpoint instead of a redirect
+	if core.WantsJSON(r) {
+		fs.HandleUpdateItem(w, r)
+		return
+	}
+
 	// Parse the form
 	if err := r.ParseForm(); err != nil {
 		slog.Error("Failed to parse form", "error", err)
@@ -118,10 +122,9 @@ func (fs *FeatureServer) HandleUpdateForm(w http.ResponseWriter, r *http.Request
 
 			// Create page title for validation error
 			pageTitle := fmt.Sprintf("Edit %s", item.Item.Name)
-			csrfToken := "token" // Replace with actual CSRF token
 
 			// Render the page with validation errors
-			if err := RenderPage(w, pageTitle, ItemForm(formData, &item.Item, csrfToken)); err != nil {
+			if err := RenderPage(w, pageTitle, ItemForm(r.Context(), formData, &item.Item)); err != nil {
 				slog.Error("Error rendering form with validation errors", "error", err)
 				http.Error(w, "Error rendering form", http.StatusInternalServerError)
 			}
@@ -135,14 +138,19 @@ func (fs *FeatureServer) HandleUpdateForm(w http.ResponseWriter, r *http.Request
 	}
 
 	// Set additional fields not from form
-	cmd.UpdatedBy = "user" // Replace with actual user ID if authentication is implemented
+	cmd.UpdatedBy = core.PrincipalID(r.Context()) // Empty for anonymous requests
 	cmd.UpdatedAt = time.Now().UTC()
 
 	// Execute the command
 	err := fs.app.Executor.Execute(r.Context(), &cmd)
 	if err != nil {
+		if retryAfter, limited := core.RateLimited(err); limited {
+			core.WriteRateLimited(w, r, retryAfter)
+			return
+		}
 		// Check if it's a validation error
-		if strings.Contains(err.Error(), "validation failed") || strings.Contains(err.Error(), "not found") {
+		var validationErr *core.ValidationError
+		if errors.As(err, &validationErr) {
 			// Create FormData with validation error
 			uiErrors := []ui.ParseError{
 				{
@@ -172,10 +180,9 @@ func (fs *FeatureServer) HandleUpdateForm(w http.ResponseWriter, r *http.Request
 
 			// Create page title for validation error
 			pageTitle := fmt.Sprintf("Edit %s", item.Item.Name)
-			csrfToken := "token" // Replace with actual CSRF token
 
 			// Render the page with validation errors
-			if err := RenderPage(w, pageTitle, ItemForm(formData, &item.Item, csrfToken)); err != nil {
+			if err := RenderPage(w, pageTitle, ItemForm(r.Context(), formData, &item.Item)); err != nil {
 				slog.Error("Error rendering form with validation errors", "error", err)
 				http.Error(w, "Error rendering form", http.StatusInternalServerError)
 			}
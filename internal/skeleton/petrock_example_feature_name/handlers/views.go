@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"context"
+
 	g "maragu.dev/gomponents"
 	
 	"github.com/petrock/example_module_path/petrock_example_feature_name/queries"
@@ -12,8 +14,9 @@ func ItemView(item queries.ItemResult) g.Node {
 	return pages.ItemView(pages.Result(item))
 }
 
-// ItemsListView renders a list of items, potentially with pagination.
-func ItemsListView(result queries.ListQueryResult) g.Node {
+// ItemsListView renders a list of items, potentially with pagination. filter is the
+// filter the list was queried with.
+func ItemsListView(result queries.ListQueryResult, filter string) g.Node {
 	// Convert from queries.ListQueryResult to pages.ListResult
 	pageResult := pages.ListResult{
 		Page:       result.Page,
@@ -27,25 +30,26 @@ func ItemsListView(result queries.ListQueryResult) g.Node {
 		pageResult.Items[i] = pages.Result(item)
 	}
 	
-	return pages.ItemsListView(pageResult)
+	// Keep the list current as items are created, updated or summarized by the worker
+	return pages.ItemsListViewWithOptions(pageResult, pages.ListViewOptions{Live: true, Filter: filter})
 }
 
 // ItemForm renders an HTML <form> for creating or editing an item.
-func ItemForm(form interface{}, item *queries.ItemResult, csrfToken string) g.Node {
+func ItemForm(ctx context.Context, form interface{}, item *queries.ItemResult) g.Node {
 	var pageItem *pages.Result
 	if item != nil {
 		converted := pages.Result(*item)
 		pageItem = &converted
 	}
-	return pages.EditForm(form, pageItem, csrfToken)
+	return pages.EditForm(ctx, form, pageItem)
 }
 
 // DeleteConfirmForm renders a form to confirm deletion of an item.
-func DeleteConfirmForm(item *queries.ItemResult, csrfToken string) g.Node {
+func DeleteConfirmForm(ctx context.Context, item *queries.ItemResult) g.Node {
 	var pageItem *pages.Result
 	if item != nil {
 		converted := pages.Result(*item)
 		pageItem = &converted
 	}
-	return pages.DeleteForm(pageItem, csrfToken)
+	return pages.DeleteForm(ctx, pageItem)
 }
@@ -2,6 +2,7 @@ package petrock_example_feature_name
 
 import (
 	"log/slog"
+	"time"
 
 	"github.com/petrock/example_module_path/core" // Placeholder for target project's core package
 	"github.com/petrock/example_module_path/petrock_example_feature_name/commands"
@@ -73,6 +74,13 @@ func RegisterFeature(app *core.App, featureState *state.State) {
 	app.CommandRegistry.Register(&commands.UpdateCommand{}, featureExecutor.HandleUpdate, featureExecutor)
 	app.CommandRegistry.Register(&commands.DeleteCommand{}, featureExecutor.HandleDelete, featureExecutor)
 
+	// Limit how fast each user, or each IP for anonymous requests, can change items.
+	// Commands from workers are not limited.
+	for _, cmd := range []core.Command{&commands.CreateCommand{}, &commands.UpdateCommand{}, &commands.DeleteCommand{}} {
+		app.CommandRegistry.SetRateLimits(cmd.CommandName(),
+			core.RateLimit{Key: core.RateLimitByPrincipal, Requests: 30, Per: time.Minute})
+	}
+
 	// Register summary-related commands
 	app.CommandRegistry.Register(&commands.RequestSummaryGenerationCommand{}, featureExecutor.HandleRequestSummaryGeneration, featureExecutor)
 	app.CommandRegistry.Register(&commands.FailSummaryGenerationCommand{}, featureExecutor.HandleFailSummaryGeneration, featureExecutor)
@@ -92,9 +100,18 @@ func RegisterFeature(app *core.App, featureState *state.State) {
 	// --- 7. Register Worker ---
 	// Initialize and register the worker with the app
 	slog.Debug("Registering worker", "feature", "petrock_example_feature_name")
-	worker := workers.NewWorker(app, featureState, app.MessageLog, app.Executor)
+	// Its settings come from the [petrock_example_feature_name.summarization] config section
+	workerConfig := workers.DefaultConfig()
+	app.Config.Section(workers.ConfigSection, &workerConfig)
+	worker := workers.NewWorker(app, featureState, app.MessageLog, app.Executor, workerConfig)
 	app.RegisterWorker(worker)
 
+	// --- 8. Register Metrics ---
+	// Served at /_/metrics with the core's metrics
+	app.Metrics.GaugeFunc("petrock_example_feature_name_items", "Number of petrock_example_feature_name items", func() float64 {
+		return float64(featureState.Count())
+	})
+
 	slog.Info("Feature registered successfully", "feature", "petrock_example_feature_name")
 }
 
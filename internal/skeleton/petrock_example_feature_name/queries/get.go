@@ -6,10 +6,11 @@ import (
 	"log/slog"
 
 	"github.com/petrock/example_module_path/core" // Placeholder for target project's core package
+	"github.com/petrock/example_module_path/petrock_example_feature_name/state"
 )
 
 // Ensure query and result implement the marker interfaces
-var _ core.Query = (*GetQuery)(nil)
+var _ core.TypedQuery = (*GetQuery)(nil)
 var _ core.QueryResult = (*GetQueryResult)(nil)
 
 // GetQuery holds data needed to retrieve a single entity.
@@ -22,6 +23,11 @@ func (q GetQuery) QueryName() string {
 	return "petrock_example_feature_name/get" // Removed suffix
 }
 
+// NewResult returns an empty GetQueryResult, the type HandleGet returns.
+func (q GetQuery) NewResult() core.QueryResult {
+	return &GetQueryResult{}
+}
+
 // GetQueryResult wraps an ItemResult as a specific result type for GetQuery
 type GetQueryResult struct {
 	Item ItemResult `json:"item"`
@@ -45,8 +51,7 @@ func (q *Querier) HandleGet(ctx context.Context, query core.Query) (core.QueryRe
 	// 1. Retrieve item from state
 	item, found := q.state.GetItem(getQuery.ID)
 	if !found {
-		// Return a specific "not found" error if defined, otherwise a generic one
-		return nil, fmt.Errorf("item with ID %s not found", getQuery.ID)
+		return nil, fmt.Errorf("%w with ID %q", state.ErrItemNotFound, getQuery.ID)
 	}
 
 	// 2. Map internal state representation to the QueryResult struct
@@ -9,7 +9,7 @@ import (
 )
 
 // Ensure query and result implement the marker interfaces
-var _ core.Query = (*ListQuery)(nil)
+var _ core.TypedQuery = (*ListQuery)(nil)
 var _ core.QueryResult = (*ListQueryResult)(nil)
 
 // ListQuery holds data needed to retrieve a list of entities, possibly filtered or paginated.
@@ -24,6 +24,11 @@ func (q ListQuery) QueryName() string {
 	return "petrock_example_feature_name/list" // Removed suffix
 }
 
+// NewResult returns an empty ListQueryResult, the type HandleList returns.
+func (q ListQuery) NewResult() core.QueryResult {
+	return &ListQueryResult{}
+}
+
 // ListQueryResult holds a list of entities and pagination details.
 type ListQueryResult struct {
 	Items      []ItemResult `json:"items"`
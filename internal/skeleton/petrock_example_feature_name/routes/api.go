@@ -7,21 +7,24 @@ import (
 	"github.com/petrock/example_module_path/petrock_example_feature_name/handlers"
 )
 
-// registerAPIRoutes registers the REST API routes for the feature
+// registerAPIRoutes registers the REST API routes for the feature. They execute the
+// same commands and queries as the web UI and POST /commands, and speak JSON.
 func registerAPIRoutes(app *core.App, deps *handlers.FeatureServer) {
-	apiPrefix := "/api/petrock_example_feature_name" // Base path for this feature's API routes
+	apiPrefix := handlers.APIPath
 	slog.Debug("Registering API routes", "feature", "petrock_example_feature_name", "prefix", apiPrefix)
 
-	// Example API routes - not implemented in the original code but showing as an example
-	// POST /api/petrock_example_feature_name - Create a new item via API
-	// app.RegisterRoute("POST "+apiPrefix, deps.HandleCreateItem)
+	// GET /api/petrock_example_feature_name - List items
+	app.RegisterRoute("GET "+apiPrefix, deps.HandleListItemsAPI)
 
-	// GET /api/petrock_example_feature_name/{id} - Get a specific item via API
-	// app.RegisterRoute("GET "+apiPrefix+"/{id}", deps.HandleGetItemAPI)
+	// POST /api/petrock_example_feature_name - Create an item; 201 with a Location header
+	app.RegisterRoute("POST "+apiPrefix, deps.HandleCreateItem)
 
-	// PUT /api/petrock_example_feature_name/{id} - Update a specific item via API
-	// app.RegisterRoute("PUT "+apiPrefix+"/{id}", deps.HandleUpdateItem)
+	// GET /api/petrock_example_feature_name/{id} - Get an item
+	app.RegisterRoute("GET "+apiPrefix+"/{id}", deps.HandleGetItemAPI)
 
-	// DELETE /api/petrock_example_feature_name/{id} - Delete a specific item via API
-	// app.RegisterRoute("DELETE "+apiPrefix+"/{id}", deps.HandleDeleteItem)
+	// PUT /api/petrock_example_feature_name/{id} - Update an item
+	app.RegisterRoute("PUT "+apiPrefix+"/{id}", deps.HandleUpdateItem)
+
+	// DELETE /api/petrock_example_feature_name/{id} - Delete an item; 204 on success
+	app.RegisterRoute("DELETE "+apiPrefix+"/{id}", deps.HandleDeleteItem)
 }
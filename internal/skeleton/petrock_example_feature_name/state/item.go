@@ -1,11 +1,20 @@
 package state
 
 import (
+	"errors"
 	"sort"
 	"strings"
 	"time"
 )
 
+// ErrItemNotFound is wrapped by the errors of commands and queries that need an item
+// that doesn't exist, so that handlers can answer 404 Not Found with errors.Is.
+var ErrItemNotFound = errors.New("item not found")
+
+// ErrItemExists is wrapped by the errors of commands that would create an item that
+// already exists, so that handlers can answer 409 Conflict with errors.Is.
+var ErrItemExists = errors.New("item already exists")
+
 // Item represents the internal state of a single entity managed by this feature.
 // Adapt fields based on the specific feature's needs.
 type Item struct {
@@ -32,6 +41,13 @@ func (s *State) GetItem(id string) (*Item, bool) {
 	return item, found
 }
 
+// Count returns the number of items.
+func (s *State) Count() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.Items)
+}
+
 // ListItems retrieves a slice of items, applying filtering and pagination.
 // Returns the slice of items for the current page and the total count of matching items.
 // Note: Basic filtering and pagination implemented here. More complex queries might need optimization.
@@ -12,7 +12,7 @@ func (s *State) AddItem(item *Item) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if _, exists := s.Items[item.ID]; exists {
-		return fmt.Errorf("item with ID %s already exists", item.ID)
+		return fmt.Errorf("%w with ID %q", ErrItemExists, item.ID)
 	}
 	s.Items[item.ID] = item
 	return nil
@@ -23,7 +23,7 @@ func (s *State) UpdateItem(item *Item) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if _, exists := s.Items[item.ID]; !exists {
-		return fmt.Errorf("item with ID %s not found for update", item.ID)
+		return fmt.Errorf("%w with ID %q", ErrItemNotFound, item.ID)
 	}
 	// Consider version checking here if needed
 	s.Items[item.ID] = item // Replace existing pointer
@@ -35,7 +35,7 @@ func (s *State) DeleteItem(id string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if _, exists := s.Items[id]; !exists {
-		return fmt.Errorf("item with ID %s not found for deletion", id)
+		return fmt.Errorf("%w with ID %q", ErrItemNotFound, id)
 	}
 	delete(s.Items, id)
 	return nil
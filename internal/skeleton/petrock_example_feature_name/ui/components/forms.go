@@ -1,6 +1,7 @@
 package components
 
 import (
+	"context"
 	"fmt"
 
 	g "maragu.dev/gomponents"
@@ -39,8 +40,8 @@ func NewItemButton() g.Node {
 // ItemForm renders an HTML <form> for creating or editing an item.
 // It uses ui.FormData for data and error handling with new ui components.
 // 'item' can be nil when creating a new item.
-// 'csrfToken' should be provided by the handler.
-func ItemForm(formData *ui.FormData, item *state.Item, csrfToken string) g.Node {
+// The CSRF field is taken from ctx, the context of the request being answered.
+func ItemForm(ctx context.Context, formData *ui.FormData, item *state.Item) g.Node {
 	// Determine if we're creating or editing
 	isEdit := item != nil
 	var title, submitLabel string
@@ -102,7 +103,7 @@ func ItemForm(formData *ui.FormData, item *state.Item, csrfToken string) g.Node
 					ui.CSSClass("space-y-6"),
 
 					// CSRF Token
-					ui.CSRFInput(csrfToken),
+					ui.CSRFField(ctx),
 
 					// Name field using new ui components
 					ui.FormGroupWithValidation(formData, "name", "Name",
@@ -153,7 +154,7 @@ func ItemForm(formData *ui.FormData, item *state.Item, csrfToken string) g.Node
 }
 
 // DeleteConfirmForm renders a form to confirm deletion of an item.
-func DeleteConfirmForm(item *state.Item, csrfToken string) g.Node {
+func DeleteConfirmForm(ctx context.Context, item *state.Item) g.Node {
 	return ui.Container(ui.ContainerProps{Variant: "default"},
 		// Navigation breadcrumbs
 		ui.Breadcrumbs(ui.BreadcrumbsProps{
@@ -202,7 +203,7 @@ func DeleteConfirmForm(item *state.Item, csrfToken string) g.Node {
 						ui.CSSClass("w-full"),
 
 						// CSRF Token
-						ui.CSRFInput(csrfToken),
+						ui.CSRFField(ctx),
 
 						ui.ButtonGroup(ui.ButtonGroupProps{
 							Orientation: "horizontal",
@@ -1,13 +1,15 @@
 package pages
 
 import (
+	"context"
+
 	g "maragu.dev/gomponents"
 	"maragu.dev/gomponents/html"
 	"github.com/petrock/example_module_path/core/ui"
 )
 
 // EditForm renders an HTML <form> for creating or editing an item.
-func EditForm(form interface{}, item *Result, csrfToken string) g.Node {
+func EditForm(ctx context.Context, form interface{}, item *Result) g.Node {
 	// Cast the form to FormData
 	var formData *ui.FormData
 	if fd, ok := form.(*ui.FormData); ok {
@@ -66,7 +68,7 @@ func EditForm(form interface{}, item *Result, csrfToken string) g.Node {
 					ui.CSSClass("space-y-6"),
 					
 					// CSRF protection
-					ui.CSRFInput(csrfToken),
+					ui.CSRFField(ctx),
 					
 					// Form fields with validation
 					ui.FormGroupWithValidation(formData, "name", "Name",
@@ -147,7 +149,7 @@ func EditForm(form interface{}, item *Result, csrfToken string) g.Node {
 }
 
 // DeleteForm renders a form to confirm deletion of an item.
-func DeleteForm(item *Result, csrfToken string) g.Node {
+func DeleteForm(ctx context.Context, item *Result) g.Node {
 	if item == nil {
 		return ui.Container(ui.ContainerProps{Variant: "default"},
 			ui.Alert(ui.AlertProps{
@@ -218,7 +220,7 @@ func DeleteForm(item *Result, csrfToken string) g.Node {
 						ui.CSSClass("w-full"),
 						
 						// CSRF protection
-						ui.CSRFInput(csrfToken),
+						ui.CSRFField(ctx),
 
 						ui.ButtonGroup(ui.ButtonGroupProps{
 							Orientation: "horizontal",
@@ -2,6 +2,8 @@ package workers
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"net/http"
 	"time"
 
@@ -16,20 +18,60 @@ type WorkerState struct {
 	apiURL           string                     // Configuration for external service
 	apiKey           string
 	client           *http.Client
+	outbox           *core.Outbox // Delivers API calls durably when an API URL is configured
+}
+
+// PendingCounts reports outstanding work for the worker status page (see core.PendingCounter)
+func (s *WorkerState) PendingCounts() map[string]int {
+	return map[string]int{"summaries": len(s.pendingSummaries)}
+}
+
+// Reset clears the pending summaries so that `worker replay` rebuilds them from the log (see core.Resetter)
+func (s *WorkerState) Reset() {
+	s.pendingSummaries = make(map[string]PendingSummary)
+}
+
+// ConfigSection names the config section holding the worker's Config.
+const ConfigSection = "petrock_example_feature_name.summarization"
+
+// Config holds the settings of the summarization API, loaded from the ConfigSection
+// of the application's configuration.
+type Config struct {
+	APIURL  string        `json:"api_url" description:"Summarization API endpoint; without it summaries are generated locally"`
+	APIKey  string        `json:"api_key" secret:"true" description:"Bearer token for the summarization API"`
+	Timeout time.Duration `json:"timeout" description:"Timeout of summarization API calls"`
+}
+
+// DefaultConfig returns the settings used when nothing is configured.
+func DefaultConfig() Config {
+	return Config{Timeout: 30 * time.Second}
 }
 
 // NewWorker creates a new worker instance using the core worker infrastructure
-func NewWorker(app *core.App, state *State, log *core.MessageLog, executor *core.Executor) core.Worker {
+func NewWorker(app *core.App, state *State, log *core.MessageLog, executor *core.Executor, cfg Config) core.Worker {
 	workerState := &WorkerState{
 		pendingSummaries: make(map[string]PendingSummary),
 		state:            state,
 		executor:         executor,
 		client: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout: cfg.Timeout,
 		},
-		// These values should be configured from environment variables in a real application
-		apiURL: "https://api.example.com/summarize",
-		apiKey: "YOUR_API_KEY",
+		// Without an API URL the worker generates fake summaries locally
+		apiURL: cfg.APIURL,
+		apiKey: cfg.APIKey,
+	}
+
+	// Calls to a real API go through the outbox, which retries them and feeds the
+	// response back as a command once delivered
+	if workerState.apiURL != "" {
+		workerState.outbox = app.UseOutbox()
+		workerState.outbox.OnResult(summaryResultHandler, handleSummaryResult)
+		workerState.outbox.RegisterCredentials(summaryCredentials, func(ctx context.Context) (map[string]string, error) {
+			if workerState.apiKey == "" {
+				return nil, nil
+			}
+			return map[string]string{"Authorization": "Bearer " + workerState.apiKey}, nil
+		})
 	}
 
 	worker := core.NewWorker(
@@ -55,6 +97,18 @@ func NewWorker(app *core.App, state *State, log *core.MessageLog, executor *core
 		return handleSummarySetCommand(ctx, cmd, msg, workerState, pctx)
 	})
 
+	// Declare when Work() runs; without a schedule the worker runs every second (plus jitter).
+	// worker.SetSchedule(core.Every(time.Minute))    // fixed interval
+	// worker.SetSchedule(core.MustCron("0 3 * * *")) // nightly at 03:00 local time
+	// worker.SetSchedule(core.OnEvents())            // only when new messages are logged
+
+	// Failing command handlers are retried with backoff before the message is dead-lettered;
+	// the default is core.DefaultRetryPolicy().
+	// worker.SetRetryPolicy(core.RetryPolicy{MaxAttempts: 3, InitialBackoff: 10 * time.Second, MaxBackoff: time.Minute, Multiplier: 2})
+
+	// Handle messages for different items concurrently (handlers must then guard shared state):
+	// worker.SetPartitioning(func(cmd core.Command) string { ... return the item ID ... }, 4)
+
 	// Set periodic work
 	worker.SetPeriodicWork(func(ctx context.Context) error {
 		return processPendingSummaries(ctx, worker.State().(*WorkerState))
@@ -62,3 +116,31 @@ func NewWorker(app *core.App, state *State, log *core.MessageLog, executor *core
 
 	return worker
 }
+
+// summaryResultHandler names the outbox result handler for summarization API calls
+const summaryResultHandler = "petrock_example_feature_name/summary-result"
+
+// summaryCredentials names the outbox credentials that add the API key to summarization
+// API calls when they are sent, so the key is never stored in the outbox
+const summaryCredentials = "petrock_example_feature_name/summary-api"
+
+// handleSummaryResult turns the outcome of a summarization API call into a command
+func handleSummaryResult(ctx context.Context, entry core.OutboxEntry) (core.Command, error) {
+	itemID, requestID := entry.Meta["item_id"], entry.Meta["request_id"]
+
+	if entry.Status == core.OutboxFailed {
+		return &FailSummaryGenerationCommand{ID: itemID, RequestID: requestID, Reason: entry.LastError}, nil
+	}
+
+	var response core.HTTPResponse
+	if err := json.Unmarshal(entry.Result, &response); err != nil {
+		return nil, fmt.Errorf("invalid outbox result: %w", err)
+	}
+	var result struct {
+		Summary string `json:"summary"`
+	}
+	if err := json.Unmarshal([]byte(response.Body), &result); err != nil || result.Summary == "" {
+		return &FailSummaryGenerationCommand{ID: itemID, RequestID: requestID, Reason: "invalid API response"}, nil
+	}
+	return &SetGeneratedSummaryCommand{ID: itemID, RequestID: requestID, Summary: result.Summary}, nil
+}
@@ -2,9 +2,11 @@ package workers
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log/slog"
 	"math/rand"
+	"net/http"
 	"time"
 
 	"github.com/petrock/example_module_path/core" // Placeholder for target project's core package
@@ -26,15 +28,17 @@ func handleCreateCommand(ctx context.Context, cmd core.Command, msg *core.Messag
 		return nil
 	}
 
-	// Request summarization for the new item's content
-	requestID := fmt.Sprintf("req-%d", time.Now().UnixNano())
+	// Request summarization for the new item's content; deriving the request ID from
+	// the message keeps it stable if the handler is retried
+	requestID := fmt.Sprintf("req-%d", msg.ID)
 	summarizeCmd := &RequestSummaryGenerationCommand{
 		ID:        createCmd.Name, // Using name as ID from our CreateCommand
 		RequestID: requestID,
 	}
 
-	// Use a separate context with longer timeout for command execution
-	execCtx, execCancel := context.WithTimeout(context.Background(), 60*time.Second)
+	// Use a separate context with longer timeout for command execution; it keeps the
+	// trace of ctx so the command shows up under the message that caused it
+	execCtx, execCancel := context.WithTimeout(context.WithoutCancel(ctx), 60*time.Second)
 	defer execCancel()
 
 	if err := workerState.executor.Execute(execCtx, summarizeCmd); err != nil {
@@ -73,7 +77,7 @@ func handleSummaryRequestCommand(ctx context.Context, cmd core.Command, msg *cor
 		RequestID: requestCmd.RequestID,
 		ItemID:    requestCmd.ID,
 		Content:   item.Content,
-		CreatedAt: time.Now(),
+		CreatedAt: core.Now(ctx),
 	}
 
 	// Skip side effects during replay
@@ -157,12 +161,13 @@ func processPendingSummaries(ctx context.Context, workerState *WorkerState) erro
 	// Process each pending summary
 	for _, summary := range summariesToProcess {
 		// Skip if older than 24 hours (prevent infinite retries)
-		if time.Since(summary.CreatedAt) > 24*time.Hour {
+		age := core.Now(ctx).Sub(summary.CreatedAt)
+		if age > 24*time.Hour {
 			slog.Warn("Abandoning old summary request",
 				"feature", "petrock_example_feature_name",
 				"itemID", summary.ItemID,
 				"requestID", summary.RequestID,
-				"age", time.Since(summary.CreatedAt))
+				"age", age)
 
 			// Send a failure command
 			failCmd := &FailSummaryGenerationCommand{
@@ -197,7 +202,11 @@ func processPendingSummaries(ctx context.Context, workerState *WorkerState) erro
 
 // callSummarizationAPI calls the external API to generate a summary
 func callSummarizationAPI(ctx context.Context, workerState *WorkerState, summary PendingSummary) error {
-	// This is a mock implementation - in a real application, this would call an actual API
+	if workerState.outbox != nil {
+		return enqueueSummarizationRequest(ctx, workerState, summary)
+	}
+
+	// Without a configured API this is a mock implementation
 
 	// Simulate API call with a random delay between 500ms and 1.5s
 	delay := time.Duration(500+rand.Intn(1000)) * time.Millisecond
@@ -207,45 +216,6 @@ func callSummarizationAPI(ctx context.Context, workerState *WorkerState, summary
 		"delay", delay.String())
 	time.Sleep(delay)
 
-	// In a real implementation, you would make an actual HTTP request:
-	//
-	// type SummarizeRequest struct {
-	// 	Content string `json:"content"`
-	// }
-	//
-	// type SummarizeResponse struct {
-	// 	Summary string `json:"summary"`
-	// }
-	//
-	// payload := SummarizeRequest{Content: summary.Content}
-	// jsonData, err := json.Marshal(payload)
-	// if err != nil {
-	// 	return fmt.Errorf("failed to marshal request: %w", err)
-	// }
-	//
-	// req, err := http.NewRequestWithContext(ctx, "POST", workerState.apiURL, bytes.NewBuffer(jsonData))
-	// if err != nil {
-	// 	return fmt.Errorf("failed to create request: %w", err)
-	// }
-	//
-	// req.Header.Set("Content-Type", "application/json")
-	// req.Header.Set("Authorization", "Bearer "+workerState.apiKey)
-	//
-	// resp, err := workerState.client.Do(req)
-	// if err != nil {
-	// 	return fmt.Errorf("API request failed: %w", err)
-	// }
-	// defer resp.Body.Close()
-	//
-	// if resp.StatusCode != http.StatusOK {
-	// 	return fmt.Errorf("API returned non-200 status: %d", resp.StatusCode)
-	// }
-	//
-	// var result SummarizeResponse
-	// if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-	// 	return fmt.Errorf("failed to decode response: %w", err)
-	// }
-
 	// For demo purposes, generate a fake summary
 	fakeSummary := fmt.Sprintf("This is a concise summary of the content for item '%s'. The original text has been analyzed and condensed to capture the key points while maintaining clarity and context.", summary.ItemID)
 
@@ -271,3 +241,39 @@ func callSummarizationAPI(ctx context.Context, workerState *WorkerState, summary
 
 	return nil
 }
+
+// enqueueSummarizationRequest records the API call in the outbox. The request ID is the
+// idempotency key, so enqueuing the same pending summary on every cycle is a no-op; the
+// outbox worker delivers it and handleSummaryResult records the outcome.
+func enqueueSummarizationRequest(ctx context.Context, workerState *WorkerState, summary PendingSummary) error {
+	body, err := json.Marshal(map[string]string{"content": summary.Content})
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	request := core.HTTPRequest{
+		Method:      http.MethodPost,
+		URL:         workerState.apiURL,
+		Header:      map[string]string{"Content-Type": "application/json"},
+		Body:        string(body),
+		Credentials: summaryCredentials,
+	}
+
+	_, created, err := workerState.outbox.EnqueueHTTP(ctx,
+		"petrock_example_feature_name-summary-"+summary.RequestID,
+		"petrock_example_feature_name Worker",
+		request,
+		summaryResultHandler,
+		map[string]string{"item_id": summary.ItemID, "request_id": summary.RequestID})
+	if err != nil {
+		return err
+	}
+
+	if created {
+		slog.Info("Queued summarization API call",
+			"feature", "petrock_example_feature_name",
+			"itemID", summary.ItemID,
+			"requestID", summary.RequestID)
+	}
+	return nil
+}
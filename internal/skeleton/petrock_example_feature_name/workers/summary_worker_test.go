@@ -0,0 +1,83 @@
+package workers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/petrock/example_module_path/core"
+	"github.com/petrock/example_module_path/core/workertest"
+	"github.com/petrock/example_module_path/petrock_example_feature_name/state"
+)
+
+// newTestWorker starts the worker in a harness, with one item in the application state
+func newTestWorker(t *testing.T) *workertest.Harness {
+	appState := state.NewState()
+	if err := appState.AddItem(&Item{ID: "first-post", Name: "first-post", Content: "Content to be summarized"}); err != nil {
+		t.Fatalf("AddItem failed: %v", err)
+	}
+
+	// Without an API URL the worker uses the mock API, not the outbox
+	h := workertest.New(t)
+	h.Start(NewWorker(nil, appState, nil, h.Executor, DefaultConfig()).(*core.CommandWorker))
+	return h
+}
+
+func pendingSummaries(h *workertest.Harness) int {
+	return h.State().(*WorkerState).PendingCounts()["summaries"]
+}
+
+func TestSummaryWorker_RequestsSummaryForNewItems(t *testing.T) {
+	h := newTestWorker(t)
+
+	h.Live(&CreateCommand{Name: "first-post", Content: "Content to be summarized"})
+
+	executed := h.Executed()
+	if len(executed) != 1 {
+		t.Fatalf("Expected one command, got %d", len(executed))
+	}
+	request, ok := executed[0].(*RequestSummaryGenerationCommand)
+	if !ok || request.ID != "first-post" || request.RequestID != "req-1" {
+		t.Fatalf("Unexpected command: %#v", executed[0])
+	}
+
+	// Once the request is logged, the worker tracks it as pending
+	h.Loopback()
+	if got := pendingSummaries(h); got != 1 {
+		t.Errorf("Expected 1 pending summary, got %d", got)
+	}
+}
+
+func TestSummaryWorker_ReplayHasNoSideEffects(t *testing.T) {
+	h := newTestWorker(t)
+
+	h.Replay(
+		&CreateCommand{Name: "first-post", Content: "Content to be summarized"},
+		&RequestSummaryGenerationCommand{ID: "first-post", RequestID: "req-1"},
+	)
+
+	if executed := h.Executed(); len(executed) != 0 {
+		t.Errorf("Expected no commands during replay, got %v", executed)
+	}
+	if got := pendingSummaries(h); got != 1 {
+		t.Errorf("Expected replay to rebuild 1 pending summary, got %d", got)
+	}
+}
+
+func TestSummaryWorker_AbandonsRequestsAfterADay(t *testing.T) {
+	h := newTestWorker(t)
+	h.Replay(&RequestSummaryGenerationCommand{ID: "first-post", RequestID: "req-1"})
+
+	h.Advance(25 * time.Hour)
+	h.Work()
+
+	executed := h.Loopback()
+	if len(executed) != 1 {
+		t.Fatalf("Expected one command, got %d", len(executed))
+	}
+	if fail, ok := executed[0].(*FailSummaryGenerationCommand); !ok || fail.RequestID != "req-1" || fail.Reason != "timeout" {
+		t.Errorf("Unexpected command: %#v", executed[0])
+	}
+	if got := pendingSummaries(h); got != 0 {
+		t.Errorf("Expected no pending summaries, got %d", got)
+	}
+}